@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/kirsrus/iso2repo/internal/repo"
+	"github.com/kirsrus/iso2repo/pkg/logging"
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/slog"
+)
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle <пакет>...",
+	Short: "Собрать пакеты со всеми зависимостями для переноса в изолированную сеть",
+	Long: `Вычисляет замыкание зависимостей (Pre-Depends, Depends, с --recommends — и Recommends)
+указанных пакетов по репозиториям корневой директории и записывает пакеты вместе с индексами
+Packages и Release (плоский APT-репозиторий) в архив .tar, .zip или в директорию — по
+расширению --out. Директория с суффиксом .iso, помещённая в корневую директорию, обслуживается
+iso2repo как пользовательская папка; комплект также подключается напрямую строкой
+"deb [trusted=yes] file:/путь ./". Пакет указывается именем или в виде имя=версия.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runBundle,
+}
+
+func init() {
+	bundleCmd.Flags().String(FlagArch, "amd64", "архитектура комплекта")
+	bundleCmd.Flags().StringSlice(FlagRepos, nil, "репозитории-источники через запятую (по умолчанию все)")
+	bundleCmd.Flags().String(FlagOut, "", "файл .tar, .zip или директория комплекта")
+	bundleCmd.Flags().Bool(FlagRecommends, false, "включать в комплект Recommends")
+	bundleCmd.Flags().Bool(FlagDryRun, false, "только вывести состав комплекта")
+
+	rootCmd.AddCommand(bundleCmd)
+}
+
+// runBundle собирает комплект пакетов args.
+func runBundle(cmd *cobra.Command, args []string) error {
+	err := func() error {
+		levelFlag, _ := cmd.Flags().GetString(FlagLevel)
+		log := logging.NewTintLogging(levelFlag)
+
+		arch, _ := cmd.Flags().GetString(FlagArch)
+		names, _ := cmd.Flags().GetStringSlice(FlagRepos)
+		out, _ := cmd.Flags().GetString(FlagOut)
+		recommends, _ := cmd.Flags().GetBool(FlagRecommends)
+		dryRun, _ := cmd.Flags().GetBool(FlagDryRun)
+		if out == "" && !dryRun {
+			return errors.Newf("не указан --%s", FlagOut)
+		}
+
+		rootDir, err := resolveRootDir(cmd, log)
+		if err != nil {
+			return err
+		}
+		repos, err := repo.Discover(rootDir, log, customOptions(cmd))
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		catalog := repo.NewCatalog(log)
+		selected := make(map[string]bool)
+		for _, name := range names {
+			selected[name] = true
+		}
+		for _, r := range repos {
+			if len(selected) > 0 && !selected[r.Metadata().Name] {
+				continue
+			}
+			if err := catalog.Update(ctx, r); err != nil {
+				log.Warn("репозиторий пропущен", slog.String("repo", r.Metadata().Name), slog.String("error", err.Error()))
+			}
+		}
+
+		bundle, err := catalog.ResolveBundle(repo.BundleQuery{
+			Packages:   args,
+			Repos:      names,
+			Arch:       arch,
+			Recommends: recommends,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, e := range bundle.Packages {
+			fmt.Printf("%s %s %s (%s)\n", e.Name, e.Version, e.Arch, e.Repo)
+		}
+		for _, u := range bundle.Unmet {
+			reason := ""
+			if u.Reason != "" {
+				reason = ": " + u.Reason
+			}
+			fmt.Fprintf(os.Stderr, "не удовлетворена зависимость %s %s: %s %s%s\n", u.Name, u.Version, u.Field, u.Relation, reason)
+		}
+		fmt.Printf("пакетов в комплекте: %d, неудовлетворённых зависимостей: %d\n", len(bundle.Packages), len(bundle.Unmet))
+		if dryRun {
+			return nil
+		}
+
+		var w repo.BundleWriter
+		switch strings.ToLower(filepath.Ext(out)) {
+		case ".tar", ".zip":
+			f, err := os.Create(out)
+			if err != nil {
+				return errors.Wrapf(err, "не удалось создать %s", out)
+			}
+			defer f.Close()
+			if strings.EqualFold(filepath.Ext(out), ".zip") {
+				w = repo.NewZipBundleWriter(f)
+			} else {
+				w = repo.NewTarBundleWriter(f)
+			}
+		default:
+			if w, err = repo.NewDirBundleWriter(out); err != nil {
+				return err
+			}
+		}
+
+		if err := catalog.WriteBundle(ctx, bundle, w); err != nil {
+			return err
+		}
+		fmt.Printf("комплект записан в %s\n", out)
+
+		return nil
+	}()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+	}
+
+	return err
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cockroachdb/errors"
+	"github.com/kirsrus/iso2repo/internal/repo"
+	"github.com/kirsrus/iso2repo/pkg/logging"
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <репозиторий A> <репозиторий B>",
+	Short: "Сравнить пакеты двух репозиториев",
+	Long: `Сравнивает индексы Packages двух репозиториев корневой директории, например двух
+версий ISO-образа поставщика: пакеты, добавленные в B, удалённые из A, обновлённые и
+пониженные — с версиями и размерами. Пакеты сопоставляются по имени и архитектуре;
+из нескольких версий пакета в репозитории сравнивается наибольшая.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runDiff,
+}
+
+func init() {
+	diffCmd.Flags().String(FlagFormat, repo.DiffText, "формат: text или json")
+	diffCmd.Flags().String(FlagOut, "", "файл результата (по умолчанию — стандартный вывод)")
+
+	rootCmd.AddCommand(diffCmd)
+}
+
+// runDiff сравнивает репозитории args[0] и args[1].
+func runDiff(cmd *cobra.Command, args []string) error {
+	err := func() error {
+		levelFlag, _ := cmd.Flags().GetString(FlagLevel)
+		log := logging.NewTintLogging(levelFlag)
+
+		format, _ := cmd.Flags().GetString(FlagFormat)
+		out, _ := cmd.Flags().GetString(FlagOut)
+		if format != repo.DiffText && format != repo.DiffJSON {
+			return errors.Newf("неизвестный формат отчёта %s", format)
+		}
+
+		rootDir, err := resolveRootDir(cmd, log)
+		if err != nil {
+			return err
+		}
+		repos, err := repo.Discover(rootDir, log, customOptions(cmd))
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		catalog := repo.NewCatalog(log)
+		found := make(map[string]bool)
+		for _, r := range repos {
+			name := r.Metadata().Name
+			if name != args[0] && name != args[1] {
+				continue
+			}
+			if err := catalog.Update(ctx, r); err != nil {
+				return err
+			}
+			found[name] = true
+		}
+		for _, name := range args {
+			if !found[name] {
+				return errors.Newf("репозиторий %s не найден в %s", name, rootDir)
+			}
+		}
+
+		diff, err := catalog.Diff(ctx, args[0], args[1])
+		if err != nil {
+			return err
+		}
+
+		var w io.Writer = os.Stdout
+		if out != "" {
+			f, err := os.Create(out)
+			if err != nil {
+				return errors.Wrapf(err, "не удалось создать %s", out)
+			}
+			defer f.Close()
+			w = f
+		}
+		if err := diff.Write(w, format); err != nil {
+			return errors.Wrap(err, "не удалось записать отчёт")
+		}
+		if out != "" {
+			fmt.Printf("отчёт о различиях (изменённых пакетов: %d) записан в %s\n", len(diff.Packages), out)
+		}
+
+		return nil
+	}()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+	}
+
+	return err
+}
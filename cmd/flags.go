@@ -12,4 +12,44 @@ const (
 	FlagPort = "port"
 	// Флаг для указания типа логирования.
 	FlagLogging = "logging"
+	// Режим карантина для пользовательских репозиториев.
+	FlagQuarantine = "quarantine"
+	// Глубина истории PDiff (Packages.diff) пользовательских репозиториев.
+	FlagPDiffDepth = "pdiff-depth"
+	// Путь к журналу аудита операций с пакетами.
+	FlagAuditLog = "audit-log"
+	// Директория локальных баз уязвимостей.
+	FlagVulnDir = "vuln-dir"
+	// Файл политики раздачи пакетов.
+	FlagPolicy = "policy"
+	// Закрытый ключ подписи Release, переписанных политикой.
+	FlagSigningKey = "signing-key"
+	// Файл описаний объединённых репозиториев.
+	FlagMerged = "merged"
+	// Максимальный размер загрузки пакетов через API.
+	FlagMaxUpload = "max-upload"
+	// Адрес сервера, по которому к нему обращаются клиенты.
+	FlagAddress = "address"
+	// Репозиторий, над которым выполняется операция.
+	FlagRepo = "repo"
+	// Репозиторий-источник при копировании и продвижении пакета.
+	FlagFrom = "from"
+	// Репозиторий-получатель при копировании и продвижении пакета.
+	FlagTo = "to"
+	// Версия пакета.
+	FlagVersion = "version"
+	// Архитектура пакета.
+	FlagArch = "arch"
+	// Репозитории-источники пакетов (через запятую).
+	FlagRepos = "repos"
+	// Файл или директория, куда записывается результат.
+	FlagOut = "out"
+	// Учитывать зависимости Recommends.
+	FlagRecommends = "recommends"
+	// Только показать результат, ничего не записывая.
+	FlagDryRun = "dry-run"
+	// Формат результата.
+	FlagFormat = "format"
+	// Определять лицензии пакетов по файлам copyright.
+	FlagLicenses = "licenses"
 )
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cockroachdb/errors"
+	"github.com/kirsrus/iso2repo/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint <директория>",
+	Short: "Проверить пакеты пользовательского репозитория",
+	Long: `Проверяет .deb, .udeb и .ddeb файлы директории (пользовательского репозитория) теми же
+проверками, что выполняет сервер при сканировании, и выводит найденные проблемы.
+Завершается с ошибкой, если найдена хотя бы одна ошибка.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runLint,
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+}
+
+// runLint сканирует директорию как пользовательский репозиторий и выводит проблемы пакетов.
+func runLint(cmd *cobra.Command, args []string) error {
+	dir, err := filepath.Abs(args[0])
+	if err == nil {
+		var info os.FileInfo
+		if info, err = os.Stat(dir); err == nil && !info.IsDir() {
+			err = errors.Newf("%s не является директорией", dir)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return err
+	}
+
+	// Проблемы выводятся ниже, поэтому предупреждения сканирования в лог не дублируем
+	findings := repo.NewRepoCustom(dir, nil, repo.CustomOptions{}).Lint()
+
+	errorsCount := 0
+	for _, f := range findings {
+		severity := "предупреждение"
+		if f.Severity == repo.LintError {
+			severity = "ошибка"
+			errorsCount++
+		}
+		fmt.Printf("%s: %s: %s [%s]\n", f.File, severity, f.Message, f.Check)
+	}
+	fmt.Printf("проверено: ошибок %d, предупреждений %d\n", errorsCount, len(findings)-errorsCount)
+
+	if errorsCount > 0 {
+		return errors.Newf("найдено ошибок: %d", errorsCount)
+	}
+
+	return nil
+}
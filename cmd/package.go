@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+
+	"github.com/cockroachdb/errors"
+	"github.com/kirsrus/iso2repo/internal/audit"
+	"github.com/kirsrus/iso2repo/internal/repo"
+	"github.com/kirsrus/iso2repo/models"
+	"github.com/kirsrus/iso2repo/pkg/logging"
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/slog"
+)
+
+// packageCmd группа команд управления пакетами пользовательских репозиториев.
+// Команды работают напрямую с файлами в корневой директории и не требуют
+// запущенного сервера: запущенный сервер подхватит изменения при очередном опросе.
+var packageCmd = &cobra.Command{
+	Use:   "package",
+	Short: "Управление пакетами пользовательских репозиториев",
+}
+
+var packageRemoveCmd = &cobra.Command{
+	Use:   "remove <пакет>",
+	Short: "Удалить версию пакета из пользовательского репозитория",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runPackageCommand(cmd, audit.ActionRemove, args[0])
+	},
+}
+
+var packageCopyCmd = &cobra.Command{
+	Use:   "copy <пакет>",
+	Short: "Скопировать пакет из любого репозитория в пользовательский",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runPackageCommand(cmd, audit.ActionCopy, args[0])
+	},
+}
+
+var packagePromoteCmd = &cobra.Command{
+	Use:   "promote <пакет>",
+	Short: "Продвинуть версию пакета из одного пользовательского репозитория в другой",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runPackageCommand(cmd, audit.ActionPromote, args[0])
+	},
+}
+
+func init() {
+	packageRemoveCmd.Flags().String(FlagRepo, "", "пользовательский репозиторий (например, custom.iso)")
+	_ = packageRemoveCmd.MarkFlagRequired(FlagRepo)
+
+	for _, c := range []*cobra.Command{packageCopyCmd, packagePromoteCmd} {
+		c.Flags().String(FlagFrom, "", "репозиторий-источник")
+		c.Flags().String(FlagTo, "", "пользовательский репозиторий-получатель")
+		_ = c.MarkFlagRequired(FlagFrom)
+		_ = c.MarkFlagRequired(FlagTo)
+	}
+
+	for _, c := range []*cobra.Command{packageRemoveCmd, packageCopyCmd, packagePromoteCmd} {
+		c.Flags().String(FlagVersion, "", "версия пакета")
+		c.Flags().String(FlagArch, "", "архитектура пакета")
+		packageCmd.AddCommand(c)
+	}
+	_ = packageRemoveCmd.MarkFlagRequired(FlagVersion)
+	_ = packagePromoteCmd.MarkFlagRequired(FlagVersion)
+
+	rootCmd.AddCommand(packageCmd)
+}
+
+// runPackageCommand выполняет операцию action над пакетом name и записывает её в журнал аудита.
+func runPackageCommand(cmd *cobra.Command, action audit.Action, name string) error {
+	levelFlag, _ := cmd.Flags().GetString(FlagLevel)
+	log := logging.NewTintLogging(levelFlag)
+
+	rootDir, err := resolveRootDir(cmd, log)
+	if err != nil {
+		return err
+	}
+
+	auditLog, err := openAuditLog(cmd, rootDir)
+	if err != nil {
+		return err
+	}
+
+	repos, err := repo.Discover(rootDir, log, customOptions(cmd))
+	if err != nil {
+		return err
+	}
+
+	version, _ := cmd.Flags().GetString(FlagVersion)
+	arch, _ := cmd.Flags().GetString(FlagArch)
+
+	entry := audit.Entry{
+		Action:  action,
+		Source:  "cli",
+		Actor:   currentUser(),
+		Package: name,
+		Version: version,
+		Arch:    arch,
+	}
+
+	err = func() error {
+		if action == audit.ActionRemove {
+			entry.Repo, _ = cmd.Flags().GetString(FlagRepo)
+			custom, err := findCustom(repos, entry.Repo)
+			if err != nil {
+				return err
+			}
+
+			removed, err := custom.RemovePackage(name, version, arch)
+			entry.Files = removed
+			for _, f := range removed {
+				fmt.Printf("удалён %s\n", f)
+			}
+
+			return err
+		}
+
+		entry.FromRepo, _ = cmd.Flags().GetString(FlagFrom)
+		entry.Repo, _ = cmd.Flags().GetString(FlagTo)
+
+		custom, err := findCustom(repos, entry.Repo)
+		if err != nil {
+			return err
+		}
+
+		var src models.Repoes
+		if action == audit.ActionPromote {
+			if src, err = findCustom(repos, entry.FromRepo); err != nil {
+				return err
+			}
+		} else {
+			var ok bool
+			if src, ok = repo.FindByName(repos, entry.FromRepo); !ok {
+				return errors.Newf("репозиторий %s не найден", entry.FromRepo)
+			}
+		}
+
+		file, result, err := custom.ImportPackage(context.Background(), src, name, version, arch)
+		if file.Name != "" {
+			entry.Files = []string{file.Name}
+		}
+		if err == nil {
+			fmt.Printf("%s: %s → %s\n", file.Name, entry.FromRepo, entry.Repo)
+			if len(result.Staged) > 0 {
+				fmt.Println("пакет помещён на карантин и ожидает одобрения")
+			}
+		}
+
+		return err
+	}()
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	if errAudit := auditLog.Record(entry); errAudit != nil {
+		log.Warn("не удалось записать операцию в журнал аудита", slog.Any("error", errAudit))
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+	}
+
+	return err
+}
+
+// findCustom ищет пользовательский репозиторий по имени.
+func findCustom(repos []models.Repoes, name string) (*repo.RepoCustom, error) {
+	r, ok := repo.FindByName(repos, name)
+	if !ok {
+		return nil, errors.Newf("репозиторий %s не найден", name)
+	}
+
+	custom, ok := r.(*repo.RepoCustom)
+	if !ok {
+		return nil, errors.Newf("репозиторий %s не является пользовательским", name)
+	}
+
+	return custom, nil
+}
+
+// currentUser возвращает имя пользователя ОС для журнала аудита.
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+
+	return os.Getenv("USER")
+}
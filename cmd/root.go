@@ -10,8 +10,12 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/cockroachdb/errors"
 	"github.com/gin-gonic/gin"
+	"github.com/kirsrus/iso2repo/internal/audit"
+	"github.com/kirsrus/iso2repo/internal/policy"
 	"github.com/kirsrus/iso2repo/internal/repo"
+	"github.com/kirsrus/iso2repo/internal/vuln"
 	"github.com/kirsrus/iso2repo/internal/watcher"
 	"github.com/kirsrus/iso2repo/internal/web"
 	"github.com/kirsrus/iso2repo/models"
@@ -26,6 +30,10 @@ var version string = "v0.0.0"
 
 const copyright = "Стерликов Кирилл @2023-2026"
 
+// Скрытая служебная директория внутри корневой директории с образами
+// (журнал аудита и прочие данные программы). Не отслеживается как репозиторий.
+const stateDir = ".iso2repo"
+
 var rootCmd = &cobra.Command{
 	Use:   "iso2repo",
 	Short: "HTTP-транслятор локальных APT-репозиториев",
@@ -52,6 +60,92 @@ func init() {
 	rootCmd.PersistentFlags().Duration(FlagPollInterval, 60*time.Second, "интервал опроса директории")
 	rootCmd.PersistentFlags().Int(FlagPort, 4309, "порт WEB-интерфейса")
 	rootCmd.PersistentFlags().Bool(FlagLogging, false, "серверное логирование")
+	rootCmd.PersistentFlags().Bool(FlagQuarantine, false, "карантин: новые пакеты пользовательских репозиториев публикуются только после одобрения")
+	rootCmd.PersistentFlags().Int(FlagPDiffDepth, 20, "сколько последних изменений Packages пользовательских репозиториев публиковать в виде PDiff (0 — отключить)")
+	rootCmd.PersistentFlags().String(FlagAuditLog, "", "журнал аудита операций с пакетами (по умолчанию <dir>/"+stateDir+"/audit.log)")
+	rootCmd.PersistentFlags().String(FlagVulnDir, "", "директория локальных баз уязвимостей (по умолчанию <dir>/"+stateDir+"/vulns)")
+	rootCmd.PersistentFlags().String(FlagPolicy, "", "файл политики раздачи пакетов (по умолчанию <dir>/"+stateDir+"/policy.yaml)")
+	rootCmd.PersistentFlags().String(FlagSigningKey, "", "ключ подписи Release, переписанных политикой; создаётся при отсутствии (по умолчанию <dir>/"+stateDir+"/signing-key.asc)")
+	rootCmd.PersistentFlags().String(FlagMerged, "", "файл описаний объединённых репозиториев (по умолчанию <dir>/"+stateDir+"/merged.yaml)")
+	rootCmd.PersistentFlags().Int64(FlagMaxUpload, web.DefaultMaxUploadSize>>20, "максимальный размер тела запроса загрузки пакетов через API, МиБ")
+	rootCmd.PersistentFlags().String(FlagAddress, web.DefaultAddress, "адрес сервера для клиентов в поле Changelogs файла Release пользовательских репозиториев")
+}
+
+// resolveRootDir возвращает корневую директорию с образами: из флага --dir или,
+// если он не задан, директорию исполняемого файла.
+func resolveRootDir(cmd *cobra.Command, log *slog.Logger) (string, error) {
+	rootDir, _ := cmd.Flags().GetString(FlagRootDir)
+	if rootDir != "" {
+		log.Info(fmt.Sprintf("установлена корневая директория с образами: %s", rootDir))
+		return rootDir, nil
+	}
+
+	execPath, err := os.Executable()
+	if err != nil {
+		return "", errors.Wrap(err, "не удалось определить путь к исполняемому файлу")
+	}
+	rootDir = filepath.Dir(execPath)
+	log.Info(fmt.Sprintf("автоматически определена корневая директория с образами: %s", rootDir))
+
+	return rootDir, nil
+}
+
+// customOptions собирает параметры пользовательских репозиториев из флагов.
+func customOptions(cmd *cobra.Command) repo.CustomOptions {
+	quarantine, _ := cmd.Flags().GetBool(FlagQuarantine)
+	pdiffDepth, _ := cmd.Flags().GetInt(FlagPDiffDepth)
+
+	return repo.CustomOptions{
+		Quarantine: quarantine,
+		PDiffDepth: pdiffDepth,
+	}
+}
+
+// openAuditLog открывает журнал аудита по флагу --audit-log или по пути по умолчанию.
+func openAuditLog(cmd *cobra.Command, rootDir string) (*audit.Log, error) {
+	auditPath, _ := cmd.Flags().GetString(FlagAuditLog)
+	if auditPath == "" {
+		auditPath = filepath.Join(rootDir, stateDir, "audit.log")
+	}
+
+	return audit.NewLog(auditPath)
+}
+
+// openVulnStore открывает хранилище баз уязвимостей по флагу --vuln-dir или по пути
+// по умолчанию.
+func openVulnStore(cmd *cobra.Command, rootDir string, log *slog.Logger) (*vuln.Store, error) {
+	dir, _ := cmd.Flags().GetString(FlagVulnDir)
+	if dir == "" {
+		dir = filepath.Join(rootDir, stateDir, "vulns")
+	}
+
+	return vuln.NewStore(dir, log)
+}
+
+// openPolicy открывает файл политики и ключ подписи по флагам --policy и
+// --signing-key или по путям по умолчанию.
+func openPolicy(cmd *cobra.Command, rootDir string, log *slog.Logger) (*policy.File, *policy.Signer) {
+	policyPath, _ := cmd.Flags().GetString(FlagPolicy)
+	if policyPath == "" {
+		policyPath = filepath.Join(rootDir, stateDir, "policy.yaml")
+	}
+	keyPath, _ := cmd.Flags().GetString(FlagSigningKey)
+	if keyPath == "" {
+		keyPath = filepath.Join(rootDir, stateDir, "signing-key.asc")
+	}
+
+	return policy.NewFile(policyPath, log), policy.NewSigner(keyPath)
+}
+
+// openMerged открывает файл описаний объединённых репозиториев по флагу --merged
+// или по пути по умолчанию.
+func openMerged(cmd *cobra.Command, rootDir string, log *slog.Logger) *repo.MergedFile {
+	mergedPath, _ := cmd.Flags().GetString(FlagMerged)
+	if mergedPath == "" {
+		mergedPath = filepath.Join(rootDir, stateDir, "merged.yaml")
+	}
+
+	return repo.NewMergedFile(mergedPath, log)
 }
 
 func rootRun(cmd *cobra.Command, _ []string) {
@@ -68,17 +162,35 @@ func rootRun(cmd *cobra.Command, _ []string) {
 
 	log.Info(fmt.Sprintf("программа iso2repo стартовала; версия %s", version))
 
-	rootDir, _ := cmd.Flags().GetString(FlagRootDir)
-	if rootDir == "" {
-		execPath, errExec := os.Executable()
-		if errExec != nil {
-			log.Error("не удалось определить путь к исполняемому файлу", err, slog.Any("error", errExec))
-			return
-		}
-		rootDir = filepath.Dir(execPath)
-		log.Info(fmt.Sprintf("автоматически определена корневая директория с образами: %s", rootDir))
-	} else {
-		log.Info(fmt.Sprintf("установлена корневая директория с образами: %s", rootDir))
+	rootDir, err := resolveRootDir(cmd, log)
+	if err != nil {
+		log.Error("не удалось определить корневую директорию", err, slog.Any("error", err))
+		return
+	}
+
+	auditLog, err := openAuditLog(cmd, rootDir)
+	if err != nil {
+		log.Error("не удалось открыть журнал аудита", err, slog.Any("error", err))
+		return
+	}
+
+	vulnStore, err := openVulnStore(cmd, rootDir, log)
+	if err != nil {
+		log.Error("не удалось открыть хранилище баз уязвимостей", err, slog.Any("error", err))
+		return
+	}
+
+	// Некорректная политика при запуске — ошибка: иначе запрещённые пакеты раздавались бы
+	policyFile, signer := openPolicy(cmd, rootDir, log)
+	if _, _, err := policyFile.Policy(); err != nil {
+		log.Error("не удалось загрузить политику раздачи пакетов", err, slog.Any("error", err))
+		return
+	}
+
+	mergedFile := openMerged(cmd, rootDir, log)
+	if _, _, err := mergedFile.Configs(); err != nil {
+		log.Error("не удалось загрузить описания объединённых репозиториев", err, slog.Any("error", err))
+		return
 	}
 
 	// Контекст, завершаемый по SIGINT (Ctrl+C) или SIGTERM.
@@ -117,6 +229,7 @@ func rootRun(cmd *cobra.Command, _ []string) {
 		Log:         log,
 		ChangeFiles: changeFiles,
 		ChangeRepos: changeRepo,
+		Custom:      customOptions(cmd),
 	})
 	if err != nil {
 		log.Error("не удалось создать процесс отслеживания репозиториев", err, slog.Any("error", err))
@@ -132,15 +245,24 @@ func rootRun(cmd *cobra.Command, _ []string) {
 	router.Use(gin.Recovery())
 
 	port, _ := cmd.Flags().GetInt(FlagPort)
+	maxUpload, _ := cmd.Flags().GetInt64(FlagMaxUpload)
+	address, _ := cmd.Flags().GetString(FlagAddress)
 
 	webWorker, err := web.NewWeb(&web.Config{
-		Log:         log,
-		Port:        port,
-		Router:      router,
-		ChangeRepos: changeRepo,
-		RootDir:     rootDir,
-		Copyright:   copyright,
-		Version:     strings.ReplaceAll(version, "v", ""),
+		Log:           log,
+		Port:          port,
+		Address:       address,
+		Router:        router,
+		ChangeRepos:   changeRepo,
+		RootDir:       rootDir,
+		Copyright:     copyright,
+		Version:       strings.ReplaceAll(version, "v", ""),
+		Audit:         auditLog,
+		Vulns:         vulnStore,
+		Policy:        policyFile,
+		Signer:        signer,
+		Merged:        mergedFile,
+		MaxUploadSize: maxUpload << 20,
 	})
 	if err != nil {
 		log.Error("не удалось создать веб-сервер", err, slog.Any("error", err))
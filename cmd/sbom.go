@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/kirsrus/iso2repo/internal/repo"
+	"github.com/kirsrus/iso2repo/pkg/logging"
+	"github.com/spf13/cobra"
+)
+
+var sbomCmd = &cobra.Command{
+	Use:   "sbom <репозиторий>",
+	Short: "Сформировать перечень компонентов (SBOM) репозитория",
+	Long: `Формирует перечень компонентов (SBOM) репозитория корневой директории в формате
+CycloneDX 1.5 (--format cyclonedx) или SPDX 2.3 (--format spdx), оба — JSON. Для каждого
+пакета указываются версия, архитектура, контрольные суммы, сопровождающий, домашняя
+страница и лицензия, взятая из машиночитаемого файла copyright пакета. Определение
+лицензий читает все пакеты репозитория; --licenses=false его отключает.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSBOM,
+}
+
+func init() {
+	sbomCmd.Flags().String(FlagFormat, repo.SBOMCycloneDX, "формат: cyclonedx или spdx")
+	sbomCmd.Flags().String(FlagOut, "", "файл результата (по умолчанию — стандартный вывод)")
+	sbomCmd.Flags().Bool(FlagLicenses, true, "определять лицензии по файлам copyright пакетов")
+
+	rootCmd.AddCommand(sbomCmd)
+}
+
+// runSBOM формирует SBOM репозитория args[0].
+func runSBOM(cmd *cobra.Command, args []string) error {
+	err := func() error {
+		levelFlag, _ := cmd.Flags().GetString(FlagLevel)
+		log := logging.NewTintLogging(levelFlag)
+
+		format, _ := cmd.Flags().GetString(FlagFormat)
+		out, _ := cmd.Flags().GetString(FlagOut)
+		licenses, _ := cmd.Flags().GetBool(FlagLicenses)
+		if format != repo.SBOMCycloneDX && format != repo.SBOMSPDX {
+			return errors.Newf("неизвестный формат SBOM %s", format)
+		}
+
+		rootDir, err := resolveRootDir(cmd, log)
+		if err != nil {
+			return err
+		}
+		repos, err := repo.Discover(rootDir, log, customOptions(cmd))
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		catalog := repo.NewCatalog(log)
+		found := false
+		for _, r := range repos {
+			if r.Metadata().Name != args[0] {
+				continue
+			}
+			if err := catalog.Update(ctx, r); err != nil {
+				return err
+			}
+			found = true
+		}
+		if !found {
+			return errors.Newf("репозиторий %s не найден в %s", args[0], rootDir)
+		}
+
+		sbom, err := catalog.SBOM(ctx, repo.SBOMQuery{
+			Repo:        args[0],
+			Licenses:    licenses,
+			ToolVersion: strings.ReplaceAll(version, "v", ""),
+		}, nil)
+		if err != nil {
+			return err
+		}
+
+		var w io.Writer = os.Stdout
+		if out != "" {
+			f, err := os.Create(out)
+			if err != nil {
+				return errors.Wrapf(err, "не удалось создать %s", out)
+			}
+			defer f.Close()
+			w = f
+		}
+		if err := sbom.Write(w, format); err != nil {
+			return errors.Wrap(err, "не удалось записать SBOM")
+		}
+		if out != "" {
+			fmt.Printf("SBOM (%s, пакетов: %d) записан в %s\n", format, len(sbom.Packages), out)
+		}
+
+		return nil
+	}()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+	}
+
+	return err
+}
@@ -0,0 +1,157 @@
+// Package audit ведёт журнал операций с содержимым репозиториев.
+// Каждая запись — отдельная строка JSON в файле журнала.
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// Action тип операции, записываемой в журнал.
+type Action string
+
+const (
+	// Публикация загруженного пакета.
+	ActionUpload Action = "upload"
+	// Удаление пакета из пользовательского репозитория.
+	ActionRemove Action = "remove"
+	// Копирование пакета из любого репозитория в пользовательский.
+	ActionCopy Action = "copy"
+	// Продвижение пакета между пользовательскими репозиториями.
+	ActionPromote Action = "promote"
+	// Одобрение пакета на карантине.
+	ActionApprove Action = "approve"
+	// Отклонение пакета на карантине.
+	ActionReject Action = "reject"
+	// Исключение пакета политикой из индексов Packages.
+	ActionBlock Action = "block"
+	// Отказ политики в загрузке файла пакета.
+	ActionDeny Action = "deny"
+	// Создание снимка пользовательского репозитория.
+	ActionSnapshot Action = "snapshot"
+	// Удаление снимка пользовательского репозитория.
+	ActionSnapshotDelete Action = "snapshot-delete"
+)
+
+// Entry запись журнала.
+type Entry struct {
+	Time time.Time `json:"time"`
+	// Тип операции.
+	Action Action `json:"action"`
+	// Откуда выполнена операция: "api", "cli" или "policy".
+	Source string `json:"source"`
+	// Инициатор: IP-адрес клиента API или имя пользователя ОС.
+	Actor string `json:"actor,omitempty"`
+	// Репозиторий-источник (для copy и promote).
+	FromRepo string `json:"from_repo,omitempty"`
+	// Репозиторий, содержимое которого изменилось.
+	Repo string `json:"repo"`
+	// Пакет.
+	Package string `json:"package,omitempty"`
+	Version string `json:"version,omitempty"`
+	Arch    string `json:"arch,omitempty"`
+	// Затронутые файлы.
+	Files []string `json:"files,omitempty"`
+	// Снимок (для snapshot и snapshot-delete).
+	Snapshot string `json:"snapshot,omitempty"`
+	// Правило политики и причина решения (для block и deny).
+	Reason string `json:"reason,omitempty"`
+	// Текст ошибки, если операция не удалась.
+	Error string `json:"error,omitempty"`
+}
+
+// Log журнал операций. Безопасен для параллельного использования.
+// Нулевое значение (nil) допустимо — записи тогда отбрасываются.
+type Log struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewLog создаёт журнал в файле path. Директория файла создаётся при необходимости.
+func NewLog(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, errors.Wrap(err, "не удалось создать директорию журнала аудита")
+	}
+
+	return &Log{path: path}, nil
+}
+
+// Path возвращает путь к файлу журнала.
+func (l *Log) Path() string {
+	if l == nil {
+		return ""
+	}
+
+	return l.path
+}
+
+// Record добавляет запись в журнал. Если время не указано, подставляется текущее.
+func (l *Log) Record(entry Entry) error {
+	if l == nil {
+		return nil
+	}
+
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return errors.Wrap(err, "не удалось открыть журнал аудита")
+	}
+	defer f.Close()
+
+	if _, err := f.Write(line); err != nil {
+		return errors.Wrap(err, "не удалось записать в журнал аудита")
+	}
+
+	return nil
+}
+
+// Read возвращает не более limit последних записей журнала (все при limit <= 0).
+func (l *Log) Read(limit int) ([]Entry, error) {
+	if l == nil {
+		return []Entry{}, nil
+	}
+
+	l.mu.Lock()
+	data, err := os.ReadFile(l.path)
+	l.mu.Unlock()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Entry{}, nil
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	entries := make([]Entry, 0)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var e Entry
+		if err := dec.Decode(&e); err != nil {
+			break
+		}
+		entries = append(entries, e)
+	}
+
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	return entries, nil
+}
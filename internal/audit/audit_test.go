@@ -0,0 +1,96 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "audit.log")
+	l, err := NewLog(path)
+	if err != nil {
+		t.Fatalf("NewLog() error = %v", err)
+	}
+	if l.Path() != path {
+		t.Errorf("Path() = %s, want %s", l.Path(), path)
+	}
+
+	entries, err := l.Read(0)
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("Read() before records = %v, %v", entries, err)
+	}
+
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	records := []Entry{
+		{Time: at, Action: ActionUpload, Source: "api", Repo: "custom.iso", Files: []string{"app_1.0_amd64.deb"}},
+		{Action: ActionRemove, Source: "cli", Repo: "custom.iso", Package: "app", Version: "1.0"},
+		{Action: ActionDeny, Source: "policy", Repo: "vendor.iso", Reason: "license", Error: "forbidden"},
+	}
+	for _, e := range records {
+		if err := l.Record(e); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	// Каждая запись — отдельная строка JSON
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != len(records) {
+		t.Errorf("journal has %d lines, want %d", len(lines), len(records))
+	}
+
+	tests := []struct {
+		name    string
+		limit   int
+		actions []Action
+	}{
+		{name: "all", limit: 0, actions: []Action{ActionUpload, ActionRemove, ActionDeny}},
+		{name: "last two", limit: 2, actions: []Action{ActionRemove, ActionDeny}},
+		{name: "limit above size", limit: 10, actions: []Action{ActionUpload, ActionRemove, ActionDeny}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := l.Read(tt.limit)
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if len(entries) != len(tt.actions) {
+				t.Fatalf("Read() = %d entries, want %d", len(entries), len(tt.actions))
+			}
+			for i, e := range entries {
+				if e.Action != tt.actions[i] {
+					t.Errorf("entry %d action = %s, want %s", i, e.Action, tt.actions[i])
+				}
+				if e.Time.IsZero() {
+					t.Errorf("entry %d has no time", i)
+				}
+			}
+		})
+	}
+
+	entries, _ = l.Read(0)
+	if !entries[0].Time.Equal(at) || entries[0].Files[0] != "app_1.0_amd64.deb" {
+		t.Errorf("first entry = %+v", entries[0])
+	}
+	if entries[2].Reason != "license" || entries[2].Error != "forbidden" {
+		t.Errorf("last entry = %+v", entries[2])
+	}
+}
+
+func TestLog_nil(t *testing.T) {
+	var l *Log
+	if err := l.Record(Entry{Action: ActionUpload}); err != nil {
+		t.Errorf("Record() error = %v", err)
+	}
+	if entries, err := l.Read(0); err != nil || len(entries) != 0 {
+		t.Errorf("Read() = %v, %v", entries, err)
+	}
+	if l.Path() != "" {
+		t.Errorf("Path() = %q", l.Path())
+	}
+}
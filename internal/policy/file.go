@@ -0,0 +1,90 @@
+package policy
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"golang.org/x/exp/slog"
+)
+
+// File файл политики, перечитываемый при изменении. Нулевое значение (nil) допустимо —
+// политика тогда пуста.
+type File struct {
+	log  *slog.Logger
+	path string
+
+	mu sync.Mutex
+	// Действующая политика (последняя корректная версия файла)
+	policy *Policy
+	// Номер версии политики: увеличивается при каждой смене действующей политики
+	generation int
+	// Размер и время изменения прочитанного файла
+	size    int64
+	modTime time.Time
+	// Ошибка разбора текущей версии файла
+	err error
+}
+
+// NewFile конструктор File для файла path. Отсутствующий файл означает пустую политику.
+func NewFile(path string, log *slog.Logger) *File {
+	return &File{
+		log:  log.With(slog.String("module", "policy")),
+		path: path,
+	}
+}
+
+// Path возвращает путь к файлу политики.
+func (m *File) Path() string {
+	if m == nil {
+		return ""
+	}
+
+	return m.path
+}
+
+// Policy возвращает действующую политику, её номер версии и ошибку разбора файла,
+// перечитывая файл, если он изменился. При ошибке разбора продолжает действовать
+// предыдущая корректная версия.
+func (m *File) Policy() (*Policy, int, error) {
+	if m == nil {
+		return nil, 0, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stat, err := os.Stat(m.path)
+	if os.IsNotExist(err) {
+		if m.policy != nil || !m.modTime.IsZero() {
+			m.log.Info("файл политики удалён, ограничения сняты", slog.String("path", m.path))
+			m.policy, m.size, m.modTime, m.err = nil, 0, time.Time{}, nil
+			m.generation++
+		}
+		return nil, m.generation, nil
+	}
+	if err != nil {
+		return m.policy, m.generation, errors.Wrap(err, "не удалось прочитать файл политики")
+	}
+	if stat.Size() == m.size && stat.ModTime().Equal(m.modTime) {
+		return m.policy, m.generation, m.err
+	}
+	m.size, m.modTime = stat.Size(), stat.ModTime()
+
+	data, err := os.ReadFile(m.path)
+	if err == nil {
+		var policy *Policy
+		if policy, err = Parse(data); err == nil {
+			m.policy, m.err = policy, nil
+			m.generation++
+			m.log.Info("загружена политика раздачи пакетов", slog.String("path", m.path), slog.Int("rules", len(policy.Rules)))
+			return m.policy, m.generation, nil
+		}
+	}
+
+	m.err = errors.Wrapf(err, "файл политики %s", m.path)
+	m.log.Error("файл политики не применён, действует предыдущая версия", m.err, slog.String("path", m.path))
+
+	return m.policy, m.generation, m.err
+}
@@ -0,0 +1,277 @@
+// Package policy правила, запрещающие раздачу пакетов клиентам: по имени пакета,
+// лицензии, сопровождающему и неисправленным уязвимостям. Политика применяется при
+// отдаче индексов и файлов пакетов, не изменяя сами репозитории.
+package policy
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/kirsrus/iso2repo/internal/vuln"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidPolicy некорректный файл политики.
+var ErrInvalidPolicy = errors.New("некорректный файл политики")
+
+// Action действие правила.
+type Action string
+
+const (
+	// ActionBlock пакет исключается из индексов, его загрузка запрещается.
+	ActionBlock Action = "block"
+	// ActionAllow пакет раздаётся, даже если его запрещают следующие правила.
+	ActionAllow Action = "allow"
+)
+
+// NoLicense шаблон лицензии, соответствующий пакетам без машиночитаемого copyright.
+const NoLicense = "NOASSERTION"
+
+// Rule правило политики. Условия правила объединяются по И, шаблоны одного
+// условия — по ИЛИ. Шаблоны — в синтаксисе path.Match ("lib*-dev", "GPL-3.0*"),
+// без учёта регистра. Пустое условие не проверяется.
+type Rule struct {
+	// Имя правила для журнала; по умолчанию "rule <номер>".
+	Name string `yaml:"name" json:"name"`
+	// Действие; по умолчанию ActionBlock.
+	Action Action `yaml:"action" json:"action"`
+	// Репозитории, к которым применяется правило; пустой список — ко всем.
+	Repos []string `yaml:"repos,omitempty" json:"repos,omitempty"`
+	// Имена пакетов.
+	Packages []string `yaml:"packages,omitempty" json:"packages,omitempty"`
+	// Идентификаторы лицензий SPDX из выражения лицензии пакета; NoLicense —
+	// лицензия не определена.
+	Licenses []string `yaml:"licenses,omitempty" json:"licenses,omitempty"`
+	// Поле Maintainer ("*@example.org>").
+	Maintainers []string `yaml:"maintainers,omitempty" json:"maintainers,omitempty"`
+	// Идентификаторы неисправленных уязвимостей (CVE или бюллетеня).
+	CVEs []string `yaml:"cves,omitempty" json:"cves,omitempty"`
+	// Минимальная важность неисправленной уязвимости.
+	Severity vuln.Severity `yaml:"severity,omitempty" json:"severity,omitempty"`
+}
+
+// Policy правила политики в порядке проверки: решение принимает первое подходящее
+// правило, пакеты без подходящих правил раздаются.
+type Policy struct {
+	Rules []Rule `yaml:"rules" json:"rules"`
+}
+
+// Subject пакет, для которого принимается решение.
+type Subject struct {
+	Repo       string
+	Name       string
+	Version    string
+	Arch       string
+	Maintainer string
+	// Выражение лицензии SPDX; пустое — лицензия не определена.
+	License string
+	// Неисправленные уязвимости пакета.
+	Vulnerabilities []vuln.Match
+}
+
+// Decision решение политики по пакету.
+type Decision struct {
+	Allowed bool `json:"allowed"`
+	// Правило, принявшее решение; пустое — ни одно правило не подошло.
+	Rule string `json:"rule,omitempty"`
+	// Совпавшие условия правила.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Parse разбирает файл политики в формате YAML.
+func Parse(data []byte) (*Policy, error) {
+	result := &Policy{Rules: make([]Rule, 0)}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(result); err != nil && err != io.EOF {
+		return nil, errors.Wrapf(ErrInvalidPolicy, "%v", err)
+	}
+
+	for i := range result.Rules {
+		r := &result.Rules[i]
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		if r.Action == "" {
+			r.Action = ActionBlock
+		}
+		if r.Action != ActionBlock && r.Action != ActionAllow {
+			return nil, errors.Wrapf(ErrInvalidPolicy, "правило %s: неизвестное действие %s", r.Name, r.Action)
+		}
+		if r.Severity != "" {
+			severity := vuln.ParseSeverity(string(r.Severity))
+			if severity == vuln.SeverityUnknown && !strings.EqualFold(string(r.Severity), string(vuln.SeverityUnknown)) {
+				return nil, errors.Wrapf(ErrInvalidPolicy, "правило %s: неизвестная важность %s", r.Name, r.Severity)
+			}
+			r.Severity = severity
+		}
+		if len(r.Packages)+len(r.Licenses)+len(r.Maintainers)+len(r.CVEs) == 0 && r.Severity == "" {
+			return nil, errors.Wrapf(ErrInvalidPolicy, "правило %s: не задано ни одного условия", r.Name)
+		}
+		for _, list := range [][]string{r.Repos, r.Packages, r.Licenses, r.Maintainers, r.CVEs} {
+			for _, pattern := range list {
+				if _, err := path.Match(strings.ToLower(pattern), ""); err != nil {
+					return nil, errors.Wrapf(ErrInvalidPolicy, "правило %s: некорректный шаблон %q", r.Name, pattern)
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// NeedsLicenses проверяет, есть ли в политике условия по лицензии: их проверка
+// требует чтения файлов copyright пакетов.
+func (p *Policy) NeedsLicenses() bool {
+	if p == nil {
+		return false
+	}
+	for _, r := range p.Rules {
+		if len(r.Licenses) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NeedsVulnerabilities проверяет, есть ли в политике условия по уязвимостям.
+func (p *Policy) NeedsVulnerabilities() bool {
+	if p == nil {
+		return false
+	}
+	for _, r := range p.Rules {
+		if len(r.CVEs) > 0 || r.Severity != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Empty проверяет, что в политике нет правил (nil — тоже пустая политика).
+func (p *Policy) Empty() bool {
+	return p == nil || len(p.Rules) == 0
+}
+
+// Evaluate принимает решение по пакету s: действие первого подходящего правила или
+// разрешение, если ни одно правило не подошло.
+func (p *Policy) Evaluate(s Subject) Decision {
+	if p == nil {
+		return Decision{Allowed: true}
+	}
+
+	for _, r := range p.Rules {
+		if reason, ok := r.match(s); ok {
+			return Decision{Allowed: r.Action == ActionAllow, Rule: r.Name, Reason: reason}
+		}
+	}
+
+	return Decision{Allowed: true}
+}
+
+// match проверяет условия правила и возвращает описание совпадения.
+func (r *Rule) match(s Subject) (string, bool) {
+	if len(r.Repos) > 0 && !matchAny(r.Repos, s.Repo) {
+		return "", false
+	}
+
+	reasons := make([]string, 0)
+	if len(r.Packages) > 0 {
+		if !matchAny(r.Packages, s.Name) {
+			return "", false
+		}
+		reasons = append(reasons, "package "+s.Name)
+	}
+
+	if len(r.Licenses) > 0 {
+		ids := licenseIDs(s.License)
+		if len(ids) == 0 {
+			ids = []string{NoLicense}
+		}
+		matched := ""
+		for _, id := range ids {
+			if matchAny(r.Licenses, id) {
+				matched = id
+				break
+			}
+		}
+		if matched == "" {
+			return "", false
+		}
+		reasons = append(reasons, "license "+matched)
+	}
+
+	if len(r.Maintainers) > 0 {
+		if !matchAny(r.Maintainers, s.Maintainer) {
+			return "", false
+		}
+		reasons = append(reasons, "maintainer "+s.Maintainer)
+	}
+
+	if len(r.CVEs) > 0 || r.Severity != "" {
+		matched := ""
+		for _, m := range s.Vulnerabilities {
+			if r.Severity != "" && m.Severity.Rank() < r.Severity.Rank() {
+				continue
+			}
+			if len(r.CVEs) == 0 {
+				matched = m.ID + " (" + string(m.Severity) + ")"
+				break
+			}
+			for _, id := range append([]string{m.ID}, m.CVEs...) {
+				if matchAny(r.CVEs, id) {
+					matched = id
+					break
+				}
+			}
+			if matched != "" {
+				break
+			}
+		}
+		if matched == "" {
+			return "", false
+		}
+		reasons = append(reasons, "vulnerability "+matched)
+	}
+
+	return strings.Join(reasons, ", "), true
+}
+
+// matchAny проверяет соответствие value (без учёта регистра) хотя бы одному шаблону.
+func matchAny(patterns []string, value string) bool {
+	value = strings.ToLower(value)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), value); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// licenseIDs возвращает идентификаторы лицензий выражения SPDX
+// ("(MIT OR Apache-2.0) AND Zlib" → MIT, Apache-2.0, Zlib).
+func licenseIDs(expr string) []string {
+	result := make([]string, 0)
+	exception := false
+	for _, token := range strings.Fields(strings.NewReplacer("(", " ", ")", " ").Replace(expr)) {
+		switch {
+		case token == "AND" || token == "OR":
+		case token == "WITH":
+			// Следующий идентификатор — исключение из лицензии, а не лицензия
+			exception = true
+		case exception:
+			exception = false
+		default:
+			result = append(result, token)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,202 @@
+package policy
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	"github.com/kirsrus/iso2repo/internal/vuln"
+)
+
+const testPolicy = `
+rules:
+  - name: keep-openssl
+    action: allow
+    packages: [openssl, libssl*]
+  - name: no-gpl3
+    repos: ["*.iso"]
+    licenses: [GPL-3.0*, NOASSERTION]
+  - maintainers: ["*@bad.example>"]
+  - name: critical
+    severity: HIGH
+  - name: log4shell
+    cves: [CVE-2021-44228]
+`
+
+func TestParse(t *testing.T) {
+	policy, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(policy.Rules) != 5 {
+		t.Fatalf("Parse() rules = %d, want 5", len(policy.Rules))
+	}
+	if r := policy.Rules[2]; r.Name != "rule 3" || r.Action != ActionBlock {
+		t.Errorf("Parse() defaults = %+v", r)
+	}
+	if r := policy.Rules[3]; r.Severity != vuln.SeverityHigh {
+		t.Errorf("Parse() severity = %s, want %s", r.Severity, vuln.SeverityHigh)
+	}
+	if !policy.NeedsLicenses() || !policy.NeedsVulnerabilities() || policy.Empty() {
+		t.Errorf("Parse() needs licenses/vulnerabilities = %v/%v", policy.NeedsLicenses(), policy.NeedsVulnerabilities())
+	}
+
+	empty, err := Parse(nil)
+	if err != nil || !empty.Empty() || empty.NeedsLicenses() {
+		t.Errorf("Parse(empty) = %+v, %v", empty, err)
+	}
+
+	invalid := []struct {
+		name string
+		data string
+	}{
+		{name: "unknown action", data: "rules: [{action: drop, packages: [a]}]"},
+		{name: "unknown severity", data: "rules: [{severity: urgent}]"},
+		{name: "no conditions", data: "rules: [{name: empty, repos: [a.iso]}]"},
+		{name: "bad pattern", data: "rules: [{packages: ['lib[']}]"},
+		{name: "unknown field", data: "rules: [{package: [a]}]"},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.data)); !errors.Is(err, ErrInvalidPolicy) {
+				t.Errorf("Parse() error = %v, want ErrInvalidPolicy", err)
+			}
+		})
+	}
+}
+
+func TestPolicy_Evaluate(t *testing.T) {
+	policy, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		subject Subject
+		want    Decision
+	}{
+		{
+			name:    "no rule",
+			subject: Subject{Repo: "a.iso", Name: "bash", License: "GPL-2.0-or-later"},
+			want:    Decision{Allowed: true},
+		},
+		{
+			name: "allowed before block",
+			subject: Subject{Repo: "a.iso", Name: "libssl3", Vulnerabilities: []vuln.Match{
+				{ID: "DSA-1-1", Severity: vuln.SeverityCritical},
+			}},
+			want: Decision{Allowed: true, Rule: "keep-openssl", Reason: "package libssl3"},
+		},
+		{
+			name:    "license in expression",
+			subject: Subject{Repo: "a.iso", Name: "bash", License: "(MIT OR GPL-3.0-or-later) AND Zlib"},
+			want:    Decision{Rule: "no-gpl3", Reason: "license GPL-3.0-or-later"},
+		},
+		{
+			name:    "license exception is not a license",
+			subject: Subject{Repo: "a.iso", Name: "gcc", License: "GPL-2.0-only WITH GPL-3.0-linking-exception"},
+			want:    Decision{Allowed: true},
+		},
+		{
+			name:    "unknown license",
+			subject: Subject{Repo: "a.iso", Name: "bash"},
+			want:    Decision{Rule: "no-gpl3", Reason: "license NOASSERTION"},
+		},
+		{
+			name:    "other repo",
+			subject: Subject{Repo: "custom", Name: "bash"},
+			want:    Decision{Allowed: true},
+		},
+		{
+			name:    "maintainer",
+			subject: Subject{Repo: "custom", Name: "tool", Maintainer: "Eve <eve@bad.example>"},
+			want:    Decision{Rule: "rule 3", Reason: "maintainer Eve <eve@bad.example>"},
+		},
+		{
+			name: "severity",
+			subject: Subject{Repo: "custom", Name: "tool", Vulnerabilities: []vuln.Match{
+				{ID: "CVE-2024-1", Severity: vuln.SeverityLow},
+				{ID: "DSA-2-1", CVEs: []string{"CVE-2024-2"}, Severity: vuln.SeverityHigh},
+			}},
+			want: Decision{Rule: "critical", Reason: "vulnerability DSA-2-1 (high)"},
+		},
+		{
+			name: "cve in advisory",
+			subject: Subject{Repo: "custom", Name: "liblog4j2-java", Vulnerabilities: []vuln.Match{
+				{ID: "DSA-5022-1", CVEs: []string{"CVE-2021-44228"}, Severity: vuln.SeverityMedium},
+			}},
+			want: Decision{Rule: "log4shell", Reason: "vulnerability CVE-2021-44228"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Evaluate(tt.subject); got != tt.want {
+				t.Errorf("Evaluate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	var none *Policy
+	if got := none.Evaluate(Subject{Name: "bash"}); !got.Allowed {
+		t.Errorf("nil Evaluate() = %+v", got)
+	}
+}
+
+func TestSigner(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "signing-key.asc")
+	release := []byte("Origin: Test\nSuite: stable\nSHA256:\n 00 1 main/binary-amd64/Packages")
+
+	signer := NewSigner(path)
+	public, err := signer.PublicKey()
+	if err != nil {
+		t.Fatalf("PublicKey() error = %v", err)
+	}
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(public))
+	if err != nil {
+		t.Fatalf("PublicKey() invalid key: %v", err)
+	}
+
+	detached, err := signer.DetachSign(release)
+	if err != nil {
+		t.Fatalf("DetachSign() error = %v", err)
+	}
+	if _, err := openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(release), bytes.NewReader(detached), nil); err != nil {
+		t.Errorf("DetachSign() signature invalid: %v", err)
+	}
+
+	// Ключ сохранён в файле и используется повторно
+	fingerprint, _ := signer.Fingerprint()
+	again, err := NewSigner(path).Fingerprint()
+	if err != nil || again != fingerprint {
+		t.Errorf("Fingerprint() after reload = %s, %v, want %s", again, err, fingerprint)
+	}
+
+	signed, err := NewSigner(path).ClearSign(release)
+	if err != nil {
+		t.Fatalf("ClearSign() error = %v", err)
+	}
+	block, _ := clearsign.Decode(signed)
+	if block == nil {
+		t.Fatalf("ClearSign() = %s", signed)
+	}
+	if strings.TrimSuffix(string(block.Plaintext), "\n") != string(release) {
+		t.Errorf("ClearSign() plaintext = %q, want %q", block.Plaintext, release)
+	}
+	if _, err := openpgp.CheckDetachedSignature(keyring, bytes.NewReader(block.Bytes), block.ArmoredSignature.Body, nil); err != nil {
+		t.Errorf("ClearSign() signature invalid: %v", err)
+	}
+	// gpgv 2.2 не принимает подпись без контрольной суммы armor
+	if !regexp.MustCompile(`\n=[A-Za-z0-9+/]{4}\n-----END PGP SIGNATURE-----\n$`).Match(signed) {
+		t.Errorf("ClearSign() signature without armor checksum:\n%s", signed)
+	}
+	if !strings.HasPrefix(string(public), "-----BEGIN PGP PUBLIC KEY BLOCK-----") {
+		t.Errorf("PublicKey() = %s", public)
+	}
+}
@@ -0,0 +1,200 @@
+package policy
+
+import (
+	"bytes"
+	"crypto"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/cockroachdb/errors"
+)
+
+// signingConfig параметры создания ключа и подписей.
+var signingConfig = &packet.Config{DefaultHash: crypto.SHA256, RSABits: 3072}
+
+// Signer подписывает переписанные политикой файлы Release ключом OpenPGP.
+// Ключ читается из файла при первой подписи; если файла нет, ключ создаётся и
+// сохраняется в нём.
+type Signer struct {
+	path string
+
+	mu     sync.Mutex
+	entity *openpgp.Entity
+}
+
+// NewSigner конструктор Signer с закрытым ключом в файле path (ASCII armor).
+func NewSigner(path string) *Signer {
+	return &Signer{path: path}
+}
+
+// Path возвращает путь к файлу закрытого ключа.
+func (m *Signer) Path() string {
+	return m.path
+}
+
+// key возвращает ключ подписи, при необходимости загружая или создавая его.
+func (m *Signer) key() (*openpgp.Entity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.entity != nil {
+		return m.entity, nil
+	}
+
+	data, err := os.ReadFile(m.path)
+	if err == nil {
+		list, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+		if err != nil {
+			return nil, errors.Wrapf(err, "не удалось прочитать ключ подписи %s", m.path)
+		}
+		if len(list) == 0 || list[0].PrivateKey == nil {
+			return nil, errors.Newf("в файле %s нет закрытого ключа подписи", m.path)
+		}
+		if list[0].PrivateKey.Encrypted {
+			return nil, errors.Newf("закрытый ключ подписи %s защищён паролем", m.path)
+		}
+		m.entity = list[0]
+		return m.entity, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "не удалось прочитать ключ подписи %s", m.path)
+	}
+
+	entity, err := openpgp.NewEntity("iso2repo", "policy signing key", "", signingConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "не удалось создать ключ подписи")
+	}
+
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := entity.SerializePrivate(w, signingConfig); err != nil {
+		return nil, errors.Wrap(err, "не удалось сохранить ключ подписи")
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0o755); err != nil {
+		return nil, errors.Wrap(err, "не удалось создать директорию ключа подписи")
+	}
+	if err := os.WriteFile(m.path, buf.Bytes(), 0o600); err != nil {
+		return nil, errors.Wrapf(err, "не удалось сохранить ключ подписи %s", m.path)
+	}
+	m.entity = entity
+
+	return m.entity, nil
+}
+
+// PublicKey возвращает открытый ключ подписи в ASCII armor для установки на клиентах.
+func (m *Signer) PublicKey() ([]byte, error) {
+	entity, err := m.key()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
+
+// Fingerprint возвращает отпечаток ключа подписи.
+func (m *Signer) Fingerprint() (string, error) {
+	entity, err := m.key()
+	if err != nil {
+		return "", err
+	}
+
+	return strings.ToUpper(fmt.Sprintf("%x", entity.PrimaryKey.Fingerprint)), nil
+}
+
+// ClearSign возвращает data, подписанные встроенной подписью (InRelease).
+func (m *Signer) ClearSign(data []byte) ([]byte, error) {
+	entity, err := m.key()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w, err := clearsign.Encode(&buf, entity.PrivateKey, signingConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "не удалось подписать Release")
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.Wrap(err, "не удалось подписать Release")
+	}
+
+	return withArmorChecksum(buf.Bytes())
+}
+
+// withArmorChecksum добавляет контрольную сумму в ASCII armor подписи сообщения signed,
+// подписанного встроенной подписью. Без неё gpgv версий 2.2, которым apt проверяет
+// InRelease, завершается ошибкой, хотя подпись верна.
+func withArmorChecksum(signed []byte) ([]byte, error) {
+	i := bytes.Index(signed, []byte("-----BEGIN PGP SIGNATURE-----"))
+	if i < 0 {
+		return nil, errors.New("в подписанном Release нет подписи")
+	}
+	block, err := armor.Decode(bytes.NewReader(signed[i:]))
+	if err != nil {
+		return nil, errors.Wrap(err, "не удалось прочитать подпись Release")
+	}
+	signature, err := io.ReadAll(block.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "не удалось прочитать подпись Release")
+	}
+
+	buf := bytes.NewBuffer(append([]byte(nil), signed[:i]...))
+	w, err := armor.Encode(buf, block.Type, block.Header)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if _, err := w.Write(signature); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
+
+// DetachSign возвращает отсоединённую подпись data в ASCII armor (Release.gpg).
+func (m *Signer) DetachSign(data []byte) ([]byte, error) {
+	entity, err := m.key()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&buf, entity, bytes.NewReader(data), signingConfig); err != nil {
+		return nil, errors.Wrap(err, "не удалось подписать Release")
+	}
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
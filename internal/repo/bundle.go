@@ -0,0 +1,417 @@
+package repo
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/kirsrus/iso2repo/models"
+	"github.com/kirsrus/iso2repo/pkg/deb"
+)
+
+// bundlePoolDir директория пакетов внутри комплекта.
+const bundlePoolDir = "pool"
+
+// BundleQuery условия сборки комплекта пакетов для переноса в изолированную сеть.
+type BundleQuery struct {
+	// Пакеты: имя ("foo") или имя с версией ("foo=1.2-1").
+	Packages []string
+	// Репозитории, из которых берутся пакеты; пустой — все. При равных версиях
+	// предпочтение отдаётся репозиторию, указанному раньше.
+	Repos []string
+	// Архитектура комплекта; пакеты "all" подходят под любую.
+	Arch string
+	// Включать в комплект Recommends (по умолчанию только Pre-Depends и Depends).
+	Recommends bool
+}
+
+// Bundle комплект пакетов: запрошенные пакеты со всеми зависимостями.
+type Bundle struct {
+	Arch     string         `json:"arch"`
+	Packages []CatalogEntry `json:"packages"`
+	// Зависимости, которые не удалось удовлетворить пакетами выбранных репозиториев.
+	Unmet []UnmetDependency `json:"unmet"`
+
+	// Репозитории пакетов по имени
+	sources map[string]models.Repoes
+}
+
+// BundleWriter получатель файлов комплекта: архив или директория.
+type BundleWriter interface {
+	// WriteFile записывает файл name (путь внутри комплекта) размером size.
+	WriteFile(name string, size int64, r io.Reader) error
+	Close() error
+}
+
+// bundleRef пакет каталога: репозиторий и номер записи.
+type bundleRef struct {
+	repo *catalogRepo
+	i    int
+}
+
+func (r bundleRef) entry() CatalogEntry {
+	return r.repo.entries[r.i]
+}
+
+// ResolveBundle вычисляет замыкание зависимостей пакетов q.Packages по репозиториям
+// q.Repos: для каждой группы альтернатив берётся уже включённый в комплект пакет или
+// пакет с наибольшей версией. Каждый пакет входит в комплект одной версией.
+func (m *Catalog) ResolveBundle(q BundleQuery) (*Bundle, error) {
+	if len(q.Packages) == 0 {
+		return nil, errors.Wrap(ErrInvalidQuery, "не указаны пакеты")
+	}
+	if q.Arch == "" {
+		return nil, errors.Wrap(ErrInvalidQuery, "не указана архитектура")
+	}
+
+	fields := []string{"Pre-Depends", "Depends"}
+	if q.Recommends {
+		fields = append(fields, "Recommends")
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := q.Repos
+	if len(names) == 0 {
+		names = sortedKeys(m.repos)
+	}
+	pool := make([]*catalogRepo, 0, len(names))
+	bundle := &Bundle{
+		Arch:     q.Arch,
+		Packages: make([]CatalogEntry, 0),
+		Unmet:    make([]UnmetDependency, 0),
+		sources:  make(map[string]models.Repoes),
+	}
+	for _, name := range names {
+		repo, ok := m.repos[name]
+		if !ok {
+			return nil, errors.Wrapf(ErrPackageNotFound, "репозиторий %s", name)
+		}
+		pool = append(pool, repo)
+		bundle.sources[name] = repo.repo
+	}
+
+	// candidates возвращает пакеты архитектуры комплекта, удовлетворяющие отношению r
+	// пакета from, по убыванию версии.
+	candidates := func(from CatalogEntry, r deb.Relation) []bundleRef {
+		result := make([]bundleRef, 0)
+		for _, repo := range pool {
+			for _, i := range repo.resolve(from, r) {
+				if arch := repo.entries[i].Arch; arch == q.Arch || arch == "all" {
+					result = append(result, bundleRef{repo, i})
+				}
+			}
+		}
+		sort.SliceStable(result, func(i, j int) bool {
+			return deb.CompareVersions(result[i].entry().Version, result[j].entry().Version) > 0
+		})
+		return result
+	}
+
+	chosen := make(map[bundleRef]bool)
+	byName := make(map[string]bool)
+	queue := make([]bundleRef, 0)
+	// choose включает в комплект первый из пакетов refs, другая версия которого ещё
+	// не включена. Возвращает false, если подходящего пакета нет.
+	choose := func(refs []bundleRef) bool {
+		for _, ref := range refs {
+			if chosen[ref] {
+				return true
+			}
+		}
+		for _, ref := range refs {
+			if byName[ref.entry().Name] {
+				continue
+			}
+			chosen[ref] = true
+			byName[ref.entry().Name] = true
+			queue = append(queue, ref)
+			return true
+		}
+		return false
+	}
+
+	target := CatalogEntry{Arch: q.Arch}
+	for _, spec := range q.Packages {
+		name, version, _ := strings.Cut(spec, "=")
+		r := deb.Relation{Name: name}
+		if version != "" {
+			r.Op, r.Version = deb.OpEqual, version
+		}
+		refs := candidates(target, r)
+		if len(refs) == 0 {
+			return nil, errors.Wrapf(ErrPackageNotFound, "%s", packageSpec(name, version, q.Arch))
+		}
+		choose(refs)
+	}
+
+	for len(queue) > 0 {
+		ref := queue[0]
+		queue = queue[1:]
+		from := ref.entry()
+
+		for _, field := range fields {
+			groups, err := relationsField(ref.repo.relations[ref.i], field)
+			if err != nil {
+				bundle.Unmet = append(bundle.Unmet, UnmetDependency{CatalogEntry: from, Field: field, Relation: ref.repo.relations[ref.i].Get(field), Reason: err.Error()})
+				continue
+			}
+			for _, alts := range groups {
+				refs := make([]bundleRef, 0)
+				for _, r := range alts {
+					refs = append(refs, candidates(from, r)...)
+				}
+				if !choose(refs) {
+					unmet := UnmetDependency{CatalogEntry: from, Field: field, Relation: alts.String()}
+					if len(refs) > 0 {
+						unmet.Reason = "в комплект уже включена другая версия пакета"
+					}
+					bundle.Unmet = append(bundle.Unmet, unmet)
+				}
+			}
+		}
+	}
+
+	for ref := range chosen {
+		bundle.Packages = append(bundle.Packages, ref.entry())
+	}
+	sortCatalogEntries(bundle.Packages)
+	sort.SliceStable(bundle.Unmet, func(i, j int) bool {
+		return catalogEntryLess(bundle.Unmet[i].CatalogEntry, bundle.Unmet[j].CatalogEntry)
+	})
+
+	return bundle, nil
+}
+
+// WriteBundle записывает комплект bundle в w в виде плоского APT-репозитория:
+// пакеты в pool/, индексы Packages, Packages.gz и Release в корне. Такой комплект
+// подключается строкой "deb [trusted=yes] file:/путь ./", а директория с суффиксом
+// .iso обслуживается iso2repo как пользовательская папка. Контрольные суммы пакетов
+// вычисляются при копировании и сверяются с индексом репозитория-источника; индексы
+// записываются после пакетов.
+func (m *Catalog) WriteBundle(ctx context.Context, bundle *Bundle, w BundleWriter) error {
+	controls := m.readControls(ctx, bundle.Packages, bundle.sources)
+
+	var packages bytes.Buffer
+	for i, e := range bundle.Packages {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		p := controls[i]
+		if p == nil {
+			return errors.Wrapf(ErrPackageNotFound, "запись индекса %s в %s", packageSpec(e.Name, e.Version, e.Arch), e.Repo)
+		}
+		size, err := strconv.ParseInt(p.Get("Size"), 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "некорректный размер пакета %s в %s", packageSpec(e.Name, e.Version, e.Arch), e.Repo)
+		}
+
+		reader, err := bundle.sources[e.Repo].Open(ctx, e.Filename)
+		if err != nil {
+			return errors.Wrapf(err, "не удалось открыть %s в %s", e.Filename, e.Repo)
+		}
+		md5Hash, sha1Hash, sha256Hash := md5.New(), sha1.New(), sha256.New()
+		err = w.WriteFile(bundleFilename(e), size, io.TeeReader(reader, io.MultiWriter(md5Hash, sha1Hash, sha256Hash)))
+		reader.Close()
+		if err != nil {
+			return errors.Wrapf(err, "не удалось записать %s", e.Filename)
+		}
+
+		sums := map[string]string{
+			"MD5sum": hex.EncodeToString(md5Hash.Sum(nil)),
+			"SHA1":   hex.EncodeToString(sha1Hash.Sum(nil)),
+			"SHA256": hex.EncodeToString(sha256Hash.Sum(nil)),
+		}
+		if expected := p.Get("SHA256"); expected != "" && !strings.EqualFold(expected, sums["SHA256"]) {
+			return errors.Newf("контрольная сумма %s в %s не совпадает с индексом", e.Filename, e.Repo)
+		}
+
+		stanza := make(deb.Paragraph, 0, len(p)+len(sums))
+		for _, f := range p {
+			switch f.Name {
+			case "Filename":
+				f.Value = bundleFilename(e)
+			case "MD5sum", "SHA1", "SHA256", "SHA512":
+				continue
+			}
+			stanza = append(stanza, f)
+		}
+		for _, name := range []string{"MD5sum", "SHA1", "SHA256"} {
+			stanza = append(stanza, deb.Field{Name: name, Value: sums[name]})
+		}
+		if _, err := stanza.WriteTo(&packages); err != nil {
+			return err
+		}
+	}
+
+	indexes := make(map[string][]byte)
+	addCompressed(indexes, "Packages", packages.Bytes())
+	indexes["Release"] = bundleRelease(bundle.Arch, indexes)
+	for _, name := range []string{"Packages", "Packages.gz", "Release"} {
+		if err := w.WriteFile(name, int64(len(indexes[name])), bytes.NewReader(indexes[name])); err != nil {
+			return errors.Wrapf(err, "не удалось записать %s", name)
+		}
+	}
+
+	return w.Close()
+}
+
+// bundleFilename возвращает путь пакета e внутри комплекта.
+func bundleFilename(e CatalogEntry) string {
+	return bundlePoolDir + "/" + path.Base(e.Filename)
+}
+
+// bundleRelease формирует файл Release плоского репозитория с контрольными суммами
+// индексов files.
+func bundleRelease(arch string, files map[string][]byte) []byte {
+	names := sortedKeys(files)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Origin: iso2repo\n")
+	fmt.Fprintf(&buf, "Label: iso2repo bundle\n")
+	fmt.Fprintf(&buf, "Date: %s\n", time.Now().UTC().Format("Mon, 02 Jan 2006 15:04:05 MST"))
+	fmt.Fprintf(&buf, "Architectures: %s all\n", arch)
+	fmt.Fprintf(&buf, "Description: Offline package bundle\n")
+	fmt.Fprintf(&buf, "MD5Sum:\n")
+	for _, name := range names {
+		fmt.Fprintf(&buf, " %x %d %s\n", md5.Sum(files[name]), len(files[name]), name)
+	}
+	fmt.Fprintf(&buf, "SHA1:\n")
+	for _, name := range names {
+		fmt.Fprintf(&buf, " %x %d %s\n", sha1.Sum(files[name]), len(files[name]), name)
+	}
+	fmt.Fprintf(&buf, "SHA256:\n")
+	for _, name := range names {
+		fmt.Fprintf(&buf, " %x %d %s\n", sha256.Sum256(files[name]), len(files[name]), name)
+	}
+
+	return buf.Bytes()
+}
+
+// tarBundleWriter записывает комплект в архив tar.
+type tarBundleWriter struct {
+	tw      *tar.Writer
+	modTime time.Time
+}
+
+// NewTarBundleWriter конструктор BundleWriter, записывающего комплект в архив tar.
+func NewTarBundleWriter(w io.Writer) BundleWriter {
+	return &tarBundleWriter{tw: tar.NewWriter(w), modTime: time.Now()}
+}
+
+func (m *tarBundleWriter) WriteFile(name string, size int64, r io.Reader) error {
+	err := m.tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    size,
+		ModTime: m.modTime,
+		Format:  tar.FormatPAX,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(m.tw, r); err != nil {
+		return err
+	}
+
+	return m.tw.Flush()
+}
+
+func (m *tarBundleWriter) Close() error {
+	return m.tw.Close()
+}
+
+// zipBundleWriter записывает комплект в архив zip. Пакеты и так сжаты, поэтому
+// сохраняются без сжатия.
+type zipBundleWriter struct {
+	zw      *zip.Writer
+	modTime time.Time
+}
+
+// NewZipBundleWriter конструктор BundleWriter, записывающего комплект в архив zip.
+func NewZipBundleWriter(w io.Writer) BundleWriter {
+	return &zipBundleWriter{zw: zip.NewWriter(w), modTime: time.Now()}
+}
+
+func (m *zipBundleWriter) WriteFile(name string, size int64, r io.Reader) error {
+	header := &zip.FileHeader{Name: name, Method: zip.Store, Modified: m.modTime}
+	header.SetMode(0o644)
+	fw, err := m.zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	n, err := io.Copy(fw, r)
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return errors.Newf("размер %s: %d вместо %d", name, n, size)
+	}
+
+	return nil
+}
+
+func (m *zipBundleWriter) Close() error {
+	return m.zw.Close()
+}
+
+// dirBundleWriter записывает комплект в директорию.
+type dirBundleWriter struct {
+	dir string
+}
+
+// NewDirBundleWriter конструктор BundleWriter, записывающего комплект в директорию dir.
+// Директория создаётся; существующая директория должна быть пустой.
+func NewDirBundleWriter(dir string) (BundleWriter, error) {
+	entries, err := os.ReadDir(dir)
+	if err == nil && len(entries) > 0 {
+		return nil, errors.Newf("директория %s не пуста", dir)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, bundlePoolDir), 0o755); err != nil {
+		return nil, errors.Wrapf(err, "не удалось создать директорию %s", dir)
+	}
+
+	return &dirBundleWriter{dir: dir}, nil
+}
+
+func (m *dirBundleWriter) WriteFile(name string, size int64, r io.Reader) error {
+	f, err := os.Create(filepath.Join(m.dir, filepath.FromSlash(name)))
+	if err != nil {
+		return err
+	}
+
+	n, err := io.Copy(f, r)
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return errors.Newf("размер %s: %d вместо %d", name, n, size)
+	}
+
+	return nil
+}
+
+func (m *dirBundleWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,186 @@
+package repo
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kirsrus/iso2repo/pkg/deb"
+	"golang.org/x/exp/slog"
+)
+
+func TestCatalog_ResolveBundle(t *testing.T) {
+	catalog := newTestDependsCatalog([]testPackage{
+		{"app", "2.0", "amd64", deb.Paragraph{
+			{Name: "Depends", Value: "libfoo1 (>= 1.2), mail-transport-agent | exim4, app-data"},
+			{Name: "Recommends", Value: "app-doc"},
+		}},
+		{"app", "1.0", "amd64", deb.Paragraph{{Name: "Depends", Value: "libfoo1 (<< 1.2)"}}},
+		{"libfoo1", "1.1", "amd64", nil},
+		{"libfoo1", "1.3", "i386", nil},
+		{"app-data", "2.0", "all", deb.Paragraph{{Name: "Depends", Value: "app (= 2.0)"}}},
+		{"app-doc", "2.0", "all", nil},
+		{"postfix", "3.7", "amd64", deb.Paragraph{{Name: "Provides", Value: "mail-transport-agent"}}},
+	})
+	b := newCatalogRepo(nil)
+	b.add(CatalogEntry{Name: "libfoo1", Version: "1.3", Arch: "amd64", Repo: "b.iso", Suite: "bookworm"}, nil)
+	catalog.repos["b.iso"] = b
+
+	tests := []struct {
+		name      string
+		query     BundleQuery
+		want      []string
+		wantUnmet []string
+		wantErr   error
+	}{
+		{
+			name:      "single repo",
+			query:     BundleQuery{Packages: []string{"app"}, Repos: []string{"a.iso"}, Arch: "amd64"},
+			want:      []string{"app 2.0 a.iso", "app-data 2.0 a.iso", "postfix 3.7 a.iso"},
+			wantUnmet: []string{"app: libfoo1 (>= 1.2)"},
+		},
+		{
+			name:  "closure across repos with recommends",
+			query: BundleQuery{Packages: []string{"app"}, Arch: "amd64", Recommends: true},
+			want:  []string{"app 2.0 a.iso", "app-data 2.0 a.iso", "app-doc 2.0 a.iso", "libfoo1 1.3 b.iso", "postfix 3.7 a.iso"},
+		},
+		{
+			name:      "one version per package",
+			query:     BundleQuery{Packages: []string{"app=1.0", "libfoo1"}, Arch: "amd64"},
+			want:      []string{"app 1.0 a.iso", "libfoo1 1.3 b.iso"},
+			wantUnmet: []string{"app: libfoo1 (<< 1.2)"},
+		},
+		{name: "unknown package", query: BundleQuery{Packages: []string{"ghost"}, Arch: "amd64"}, wantErr: ErrPackageNotFound},
+		{name: "other arch", query: BundleQuery{Packages: []string{"app"}, Arch: "i386"}, wantErr: ErrPackageNotFound},
+		{name: "unknown repo", query: BundleQuery{Packages: []string{"app"}, Repos: []string{"c.iso"}, Arch: "amd64"}, wantErr: ErrPackageNotFound},
+		{name: "no arch", query: BundleQuery{Packages: []string{"app"}}, wantErr: ErrInvalidQuery},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bundle, err := catalog.ResolveBundle(tt.query)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ResolveBundle() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveBundle() error = %v", err)
+			}
+
+			var got, unmet []string
+			for _, e := range bundle.Packages {
+				got = append(got, e.Name+" "+e.Version+" "+e.Repo)
+			}
+			for _, u := range bundle.Unmet {
+				unmet = append(unmet, u.Name+": "+u.Relation)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveBundle() packages = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(unmet, tt.wantUnmet) {
+				t.Errorf("ResolveBundle() unmet = %v, want %v", unmet, tt.wantUnmet)
+			}
+		})
+	}
+}
+
+func TestCatalog_WriteBundle(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a.iso")
+	files := map[string]string{
+		"pool/main/a/app/app_1.0_amd64.deb": "app package",
+		"pool/main/l/lib/lib_2.0_all.deb":   "lib package",
+	}
+	var packages strings.Builder
+	for _, p := range []struct{ name, version, arch, file, depends string }{
+		{"app", "1.0", "amd64", "pool/main/a/app/app_1.0_amd64.deb", "lib"},
+		{"lib", "2.0", "all", "pool/main/l/lib/lib_2.0_all.deb", ""},
+	} {
+		data := files[p.file]
+		fmt.Fprintf(&packages, "Package: %s\nVersion: %s\nArchitecture: %s\n", p.name, p.version, p.arch)
+		if p.depends != "" {
+			fmt.Fprintf(&packages, "Depends: %s\n", p.depends)
+		}
+		fmt.Fprintf(&packages, "Filename: %s\nSize: %d\nSHA256: %x\n\n", p.file, len(data), sha256.Sum256([]byte(data)))
+	}
+	files["dists/stable/main/binary-amd64/Packages"] = packages.String()
+	files["dists/stable/Release"] = "Suite: stable\nCodename: stable\nComponents: main\nArchitectures: amd64\n"
+	for name, data := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard))
+	catalog := NewCatalog(log)
+	if err := catalog.Update(context.Background(), NewRepoExtracted(dir, log)); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	bundle, err := catalog.ResolveBundle(BundleQuery{Packages: []string{"app"}, Arch: "amd64"})
+	if err != nil {
+		t.Fatalf("ResolveBundle() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := catalog.WriteBundle(context.Background(), bundle, NewTarBundleWriter(&buf)); err != nil {
+		t.Fatalf("WriteBundle() error = %v", err)
+	}
+
+	got := make(map[string]string)
+	names := make([]string, 0)
+	tr := tar.NewReader(&buf)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("tar: %v", err)
+		}
+		data, _ := io.ReadAll(tr)
+		got[header.Name] = string(data)
+		names = append(names, header.Name)
+	}
+
+	wantNames := []string{"pool/app_1.0_amd64.deb", "pool/lib_2.0_all.deb", "Packages", "Packages.gz", "Release"}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("WriteBundle() files = %v, want %v", names, wantNames)
+	}
+	if got["pool/app_1.0_amd64.deb"] != "app package" {
+		t.Errorf("WriteBundle() app = %q", got["pool/app_1.0_amd64.deb"])
+	}
+	for _, want := range []string{
+		"Filename: pool/lib_2.0_all.deb\n",
+		fmt.Sprintf("SHA256: %x\n", sha256.Sum256([]byte("lib package"))),
+		"MD5sum: ",
+	} {
+		if !strings.Contains(got["Packages"], want) {
+			t.Errorf("WriteBundle() Packages does not contain %q:\n%s", want, got["Packages"])
+		}
+	}
+	if !strings.Contains(got["Release"], fmt.Sprintf("%x %d Packages\n", sha256.Sum256([]byte(got["Packages"])), len(got["Packages"]))) {
+		t.Errorf("WriteBundle() Release does not list Packages:\n%s", got["Release"])
+	}
+
+	// Файл, не совпадающий с индексом, не попадает в комплект молча
+	if err := os.WriteFile(filepath.Join(dir, "pool/main/l/lib/lib_2.0_all.deb"), []byte("lib pack4ge"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := catalog.WriteBundle(context.Background(), bundle, NewTarBundleWriter(io.Discard)); err == nil {
+		t.Errorf("WriteBundle() with corrupted package error = nil")
+	}
+}
@@ -0,0 +1,494 @@
+package repo
+
+import (
+	"context"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/kirsrus/iso2repo/models"
+	"github.com/kirsrus/iso2repo/pkg/deb"
+	"golang.org/x/exp/slog"
+)
+
+// defaultCatalogLimit количество результатов поиска по умолчанию.
+const defaultCatalogLimit = 500
+
+// CatalogMode способ сопоставления имени пакета с запросом.
+type CatalogMode string
+
+const (
+	// CatalogPrefix имя пакета начинается с запроса.
+	CatalogPrefix CatalogMode = "prefix"
+	// CatalogExact имя пакета совпадает с запросом.
+	CatalogExact CatalogMode = "exact"
+	// CatalogRegex имя пакета соответствует регулярному выражению.
+	CatalogRegex CatalogMode = "regex"
+)
+
+// ErrInvalidQuery некорректный поисковый запрос.
+var ErrInvalidQuery = errors.New("некорректный поисковый запрос")
+
+// packagesIndexRe путь к индексу Packages в списке файлов Release.
+var packagesIndexRe = regexp.MustCompile(`^(.+/)?binary-[^/]+/Packages(\.[a-z0-9]+)?$`)
+
+// CatalogEntry пакет в индексе Packages одного из обслуживаемых репозиториев.
+type CatalogEntry struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Arch    string `json:"arch"`
+	// Имя репозитория.
+	Repo string `json:"repo"`
+	// Дистрибутив — имя директории в dists/.
+	Suite     string `json:"suite"`
+	Component string `json:"component"`
+	// Путь к файлу пакета относительно корня репозитория.
+	Filename string `json:"filename"`
+
+	// Индекс Packages, из которого взята запись (путь относительно корня репозитория)
+	index string
+	// Исходный пакет и его версия (поле Source)
+	source, sourceVersion string
+}
+
+// CatalogQuery условия поиска пакетов.
+type CatalogQuery struct {
+	// Строка поиска (префикс, имя или регулярное выражение, в зависимости от Mode).
+	Query string
+	// Способ сопоставления; по умолчанию CatalogPrefix.
+	Mode CatalogMode
+	// Архитектура; пакеты "all" подходят под любую. Пустая — любая архитектура.
+	Arch string
+	// Максимальное количество результатов; 0 — defaultCatalogLimit.
+	Limit int
+}
+
+// Catalog сводный индекс пакетов и их файлов всех обслуживаемых репозиториев,
+// построенный по их индексам Packages и Contents.
+type Catalog struct {
+	log *slog.Logger
+
+	mu sync.RWMutex
+	// Содержимое каталога по имени репозитория
+	repos map[string]*catalogRepo
+	// Номер версии каталога: увеличивается при каждом изменении
+	generation int
+
+	// Сериализует обновления, чтобы устаревшее чтение индексов не перезаписало свежее
+	updateMu sync.Mutex
+}
+
+// catalogRepo пакеты и индексы Contents одного репозитория.
+type catalogRepo struct {
+	repo models.Repoes
+
+	entries []CatalogEntry
+	// Поля отношений (Depends, Provides и т.п.) записей entries
+	relations []deb.Paragraph
+	// Номера записей entries по имени пакета
+	byName map[string][]int
+	// Номера записей, предоставляющих виртуальный пакет (Provides), по его имени
+	providers map[string][]int
+	// Номера записей, зависящих от пакета, по его имени
+	rdeps map[string][]int
+	// Дистрибутивы обновлений безопасности
+	security map[string]bool
+	// Кодовые имена выпусков (bookworm) по имени дистрибутива
+	codenames map[string]string
+	// Издатели выпусков (поле Origin) по имени дистрибутива
+	origins map[string]string
+
+	contents []*contentsIndex
+}
+
+// newCatalogRepo конструктор catalogRepo.
+func newCatalogRepo(r models.Repoes) *catalogRepo {
+	return &catalogRepo{
+		repo:      r,
+		entries:   make([]CatalogEntry, 0),
+		relations: make([]deb.Paragraph, 0),
+		byName:    make(map[string][]int),
+		providers: make(map[string][]int),
+		rdeps:     make(map[string][]int),
+		security:  make(map[string]bool),
+		codenames: make(map[string]string),
+		origins:   make(map[string]string),
+		contents:  make([]*contentsIndex, 0),
+	}
+}
+
+// add добавляет пакет e с полями отношений relations.
+func (m *catalogRepo) add(e CatalogEntry, relations deb.Paragraph) {
+	i := len(m.entries)
+	m.byName[e.Name] = append(m.byName[e.Name], i)
+	m.entries = append(m.entries, e)
+	m.relations = append(m.relations, relations)
+
+	if provides, err := relationsField(relations, "Provides"); err == nil {
+		for _, alts := range provides {
+			for _, r := range alts {
+				m.providers[r.Name] = appendIndex(m.providers[r.Name], i)
+			}
+		}
+	}
+
+	for _, field := range dependencyFields {
+		groups, err := relationsField(relations, field)
+		if err != nil {
+			continue
+		}
+		for _, alts := range groups {
+			for _, r := range alts {
+				m.rdeps[r.Name] = appendIndex(m.rdeps[r.Name], i)
+			}
+		}
+	}
+}
+
+// appendIndex добавляет номер i в конец list, если он ещё не последний.
+func appendIndex(list []int, i int) []int {
+	if len(list) > 0 && list[len(list)-1] == i {
+		return list
+	}
+
+	return append(list, i)
+}
+
+// NewCatalog конструктор Catalog.
+func NewCatalog(log *slog.Logger) *Catalog {
+	return &Catalog{
+		log:   log.With(slog.String("module", "catalog")),
+		repos: make(map[string]*catalogRepo),
+	}
+}
+
+// Update перечитывает индексы Packages и Contents репозитория r и заменяет его
+// содержимое в каталоге. Ошибки чтения Contents не прерывают обновление: такие
+// индексы пропускаются с предупреждением в логе.
+func (m *Catalog) Update(ctx context.Context, r models.Repoes) error {
+	m.updateMu.Lock()
+	defer m.updateMu.Unlock()
+
+	name := r.Metadata().Name
+	repo, err := m.loadRepo(ctx, r)
+	if err != nil {
+		return errors.Wrapf(err, "не удалось проиндексировать репозиторий %s", name)
+	}
+
+	m.mu.Lock()
+	m.repos[name] = repo
+	m.generation++
+	m.mu.Unlock()
+
+	files := 0
+	for _, c := range repo.contents {
+		files += len(c.offsets)
+	}
+	m.log.Debug("каталог пакетов обновлён", slog.String("repo", name), slog.Int("packages", len(repo.entries)), slog.Int("files", files))
+
+	return nil
+}
+
+// Remove удаляет пакеты репозитория name из каталога.
+func (m *Catalog) Remove(name string) {
+	m.updateMu.Lock()
+	defer m.updateMu.Unlock()
+
+	m.mu.Lock()
+	if _, ok := m.repos[name]; ok {
+		delete(m.repos, name)
+		m.generation++
+	}
+	m.mu.Unlock()
+}
+
+// Generation возвращает номер версии каталога, который увеличивается при каждом
+// обновлении или удалении репозитория.
+func (m *Catalog) Generation() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.generation
+}
+
+// Search ищет пакеты по условиям q. Результаты отсортированы по имени, затем
+// по убыванию версии и по имени репозитория.
+func (m *Catalog) Search(q CatalogQuery) ([]CatalogEntry, error) {
+	match, err := catalogMatcher(q)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultCatalogLimit
+	}
+
+	result := make([]CatalogEntry, 0)
+
+	m.mu.RLock()
+	for _, repo := range m.repos {
+		for _, e := range repo.entries {
+			if q.Arch != "" && e.Arch != q.Arch && e.Arch != "all" {
+				continue
+			}
+			if match(e.Name) {
+				result = append(result, e)
+			}
+		}
+	}
+	m.mu.RUnlock()
+
+	sortCatalogEntries(result)
+	if len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result, nil
+}
+
+// catalogMatcher возвращает функцию сопоставления имени пакета с запросом q.
+func catalogMatcher(q CatalogQuery) (func(string) bool, error) {
+	switch q.Mode {
+	case "", CatalogPrefix:
+		return func(name string) bool { return strings.HasPrefix(name, q.Query) }, nil
+
+	case CatalogExact:
+		return func(name string) bool { return name == q.Query }, nil
+
+	case CatalogRegex:
+		re, err := regexp.Compile(q.Query)
+		if err != nil {
+			return nil, errors.Wrapf(ErrInvalidQuery, "%v", err)
+		}
+		return re.MatchString, nil
+	}
+
+	return nil, errors.Wrapf(ErrInvalidQuery, "неизвестный способ поиска %q", q.Mode)
+}
+
+// sortCatalogEntries сортирует пакеты (см. catalogEntryLess).
+func sortCatalogEntries(entries []CatalogEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return catalogEntryLess(entries[i], entries[j])
+	})
+}
+
+// catalogEntryLess порядок пакетов: по имени, по убыванию версии, затем по
+// репозиторию, дистрибутиву и архитектуре.
+func catalogEntryLess(a, b CatalogEntry) bool {
+	if a.Name != b.Name {
+		return a.Name < b.Name
+	}
+	if c := deb.CompareVersions(a.Version, b.Version); c != 0 {
+		return c > 0
+	}
+	if a.Repo != b.Repo {
+		return a.Repo < b.Repo
+	}
+	if a.Suite != b.Suite {
+		return a.Suite < b.Suite
+	}
+	return a.Arch < b.Arch
+}
+
+// SearchContents ищет файлы по условиям q и возвращает содержащие их пакеты.
+// Версия и архитектура пакета берутся из индекса Packages того же дистрибутива.
+// Репозитории просматриваются в порядке имён до набора q.Limit совпадений;
+// результаты отсортированы по пути и имени пакета.
+func (m *Catalog) SearchContents(q ContentsQuery) ([]ContentsEntry, error) {
+	match, err := contentsMatcher(q)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultCatalogLimit
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := make([]string, 0, len(m.repos))
+	for name := range m.repos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]ContentsEntry, 0)
+	seen := make(map[ContentsEntry]bool)
+	for _, name := range names {
+		repo := m.repos[name]
+		for _, index := range repo.contents {
+			if q.Arch != "" && index.arch != q.Arch && index.arch != "all" {
+				continue
+			}
+			for i := range index.offsets {
+				if len(result) >= limit {
+					break
+				}
+
+				p := index.path(i)
+				if !match(p) {
+					continue
+				}
+				for _, e := range repo.resolveContents(name, index, "/"+string(p), index.names[index.packages[i]], q.Arch) {
+					if !seen[e] {
+						seen[e] = true
+						result = append(result, e)
+					}
+				}
+			}
+		}
+	}
+
+	sortContentsEntries(result)
+	if len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result, nil
+}
+
+// resolveContents сопоставляет пакетам qualified (список квалифицированных имён через ",")
+// из индекса Contents их версии из индекса Packages. Пакет, отсутствующий в
+// Packages, возвращается без версии.
+func (m *catalogRepo) resolveContents(repoName string, index *contentsIndex, filePath, qualified, arch string) []ContentsEntry {
+	result := make([]ContentsEntry, 0, 1)
+	for _, q := range strings.Split(qualified, ",") {
+		name := deb.ContentsPackageName(q)
+		found := false
+		for _, i := range m.byName[name] {
+			e := m.entries[i]
+			if e.Suite != index.suite || index.component != "" && e.Component != index.component {
+				continue
+			}
+			if index.arch != "all" && e.Arch != index.arch && e.Arch != "all" {
+				continue
+			}
+			if arch != "" && e.Arch != arch && e.Arch != "all" {
+				continue
+			}
+			found = true
+			result = append(result, ContentsEntry{
+				Path:      filePath,
+				Package:   e.Name,
+				Version:   e.Version,
+				Arch:      e.Arch,
+				Repo:      repoName,
+				Suite:     e.Suite,
+				Component: e.Component,
+			})
+		}
+
+		if !found {
+			result = append(result, ContentsEntry{
+				Path:      filePath,
+				Package:   name,
+				Arch:      index.arch,
+				Repo:      repoName,
+				Suite:     index.suite,
+				Component: index.component,
+			})
+		}
+	}
+
+	return result
+}
+
+// loadRepo читает все индексы Packages и Contents репозитория r.
+func (m *Catalog) loadRepo(ctx context.Context, r models.Repoes) (*catalogRepo, error) {
+	dists, err := Distributions(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	repoName := r.Metadata().Name
+	result := newCatalogRepo(r)
+	for _, dist := range dists {
+		release, err := ReadRelease(ctx, r, dist)
+		if err != nil {
+			return nil, err
+		}
+		if isSecuritySuite(dist, release) {
+			result.security[dist] = true
+		}
+		result.codenames[dist] = releaseCodename(dist, release)
+		result.origins[dist] = release.Origin
+
+		for _, index := range packagesIndexes(release) {
+			component, _, _ := strings.Cut(index, "/")
+			indexPath := path.Join("dists", dist, index)
+			err := ReadPackagesIndex(ctx, r, indexPath, func(p *deb.IndexPackage) error {
+				source, sourceVersion := packageSource(&p.PackageMeta)
+				result.add(CatalogEntry{
+					Name:          p.Package,
+					Version:       p.Version,
+					Arch:          p.Architecture,
+					Repo:          repoName,
+					Suite:         dist,
+					Component:     component,
+					Filename:      p.Filename,
+					index:         indexPath,
+					source:        source,
+					sourceVersion: sourceVersion,
+				}, packageRelations(&p.PackageMeta))
+				return nil
+			})
+			if errors.Is(err, ErrPackageNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, loc := range contentsIndexes(release) {
+			index, err := readContentsIndex(ctx, r, dist, loc)
+			if errors.Is(err, ErrPackageNotFound) {
+				continue
+			}
+			if err != nil {
+				m.log.Warn("индекс Contents пропущен", slog.String("repo", repoName), slog.String("dist", dist), slog.String("error", err.Error()))
+				continue
+			}
+			result.contents = append(result.contents, index)
+		}
+	}
+
+	return result, nil
+}
+
+// packagesIndexes возвращает пути индексов Packages относительно директории
+// дистрибутива (без расширения сжатия). Берутся из списка файлов Release, а если он
+// пуст — составляются из полей Components и Architectures.
+func packagesIndexes(release *deb.Release) []string {
+	result := make([]string, 0)
+	for _, list := range [][]deb.FileChecksum{release.SHA256, release.SHA1, release.MD5Sum} {
+		for _, f := range list {
+			m := packagesIndexRe.FindStringSubmatch(f.Path)
+			if m == nil {
+				continue
+			}
+			index := strings.TrimSuffix(f.Path, m[2])
+			if !containsString(result, index) {
+				result = append(result, index)
+			}
+		}
+	}
+	if len(result) > 0 {
+		return result
+	}
+
+	for _, component := range release.Components {
+		for _, arch := range release.Architectures {
+			result = append(result, path.Join(component, "binary-"+arch, "Packages"))
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,218 @@
+package repo
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/kirsrus/iso2repo/pkg/deb"
+	"golang.org/x/exp/slog"
+)
+
+// newTestCatalog создаёт каталог с пакетами entries по имени репозитория.
+func newTestCatalog(entries map[string][]CatalogEntry) *Catalog {
+	catalog := NewCatalog(slog.New(slog.NewTextHandler(io.Discard)))
+	for name, list := range entries {
+		repo := newCatalogRepo(nil)
+		for _, e := range list {
+			repo.add(e, nil)
+		}
+		catalog.repos[name] = repo
+	}
+
+	return catalog
+}
+
+func TestCatalog_Search(t *testing.T) {
+	catalog := newTestCatalog(map[string][]CatalogEntry{
+		"a.iso": {
+			{Name: "foo", Version: "1.0", Arch: "amd64", Repo: "a.iso"},
+			{Name: "foo-doc", Version: "1.0", Arch: "all", Repo: "a.iso"},
+			{Name: "libfoo1", Version: "1.0", Arch: "i386", Repo: "a.iso"},
+		},
+		"b.iso": {
+			{Name: "foo", Version: "1.10", Arch: "amd64", Repo: "b.iso"},
+			{Name: "foo", Version: "1:0.9", Arch: "arm64", Repo: "b.iso"},
+		},
+	})
+
+	type result struct{ name, version, repo string }
+	tests := []struct {
+		name    string
+		query   CatalogQuery
+		want    []result
+		wantErr bool
+	}{
+		{
+			name:  "prefix sorted by version descending",
+			query: CatalogQuery{Query: "foo"},
+			want: []result{
+				{"foo", "1:0.9", "b.iso"},
+				{"foo", "1.10", "b.iso"},
+				{"foo", "1.0", "a.iso"},
+				{"foo-doc", "1.0", "a.iso"},
+			},
+		},
+		{
+			name:  "exact",
+			query: CatalogQuery{Query: "foo", Mode: CatalogExact, Arch: "amd64"},
+			want:  []result{{"foo", "1.10", "b.iso"}, {"foo", "1.0", "a.iso"}},
+		},
+		{
+			name:  "arch filter includes all",
+			query: CatalogQuery{Query: "foo", Arch: "arm64"},
+			want:  []result{{"foo", "1:0.9", "b.iso"}, {"foo-doc", "1.0", "a.iso"}},
+		},
+		{
+			name:  "regex",
+			query: CatalogQuery{Query: `^lib.*1$`, Mode: CatalogRegex},
+			want:  []result{{"libfoo1", "1.0", "a.iso"}},
+		},
+		{
+			name:  "limit",
+			query: CatalogQuery{Query: "foo", Limit: 1},
+			want:  []result{{"foo", "1:0.9", "b.iso"}},
+		},
+		{name: "invalid regex", query: CatalogQuery{Query: "(", Mode: CatalogRegex}, wantErr: true},
+		{name: "unknown mode", query: CatalogQuery{Query: "foo", Mode: "fuzzy"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := catalog.Search(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Search() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidQuery) {
+					t.Errorf("Search() error = %v, want ErrInvalidQuery", err)
+				}
+				return
+			}
+
+			got := make([]result, 0, len(entries))
+			for _, e := range entries {
+				got = append(got, result{e.Name, e.Version, e.Repo})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPackagesIndexes(t *testing.T) {
+	release := &deb.Release{
+		Components:    []string{"main"},
+		Architectures: []string{"amd64"},
+		SHA256: []deb.FileChecksum{
+			{Path: "main/binary-amd64/Packages"},
+			{Path: "main/binary-amd64/Packages.gz"},
+			{Path: "main/binary-amd64/Release"},
+			{Path: "main/debian-installer/binary-amd64/Packages.xz"},
+			{Path: "main/i18n/Translation-en"},
+		},
+		MD5Sum: []deb.FileChecksum{
+			{Path: "contrib/binary-i386/Packages.bz2"},
+		},
+	}
+
+	want := []string{
+		"main/binary-amd64/Packages",
+		"main/debian-installer/binary-amd64/Packages",
+		"contrib/binary-i386/Packages",
+	}
+	if got := packagesIndexes(release); !reflect.DeepEqual(got, want) {
+		t.Errorf("packagesIndexes() = %v, want %v", got, want)
+	}
+
+	release.SHA256, release.MD5Sum = nil, nil
+	want = []string{"main/binary-amd64/Packages"}
+	if got := packagesIndexes(release); !reflect.DeepEqual(got, want) {
+		t.Errorf("packagesIndexes() without file list = %v, want %v", got, want)
+	}
+}
+
+func TestCatalog_SearchContents(t *testing.T) {
+	catalog := newTestCatalog(map[string][]CatalogEntry{
+		"a.iso": {
+			{Name: "foo", Version: "1.0", Arch: "amd64", Suite: "stable", Component: "main"},
+			{Name: "libbar3", Version: "3.1", Arch: "amd64", Suite: "stable", Component: "main"},
+			{Name: "bar-data", Version: "3.1", Arch: "all", Suite: "stable", Component: "main"},
+		},
+	})
+
+	index := &contentsIndex{suite: "stable", component: "main", arch: "amd64"}
+	for _, line := range []struct{ path, packages string }{
+		{"usr/bin/foo", "utils/foo"},
+		{"usr/lib/x86_64-linux-gnu/libbar.so.3", "libs/libbar3"},
+		{"usr/share/bar/foo", "misc/bar-data,misc/ghost"},
+	} {
+		index.offsets = append(index.offsets, uint32(len(index.paths)))
+		index.paths = append(index.paths, line.path...)
+		index.packages = append(index.packages, uint32(len(index.names)))
+		index.names = append(index.names, line.packages)
+	}
+	catalog.repos["a.iso"].contents = append(catalog.repos["a.iso"].contents, index)
+
+	type result struct{ path, pkg, version string }
+	tests := []struct {
+		name    string
+		query   ContentsQuery
+		want    []result
+		wantErr bool
+	}{
+		{
+			name:  "file name",
+			query: ContentsQuery{Path: "libbar.so.3"},
+			want:  []result{{"/usr/lib/x86_64-linux-gnu/libbar.so.3", "libbar3", "3.1"}},
+		},
+		{
+			name:  "path tail matches whole segments",
+			query: ContentsQuery{Path: "foo"},
+			want: []result{
+				{"/usr/bin/foo", "foo", "1.0"},
+				{"/usr/share/bar/foo", "bar-data", "3.1"},
+				{"/usr/share/bar/foo", "ghost", ""},
+			},
+		},
+		{
+			name:  "exact path",
+			query: ContentsQuery{Path: "/usr/bin/foo", Mode: CatalogExact},
+			want:  []result{{"/usr/bin/foo", "foo", "1.0"}},
+		},
+		{
+			name:  "prefix",
+			query: ContentsQuery{Path: "/usr/lib/", Mode: CatalogPrefix},
+			want:  []result{{"/usr/lib/x86_64-linux-gnu/libbar.so.3", "libbar3", "3.1"}},
+		},
+		{
+			name:  "regex",
+			query: ContentsQuery{Path: `^/usr/bin/`, Mode: CatalogRegex},
+			want:  []result{{"/usr/bin/foo", "foo", "1.0"}},
+		},
+		{name: "other arch", query: ContentsQuery{Path: "foo", Arch: "i386"}, want: []result{}},
+		{name: "empty path", query: ContentsQuery{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := catalog.SearchContents(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SearchContents() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			got := make([]result, 0, len(entries))
+			for _, e := range entries {
+				got = append(got, result{e.Path, e.Package, e.Version})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SearchContents() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
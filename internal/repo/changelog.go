@@ -0,0 +1,259 @@
+package repo
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/kirsrus/iso2repo/models"
+	"github.com/kirsrus/iso2repo/pkg/deb"
+)
+
+// changelogsTemplate шаблон поля Changelogs в Release пользовательского репозитория.
+// Адрес 0.0.0.0 заменяется WEB-сервером на адрес сервера для клиентов.
+const changelogsTemplate = "http://0.0.0.0/changelogs/%s/@CHANGEPATH@"
+
+// Changelogs извлекает журналы изменений пакетов (для "apt changelog") и хранит
+// извлечённые журналы в памяти, вытесняя давно запрошенные при превышении лимита.
+type Changelogs struct {
+	mu sync.Mutex
+
+	// Предельный суммарный размер кэша в байтах
+	maxSize int
+
+	// Текущий суммарный размер кэша
+	size int
+
+	// Элементы кэша по ключу и порядок их использования (в начале — свежие)
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+// changelogEntry элемент кэша журналов изменений.
+type changelogEntry struct {
+	key  string
+	data []byte
+}
+
+// changelogSource файл пакета, из которого можно извлечь журнал изменений.
+type changelogSource struct {
+	file PackageFile
+	// Ключ кэша: репозиторий, путь, размер и время изменения файла
+	key string
+}
+
+// NewChangelogs конструктор Changelogs. maxSize — предельный размер кэша в байтах.
+func NewChangelogs(maxSize int) *Changelogs {
+	return &Changelogs{
+		maxSize: maxSize,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Get возвращает журнал изменений по пути changePath в формате apt
+// ("<компонент>/<префикс>/<исходный пакет>/<исходный пакет>_<версия без эпохи>").
+// Журнал берётся из usr/share/doc/<пакет>/changelog.Debian.gz бинарного пакета,
+// собранного из указанного исходного пакета. Если allow не nil, журнал берётся только
+// из пакетов, для пути которых allow не вернула ошибку; если таких нет, возвращается
+// ошибка allow.
+func (m *Changelogs) Get(ctx context.Context, r models.Repoes, changePath string, allow func(file string) error) ([]byte, error) {
+	component, source, version, err := parseChangePath(changePath)
+	if err != nil {
+		return nil, err
+	}
+
+	sources, err := changelogSources(ctx, r, component, source, version)
+	if err != nil {
+		return nil, err
+	}
+
+	var denied error
+	for _, src := range sources {
+		if allow != nil {
+			if err := allow(src.file.Path); err != nil {
+				denied = err
+				continue
+			}
+		}
+
+		if data, ok := m.load(src.key); ok {
+			return data, nil
+		}
+
+		data, err := extractChangelog(ctx, r, src.file)
+		if errors.Is(err, deb.ErrChangelogNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "не удалось извлечь журнал изменений из %s", src.file.Name)
+		}
+
+		m.store(src.key, data)
+
+		return data, nil
+	}
+	if denied != nil {
+		return nil, denied
+	}
+
+	return nil, errors.Wrapf(ErrPackageNotFound, "журнал изменений %s %s в %s", source, version, r.Metadata().Name)
+}
+
+// load возвращает журнал из кэша.
+func (m *Changelogs) load(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	m.lru.MoveToFront(e)
+
+	return e.Value.(*changelogEntry).data, true
+}
+
+// store помещает журнал в кэш, вытесняя давно запрошенные журналы.
+func (m *Changelogs) store(key string, data []byte) {
+	if len(data) > m.maxSize {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.entries[key]; ok {
+		return
+	}
+
+	m.entries[key] = m.lru.PushFront(&changelogEntry{key: key, data: data})
+	m.size += len(data)
+
+	for m.size > m.maxSize {
+		oldest := m.lru.Back()
+		entry := m.lru.Remove(oldest).(*changelogEntry)
+		delete(m.entries, entry.key)
+		m.size -= len(entry.data)
+	}
+}
+
+// parseChangePath разбирает путь @CHANGEPATH@ из шаблона Changelogs.
+func parseChangePath(changePath string) (component, source, version string, err error) {
+	changePath = strings.Trim(changePath, "/")
+	parts := strings.Split(changePath, "/")
+	if len(parts) < 4 {
+		return "", "", "", errors.Wrapf(ErrPackageNotFound, "некорректный путь журнала изменений %s", changePath)
+	}
+
+	component = strings.Join(parts[:len(parts)-3], "/")
+	source = parts[len(parts)-2]
+
+	name, version, ok := strings.Cut(parts[len(parts)-1], "_")
+	if !ok || name != source || version == "" || parts[len(parts)-3] != sourcePrefix(source) {
+		return "", "", "", errors.Wrapf(ErrPackageNotFound, "некорректный путь журнала изменений %s", changePath)
+	}
+
+	return component, source, version, nil
+}
+
+// sourcePrefix возвращает префикс директории исходного пакета в pool/: "libfoo" → "libf", "foo" → "f".
+func sourcePrefix(source string) string {
+	if strings.HasPrefix(source, "lib") && len(source) > 3 {
+		return source[:4]
+	}
+
+	return source[:1]
+}
+
+// sourceVersionMatches проверяет, собран ли бинарный пакет версии binary из исходного
+// пакета версии source (без эпохи). Учитываются бинарные пересборки ("+b1").
+func sourceVersionMatches(binary, source string) bool {
+	if _, withoutEpoch, ok := strings.Cut(binary, ":"); ok {
+		binary = withoutEpoch
+	}
+
+	return binary == source || strings.HasPrefix(binary, source+"+b")
+}
+
+// changelogSources ищет в репозитории бинарные пакеты, собранные из исходного пакета
+// source версии version. Первыми идут пакеты, имя которых совпадает с исходным.
+func changelogSources(ctx context.Context, r models.Repoes, component, source, version string) ([]changelogSource, error) {
+	result := make([]changelogSource, 0)
+	repoName := r.Metadata().Name
+
+	if custom, ok := r.(*RepoCustom); ok {
+		custom.mu.RLock()
+		for _, d := range custom.debFiles {
+			if d.Meta == nil {
+				continue
+			}
+
+			srcName, srcVersion := d.Meta.Package, d.Meta.Version
+			if s := d.Meta.Extra["Source"]; s != "" {
+				name, ver, _ := strings.Cut(s, " ")
+				srcName = name
+				if ver = strings.Trim(ver, " ()"); ver != "" {
+					srcVersion = ver
+				}
+			}
+			if srcName != source || !sourceVersionMatches(srcVersion, version) {
+				continue
+			}
+
+			result = append(result, changelogSource{
+				file: PackageFile{
+					Path:    d.Kind.poolDir() + "/" + d.Name,
+					Name:    d.Name,
+					Package: d.Meta.Package,
+					Version: d.Meta.Version,
+					Arch:    d.Meta.Architecture,
+				},
+				key: fmt.Sprintf("%s/%s/%s", repoName, d.Name, d.SHA256Sum),
+			})
+		}
+		custom.mu.RUnlock()
+	} else {
+		// В репозиториях Debian пакеты исходника лежат в pool/<компонент>/<префикс>/<исходник>/
+		dir := path.Join("pool", component, sourcePrefix(source), source)
+		entries, err := r.List(ctx, dir)
+		if err != nil {
+			return nil, errors.Wrapf(ErrPackageNotFound, "%s в %s", source, repoName)
+		}
+
+		for _, e := range entries {
+			f, ok := parsePoolFileName(e.Name)
+			if e.IsDir || !ok || !sourceVersionMatches(f.Version, version) {
+				continue
+			}
+			f.Path = path.Join(dir, e.Name)
+
+			result = append(result, changelogSource{
+				file: f,
+				key:  fmt.Sprintf("%s/%s/%d/%d", repoName, f.Path, e.Size, e.CreateAt.Unix()),
+			})
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].file.Package == source && result[j].file.Package != source
+	})
+
+	return result, nil
+}
+
+// extractChangelog извлекает журнал изменений из файла пакета f репозитория r.
+func extractChangelog(ctx context.Context, r models.Repoes, f PackageFile) ([]byte, error) {
+	reader, err := r.Open(ctx, f.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return deb.ExtractChangelog(reader, f.Package)
+}
@@ -0,0 +1,64 @@
+package repo
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseChangePath(t *testing.T) {
+	tests := []struct {
+		name       string
+		changePath string
+		component  string
+		source     string
+		version    string
+		wantErr    bool
+	}{
+		{name: "simple", changePath: "main/f/foo/foo_1.0-1", component: "main", source: "foo", version: "1.0-1"},
+		{name: "lib prefix", changePath: "main/libf/libfoo/libfoo_2.0", component: "main", source: "libfoo", version: "2.0"},
+		{name: "nested component", changePath: "updates/main/b/bar/bar_3.0", component: "updates/main", source: "bar", version: "3.0"},
+		{name: "surrounding slashes", changePath: "/main/f/foo/foo_1.0/", component: "main", source: "foo", version: "1.0"},
+		{name: "too short", changePath: "f/foo/foo_1.0", wantErr: true},
+		{name: "wrong prefix", changePath: "main/x/foo/foo_1.0", wantErr: true},
+		{name: "lib without long prefix", changePath: "main/l/libfoo/libfoo_1.0", wantErr: true},
+		{name: "name differs from source", changePath: "main/f/foo/bar_1.0", wantErr: true},
+		{name: "no version", changePath: "main/f/foo/foo", wantErr: true},
+		{name: "empty version", changePath: "main/f/foo/foo_", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			component, source, version, err := parseChangePath(tt.changePath)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseChangePath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrPackageNotFound) {
+				t.Errorf("parseChangePath() error = %v, want ErrPackageNotFound", err)
+			}
+			if component != tt.component || source != tt.source || version != tt.version {
+				t.Errorf("parseChangePath() = %q, %q, %q, want %q, %q, %q", component, source, version, tt.component, tt.source, tt.version)
+			}
+		})
+	}
+}
+
+func TestSourcePrefix(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{source: "foo", want: "f"},
+		{source: "libfoo", want: "libf"},
+		{source: "lib", want: "l"},
+		{source: "libc6", want: "libc"},
+		{source: "x", want: "x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			if got := sourcePrefix(tt.source); got != tt.want {
+				t.Errorf("sourcePrefix(%q) = %q, want %q", tt.source, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+//go:build linux
+
+package repo
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// cloneFile делает dst копией src с копированием при записи (ioctl FICLONE).
+// Возвращает ошибку, если файловая система этого не поддерживает.
+func cloneFile(dst, src *os.File) error {
+	return unix.IoctlFileClone(int(dst.Fd()), int(src.Fd()))
+}
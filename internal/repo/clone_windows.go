@@ -0,0 +1,14 @@
+//go:build windows
+
+package repo
+
+import (
+	"os"
+
+	"github.com/cockroachdb/errors"
+)
+
+// cloneFile копирование при записи в Windows не поддерживается: файл копируется целиком.
+func cloneFile(_, _ *os.File) error {
+	return errors.New("копирование при записи не поддерживается")
+}
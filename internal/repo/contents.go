@@ -0,0 +1,255 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/kirsrus/iso2repo/models"
+	"github.com/kirsrus/iso2repo/pkg/deb"
+)
+
+// contentsIndexRe путь к индексу Contents в списке файлов Release: компонент
+// (в старых репозиториях отсутствует) и архитектура.
+var contentsIndexRe = regexp.MustCompile(`^(?:([^/]+)/)?Contents-(?:udeb-)?([^./]+)(\.[a-z0-9]+)?$`)
+
+// ContentsEntry файл в пакете одного из обслуживаемых репозиториев.
+type ContentsEntry struct {
+	// Путь файла в системе после установки пакета.
+	Path    string `json:"path"`
+	Package string `json:"package"`
+	// Версия пакета; пустая, если пакет из Contents не найден в индексах Packages.
+	Version   string `json:"version"`
+	Arch      string `json:"arch"`
+	Repo      string `json:"repo"`
+	Suite     string `json:"suite"`
+	Component string `json:"component"`
+}
+
+// ContentsQuery условия поиска файлов.
+type ContentsQuery struct {
+	// Путь файла. По умолчанию ищутся файлы с таким именем или окончанием пути
+	// ("libbar.so.3", "bin/foo"), с CatalogExact — полный путь, с CatalogPrefix —
+	// начало пути, с CatalogRegex — регулярное выражение для полного пути.
+	Path string
+	Mode CatalogMode
+	// Архитектура; пакеты "all" подходят под любую. Пустая — любая архитектура.
+	Arch string
+	// Максимальное количество результатов; 0 — defaultCatalogLimit.
+	Limit int
+}
+
+// contentsIndex компактное представление одного индекса Contents: пути хранятся
+// одной строкой байт, списки пакетов интернируются.
+type contentsIndex struct {
+	suite string
+	// Компонент; пустой для индексов старого формата в корне дистрибутива.
+	component string
+	arch      string
+
+	// Пути файлов без ведущего "/", записанные подряд
+	paths []byte
+	// Начало каждого пути в paths; конец — начало следующего
+	offsets []uint32
+	// Номер списка пакетов в names для каждого пути
+	packages []uint32
+	// Интернированные списки квалифицированных имён пакетов через ","
+	names []string
+}
+
+// contentsLocation индекс Contents в дистрибутиве.
+type contentsLocation struct {
+	// Путь относительно директории дистрибутива без расширения сжатия
+	path      string
+	component string
+	arch      string
+}
+
+// path возвращает i-й путь индекса.
+func (c *contentsIndex) path(i int) []byte {
+	end := len(c.paths)
+	if i+1 < len(c.offsets) {
+		end = int(c.offsets[i+1])
+	}
+
+	return c.paths[c.offsets[i]:end]
+}
+
+// readContentsIndex читает индекс Contents loc дистрибутива dist репозитория r.
+func readContentsIndex(ctx context.Context, r models.Repoes, dist string, loc contentsLocation) (*contentsIndex, error) {
+	reader, err := OpenIndex(ctx, r, path.Join("dists", dist, loc.path))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	index := &contentsIndex{suite: dist, component: loc.component, arch: loc.arch}
+	interned := make(map[string]uint32)
+	err = deb.ReadContents(reader, func(p string, packages []string) error {
+		names := strings.Join(packages, ",")
+		id, ok := interned[names]
+		if !ok {
+			id = uint32(len(index.names))
+			interned[names] = id
+			index.names = append(index.names, names)
+		}
+
+		index.offsets = append(index.offsets, uint32(len(index.paths)))
+		index.paths = append(index.paths, p...)
+		index.packages = append(index.packages, id)
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "индекс %s", loc.path)
+	}
+
+	return index, nil
+}
+
+// contentsIndexes возвращает индексы Contents дистрибутива. Берутся из списка файлов
+// Release, а если он пуст — составляются из полей Components и Architectures.
+func contentsIndexes(release *deb.Release) []contentsLocation {
+	result := make([]contentsLocation, 0)
+	seen := make(map[string]bool)
+	for _, list := range [][]deb.FileChecksum{release.SHA256, release.SHA1, release.MD5Sum} {
+		for _, f := range list {
+			m := contentsIndexRe.FindStringSubmatch(f.Path)
+			if m == nil || m[2] == "source" {
+				continue
+			}
+			p := strings.TrimSuffix(f.Path, m[3])
+			if seen[p] {
+				continue
+			}
+			seen[p] = true
+			result = append(result, contentsLocation{path: p, component: m[1], arch: m[2]})
+		}
+	}
+	if len(result) > 0 {
+		return result
+	}
+
+	for _, component := range release.Components {
+		for _, arch := range release.Architectures {
+			result = append(result, contentsLocation{path: component + "/Contents-" + arch, component: component, arch: arch})
+		}
+	}
+
+	return result
+}
+
+// contentsMatcher возвращает функцию сопоставления пути файла (без ведущего "/")
+// с запросом q.
+func contentsMatcher(q ContentsQuery) (func([]byte) bool, error) {
+	query := []byte(strings.TrimLeft(q.Path, "/"))
+	if q.Mode != CatalogRegex && len(query) == 0 {
+		return nil, errors.Wrap(ErrInvalidQuery, "не указан путь файла")
+	}
+
+	switch q.Mode {
+	case "":
+		suffix := append([]byte("/"), query...)
+		return func(p []byte) bool { return bytes.Equal(p, query) || bytes.HasSuffix(p, suffix) }, nil
+
+	case CatalogExact:
+		return func(p []byte) bool { return bytes.Equal(p, query) }, nil
+
+	case CatalogPrefix:
+		return func(p []byte) bool { return bytes.HasPrefix(p, query) }, nil
+
+	case CatalogRegex:
+		re, err := regexp.Compile(q.Path)
+		if err != nil {
+			return nil, errors.Wrapf(ErrInvalidQuery, "%v", err)
+		}
+		// Регулярное выражение применяется к пути с ведущим "/"
+		buf := make([]byte, 0, 256)
+		return func(p []byte) bool {
+			buf = append(append(buf[:0], '/'), p...)
+			return re.Match(buf)
+		}, nil
+	}
+
+	return nil, errors.Wrapf(ErrInvalidQuery, "неизвестный способ поиска %q", q.Mode)
+}
+
+// sortContentsEntries сортирует файлы по пути, имени пакета, по убыванию версии,
+// затем по репозиторию.
+func sortContentsEntries(entries []ContentsEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		if c := deb.CompareVersions(a.Version, b.Version); c != 0 {
+			return c > 0
+		}
+		if a.Repo != b.Repo {
+			return a.Repo < b.Repo
+		}
+		return a.Arch < b.Arch
+	})
+}
+
+// contentsPaths возвращает пути файлов пакета (без директорий) в формате индекса
+// Contents — без ведущего "/".
+func contentsPaths(inspection *deb.Inspection) []string {
+	result := make([]string, 0, len(inspection.Files))
+	for _, f := range inspection.Files {
+		if f.Type == "dir" || f.Path == "/" {
+			continue
+		}
+		result = append(result, strings.TrimPrefix(f.Path, "/"))
+	}
+
+	return result
+}
+
+// generateContents формирует индексы Contents опубликованных пакетов:
+// main/Contents-<arch>, main/Contents-udeb-<arch> и main-dbg/Contents-<arch>
+// (вместе с .gz). Результат добавляется в files (ключ — путь относительно
+// dists/<codename>/). Индекс создаётся только при наличии файлов в пакетах.
+func (m *RepoCustom) generateContents(files map[string][]byte) {
+	indexes := make(map[string]map[string][]string)
+	for _, d := range m.debFiles {
+		if d.Meta == nil || len(d.Contents) == 0 {
+			continue
+		}
+
+		name := customComponent + "/Contents-" + customArch
+		switch d.Kind {
+		case debKindUdeb:
+			name = customComponent + "/Contents-udeb-" + customArch
+		case debKindDdeb:
+			name = customDbgComponent + "/Contents-" + customArch
+		}
+
+		qualified := d.Meta.Package
+		if d.Meta.Section != "" {
+			qualified = d.Meta.Section + "/" + qualified
+		}
+
+		if indexes[name] == nil {
+			indexes[name] = make(map[string][]string)
+		}
+		for _, p := range d.Contents {
+			if !containsString(indexes[name][p], qualified) {
+				indexes[name][p] = append(indexes[name][p], qualified)
+			}
+		}
+	}
+
+	for name, index := range indexes {
+		var buf bytes.Buffer
+		_ = deb.WriteContents(&buf, index)
+		addCompressed(files, name, buf.Bytes())
+	}
+}
@@ -7,8 +7,10 @@ import (
 	"crypto/sha1"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"path/filepath"
 	"sort"
@@ -23,16 +25,98 @@ import (
 
 var _ models.Repoes = (*RepoCustom)(nil)
 
+const (
+	// customCodename имя дистрибутива (suite) пользовательского репозитория.
+	customCodename = "custom"
+
+	// customArch архитектура, под которую генерируются индексы.
+	customArch = "amd64"
+
+	// customComponent основной компонент пользовательского репозитория.
+	customComponent = "main"
+
+	// customDbgComponent компонент с отладочными символами (.ddeb).
+	customDbgComponent = "main-dbg"
+)
+
+// debKind описывает разновидность бинарного пакета.
+type debKind int
+
+const (
+	// Обычный пакет (.deb).
+	debKindDeb debKind = iota
+	// Пакет для debian-installer (.udeb).
+	debKindUdeb
+	// Пакет с отладочными символами (.ddeb).
+	debKindDdeb
+)
+
+// debKindByName определяет разновидность пакета по расширению файла.
+// Второе значение равно false, если файл не является бинарным пакетом.
+func debKindByName(name string) (debKind, bool) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".deb":
+		return debKindDeb, true
+	case ".udeb":
+		return debKindUdeb, true
+	case ".ddeb":
+		return debKindDdeb, true
+	}
+
+	return debKindDeb, false
+}
+
+// packagesPath возвращает путь к индексу Packages (относительно dists/<codename>/),
+// в который попадает пакет данной разновидности.
+func (k debKind) packagesPath() string {
+	switch k {
+	case debKindUdeb:
+		return customComponent + "/debian-installer/binary-" + customArch + "/Packages"
+	case debKindDdeb:
+		return customDbgComponent + "/binary-" + customArch + "/Packages"
+	}
+
+	return customComponent + "/binary-" + customArch + "/Packages"
+}
+
+// poolDir возвращает директорию пула (относительно корня репозитория),
+// в которой публикуется пакет данной разновидности.
+func (k debKind) poolDir() string {
+	if k == debKindDdeb {
+		return "pool/" + customDbgComponent
+	}
+
+	return "pool/" + customComponent
+}
+
 // debFileInfo хранит информацию о .deb файле для генерации Packages.
 type debFileInfo struct {
 	Name      string
 	Path      string
+	Kind      debKind
 	Size      int64
 	MD5Sum    string
 	SHA1Sum   string
 	SHA256Sum string
 	FileTime  time.Time
 	Meta      *deb.PackageMeta // Метаданные из control-файла .deb пакета
+	AppStream *deb.AppStream   // Файлы AppStream из data.tar (только для .deb)
+	Lint      []LintFinding    // Проблемы, найденные проверкой файла
+	Contents  []string         // Пути файлов пакета без ведущего "/" (для индекса Contents)
+}
+
+// CustomOptions дополнительные параметры пользовательских репозиториев.
+type CustomOptions struct {
+	// Режим карантина: новые пакеты не попадают в индексы до явного одобрения.
+	Quarantine bool
+
+	// Глубина истории PDiff (Packages.diff): сколько последних изменений индекса
+	// Packages доступно клиентам в виде патчей. Ноль отключает PDiff.
+	PDiffDepth int
+
+	// Вызывается после изменения содержимого репозитория его методами (публикация,
+	// удаление, одобрение и отклонение пакетов). Может быть nil.
+	OnChange func(r *RepoCustom)
 }
 
 // RepoCustom эмулирует работу apt-репозитория на основе директории с .deb файлами.
@@ -42,13 +126,22 @@ type debFileInfo struct {
 //	  custom/
 //	    Release
 //	    main/
+//	      Contents-amd64    (и Contents-udeb-amd64 при наличии .udeb)
 //	      binary-amd64/
 //	        Packages
+//	      debian-installer/
+//	        binary-amd64/
+//	          Packages      (только при наличии .udeb)
+//	    main-dbg/
+//	      binary-amd64/
+//	        Packages        (только при наличии .ddeb)
 //	pool/
 //	  main/
-//	    <файлы>.deb
+//	    <файлы>.deb, <файлы>.udeb
+//	  main-dbg/
+//	    <файлы>.ddeb
 //
-// Release и Packages генерируются на основе реальных .deb файлов в директории.
+// Release и Packages генерируются на основе реальных пакетов в директории.
 type RepoCustom struct {
 	log      *slog.Logger
 	name     string
@@ -58,28 +151,48 @@ type RepoCustom struct {
 	// Мьютекс для защиты кэша при параллельных запросах
 	mu sync.RWMutex
 
+	// Мьютекс, упорядочивающий публикацию загруженных пакетов
+	publishMu sync.Mutex
+
 	// Кэшированная древовидная структура файлов репозитория
 	cacheFiles []models.Entry
 
 	// Индикатор заполненности кэша
 	cacheFilesIsFull bool
 
-	// Список .deb файлов с их метаданными
+	// Дополнительные параметры репозитория
+	options CustomOptions
+
+	// Список опубликованных .deb файлов с их метаданными
 	debFiles []debFileInfo
 
+	// Пакеты на карантине, ожидающие одобрения (только в режиме карантина)
+	stagedFiles []debFileInfo
+
+	// Результаты разбора файлов с предыдущего сканирования. Ключ — путь к файлу.
+	// Файл не разбирается повторно, пока не изменились его размер и время изменения.
+	scanCache map[string]debFileInfo
+
 	// Время запуска программы (фиксируется при создании репозитория)
 	startTime time.Time
 
 	// Сгенерированное содержимое Release файла
 	releaseContent []byte
 
-	// Сгенерированное содержимое Packages файла
-	packagesContent []byte
+	// Сгенерированные индексные файлы. Ключ — путь относительно dists/custom/
+	// (например, "main/binary-amd64/Packages"), значение — содержимое файла.
+	indexFiles map[string][]byte
+
+	// История изменений индексов Packages для PDiff. Ключ — путь индекса.
+	pdiffs map[string]*pdiffHistory
+
+	// Проблемы пакетов, найденные при последнем сканировании
+	lint []LintFinding
 }
 
 // NewRepoCustom конструктор RepoCustom.
 // fullPath — абсолютный путь к директории с .deb файлами.
-func NewRepoCustom(fullPath string, log *slog.Logger) *RepoCustom {
+func NewRepoCustom(fullPath string, log *slog.Logger, options CustomOptions) *RepoCustom {
 	if log == nil {
 		log = slog.New(slog.NewTextHandler(io.Discard))
 	}
@@ -89,6 +202,7 @@ func NewRepoCustom(fullPath string, log *slog.Logger) *RepoCustom {
 		name:      filepath.Base(fullPath),
 		path:      fullPath,
 		repoType:  models.RepoCustom,
+		options:   options,
 		startTime: time.Now().UTC(),
 	}
 
@@ -106,6 +220,14 @@ func (m *RepoCustom) Refresh() {
 	m.scanDebFiles()
 }
 
+// notifyChange сообщает через CustomOptions.OnChange об изменении содержимого
+// репозитория, выполненном его методами.
+func (m *RepoCustom) notifyChange() {
+	if m.options.OnChange != nil {
+		m.options.OnChange(m)
+	}
+}
+
 func (m *RepoCustom) Metadata() models.Repo {
 	return models.Repo{
 		Name: m.name,
@@ -123,6 +245,9 @@ func (m *RepoCustom) IsRepo() bool {
 // Например:
 //
 //	deb [arch=amd64] http://repo.loc:4309/repo/custom-repo.iso custom contrib main non-free
+//
+// Компонент main-dbg с отладочными символами в строку не включается: его подключают
+// отдельно только там, где он действительно нужен.
 func (m *RepoCustom) RepoString() string {
 	return fmt.Sprintf("deb [arch=amd64 trusted=yes] http://0.0.0.0/repo/%s custom contrib main non-free", m.name)
 }
@@ -176,15 +301,21 @@ func (m *RepoCustom) List(ctx context.Context, path string) ([]models.Entry, err
 func (m *RepoCustom) Open(ctx context.Context, path string) (io.ReadCloser, error) {
 	path = strings.Trim(path, "/")
 
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	// Проверяем, не запрашивается ли сгенерированный файл
-	switch path {
-	case "dists/custom/Release":
+	distsPrefix := "dists/" + customCodename + "/"
+	if path == distsPrefix+"Release" {
 		return io.NopCloser(bytes.NewReader(m.releaseContent)), nil
-	case "dists/custom/main/binary-amd64/Packages":
-		return io.NopCloser(bytes.NewReader(m.packagesContent)), nil
+	}
+	if strings.HasPrefix(path, distsPrefix) {
+		if content, ok := m.indexFiles[strings.TrimPrefix(path, distsPrefix)]; ok {
+			return io.NopCloser(bytes.NewReader(content)), nil
+		}
 	}
 
-	// Иначе — ищем .deb файл
+	// Иначе — ищем файл пакета
 	// Путь может быть вида: pool/main/<filename>.deb
 	// Ищем по имени файла в debFiles
 	fileName := filepath.Base(path)
@@ -207,19 +338,25 @@ func (m *RepoCustom) scanDebFiles() {
 	defer m.mu.Unlock()
 
 	m.debFiles = make([]debFileInfo, 0)
+	m.stagedFiles = make([]debFileInfo, 0)
+	scanned := make(map[string]debFileInfo)
 
 	err := filepath.WalkDir(m.path, func(currentPath string, d os.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 
-		// Пропускаем директории
+		// Пропускаем директории, скрытые — вместе с содержимым
 		if d.IsDir() {
+			if currentPath != m.path && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 
-		// Интересуемся только .deb файлами
-		if !strings.HasSuffix(strings.ToLower(d.Name()), ".deb") {
+		// Интересуемся только бинарными пакетами (.deb, .udeb, .ddeb)
+		kind, ok := debKindByName(d.Name())
+		if !ok {
 			return nil
 		}
 
@@ -229,6 +366,14 @@ func (m *RepoCustom) scanDebFiles() {
 			return nil
 		}
 
+		// Файл не изменился с прошлого сканирования — используем готовый результат
+		if cached, ok := m.scanCache[currentPath]; ok && cached.Size == info.Size() && cached.FileTime.Equal(info.ModTime()) {
+			cached.Name = d.Name()
+			m.debFiles = append(m.debFiles, cached)
+			scanned[currentPath] = cached
+			return nil
+		}
+
 		// Вычисляем MD5, SHA1 и SHA256 хэши файла за один проход
 		md5Sum, sha1Sum, sha256Sum, err := m.computeHashes(currentPath)
 		if err != nil {
@@ -242,16 +387,40 @@ func (m *RepoCustom) scanDebFiles() {
 			meta = nil
 		}
 
-		m.debFiles = append(m.debFiles, debFileInfo{
+		// Проверяем пакет; проблемы выводим в лог только при первом разборе файла
+		inspection, inspectErr := inspectFile(currentPath)
+		lint := lintInspection(d.Name(), inspection, inspectErr, kind)
+		for _, f := range lint {
+			m.log.Warn("проблема в пакете", slog.String("file", f.File), slog.String("check", f.Check), slog.String("message", f.Message))
+		}
+
+		// Метаданные AppStream собраны при разборе пакета; нужны только для обычных пакетов
+		var appStream *deb.AppStream
+		if kind == debKindDeb && meta != nil && inspectErr == nil {
+			appStream = inspection.AppStream
+		}
+
+		entry := debFileInfo{
 			Name:      d.Name(),
 			Path:      currentPath,
+			Kind:      kind,
 			Size:      info.Size(),
 			MD5Sum:    md5Sum,
 			SHA1Sum:   sha1Sum,
 			SHA256Sum: sha256Sum,
 			FileTime:  info.ModTime(),
 			Meta:      meta,
-		})
+			AppStream: appStream,
+			Lint:      lint,
+		}
+		if inspectErr == nil {
+			entry.Contents = contentsPaths(inspection)
+		}
+		m.debFiles = append(m.debFiles, entry)
+		// Файлы, которые не удалось прочитать, разбираем заново при следующем сканировании
+		if md5Sum != "" {
+			scanned[currentPath] = entry
+		}
 
 		return nil
 	})
@@ -265,10 +434,17 @@ func (m *RepoCustom) scanDebFiles() {
 		return m.debFiles[i].Name < m.debFiles[j].Name
 	})
 
-	m.log.Debug("сканирование завершено", slog.Int("deb_files", len(m.debFiles)))
+	m.scanCache = scanned
+
+	if m.options.Quarantine {
+		m.splitStaged()
+	}
+	m.updateLint()
+
+	m.log.Debug("сканирование завершено", slog.Int("deb_files", len(m.debFiles)), slog.Int("staged", len(m.stagedFiles)))
 
 	// Генерируем содержимое Packages и Release (важен порядок: сначала Packages, потом Release)
-	m.generatePackagesContent()
+	m.generateIndexFiles()
 	m.generateReleaseContent()
 
 	// Инвалидируем кэш дерева
@@ -300,131 +476,204 @@ func (m *RepoCustom) computeHashes(filePath string) (md5Str, sha1Str, sha256Str
 }
 
 // generateReleaseContent генерирует содержимое файла Release.
+// В контрольные суммы попадают все непустые индексные файлы из indexFiles,
+// кроме патчей PDiff.
 func (m *RepoCustom) generateReleaseContent() {
-	now := m.startTime.Format("Mon, 02 Jan 2006 15:04:05 MST")
+	m.releaseContent = customRelease("Custom apt repository", m.startTime, fmt.Sprintf(changelogsTemplate, url.PathEscape(m.name)), m.indexFiles)
+}
+
+// customRelease формирует Release пользовательского репозитория (или его снимка)
+// с описанием label, датой date, шаблоном Changelogs (пустой — без поля) и
+// контрольными суммами непустых индексов files, кроме патчей PDiff.
+func customRelease(label string, date time.Time, changelogs string, files map[string][]byte) []byte {
+	now := date.Format("Mon, 02 Jan 2006 15:04:05 MST")
+
+	components := "main contrib non-free"
+	if _, ok := files[debKindDdeb.packagesPath()]; ok {
+		components += " " + customDbgComponent
+	}
 
 	var buf bytes.Buffer
 	fmt.Fprintf(&buf, "Origin: Custom\n")
 	fmt.Fprintf(&buf, "Suite: stable\n")
-	fmt.Fprintf(&buf, "Label: Custom apt repository\n")
-	fmt.Fprintf(&buf, "Codename: custom\n")
+	fmt.Fprintf(&buf, "Label: %s\n", label)
+	fmt.Fprintf(&buf, "Codename: %s\n", customCodename)
 	fmt.Fprintf(&buf, "Date: %s\n", now)
-	fmt.Fprintf(&buf, "Architectures: amd64\n")
-	fmt.Fprintf(&buf, "Components: main contrib non-free\n")
-	fmt.Fprintf(&buf, "Description: Custom apt repository\n")
+	fmt.Fprintf(&buf, "Architectures: %s\n", customArch)
+	fmt.Fprintf(&buf, "Components: %s\n", components)
+	fmt.Fprintf(&buf, "Description: %s\n", label)
+	if changelogs != "" {
+		fmt.Fprintf(&buf, "Changelogs: %s\n", changelogs)
+	}
 
-	packagesSize := len(m.packagesContent)
+	// Стабильный порядок файлов в Release
+	paths := make([]string, 0, len(files))
+	for p, content := range files {
+		if len(content) > 0 && !isPDiffPatch(p) {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
 
 	// MD5Sum
 	fmt.Fprintf(&buf, "MD5Sum:\n")
-	if packagesSize > 0 {
-		packagesMD5 := fmt.Sprintf("%x", md5.Sum(m.packagesContent))
-		fmt.Fprintf(&buf, " %s %d main/binary-amd64/Packages\n", packagesMD5, packagesSize)
+	for _, p := range paths {
+		fmt.Fprintf(&buf, " %x %d %s\n", md5.Sum(files[p]), len(files[p]), p)
 	}
 
 	// SHA1
 	fmt.Fprintf(&buf, "SHA1:\n")
-	if packagesSize > 0 {
-		packagesSHA1 := fmt.Sprintf("%x", sha1.Sum(m.packagesContent))
-		fmt.Fprintf(&buf, " %s %d main/binary-amd64/Packages\n", packagesSHA1, packagesSize)
+	for _, p := range paths {
+		fmt.Fprintf(&buf, " %x %d %s\n", sha1.Sum(files[p]), len(files[p]), p)
 	}
 
 	// SHA256
 	fmt.Fprintf(&buf, "SHA256:\n")
-	if packagesSize > 0 {
-		packagesSHA256 := fmt.Sprintf("%x", sha256.Sum256(m.packagesContent))
-		fmt.Fprintf(&buf, " %s %d main/binary-amd64/Packages\n", packagesSHA256, packagesSize)
+	for _, p := range paths {
+		fmt.Fprintf(&buf, " %x %d %s\n", sha256.Sum256(files[p]), len(files[p]), p)
 	}
 
-	m.releaseContent = buf.Bytes()
+	return buf.Bytes()
 }
 
-// generatePackagesContent генерирует содержимое файла Packages на основе .deb файлов.
-func (m *RepoCustom) generatePackagesContent() {
-	var buf bytes.Buffer
+// generateIndexFiles раскладывает пакеты по индексам Packages в зависимости от их
+// разновидности. Индекс основного компонента создаётся всегда (даже пустым),
+// индексы debian-installer и main-dbg — только при наличии соответствующих пакетов.
+func (m *RepoCustom) generateIndexFiles() {
+	buffers := map[string]*bytes.Buffer{
+		debKindDeb.packagesPath(): {},
+	}
 
 	for _, deb := range m.debFiles {
-		// Имя пакета — имя файла без расширения .deb (запасной вариант)
-		pkgName := strings.TrimSuffix(deb.Name, ".deb")
-
-		// Используем метаданные из .deb пакета, если они доступны
-		if deb.Meta != nil {
-			fmt.Fprintf(&buf, "Package: %s\n", deb.Meta.Package)
-			if deb.Meta.Version != "" {
-				fmt.Fprintf(&buf, "Version: %s\n", deb.Meta.Version)
-			}
-			if deb.Meta.Architecture != "" {
-				fmt.Fprintf(&buf, "Architecture: %s\n", deb.Meta.Architecture)
-			}
-			if deb.Meta.Maintainer != "" {
-				fmt.Fprintf(&buf, "Maintainer: %s\n", deb.Meta.Maintainer)
-			}
-			if deb.Meta.InstalledSize != "" {
-				fmt.Fprintf(&buf, "Installed-Size: %s\n", deb.Meta.InstalledSize)
-			}
-			if deb.Meta.Depends != "" {
-				fmt.Fprintf(&buf, "Depends: %s\n", deb.Meta.Depends)
-			}
-			if deb.Meta.PreDepends != "" {
-				fmt.Fprintf(&buf, "Pre-Depends: %s\n", deb.Meta.PreDepends)
-			}
-			if deb.Meta.Recommends != "" {
-				fmt.Fprintf(&buf, "Recommends: %s\n", deb.Meta.Recommends)
-			}
-			if deb.Meta.Suggests != "" {
-				fmt.Fprintf(&buf, "Suggests: %s\n", deb.Meta.Suggests)
-			}
-			if deb.Meta.Conflicts != "" {
-				fmt.Fprintf(&buf, "Conflicts: %s\n", deb.Meta.Conflicts)
-			}
-			if deb.Meta.Replaces != "" {
-				fmt.Fprintf(&buf, "Replaces: %s\n", deb.Meta.Replaces)
-			}
-			if deb.Meta.Provides != "" {
-				fmt.Fprintf(&buf, "Provides: %s\n", deb.Meta.Provides)
-			}
-			if deb.Meta.Section != "" {
-				fmt.Fprintf(&buf, "Section: %s\n", deb.Meta.Section)
-			}
-			if deb.Meta.Priority != "" {
-				fmt.Fprintf(&buf, "Priority: %s\n", deb.Meta.Priority)
-			}
-			if deb.Meta.Homepage != "" {
-				fmt.Fprintf(&buf, "Homepage: %s\n", deb.Meta.Homepage)
-			}
-			// Дополнительные поля из Extra
-			for k, v := range deb.Meta.Extra {
-				fmt.Fprintf(&buf, "%s: %s\n", k, v)
-			}
-		} else {
-			// Если метаданные недоступны, используем запасные значения
-			fmt.Fprintf(&buf, "Package: %s\n", pkgName)
-			fmt.Fprintf(&buf, "Version: 1.0\n")
-			fmt.Fprintf(&buf, "Architecture: amd64\n")
-			fmt.Fprintf(&buf, "Maintainer: Custom Repository\n")
+		indexPath := deb.Kind.packagesPath()
+		if buffers[indexPath] == nil {
+			buffers[indexPath] = &bytes.Buffer{}
 		}
+		writePackageStanza(buffers[indexPath], deb)
+	}
+
+	m.indexFiles = make(map[string][]byte, len(buffers))
+	for p, buf := range buffers {
+		m.indexFiles[p] = buf.Bytes()
+	}
+
+	m.updatePDiffs(m.indexFiles)
+	m.generateDEP11(m.indexFiles)
+	m.generateContents(m.indexFiles)
+}
 
-		fmt.Fprintf(&buf, "Filename: pool/main/%s\n", deb.Name)
-		fmt.Fprintf(&buf, "Size: %d\n", deb.Size)
-		if deb.MD5Sum != "" {
-			fmt.Fprintf(&buf, "MD5sum: %s\n", deb.MD5Sum)
+// StanzaField — поле записи (stanza) индекса Packages.
+type StanzaField struct {
+	Name  string
+	Value string
+}
+
+// Stanza — запись индекса Packages с сохранением порядка полей.
+type Stanza []StanzaField
+
+// MarshalJSON представляет запись в виде JSON-объекта с исходным порядком полей.
+func (s Stanza) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range s {
+		if i > 0 {
+			buf.WriteByte(',')
 		}
-		if deb.SHA1Sum != "" {
-			fmt.Fprintf(&buf, "SHA1: %s\n", deb.SHA1Sum)
+		name, err := json.Marshal(f.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(f.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// writePackageStanza записывает в buf запись (stanza) индекса Packages для пакета d.
+func writePackageStanza(buf *bytes.Buffer, d debFileInfo) {
+	for _, f := range packageStanza(d) {
+		buf.WriteString(deb.FormatField(f.Name, f.Value))
+	}
+	buf.WriteString("\n")
+}
+
+// packageStanza формирует упорядоченный список полей записи индекса Packages для пакета deb.
+func packageStanza(deb debFileInfo) Stanza {
+	fields := controlStanza(deb)
+	add := func(name, value string) {
+		if value != "" {
+			fields = append(fields, StanzaField{Name: name, Value: value})
+		}
+	}
+
+	add("Filename", deb.Kind.poolDir()+"/"+deb.Name)
+	add("Size", fmt.Sprintf("%d", deb.Size))
+	add("MD5sum", deb.MD5Sum)
+	add("SHA1", deb.SHA1Sum)
+	add("SHA256", deb.SHA256Sum)
+	// Если Description не был выведен из метаданных, добавляем запасной
+	if deb.Meta == nil || deb.Meta.Description == "" {
+		add("Description", strings.TrimSuffix(deb.Name, filepath.Ext(deb.Name)))
+	}
+
+	return fields
+}
+
+// controlStanza формирует упорядоченный список полей из control-файла пакета deb.
+// Если метаданные недоступны, используются запасные значения.
+func controlStanza(deb debFileInfo) Stanza {
+	fields := make(Stanza, 0, 24)
+	add := func(name, value string) {
+		if value != "" {
+			fields = append(fields, StanzaField{Name: name, Value: value})
 		}
-		if deb.SHA256Sum != "" {
-			fmt.Fprintf(&buf, "SHA256: %s\n", deb.SHA256Sum)
+	}
+
+	// Используем метаданные из пакета, если они доступны
+	if deb.Meta != nil {
+		fields = append(fields, StanzaField{Name: "Package", Value: deb.Meta.Package})
+		add("Version", deb.Meta.Version)
+		add("Architecture", deb.Meta.Architecture)
+		add("Maintainer", deb.Meta.Maintainer)
+		add("Installed-Size", deb.Meta.InstalledSize)
+		add("Depends", deb.Meta.Depends)
+		add("Pre-Depends", deb.Meta.PreDepends)
+		add("Recommends", deb.Meta.Recommends)
+		add("Suggests", deb.Meta.Suggests)
+		add("Conflicts", deb.Meta.Conflicts)
+		add("Replaces", deb.Meta.Replaces)
+		add("Provides", deb.Meta.Provides)
+		add("Section", deb.Meta.Section)
+		add("Priority", deb.Meta.Priority)
+		add("Homepage", deb.Meta.Homepage)
+		// Дополнительные поля из Extra (в стабильном порядке)
+		extraKeys := make([]string, 0, len(deb.Meta.Extra))
+		for k := range deb.Meta.Extra {
+			extraKeys = append(extraKeys, k)
 		}
-		// Если Description не был выведен из метаданных, добавляем запасной
-		if deb.Meta != nil && deb.Meta.Description == "" {
-			fmt.Fprintf(&buf, "Description: %s\n", pkgName)
-		} else if deb.Meta == nil {
-			fmt.Fprintf(&buf, "Description: %s\n", pkgName)
+		sort.Strings(extraKeys)
+		for _, k := range extraKeys {
+			add(k, deb.Meta.Extra[k])
 		}
-		buf.WriteString("\n")
+		add("Description", deb.Meta.Description)
+
+		return fields
 	}
 
-	m.packagesContent = buf.Bytes()
+	// Имя пакета — имя файла без расширения (запасной вариант)
+	add("Package", strings.TrimSuffix(deb.Name, filepath.Ext(deb.Name)))
+	add("Version", "1.0")
+	add("Architecture", customArch)
+	add("Maintainer", "Custom Repository")
+
+	return fields
 }
 
 // buildCache строит древовидную структуру виртуального репозитория.
@@ -435,86 +684,81 @@ func (m *RepoCustom) buildCache() {
 	// dists/
 	//   custom/
 	//     Release (файл)
-	//     main/
-	//       binary-amd64/
-	//         Packages (файл)
+	//     <индексные файлы из indexFiles>
 	// pool/
 	//   main/
-	//     <deb файлы>
+	//     <deb и udeb файлы>
+	//   main-dbg/
+	//     <ddeb файлы>
 
-	// Создаём dists/custom/Release
-	releaseEntry := models.Entry{
+	distsPrefix := []string{"dists", customCodename}
+
+	insertEntry(&m.cacheFiles, distsPrefix, models.Entry{
 		Name:     "Release",
 		IsDir:    false,
 		Size:     int64(len(m.releaseContent)),
 		CreateAt: m.startTime,
 		Children: make([]models.Entry, 0),
-	}
-
-	// Создаём dists/custom/main/binary-amd64/Packages
-	packagesEntry := models.Entry{
-		Name:     "Packages",
-		IsDir:    false,
-		Size:     int64(len(m.packagesContent)),
-		CreateAt: m.startTime,
-		Children: make([]models.Entry, 0),
-	}
-
-	// Создаём dists/custom/main/binary-amd64/
-	binaryAmd64Dir := models.Entry{
-		Name:     "binary-amd64",
-		IsDir:    true,
-		Children: []models.Entry{packagesEntry},
-	}
-
-	// Создаём dists/custom/main/
-	mainDir := models.Entry{
-		Name:     "main",
-		IsDir:    true,
-		Children: []models.Entry{binaryAmd64Dir},
-	}
+	})
 
-	// Создаём dists/custom/
-	customDir := models.Entry{
-		Name:     "custom",
-		IsDir:    true,
-		Children: []models.Entry{releaseEntry, mainDir},
+	indexPaths := make([]string, 0, len(m.indexFiles))
+	for p := range m.indexFiles {
+		indexPaths = append(indexPaths, p)
 	}
+	sort.Strings(indexPaths)
 
-	// Создаём dists/
-	distsDir := models.Entry{
-		Name:     "dists",
-		IsDir:    true,
-		Children: []models.Entry{customDir},
+	for _, p := range indexPaths {
+		segments := strings.Split(p, "/")
+		dirs := append(append([]string{}, distsPrefix...), segments[:len(segments)-1]...)
+		insertEntry(&m.cacheFiles, dirs, models.Entry{
+			Name:     segments[len(segments)-1],
+			IsDir:    false,
+			Size:     int64(len(m.indexFiles[p])),
+			CreateAt: m.startTime,
+			Children: make([]models.Entry, 0),
+		})
 	}
 
-	m.cacheFiles = append(m.cacheFiles, distsDir)
-
-	// Создаём pool/main/ с .deb файлами
-	poolMainDir := models.Entry{
-		Name:     "main",
-		IsDir:    true,
-		Children: make([]models.Entry, 0),
-	}
+	// Директория pool/main существует всегда, даже если пакетов нет
+	insertEntry(&m.cacheFiles, []string{"pool", customComponent}, models.Entry{})
 
 	for _, deb := range m.debFiles {
-		debEntry := models.Entry{
+		insertEntry(&m.cacheFiles, strings.Split(deb.Kind.poolDir(), "/"), models.Entry{
 			Name:     deb.Name,
 			IsDir:    false,
 			Size:     deb.Size,
 			CreateAt: deb.FileTime,
 			Children: make([]models.Entry, 0),
-		}
-		poolMainDir.Children = append(poolMainDir.Children, debEntry)
+		})
 	}
 
-	poolDir := models.Entry{
-		Name:     "pool",
-		IsDir:    true,
-		Children: []models.Entry{poolMainDir},
-	}
+	m.cacheFilesIsFull = true
+}
 
-	m.cacheFiles = append(m.cacheFiles, poolDir)
+// insertEntry добавляет элемент entry в дерево tree по пути из директорий dirs,
+// создавая недостающие промежуточные директории. Если у entry пустое имя,
+// создаются только директории.
+func insertEntry(tree *[]models.Entry, dirs []string, entry models.Entry) {
+	for _, dir := range dirs {
+		index := -1
+		for i, e := range *tree {
+			if e.IsDir && e.Name == dir {
+				index = i
+				break
+			}
+		}
+		if index == -1 {
+			*tree = append(*tree, models.Entry{
+				Name:     dir,
+				IsDir:    true,
+				Children: make([]models.Entry, 0),
+			})
+			index = len(*tree) - 1
+		}
+		tree = &(*tree)[index].Children
+	}
 
-	m.cacheFilesIsFull = true
+	if entry.Name != "" {
+		*tree = append(*tree, entry)
+	}
 }
@@ -0,0 +1,136 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kirsrus/iso2repo/pkg/deb"
+	"github.com/kirsrus/iso2repo/pkg/deb/debtest"
+	"golang.org/x/exp/slog"
+)
+
+func TestRepoCustom_scanKinds(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "custom.iso")
+	debtest.Write(t, dir, "app_1.0_amd64.deb", debtest.Package(t, "app", "1.0", "amd64", map[string]string{"/usr/bin/app": "app"}))
+	debtest.Write(t, dir, "app-udeb_1.0_amd64.udeb", debtest.Package(t, "app-udeb", "1.0", "amd64", nil))
+	debtest.Write(t, dir, "app-dbgsym_1.0_amd64.ddeb", debtest.Package(t, "app-dbgsym", "1.0", "amd64", nil))
+
+	r := NewRepoCustom(dir, slog.New(slog.NewTextHandler(io.Discard)), CustomOptions{})
+
+	read := func(p string) string {
+		t.Helper()
+		reader, err := r.Open(context.Background(), p)
+		if err != nil {
+			t.Fatalf("Open(%s) error = %v", p, err)
+		}
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	tests := []struct {
+		name     string
+		index    string
+		want     string
+		filename string
+	}{
+		{name: "deb in main", index: "main/binary-amd64/Packages", want: "app", filename: "pool/main/app_1.0_amd64.deb"},
+		{name: "udeb in debian-installer", index: "main/debian-installer/binary-amd64/Packages", want: "app-udeb", filename: "pool/main/app-udeb_1.0_amd64.udeb"},
+		{name: "ddeb in debug component", index: "main-dbg/binary-amd64/Packages", want: "app-dbgsym", filename: "pool/main-dbg/app-dbgsym_1.0_amd64.ddeb"},
+	}
+
+	release, err := deb.ReadRelease(strings.NewReader(read("dists/custom/Release")))
+	if err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index := read("dists/custom/" + tt.index)
+
+			names := make([]string, 0)
+			err := deb.ReadParagraphs(bytes.NewReader([]byte(index)), func(p deb.Paragraph) error {
+				names = append(names, p.Get("Package"))
+				if p.Get("Filename") != tt.filename {
+					t.Errorf("Filename = %q, want %q", p.Get("Filename"), tt.filename)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("ReadParagraphs() error = %v", err)
+			}
+			if len(names) != 1 || names[0] != tt.want {
+				t.Errorf("%s packages = %v, want [%s]", tt.index, names, tt.want)
+			}
+
+			f, ok := release.File(tt.index)
+			if !ok || f.Hash != fmt.Sprintf("%x", sha256.Sum256([]byte(index))) || f.Size != int64(len(index)) {
+				t.Errorf("Release entry for %s = %+v, %v", tt.index, f, ok)
+			}
+		})
+	}
+
+	if !strings.Contains(strings.Join(release.Components, " "), customDbgComponent) {
+		t.Errorf("Release components = %v, want %s", release.Components, customDbgComponent)
+	}
+	if data := read(tests[2].filename); !strings.HasPrefix(data, "!<arch>") {
+		t.Errorf("Open(ddeb) = %q", data)
+	}
+}
+
+func TestPackageStanza_description(t *testing.T) {
+	tests := []struct {
+		name string
+		deb  debFileInfo
+		want []string
+	}{
+		{
+			name: "description from control",
+			deb: debFileInfo{Name: "foo_1.0_amd64.deb", Meta: &deb.PackageMeta{
+				Package: "foo", Version: "1.0", Architecture: "amd64", Description: "short\nlong text\n.\nmore",
+			}},
+			want: []string{"short\nlong text\n.\nmore"},
+		},
+		{
+			name: "empty description in control",
+			deb: debFileInfo{Name: "foo_1.0_amd64.deb", Meta: &deb.PackageMeta{
+				Package: "foo", Version: "1.0", Architecture: "amd64",
+			}},
+			want: []string{"foo_1.0_amd64"},
+		},
+		{
+			name: "no metadata",
+			deb:  debFileInfo{Name: "foo_1.0_amd64.deb"},
+			want: []string{"foo_1.0_amd64"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			writePackageStanza(&buf, tt.deb)
+
+			p, err := deb.NewReader(&buf).Next()
+			if err != nil {
+				t.Fatalf("Next() error = %v", err)
+			}
+			var got []string
+			for _, f := range p {
+				if f.Name == "Description" {
+					got = append(got, f.Value)
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Description = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
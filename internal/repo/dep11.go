@@ -0,0 +1,519 @@
+package repo
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"image/png"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kirsrus/iso2repo/pkg/deb"
+	"golang.org/x/exp/slog"
+	"gopkg.in/yaml.v3"
+)
+
+// dep11IconSizes размеры кэшированных иконок, публикуемых в DEP-11.
+var dep11IconSizes = []int{64, 128}
+
+// dep11Dir директория метаданных AppStream относительно dists/<codename>/.
+const dep11Dir = customComponent + "/dep11/"
+
+// dep11Header заголовочный документ файла Components.
+type dep11Header struct {
+	File    string `yaml:"File"`
+	Version string `yaml:"Version"`
+	Origin  string `yaml:"Origin"`
+}
+
+// dep11Component документ с описанием одного компонента AppStream.
+// Порядок полей соответствует принятому в архивах Debian.
+type dep11Component struct {
+	Type           string              `yaml:"Type"`
+	ID             string              `yaml:"ID"`
+	Package        string              `yaml:"Package"`
+	Name           map[string]string   `yaml:"Name"`
+	Summary        map[string]string   `yaml:"Summary"`
+	Description    map[string]string   `yaml:"Description,omitempty"`
+	ProjectLicense string              `yaml:"ProjectLicense,omitempty"`
+	DeveloperName  map[string]string   `yaml:"DeveloperName,omitempty"`
+	Categories     []string            `yaml:"Categories,omitempty"`
+	Keywords       map[string][]string `yaml:"Keywords,omitempty"`
+	URL            map[string]string   `yaml:"Url,omitempty"`
+	Launchable     map[string][]string `yaml:"Launchable,omitempty"`
+	Icon           *dep11Icon          `yaml:"Icon,omitempty"`
+	Screenshots    []dep11Screenshot   `yaml:"Screenshots,omitempty"`
+}
+
+// dep11Icon описание иконок компонента.
+type dep11Icon struct {
+	Stock  string            `yaml:"stock,omitempty"`
+	Cached []dep11CachedIcon `yaml:"cached,omitempty"`
+}
+
+// dep11CachedIcon иконка, опубликованная в архиве icons-<размер>.tar.
+type dep11CachedIcon struct {
+	Name   string `yaml:"name"`
+	Width  int    `yaml:"width"`
+	Height int    `yaml:"height"`
+}
+
+// dep11Screenshot снимок экрана компонента.
+type dep11Screenshot struct {
+	Default     bool              `yaml:"default,omitempty"`
+	Caption     map[string]string `yaml:"caption,omitempty"`
+	SourceImage map[string]string `yaml:"source-image"`
+}
+
+// metaInfoXML описание компонента в формате AppStream MetaInfo.
+type metaInfoXML struct {
+	XMLName     xml.Name
+	Type        string          `xml:"type,attr"`
+	ID          string          `xml:"id"`
+	Names       []langText      `xml:"name"`
+	Summaries   []langText      `xml:"summary"`
+	Description *descriptionXML `xml:"description"`
+	License     string          `xml:"project_license"`
+	Developer   []langText      `xml:"developer_name"`
+	DeveloperV1 []langText      `xml:"developer>name"`
+	Categories  []string        `xml:"categories>category"`
+	Keywords    []langText      `xml:"keywords>keyword"`
+	URLs        []typedText     `xml:"url"`
+	Launchables []typedText     `xml:"launchable"`
+	Icons       []typedText     `xml:"icon"`
+	Screenshots []screenshotXML `xml:"screenshots>screenshot"`
+}
+
+// langText элемент с необязательным атрибутом xml:lang.
+type langText struct {
+	Lang  string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Value string `xml:",chardata"`
+}
+
+// typedText элемент с атрибутом type.
+type typedText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// descriptionXML разметка описания компонента (абзацы и списки).
+type descriptionXML struct {
+	Items []descriptionItem `xml:",any"`
+}
+
+// descriptionItem элемент описания: p, ul, ol или li.
+type descriptionItem struct {
+	XMLName xml.Name
+	Lang    string            `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Inner   string            `xml:",innerxml"`
+	Items   []descriptionItem `xml:"li"`
+}
+
+// screenshotXML снимок экрана в MetaInfo.
+type screenshotXML struct {
+	Type     string     `xml:"type,attr"`
+	Captions []langText `xml:"caption"`
+	Images   []string   `xml:"image"`
+}
+
+// generateDEP11 формирует метаданные AppStream для опубликованных пакетов:
+// Components-<arch>.yml и архивы с иконками icons-<размер>.tar (вместе с .gz).
+// Результат добавляется в files (ключ — путь относительно dists/<codename>/).
+// Если ни в одном пакете нет описаний компонентов, файлы не создаются.
+func (m *RepoCustom) generateDEP11(files map[string][]byte) {
+	// Для каждого ID берём компонент из самого свежего файла пакета
+	byID := make(map[string]debFileInfo)
+	componentsByID := make(map[string]*dep11Component)
+	icons := make(map[int]map[string][]byte)
+
+	for _, d := range m.debFiles {
+		if d.Kind != debKindDeb || d.AppStream.Empty() || d.Meta == nil {
+			continue
+		}
+
+		for _, mi := range d.AppStream.MetaInfo {
+			component, err := newDEP11Component(d, mi)
+			if err != nil {
+				m.log.Warn("описание AppStream пропущено", slog.String("file", d.Name), slog.String("metainfo", mi.Path), slog.Any("error", err))
+				continue
+			}
+			if prev, ok := byID[component.ID]; ok && prev.FileTime.After(d.FileTime) {
+				continue
+			}
+			byID[component.ID] = d
+			componentsByID[component.ID] = component
+		}
+	}
+
+	if len(componentsByID) == 0 {
+		return
+	}
+
+	ids := make([]string, 0, len(componentsByID))
+	for id := range componentsByID {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var yml bytes.Buffer
+	enc := yaml.NewEncoder(&yml)
+	enc.SetIndent(2)
+	_ = enc.Encode(dep11Header{File: "DEP-11", Version: "0.12", Origin: customCodename + "-" + customComponent})
+
+	for _, id := range ids {
+		component := componentsByID[id]
+		attachDEP11Icons(component, byID[id], icons)
+		if err := enc.Encode(component); err != nil {
+			m.log.Warn("не удалось сформировать описание AppStream", slog.String("id", id), slog.Any("error", err))
+		}
+	}
+	_ = enc.Close()
+
+	// yaml.v3 разделяет документы "---" только начиная со второго; DEP-11 требует его перед каждым
+	addCompressed(files, dep11Dir+"Components-"+customArch+".yml", append([]byte("---\n"), yml.Bytes()...))
+
+	for _, size := range dep11IconSizes {
+		if len(icons[size]) > 0 {
+			addCompressed(files, fmt.Sprintf("%sicons-%dx%d.tar", dep11Dir, size, size), tarBytes(icons[size]))
+		}
+	}
+}
+
+// addCompressed добавляет в files файл name в несжатом виде и в gzip. apt выбирает
+// сжатый вариант, но дополнительные индексы (в отличие от Packages) скачивает,
+// только если в Release перечислен и несжатый файл.
+func addCompressed(files map[string][]byte, name string, data []byte) {
+	files[name] = data
+	files[name+".gz"] = gzipBytes(data)
+}
+
+// newDEP11Component разбирает MetaInfo и дополняет его данными из .desktop файла пакета.
+func newDEP11Component(d debFileInfo, mi deb.AppStreamFile) (*dep11Component, error) {
+	var doc metaInfoXML
+	if err := xml.Unmarshal(mi.Data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.XMLName.Local != "component" && doc.XMLName.Local != "application" {
+		return nil, fmt.Errorf("корневой элемент %s не является компонентом", doc.XMLName.Local)
+	}
+
+	c := &dep11Component{
+		Type:           doc.Type,
+		ID:             strings.TrimSpace(doc.ID),
+		Package:        d.Meta.Package,
+		Name:           langMap(doc.Names),
+		Summary:        langMap(doc.Summaries),
+		Description:    doc.Description.render(),
+		ProjectLicense: strings.TrimSpace(doc.License),
+		DeveloperName:  langMap(append(doc.Developer, doc.DeveloperV1...)),
+		URL:            make(map[string]string),
+		Launchable:     make(map[string][]string),
+	}
+	if c.Type == "" || c.Type == "desktop" {
+		c.Type = "desktop-application"
+	}
+	if c.ID == "" {
+		return nil, fmt.Errorf("не указан id компонента")
+	}
+
+	for _, cat := range doc.Categories {
+		if cat = strings.TrimSpace(cat); cat != "" {
+			c.Categories = append(c.Categories, cat)
+		}
+	}
+	for _, kw := range doc.Keywords {
+		if c.Keywords == nil {
+			c.Keywords = make(map[string][]string)
+		}
+		lang := langOrC(kw.Lang)
+		c.Keywords[lang] = append(c.Keywords[lang], strings.TrimSpace(kw.Value))
+	}
+	for _, u := range doc.URLs {
+		c.URL[u.Type] = strings.TrimSpace(u.Value)
+	}
+	for _, l := range doc.Launchables {
+		c.Launchable[l.Type] = append(c.Launchable[l.Type], strings.TrimSpace(l.Value))
+	}
+	for _, s := range doc.Screenshots {
+		if len(s.Images) == 0 {
+			continue
+		}
+		c.Screenshots = append(c.Screenshots, dep11Screenshot{
+			Default:     s.Type == "default",
+			Caption:     langMap(s.Captions),
+			SourceImage: map[string]string{"url": strings.TrimSpace(s.Images[0])},
+		})
+	}
+
+	// Настольному приложению без launchable сопоставляем .desktop файл по ID
+	if c.Type == "desktop-application" && len(c.Launchable["desktop-id"]) == 0 {
+		desktopID := c.ID
+		if !strings.HasSuffix(desktopID, ".desktop") {
+			desktopID += ".desktop"
+		}
+		c.Launchable["desktop-id"] = []string{desktopID}
+	}
+
+	stock := ""
+	for _, icon := range doc.Icons {
+		if icon.Type == "stock" {
+			stock = strings.TrimSpace(icon.Value)
+		}
+	}
+
+	// Недостающие сведения берём из .desktop файла
+	if c.Name == nil {
+		c.Name = make(map[string]string)
+	}
+	if c.Summary == nil {
+		c.Summary = make(map[string]string)
+	}
+	for _, id := range c.Launchable["desktop-id"] {
+		entry, ok := findDesktopEntry(d.AppStream, id)
+		if !ok {
+			continue
+		}
+		for lang, v := range entry.localized("Name") {
+			if _, ok := c.Name[lang]; !ok {
+				c.Name[lang] = v
+			}
+		}
+		for lang, v := range entry.localized("Comment") {
+			if _, ok := c.Summary[lang]; !ok {
+				c.Summary[lang] = v
+			}
+		}
+		if len(c.Categories) == 0 {
+			for _, cat := range strings.Split(entry["Categories"], ";") {
+				if cat != "" && !strings.HasPrefix(cat, "X-") {
+					c.Categories = append(c.Categories, cat)
+				}
+			}
+		}
+		if stock == "" {
+			stock = entry["Icon"]
+		}
+	}
+
+	if len(c.Name) == 0 || len(c.Summary) == 0 {
+		return nil, fmt.Errorf("у компонента %s нет имени или краткого описания", c.ID)
+	}
+	if stock != "" {
+		c.Icon = &dep11Icon{Stock: stock}
+	}
+	if len(c.URL) == 0 {
+		c.URL = nil
+	}
+	if len(c.Launchable) == 0 {
+		c.Launchable = nil
+	}
+
+	return c, nil
+}
+
+// attachDEP11Icons находит в пакете иконки компонента нужных размеров, добавляет их
+// в icons (размер → имя в архиве → содержимое) и ссылки на них в описание компонента.
+func attachDEP11Icons(c *dep11Component, d debFileInfo, icons map[int]map[string][]byte) {
+	if c.Icon == nil || c.Icon.Stock == "" || strings.Contains(c.Icon.Stock, "/") {
+		return
+	}
+
+	stock := strings.TrimSuffix(c.Icon.Stock, ".png")
+	cachedName := d.Meta.Package + "_" + stock + ".png"
+
+	for _, size := range dep11IconSizes {
+		data, ok := findIcon(d.AppStream, stock, size)
+		if !ok {
+			continue
+		}
+		if icons[size] == nil {
+			icons[size] = make(map[string][]byte)
+		}
+		icons[size][cachedName] = data
+		c.Icon.Cached = append(c.Icon.Cached, dep11CachedIcon{Name: cachedName, Width: size, Height: size})
+	}
+}
+
+// findIcon ищет иконку name размера size: сначала в теме hicolor,
+// затем в usr/share/pixmaps (если размер изображения совпадает).
+func findIcon(a *deb.AppStream, name string, size int) ([]byte, bool) {
+	hicolor := fmt.Sprintf("usr/share/icons/hicolor/%dx%d/apps/%s.png", size, size, name)
+	for _, f := range a.Icons {
+		if f.Path == hicolor {
+			return f.Data, true
+		}
+	}
+
+	for _, f := range a.Icons {
+		if f.Path != "usr/share/pixmaps/"+name+".png" {
+			continue
+		}
+		cfg, err := png.DecodeConfig(bytes.NewReader(f.Data))
+		if err == nil && cfg.Width == size && cfg.Height == size {
+			return f.Data, true
+		}
+	}
+
+	return nil, false
+}
+
+// desktopEntry ключи группы [Desktop Entry] .desktop файла.
+type desktopEntry map[string]string
+
+// localized возвращает значения ключа key по языкам ("C" — без указания языка).
+func (e desktopEntry) localized(key string) map[string]string {
+	result := make(map[string]string)
+	for k, v := range e {
+		switch {
+		case k == key:
+			result["C"] = v
+		case strings.HasPrefix(k, key+"[") && strings.HasSuffix(k, "]"):
+			result[k[len(key)+1:len(k)-1]] = v
+		}
+	}
+
+	return result
+}
+
+// findDesktopEntry ищет в пакете .desktop файл с именем id и разбирает его.
+func findDesktopEntry(a *deb.AppStream, id string) (desktopEntry, bool) {
+	for _, f := range a.Desktop {
+		if path.Base(f.Path) != id {
+			continue
+		}
+
+		entry := make(desktopEntry)
+		inGroup := false
+		sc := bufio.NewScanner(bytes.NewReader(f.Data))
+		for sc.Scan() {
+			line := strings.TrimSpace(sc.Text())
+			if strings.HasPrefix(line, "[") {
+				inGroup = line == "[Desktop Entry]"
+				continue
+			}
+			if !inGroup || line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if k, v, ok := strings.Cut(line, "="); ok {
+				entry[strings.TrimSpace(k)] = strings.TrimSpace(v)
+			}
+		}
+
+		return entry, true
+	}
+
+	return nil, false
+}
+
+// render преобразует описание в HTML-подобную разметку DEP-11 по языкам.
+func (d *descriptionXML) render() map[string]string {
+	if d == nil {
+		return nil
+	}
+
+	parts := make(map[string]*strings.Builder)
+	write := func(lang, s string) {
+		lang = langOrC(lang)
+		if parts[lang] == nil {
+			parts[lang] = &strings.Builder{}
+		}
+		parts[lang].WriteString(s)
+	}
+
+	for _, item := range d.Items {
+		switch item.XMLName.Local {
+		case "p":
+			write(item.Lang, "<p>"+collapseSpaces(item.Inner)+"</p>")
+		case "ul", "ol":
+			// Пункты списка группируются по языкам, каждый язык получает свой список
+			byLang := make(map[string][]string)
+			order := make([]string, 0)
+			for _, li := range item.Items {
+				lang := langOrC(li.Lang)
+				if _, ok := byLang[lang]; !ok {
+					order = append(order, lang)
+				}
+				byLang[lang] = append(byLang[lang], "<li>"+collapseSpaces(li.Inner)+"</li>")
+			}
+			for _, lang := range order {
+				write(lang, "<"+item.XMLName.Local+">"+strings.Join(byLang[lang], "")+"</"+item.XMLName.Local+">")
+			}
+		}
+	}
+
+	result := make(map[string]string, len(parts))
+	for lang, b := range parts {
+		result[lang] = b.String()
+	}
+
+	return result
+}
+
+// langMap собирает переводы в карту язык → значение ("C" — без указания языка).
+func langMap(items []langText) map[string]string {
+	result := make(map[string]string)
+	for _, item := range items {
+		if v := collapseSpaces(item.Value); v != "" {
+			result[langOrC(item.Lang)] = v
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+
+	return result
+}
+
+// langOrC возвращает язык или "C", если язык не указан.
+func langOrC(lang string) string {
+	if lang == "" {
+		return "C"
+	}
+
+	return lang
+}
+
+// collapseSpaces схлопывает последовательности пробельных символов в один пробел.
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// gzipBytes сжимает data в gzip. Заголовок не содержит времени и имени файла,
+// поэтому результат (и контрольные суммы в Release) зависит только от содержимого.
+func gzipBytes(data []byte) []byte {
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	_, _ = zw.Write(data)
+	_ = zw.Close()
+
+	return buf.Bytes()
+}
+
+// tarBytes упаковывает файлы (имя → содержимое) в tar в порядке имён.
+func tarBytes(files map[string][]byte) []byte {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range names {
+		_ = tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0o644,
+			Size:    int64(len(files[name])),
+			ModTime: time.Unix(0, 0),
+		})
+		_, _ = tw.Write(files[name])
+	}
+	_ = tw.Close()
+
+	return buf.Bytes()
+}
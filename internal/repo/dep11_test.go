@@ -0,0 +1,168 @@
+package repo
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kirsrus/iso2repo/pkg/deb"
+	"github.com/kirsrus/iso2repo/pkg/deb/debtest"
+	"gopkg.in/yaml.v3"
+)
+
+// testPNG возвращает PNG-изображение size×size.
+func testPNG(t *testing.T, size int) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, size, size))); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.String()
+}
+
+func TestRepoCustom_generateDEP11(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "custom.iso")
+	icon64, icon128 := testPNG(t, 64), testPNG(t, 128)
+	debtest.Write(t, dir, "viewer_1.0_amd64.deb", debtest.Package(t, "viewer", "1.0", "amd64", map[string]string{
+		"/usr/share/metainfo/org.example.Viewer.metainfo.xml": `<?xml version="1.0"?>
+<component type="desktop-application">
+  <id>org.example.Viewer</id>
+  <summary>Image viewer</summary>
+  <summary xml:lang="ru">Просмотр изображений</summary>
+  <description><p>Shows images.</p></description>
+  <project_license>MIT</project_license>
+  <url type="homepage">https://example.org</url>
+</component>`,
+		"/usr/share/applications/org.example.Viewer.desktop": "[Desktop Entry]\nName=Viewer\nName[ru]=Просмотрщик\nIcon=viewer\nCategories=Graphics;X-Private;\n",
+		"/usr/share/icons/hicolor/64x64/apps/viewer.png":     icon64,
+		"/usr/share/pixmaps/viewer.png":                      icon128,
+	}))
+	// Пакет без описаний компонентов в DEP-11 не попадает
+	debtest.Write(t, dir, "tool_1.0_amd64.deb", debtest.Package(t, "tool", "1.0", "amd64", map[string]string{
+		"/usr/share/applications/tool.desktop": "[Desktop Entry]\nName=Tool\n",
+	}))
+
+	r := NewRepoCustom(dir, nil, CustomOptions{})
+	read := func(p string) []byte {
+		t.Helper()
+		reader, err := r.Open(context.Background(), "dists/custom/"+p)
+		if err != nil {
+			t.Fatalf("Open(%s) error = %v", p, err)
+		}
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	yml := read(dep11Dir + "Components-amd64.yml")
+	if !bytes.HasPrefix(yml, []byte("---\n")) {
+		t.Errorf("Components does not start with document separator: %q", yml[:10])
+	}
+
+	docs := make([]map[string]any, 0)
+	dec := yaml.NewDecoder(bytes.NewReader(yml))
+	for {
+		var doc map[string]any
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Components YAML: %v", err)
+		}
+		docs = append(docs, doc)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("Components has %d documents, want header and one component", len(docs))
+	}
+	if docs[0]["File"] != "DEP-11" || docs[0]["Origin"] != "custom-main" {
+		t.Errorf("header = %v", docs[0])
+	}
+
+	component := docs[1]
+	tests := []struct {
+		field string
+		want  any
+	}{
+		{field: "Type", want: "desktop-application"},
+		{field: "ID", want: "org.example.Viewer"},
+		{field: "Package", want: "viewer"},
+		{field: "Name", want: map[string]any{"C": "Viewer", "ru": "Просмотрщик"}},
+		{field: "Summary", want: map[string]any{"C": "Image viewer", "ru": "Просмотр изображений"}},
+		{field: "Description", want: map[string]any{"C": "<p>Shows images.</p>"}},
+		{field: "ProjectLicense", want: "MIT"},
+		{field: "Categories", want: []any{"Graphics"}},
+		{field: "Url", want: map[string]any{"homepage": "https://example.org"}},
+		{field: "Launchable", want: map[string]any{"desktop-id": []any{"org.example.Viewer.desktop"}}},
+		{field: "Icon", want: map[string]any{
+			"stock": "viewer",
+			"cached": []any{
+				map[string]any{"name": "viewer_viewer.png", "width": 64, "height": 64},
+				map[string]any{"name": "viewer_viewer.png", "width": 128, "height": 128},
+			},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			if !reflect.DeepEqual(component[tt.field], tt.want) {
+				t.Errorf("%s = %#v, want %#v", tt.field, component[tt.field], tt.want)
+			}
+		})
+	}
+
+	// Архивы иконок: 64×64 из темы hicolor, 128×128 — из pixmaps по размеру изображения
+	for size, want := range map[string]string{"64x64": icon64, "128x128": icon128} {
+		tr := tar.NewReader(bytes.NewReader(read(dep11Dir + "icons-" + size + ".tar")))
+		hdr, err := tr.Next()
+		if err != nil {
+			t.Fatalf("icons-%s.tar: %v", size, err)
+		}
+		data, _ := io.ReadAll(tr)
+		if hdr.Name != "viewer_viewer.png" || string(data) != want {
+			t.Errorf("icons-%s.tar entry %s (%d bytes)", size, hdr.Name, len(data))
+		}
+		if _, err := tr.Next(); !errors.Is(err, io.EOF) {
+			t.Errorf("icons-%s.tar has extra entries", size)
+		}
+	}
+
+	// Release перечисляет метаданные в несжатом виде и в gzip
+	release, err := deb.ReadRelease(bytes.NewReader(read("Release")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Components-amd64.yml", "Components-amd64.yml.gz", "icons-64x64.tar", "icons-128x128.tar.gz"} {
+		if _, ok := release.File(dep11Dir + name); !ok {
+			t.Errorf("Release does not list %s", name)
+		}
+	}
+}
+
+func TestRepoCustom_generateDEP11_empty(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "custom.iso")
+	debtest.Write(t, dir, "tool_1.0_amd64.deb", debtest.Package(t, "tool", "1.0", "amd64", map[string]string{
+		"/usr/share/applications/tool.desktop": "[Desktop Entry]\nName=Tool\n",
+		"/usr/share/pixmaps/tool.png":          testPNG(t, 64),
+	}))
+
+	files := make(map[string][]byte)
+	NewRepoCustom(dir, nil, CustomOptions{}).generateDEP11(files)
+	for name := range files {
+		if strings.HasPrefix(name, dep11Dir) {
+			t.Errorf("unexpected %s", name)
+		}
+	}
+}
@@ -0,0 +1,417 @@
+package repo
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cockroachdb/errors"
+	"github.com/kirsrus/iso2repo/internal/vuln"
+	"github.com/kirsrus/iso2repo/models"
+	"github.com/kirsrus/iso2repo/pkg/deb"
+	"golang.org/x/exp/slog"
+)
+
+// dependencyFields поля зависимостей бинарного пакета в порядке их важности.
+var dependencyFields = []string{"Pre-Depends", "Depends", "Recommends", "Suggests"}
+
+const (
+	// defaultGraphDepth глубина графа зависимостей по умолчанию.
+	defaultGraphDepth = 2
+	// maxGraphDepth максимальная глубина графа зависимостей.
+	maxGraphDepth = 5
+	// maxGraphNodes максимальное количество вершин графа зависимостей.
+	maxGraphNodes = 200
+)
+
+// Dependency зависимость пакета (группа альтернатив "a | b") с пакетами того же
+// репозитория, которые её удовлетворяют.
+type Dependency struct {
+	// Поле control: Pre-Depends, Depends, Recommends или Suggests.
+	Field    string `json:"field"`
+	Relation string `json:"relation"`
+	// Лучший (с наибольшей версией) подходящий пакет для каждой альтернативы группы,
+	// в том числе предоставляющий её через Provides. Пустой — зависимость не удовлетворена.
+	Resolved []CatalogEntry `json:"resolved"`
+}
+
+// ReverseDependency пакет, зависящий от данного.
+type ReverseDependency struct {
+	CatalogEntry
+	Field string `json:"field"`
+	// Группа альтернатив, в которой упоминается данный пакет.
+	Relation string `json:"relation"`
+}
+
+// PackageVersion версия пакета в одном из репозиториев.
+type PackageVersion struct {
+	CatalogEntry
+	// Запись индекса Packages; nil, если индекс не удалось прочитать.
+	Control deb.Paragraph `json:"control"`
+	Depends []Dependency  `json:"depends"`
+	// Уязвимости из локальных баз; заполняются веб-сервером.
+	Vulnerabilities []vuln.Match `json:"vulnerabilities,omitempty"`
+}
+
+// PackageInfo сведения о пакете во всех обслуживаемых репозиториях.
+type PackageInfo struct {
+	Name     string           `json:"name"`
+	Versions []PackageVersion `json:"versions"`
+	// Пакеты, предоставляющие Name как виртуальный пакет (Provides).
+	ProvidedBy     []CatalogEntry      `json:"provided_by"`
+	ReverseDepends []ReverseDependency `json:"reverse_depends"`
+}
+
+// GraphQuery условия построения графа зависимостей.
+type GraphQuery struct {
+	// Репозиторий и пакет — корень графа.
+	Repo string
+	Name string
+	// Версия и архитектура корня; пустые — наибольшая версия любой архитектуры.
+	Version string
+	Arch    string
+	// Глубина графа; 0 — defaultGraphDepth, не больше maxGraphDepth.
+	Depth int
+	// Учитывать Recommends (по умолчанию только Pre-Depends и Depends).
+	Recommends bool
+}
+
+// GraphNode вершина графа зависимостей.
+type GraphNode struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Arch    string `json:"arch"`
+	// Расстояние от корня графа.
+	Depth int `json:"depth"`
+	// Зависимость не удовлетворена ни одним пакетом репозитория; Name содержит
+	// группу альтернатив.
+	Missing bool `json:"missing"`
+}
+
+// GraphEdge ребро графа зависимостей: From зависит от To.
+type GraphEdge struct {
+	From  int    `json:"from"`
+	To    int    `json:"to"`
+	Field string `json:"field"`
+}
+
+// DependencyGraph граф зависимостей пакета. Вершина 0 — корень.
+type DependencyGraph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+	// Граф усечён: вершин больше maxGraphNodes.
+	Truncated bool `json:"truncated"`
+}
+
+// Package возвращает все версии пакета name во всех репозиториях с полями control
+// и зависимостями, разрешёнными в пакеты того же репозитория, а также обратные
+// зависимости. Поля control читаются из индексов Packages при каждом вызове.
+func (m *Catalog) Package(ctx context.Context, name string) (*PackageInfo, error) {
+	info := &PackageInfo{
+		Name:           name,
+		Versions:       make([]PackageVersion, 0),
+		ProvidedBy:     make([]CatalogEntry, 0),
+		ReverseDepends: make([]ReverseDependency, 0),
+	}
+	sources := make(map[string]models.Repoes)
+
+	m.mu.RLock()
+	for repoName, repo := range m.repos {
+		for _, i := range repo.byName[name] {
+			info.Versions = append(info.Versions, PackageVersion{
+				CatalogEntry: repo.entries[i],
+				Depends:      repo.dependencies(i),
+			})
+			sources[repoName] = repo.repo
+		}
+		for _, i := range repo.providers[name] {
+			info.ProvidedBy = append(info.ProvidedBy, repo.entries[i])
+		}
+		info.ReverseDepends = append(info.ReverseDepends, repo.reverseDependencies(name)...)
+	}
+	m.mu.RUnlock()
+
+	if len(info.Versions) == 0 && len(info.ProvidedBy) == 0 && len(info.ReverseDepends) == 0 {
+		return nil, errors.Wrapf(ErrPackageNotFound, "%s", name)
+	}
+
+	sort.SliceStable(info.Versions, func(i, j int) bool {
+		return catalogEntryLess(info.Versions[i].CatalogEntry, info.Versions[j].CatalogEntry)
+	})
+	sortCatalogEntries(info.ProvidedBy)
+	sort.SliceStable(info.ReverseDepends, func(i, j int) bool {
+		return catalogEntryLess(info.ReverseDepends[i].CatalogEntry, info.ReverseDepends[j].CatalogEntry)
+	})
+
+	entries := make([]CatalogEntry, len(info.Versions))
+	for i := range info.Versions {
+		entries[i] = info.Versions[i].CatalogEntry
+	}
+	for i, p := range m.readControls(ctx, entries, sources) {
+		info.Versions[i].Control = p
+	}
+
+	return info, nil
+}
+
+// readControls возвращает записи индексов Packages для пакетов entries (в том же
+// порядке; nil — запись не найдена), читая каждый индекс один раз. Ошибки чтения
+// индексов выводятся в лог.
+func (m *Catalog) readControls(ctx context.Context, entries []CatalogEntry, sources map[string]models.Repoes) []deb.Paragraph {
+	type indexKey struct{ repo, index string }
+	type packageKey struct{ name, version, arch string }
+
+	result := make([]deb.Paragraph, len(entries))
+	wanted := make(map[indexKey]map[packageKey][]int)
+	keys := make([]indexKey, 0)
+	for i, e := range entries {
+		key := indexKey{e.Repo, e.index}
+		if sources[key.repo] == nil {
+			continue
+		}
+		if wanted[key] == nil {
+			wanted[key] = make(map[packageKey][]int)
+			keys = append(keys, key)
+		}
+		pkg := packageKey{e.Name, e.Version, e.Arch}
+		wanted[key][pkg] = append(wanted[key][pkg], i)
+	}
+
+	for _, key := range keys {
+		reader, err := OpenIndex(ctx, sources[key.repo], key.index)
+		if err != nil {
+			m.log.Warn("не удалось прочитать индекс", slog.String("repo", key.repo), slog.String("index", key.index), slog.String("error", err.Error()))
+			continue
+		}
+		err = deb.ReadParagraphs(reader, func(p deb.Paragraph) error {
+			for _, i := range wanted[key][packageKey{p.Get("Package"), p.Get("Version"), p.Get("Architecture")}] {
+				result[i] = p
+			}
+			return nil
+		})
+		reader.Close()
+		if err != nil {
+			m.log.Warn("не удалось прочитать индекс", slog.String("repo", key.repo), slog.String("index", key.index), slog.String("error", err.Error()))
+		}
+	}
+
+	return result
+}
+
+// DependencyGraph строит граф зависимостей пакета по условиям q. Зависимости
+// разрешаются в пакеты того же репозитория; для каждой группы альтернатив берётся
+// первая удовлетворённая альтернатива.
+func (m *Catalog) DependencyGraph(q GraphQuery) (*DependencyGraph, error) {
+	depth := q.Depth
+	if depth <= 0 {
+		depth = defaultGraphDepth
+	}
+	if depth > maxGraphDepth {
+		depth = maxGraphDepth
+	}
+
+	fields := []string{"Pre-Depends", "Depends"}
+	if q.Recommends {
+		fields = append(fields, "Recommends")
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	repo, ok := m.repos[q.Repo]
+	if !ok {
+		return nil, errors.Wrapf(ErrPackageNotFound, "репозиторий %s", q.Repo)
+	}
+
+	root := -1
+	for _, i := range repo.byName[q.Name] {
+		e := repo.entries[i]
+		if q.Version != "" && e.Version != q.Version || q.Arch != "" && e.Arch != q.Arch {
+			continue
+		}
+		if root < 0 || deb.CompareVersions(e.Version, repo.entries[root].Version) > 0 {
+			root = i
+		}
+	}
+	if root < 0 {
+		return nil, errors.Wrapf(ErrPackageNotFound, "%s в %s", packageSpec(q.Name, q.Version, q.Arch), q.Repo)
+	}
+
+	graph := &DependencyGraph{}
+	// Номер вершины по номеру записи репозитория или по тексту неудовлетворённой зависимости
+	nodes := make(map[int]int)
+	missing := make(map[string]int)
+	addEntry := func(i, d int) int {
+		if n, ok := nodes[i]; ok {
+			return n
+		}
+		e := repo.entries[i]
+		nodes[i] = len(graph.Nodes)
+		graph.Nodes = append(graph.Nodes, GraphNode{Name: e.Name, Version: e.Version, Arch: e.Arch, Depth: d})
+		return nodes[i]
+	}
+
+	addEntry(root, 0)
+	queue := []int{root}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		from := nodes[i]
+		d := graph.Nodes[from].Depth
+		if d >= depth {
+			continue
+		}
+
+		for _, field := range fields {
+			groups, err := relationsField(repo.relations[i], field)
+			if err != nil {
+				continue
+			}
+			for _, alts := range groups {
+				if len(graph.Nodes) >= maxGraphNodes {
+					graph.Truncated = true
+					return graph, nil
+				}
+
+				to := -1
+				for _, r := range alts {
+					if candidates := repo.resolve(repo.entries[i], r); len(candidates) > 0 {
+						_, seen := nodes[candidates[0]]
+						to = addEntry(candidates[0], d+1)
+						if !seen {
+							queue = append(queue, candidates[0])
+						}
+						break
+					}
+				}
+				if to < 0 {
+					label := alts.String()
+					n, ok := missing[label]
+					if !ok {
+						n = len(graph.Nodes)
+						missing[label] = n
+						graph.Nodes = append(graph.Nodes, GraphNode{Name: label, Depth: d + 1, Missing: true})
+					}
+					to = n
+				}
+				graph.Edges = append(graph.Edges, GraphEdge{From: from, To: to, Field: field})
+			}
+		}
+	}
+
+	return graph, nil
+}
+
+// dependencies возвращает зависимости записи i, разрешённые в пакеты репозитория.
+func (m *catalogRepo) dependencies(i int) []Dependency {
+	result := make([]Dependency, 0)
+	for _, field := range dependencyFields {
+		groups, err := relationsField(m.relations[i], field)
+		if err != nil {
+			continue
+		}
+		for _, alts := range groups {
+			dep := Dependency{Field: field, Relation: alts.String(), Resolved: make([]CatalogEntry, 0)}
+			for _, r := range alts {
+				if candidates := m.resolve(m.entries[i], r); len(candidates) > 0 {
+					dep.Resolved = append(dep.Resolved, m.entries[candidates[0]])
+				}
+			}
+			result = append(result, dep)
+		}
+	}
+
+	return result
+}
+
+// reverseDependencies возвращает пакеты репозитория, в зависимостях которых
+// упоминается пакет name.
+func (m *catalogRepo) reverseDependencies(name string) []ReverseDependency {
+	result := make([]ReverseDependency, 0)
+	for _, i := range m.rdeps[name] {
+		for _, field := range dependencyFields {
+			groups, err := relationsField(m.relations[i], field)
+			if err != nil {
+				continue
+			}
+			for _, alts := range groups {
+				for _, r := range alts {
+					if r.Name == name {
+						result = append(result, ReverseDependency{CatalogEntry: m.entries[i], Field: field, Relation: alts.String()})
+						break
+					}
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// resolve возвращает номера записей репозитория, удовлетворяющих отношению r пакета
+// from: сначала из того же дистрибутива, затем по убыванию версии.
+func (m *catalogRepo) resolve(from CatalogEntry, r deb.Relation) []int {
+	result := make([]int, 0)
+	for _, i := range m.byName[r.Name] {
+		e := m.entries[i]
+		if archCompatible(from.Arch, e.Arch, r.ArchQualifier) && r.SatisfiedBy(e.Version) {
+			result = append(result, i)
+		}
+	}
+
+	// Виртуальный пакет: версионная зависимость удовлетворяется только версионным Provides
+	for _, i := range m.providers[r.Name] {
+		e := m.entries[i]
+		if !archCompatible(from.Arch, e.Arch, r.ArchQualifier) {
+			continue
+		}
+		provides, err := relationsField(m.relations[i], "Provides")
+		if err != nil {
+			continue
+		}
+		for _, alts := range provides {
+			p := alts[0]
+			if p.Name == r.Name && (r.Op == "" || p.Op == deb.OpEqual && r.SatisfiedBy(p.Version)) {
+				result = appendIndex(result, i)
+			}
+		}
+	}
+
+	sort.SliceStable(result, func(a, b int) bool {
+		ea, eb := m.entries[result[a]], m.entries[result[b]]
+		if (ea.Suite == from.Suite) != (eb.Suite == from.Suite) {
+			return ea.Suite == from.Suite
+		}
+		return deb.CompareVersions(ea.Version, eb.Version) > 0
+	})
+
+	return result
+}
+
+// archCompatible проверяет, может ли пакет архитектуры to удовлетворить зависимость
+// пакета архитектуры from (qualifier — уточнение архитектуры в отношении).
+func archCompatible(from, to, qualifier string) bool {
+	return to == "all" || from == "all" || to == from || qualifier == "any" || qualifier == to
+}
+
+// packageRelations возвращает поля отношений пакета, используемые каталогом.
+func packageRelations(meta *deb.PackageMeta) deb.Paragraph {
+	result := make(deb.Paragraph, 0)
+	for _, f := range []deb.Field{
+		{Name: "Pre-Depends", Value: meta.PreDepends},
+		{Name: "Depends", Value: meta.Depends},
+		{Name: "Recommends", Value: meta.Recommends},
+		{Name: "Suggests", Value: meta.Suggests},
+		{Name: "Provides", Value: meta.Provides},
+	} {
+		if f.Value != "" {
+			result = append(result, f)
+		}
+	}
+
+	return result
+}
+
+// relationsField разбирает поле отношений field записи p.
+func relationsField(p deb.Paragraph, field string) ([]deb.Alternatives, error) {
+	return deb.ParseRelations(p.Get(field))
+}
@@ -0,0 +1,184 @@
+package repo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/kirsrus/iso2repo/pkg/deb"
+)
+
+// testPackage пакет тестового каталога с полями отношений.
+type testPackage struct {
+	name, version, arch string
+	relations           deb.Paragraph
+}
+
+// newTestDependsCatalog создаёт каталог с одним репозиторием a.iso из пакетов packages.
+func newTestDependsCatalog(packages []testPackage) *Catalog {
+	catalog := newTestCatalog(nil)
+	repo := newCatalogRepo(nil)
+	for _, p := range packages {
+		repo.add(CatalogEntry{Name: p.name, Version: p.version, Arch: p.arch, Repo: "a.iso", Suite: "stable", Component: "main"}, p.relations)
+	}
+	catalog.repos["a.iso"] = repo
+
+	return catalog
+}
+
+var testDependsPackages = []testPackage{
+	{"app", "2.0", "amd64", deb.Paragraph{
+		{Name: "Pre-Depends", Value: "libc6 (>= 2.30)"},
+		{Name: "Depends", Value: "libfoo1 (>= 1.2), mail-transport-agent, ghost | libfoo1"},
+		{Name: "Recommends", Value: "app-data"},
+	}},
+	{"libc6", "2.36", "amd64", nil},
+	{"libc6", "2.28", "amd64", nil},
+	{"libfoo1", "1.1", "amd64", nil},
+	{"libfoo1", "1.3", "i386", nil},
+	{"postfix", "3.7", "amd64", deb.Paragraph{
+		{Name: "Depends", Value: "libc6"},
+		{Name: "Provides", Value: "mail-transport-agent"},
+	}},
+	{"app-data", "2.0", "all", deb.Paragraph{{Name: "Depends", Value: "app (= 2.0)"}}},
+}
+
+func TestCatalogRepo_Dependencies(t *testing.T) {
+	catalog := newTestDependsCatalog(testDependsPackages)
+	repo := catalog.repos["a.iso"]
+
+	type result struct {
+		field, relation string
+		resolved        []string
+	}
+	var got []result
+	for _, d := range repo.dependencies(0) {
+		r := result{field: d.Field, relation: d.Relation}
+		for _, e := range d.Resolved {
+			r.resolved = append(r.resolved, e.Name+" "+e.Version)
+		}
+		got = append(got, r)
+	}
+
+	want := []result{
+		{"Pre-Depends", "libc6 (>= 2.30)", []string{"libc6 2.36"}},
+		{"Depends", "libfoo1 (>= 1.2)", nil},
+		{"Depends", "mail-transport-agent", []string{"postfix 3.7"}},
+		{"Depends", "ghost | libfoo1", []string{"libfoo1 1.1"}},
+		{"Recommends", "app-data", []string{"app-data 2.0"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dependencies() = %v, want %v", got, want)
+	}
+}
+
+func TestCatalogRepo_ReverseDependencies(t *testing.T) {
+	catalog := newTestDependsCatalog(testDependsPackages)
+	repo := catalog.repos["a.iso"]
+
+	tests := []struct {
+		name string
+		pkg  string
+		want []string
+	}{
+		{name: "multiple fields", pkg: "libc6", want: []string{"app Pre-Depends", "postfix Depends"}},
+		{name: "repeated in one field", pkg: "libfoo1", want: []string{"app Depends", "app Depends"}},
+		{name: "virtual", pkg: "mail-transport-agent", want: []string{"app Depends"}},
+		{name: "none", pkg: "postfix", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, r := range repo.reverseDependencies(tt.pkg) {
+				got = append(got, r.Name+" "+r.Field)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("reverseDependencies(%s) = %v, want %v", tt.pkg, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCatalog_DependencyGraph(t *testing.T) {
+	catalog := newTestDependsCatalog(testDependsPackages)
+
+	type node struct {
+		name    string
+		depth   int
+		missing bool
+	}
+	tests := []struct {
+		name      string
+		query     GraphQuery
+		wantNodes []node
+		wantEdges []GraphEdge
+		wantErr   bool
+	}{
+		{
+			name:  "depth 1",
+			query: GraphQuery{Repo: "a.iso", Name: "app", Depth: 1},
+			wantNodes: []node{
+				{"app", 0, false},
+				{"libc6", 1, false},
+				{"libfoo1 (>= 1.2)", 1, true},
+				{"postfix", 1, false},
+				{"libfoo1", 1, false},
+			},
+			wantEdges: []GraphEdge{
+				{0, 1, "Pre-Depends"},
+				{0, 2, "Depends"},
+				{0, 3, "Depends"},
+				{0, 4, "Depends"},
+			},
+		},
+		{
+			name:  "recommends with cycle",
+			query: GraphQuery{Repo: "a.iso", Name: "app-data", Recommends: true},
+			wantNodes: []node{
+				{"app-data", 0, false},
+				{"app", 1, false},
+				{"libc6", 2, false},
+				{"libfoo1 (>= 1.2)", 2, true},
+				{"postfix", 2, false},
+				{"libfoo1", 2, false},
+			},
+			wantEdges: []GraphEdge{
+				{0, 1, "Depends"},
+				{1, 2, "Pre-Depends"},
+				{1, 3, "Depends"},
+				{1, 4, "Depends"},
+				{1, 5, "Depends"},
+				{1, 0, "Recommends"},
+			},
+		},
+		{name: "unknown version", query: GraphQuery{Repo: "a.iso", Name: "app", Version: "1.0"}, wantErr: true},
+		{name: "unknown repo", query: GraphQuery{Repo: "b.iso", Name: "app"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graph, err := catalog.DependencyGraph(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DependencyGraph() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, ErrPackageNotFound) {
+					t.Errorf("DependencyGraph() error = %v, want ErrPackageNotFound", err)
+				}
+				return
+			}
+
+			var nodes []node
+			for _, n := range graph.Nodes {
+				nodes = append(nodes, node{n.Name, n.Depth, n.Missing})
+			}
+			if !reflect.DeepEqual(nodes, tt.wantNodes) {
+				t.Errorf("DependencyGraph() nodes = %v, want %v", nodes, tt.wantNodes)
+			}
+			if !reflect.DeepEqual(graph.Edges, tt.wantEdges) {
+				t.Errorf("DependencyGraph() edges = %v, want %v", graph.Edges, tt.wantEdges)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/cockroachdb/errors"
+	"github.com/kirsrus/iso2repo/models"
+	"github.com/kirsrus/iso2repo/pkg/deb"
+)
+
+// Форматы отчёта о различиях репозиториев (кроме HTML, который формирует веб-сервер).
+const (
+	DiffJSON = "json"
+	DiffText = "text"
+)
+
+// DiffChange вид изменения пакета.
+type DiffChange string
+
+const (
+	// DiffAdded пакет есть только во втором репозитории.
+	DiffAdded DiffChange = "added"
+	// DiffRemoved пакет есть только в первом репозитории.
+	DiffRemoved DiffChange = "removed"
+	// DiffUpgraded версия пакета во втором репозитории выше.
+	DiffUpgraded DiffChange = "upgraded"
+	// DiffDowngraded версия пакета во втором репозитории ниже.
+	DiffDowngraded DiffChange = "downgraded"
+)
+
+// diffMarks обозначения изменений в текстовом отчёте.
+var diffMarks = map[DiffChange]string{
+	DiffAdded:      "+",
+	DiffRemoved:    "-",
+	DiffUpgraded:   "↑",
+	DiffDowngraded: "↓",
+}
+
+// DiffPackage изменение пакета (по имени и архитектуре) между репозиториями.
+type DiffPackage struct {
+	Name   string     `json:"name"`
+	Arch   string     `json:"arch"`
+	Change DiffChange `json:"change"`
+	// Версия и размер файла пакета в первом репозитории (пусто у добавленных).
+	OldVersion string `json:"old_version,omitempty"`
+	OldSize    int64  `json:"old_size,omitempty"`
+	// Версия и размер файла пакета во втором репозитории (пусто у удалённых).
+	NewVersion string `json:"new_version,omitempty"`
+	NewSize    int64  `json:"new_size,omitempty"`
+}
+
+// RepoDiff различия индексов Packages двух репозиториев. Пакеты сопоставляются по
+// имени и архитектуре во всех дистрибутивах; если в репозитории несколько версий
+// пакета, сравнивается наибольшая.
+type RepoDiff struct {
+	// Сравниваемые репозитории: A — исходный (старый), B — новый.
+	A string `json:"a"`
+	B string `json:"b"`
+
+	Added      int `json:"added"`
+	Removed    int `json:"removed"`
+	Upgraded   int `json:"upgraded"`
+	Downgraded int `json:"downgraded"`
+	Unchanged  int `json:"unchanged"`
+	// Суммарный размер файлов сравниваемых версий пакетов в каждом репозитории.
+	SizeA int64 `json:"size_a"`
+	SizeB int64 `json:"size_b"`
+
+	// Изменённые пакеты по имени и архитектуре.
+	Packages []DiffPackage `json:"packages"`
+}
+
+// Diff сравнивает пакеты репозитория a с пакетами репозитория b. Размеры пакетов
+// берутся из индексов Packages.
+func (m *Catalog) Diff(ctx context.Context, a, b string) (*RepoDiff, error) {
+	type packageKey struct{ name, arch string }
+
+	m.mu.RLock()
+	sources := make(map[string]models.Repoes)
+	latest := make(map[string]map[packageKey]CatalogEntry)
+	for _, name := range []string{a, b} {
+		repo, ok := m.repos[name]
+		if !ok {
+			m.mu.RUnlock()
+			return nil, errors.Wrapf(ErrPackageNotFound, "репозиторий %s", name)
+		}
+		sources[name] = repo.repo
+
+		packages := make(map[packageKey]CatalogEntry)
+		for _, e := range repo.entries {
+			key := packageKey{e.Name, e.Arch}
+			if prev, ok := packages[key]; !ok || deb.CompareVersions(e.Version, prev.Version) > 0 {
+				packages[key] = e
+			}
+		}
+		latest[name] = packages
+	}
+	m.mu.RUnlock()
+
+	// Размеры сравниваемых версий из индексов
+	entries := make([]CatalogEntry, 0, len(latest[a])+len(latest[b]))
+	for _, name := range []string{a, b} {
+		for _, e := range latest[name] {
+			entries = append(entries, e)
+		}
+	}
+	sizes := make(map[string]map[packageKey]int64)
+	for i, control := range m.readControls(ctx, entries, sources) {
+		e := entries[i]
+		if sizes[e.Repo] == nil {
+			sizes[e.Repo] = make(map[packageKey]int64)
+		}
+		size, _ := strconv.ParseInt(control.Get("Size"), 10, 64)
+		sizes[e.Repo][packageKey{e.Name, e.Arch}] = size
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &RepoDiff{A: a, B: b, Packages: make([]DiffPackage, 0)}
+	keys := make(map[packageKey]bool)
+	for _, name := range []string{a, b} {
+		for key := range latest[name] {
+			keys[key] = true
+		}
+	}
+	for key := range keys {
+		oldEntry, inA := latest[a][key]
+		newEntry, inB := latest[b][key]
+		p := DiffPackage{Name: key.name, Arch: key.arch}
+		if inA {
+			p.OldVersion, p.OldSize = oldEntry.Version, sizes[a][key]
+			result.SizeA += p.OldSize
+		}
+		if inB {
+			p.NewVersion, p.NewSize = newEntry.Version, sizes[b][key]
+			result.SizeB += p.NewSize
+		}
+
+		switch {
+		case !inA:
+			p.Change = DiffAdded
+			result.Added++
+		case !inB:
+			p.Change = DiffRemoved
+			result.Removed++
+		default:
+			c := deb.CompareVersions(newEntry.Version, oldEntry.Version)
+			switch {
+			case c > 0:
+				p.Change = DiffUpgraded
+				result.Upgraded++
+			case c < 0:
+				p.Change = DiffDowngraded
+				result.Downgraded++
+			default:
+				result.Unchanged++
+				continue
+			}
+		}
+		result.Packages = append(result.Packages, p)
+	}
+
+	sort.Slice(result.Packages, func(i, j int) bool {
+		pi, pj := result.Packages[i], result.Packages[j]
+		if pi.Name != pj.Name {
+			return pi.Name < pj.Name
+		}
+		return pi.Arch < pj.Arch
+	})
+
+	return result, nil
+}
+
+// Write записывает отчёт в формате format: DiffJSON или DiffText.
+func (m *RepoDiff) Write(w io.Writer, format string) error {
+	switch format {
+	case DiffJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(m)
+	case DiffText:
+		return m.writeText(w)
+	}
+
+	return errors.Wrapf(ErrInvalidQuery, "неизвестный формат отчёта %s", format)
+}
+
+// writeText записывает отчёт текстом: строка сводки и по строке на изменённый пакет
+// ("+" добавлен, "-" удалён, "↑" обновлён, "↓" понижен) с версиями и размерами в байтах.
+func (m *RepoDiff) writeText(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "%s → %s: добавлено %d, удалено %d, обновлено %d, понижено %d, без изменений %d; размер %d → %d\n",
+		m.A, m.B, m.Added, m.Removed, m.Upgraded, m.Downgraded, m.Unchanged, m.SizeA, m.SizeB); err != nil {
+		return err
+	}
+
+	for _, p := range m.Packages {
+		var line string
+		switch p.Change {
+		case DiffAdded:
+			line = fmt.Sprintf("%s %s %s %s (%d)", diffMarks[p.Change], p.Name, p.Arch, p.NewVersion, p.NewSize)
+		case DiffRemoved:
+			line = fmt.Sprintf("%s %s %s %s (%d)", diffMarks[p.Change], p.Name, p.Arch, p.OldVersion, p.OldSize)
+		default:
+			line = fmt.Sprintf("%s %s %s %s → %s (%d → %d)", diffMarks[p.Change], p.Name, p.Arch, p.OldVersion, p.NewVersion, p.OldSize, p.NewSize)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
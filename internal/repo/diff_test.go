@@ -0,0 +1,79 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCatalog_Diff(t *testing.T) {
+	catalog := newTestCatalog(map[string][]CatalogEntry{
+		"old.iso": {
+			{Name: "bash", Version: "5.1-2", Arch: "amd64", Repo: "old.iso", Suite: "bullseye"},
+			{Name: "curl", Version: "7.74.0-1.3", Arch: "amd64", Repo: "old.iso", Suite: "bullseye"},
+			{Name: "curl", Version: "7.74.0-1.3+deb11u7", Arch: "amd64", Repo: "old.iso", Suite: "bullseye-security"},
+			{Name: "python2", Version: "2.7.18-3", Arch: "amd64", Repo: "old.iso", Suite: "bullseye"},
+			{Name: "tzdata", Version: "2024a-0+deb11u1", Arch: "all", Repo: "old.iso", Suite: "bullseye"},
+			{Name: "vim", Version: "2:8.2.2434-3", Arch: "amd64", Repo: "old.iso", Suite: "bullseye"},
+		},
+		"new.iso": {
+			{Name: "bash", Version: "5.2.15-2", Arch: "amd64", Repo: "new.iso", Suite: "bookworm"},
+			{Name: "bash", Version: "5.2.15-2", Arch: "arm64", Repo: "new.iso", Suite: "bookworm"},
+			{Name: "curl", Version: "7.88.1-10", Arch: "amd64", Repo: "new.iso", Suite: "bookworm"},
+			{Name: "tzdata", Version: "2024a-0+deb11u1", Arch: "all", Repo: "new.iso", Suite: "bookworm"},
+			{Name: "vim", Version: "2:8.2.2434-1", Arch: "amd64", Repo: "new.iso", Suite: "bookworm"},
+		},
+	})
+
+	diff, err := catalog.Diff(context.Background(), "old.iso", "new.iso")
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+
+	want := []DiffPackage{
+		{Name: "bash", Arch: "amd64", Change: DiffUpgraded, OldVersion: "5.1-2", NewVersion: "5.2.15-2"},
+		{Name: "bash", Arch: "arm64", Change: DiffAdded, NewVersion: "5.2.15-2"},
+		{Name: "curl", Arch: "amd64", Change: DiffUpgraded, OldVersion: "7.74.0-1.3+deb11u7", NewVersion: "7.88.1-10"},
+		{Name: "python2", Arch: "amd64", Change: DiffRemoved, OldVersion: "2.7.18-3"},
+		{Name: "vim", Arch: "amd64", Change: DiffDowngraded, OldVersion: "2:8.2.2434-3", NewVersion: "2:8.2.2434-1"},
+	}
+	if !reflect.DeepEqual(diff.Packages, want) {
+		t.Errorf("Diff() packages = %+v, want %+v", diff.Packages, want)
+	}
+	if diff.Added != 1 || diff.Removed != 1 || diff.Upgraded != 2 || diff.Downgraded != 1 || diff.Unchanged != 1 {
+		t.Errorf("Diff() summary = %+v", diff)
+	}
+
+	var text bytes.Buffer
+	if err := diff.Write(&text, DiffText); err != nil {
+		t.Fatalf("Write(text) error = %v", err)
+	}
+	wantText := "old.iso → new.iso: добавлено 1, удалено 1, обновлено 2, понижено 1, без изменений 1; размер 0 → 0\n" +
+		"↑ bash amd64 5.1-2 → 5.2.15-2 (0 → 0)\n" +
+		"+ bash arm64 5.2.15-2 (0)\n" +
+		"↑ curl amd64 7.74.0-1.3+deb11u7 → 7.88.1-10 (0 → 0)\n" +
+		"- python2 amd64 2.7.18-3 (0)\n" +
+		"↓ vim amd64 2:8.2.2434-3 → 2:8.2.2434-1 (0 → 0)\n"
+	if text.String() != wantText {
+		t.Errorf("Write(text) = %q, want %q", text.String(), wantText)
+	}
+
+	var data bytes.Buffer
+	if err := diff.Write(&data, DiffJSON); err != nil {
+		t.Fatalf("Write(json) error = %v", err)
+	}
+	var decoded RepoDiff
+	if err := json.Unmarshal(data.Bytes(), &decoded); err != nil || !reflect.DeepEqual(decoded.Packages, want) {
+		t.Errorf("Write(json) = %s, %v", data.String(), err)
+	}
+
+	if err := diff.Write(&data, "xml"); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("Write() unknown format error = %v", err)
+	}
+	if _, err := catalog.Diff(context.Background(), "old.iso", "missing.iso"); !errors.Is(err, ErrPackageNotFound) {
+		t.Errorf("Diff() unknown repo error = %v", err)
+	}
+}
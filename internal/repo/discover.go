@@ -0,0 +1,84 @@
+package repo
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/kirsrus/iso2repo/models"
+	"golang.org/x/exp/slog"
+)
+
+// Discover однократно обходит rootDir и возвращает все обнаруженные репозитории.
+// Правила распознавания совпадают с Repo.syncRepos: .iso файл — образ, директория
+// с суффиксом .iso — распакованный репозиторий или пользовательская папка,
+// соседняя директория <образ>.d — дополнение образа. Скрытые директории пропускаются.
+// Используется командами CLI, которым не нужен постоянно работающий процесс
+// отслеживания. options применяются к пользовательским репозиториям.
+func Discover(rootDir string, log *slog.Logger, options CustomOptions) ([]models.Repoes, error) {
+	if log == nil {
+		log = slog.New(slog.NewTextHandler(io.Discard))
+	}
+
+	result := make([]models.Repoes, 0)
+
+	err := filepath.WalkDir(rootDir, func(currentPath string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if d.IsDir() {
+			if currentPath != rootDir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			// Дополнение подключается к своему образу, а не обходится как обычная директория
+			if currentPath != rootDir && strings.HasSuffix(d.Name(), ".iso"+overlaySuffix) {
+				return filepath.SkipDir
+			}
+			if currentPath == rootDir || !strings.HasSuffix(d.Name(), ".iso") {
+				return nil
+			}
+
+			if extracted := NewRepoExtracted(currentPath, log); extracted.IsRepo() {
+				result = append(result, withOverlay(extracted, log, options))
+			} else {
+				result = append(result, NewRepoCustom(currentPath, log, options))
+			}
+
+			return filepath.SkipDir
+		}
+
+		if !strings.HasSuffix(d.Name(), ".iso") {
+			return nil
+		}
+
+		iso, err := NewRepoIso(currentPath, log)
+		if err != nil {
+			log.Warn("образ пропущен", slog.String("path", currentPath), slog.Any("error", err))
+			return nil
+		}
+		if iso.IsRepo() {
+			result = append(result, withOverlay(iso, log, options))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "ошибка поиска репозиториев в %s", rootDir)
+	}
+
+	return result, nil
+}
+
+// FindByName возвращает репозиторий с именем name из списка repos.
+func FindByName(repos []models.Repoes, name string) (models.Repoes, bool) {
+	for _, r := range repos {
+		if r.Metadata().Name == name {
+			return r, true
+		}
+	}
+
+	return nil, false
+}
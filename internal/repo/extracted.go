@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/kirsrus/iso2repo/models"
+	"github.com/kirsrus/iso2repo/pkg/deb"
 	"golang.org/x/exp/slog"
 )
 
@@ -83,7 +84,7 @@ func (m *RepoExtracted) IsRepo() bool {
 //  1. Ищем в корне каталог /dists.
 //  2. Внутри /dists ищем первую поддиректорию — это имя дистрибутива (например, "1.7_x86-64").
 //  3. Внутри директории дистрибутива ищем файл "Release".
-//  4. Читаем файл Release, ищем строку с префиксом "components:".
+//  4. Читаем файл Release и берём из него поле Components.
 //  5. Извлекаем список компонентов (contrib, main, non-free и т.д.).
 //  6. Проверяем, что для каждого компонента существует соответствующая поддиректория
 //     внутри /dists/<distributeName>/.
@@ -133,54 +134,38 @@ func (m *RepoExtracted) RepoString() string {
 		return ""
 	}
 
-	// 4. Читаем содержимое файла Release
+	// 4. Читаем файл Release
 	releasePath := filepath.Join(distPath, "Release")
-	releaseData, err := os.ReadFile(releasePath)
+	releaseFile, err := os.Open(releasePath)
 	if err != nil {
 		m.log.Warn("не удалось прочитать файл Release", slog.String("error", err.Error()))
 		return ""
 	}
+	defer releaseFile.Close()
 
-	// 5. Ищем строку с префиксом "components:"
-	findPrefix := "components:"
-	components := make([]string, 0)
-	for _, line := range strings.Split(string(releaseData), "\n") {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(strings.ToLower(line), findPrefix) {
-			// Убираем префикс и разбиваем остаток по пробелам/табуляциям
-			lineClean := strings.ReplaceAll(line, "\t", " ")
-			linePart := strings.Split(lineClean[len(findPrefix):], " ")
-			for _, p := range linePart {
-				pClean := strings.TrimSpace(p)
-				if pClean == "" {
-					continue
-				}
-				// 6. Проверяем, что для компонента существует директория
-				//    /dists/<distributeName>/<component>
-				compPath := filepath.Join(distPath, pClean)
-				compInfo, err := os.Stat(compPath)
-				if err != nil || !compInfo.IsDir() {
-					// Директория не найдена — пропускаем компонент
-					continue
-				}
-				// Проверяем, не дубликат ли это
-				alreadyExists := false
-				for _, c := range components {
-					if c == pClean {
-						alreadyExists = true
-						break
-					}
-				}
-				if !alreadyExists {
-					components = append(components, pClean)
-				}
-			}
-			break
+	release, err := deb.ReadRelease(releaseFile)
+	if err != nil {
+		m.log.Warn("не удалось разобрать файл Release", slog.String("error", err.Error()))
+		return ""
+	}
+
+	// 5. Берём компоненты из поля Components
+	components := make([]string, 0, len(release.Components))
+	for _, component := range release.Components {
+		// 6. Проверяем, что для компонента существует директория
+		//    /dists/<distributeName>/<component>
+		compInfo, err := os.Stat(filepath.Join(distPath, component))
+		if err != nil || !compInfo.IsDir() {
+			// Директория не найдена — пропускаем компонент
+			continue
+		}
+		if !containsString(components, component) {
+			components = append(components, component)
 		}
 	}
 
 	if len(components) == 0 {
-		m.log.Warn(fmt.Sprintf("в файле '%s' не найден блок '%s'", releasePath, findPrefix))
+		m.log.Warn(fmt.Sprintf("в файле '%s' не найдены компоненты", releasePath))
 		return ""
 	}
 
@@ -0,0 +1,237 @@
+package repo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/kirsrus/iso2repo/models"
+	"github.com/kirsrus/iso2repo/pkg/deb"
+)
+
+// releaseChecksumFields поля списков файлов Release и соответствующие им хеш-функции.
+var releaseChecksumFields = []struct {
+	name string
+	hash func() hash.Hash
+}{
+	{"MD5Sum", md5.New},
+	{"SHA1", sha1.New},
+	{"SHA256", sha256.New},
+	{"SHA512", sha512.New},
+}
+
+// IndexFilter решает, оставить ли в индексе Packages запись p пакета e.
+type IndexFilter func(e CatalogEntry, p deb.Paragraph) bool
+
+// FilteredDist индексы дистрибутива, из которых исключены отфильтрованные записи.
+type FilteredDist struct {
+	Dist string
+	// Контрольная сумма SHA256 исходного Release: по ней проверяется актуальность.
+	Source string
+	// Исключённые пакеты. Если их нет, индексы дистрибутива отдаются без изменений.
+	Removed []CatalogEntry
+	// Переписанный Release (без подписи).
+	Release []byte
+	// Новые индексы Packages и Packages.gz по пути относительно корня репозитория.
+	Files map[string][]byte
+
+	// Пути изменённых индексов Packages относительно корня репозитория (без сжатия)
+	indexes []string
+}
+
+// ReadReleaseData читает Release (или, при его отсутствии, InRelease) дистрибутива
+// dist без разбора и возвращает его содержимое и контрольную сумму SHA256.
+func ReadReleaseData(ctx context.Context, r models.Repoes, dist string) ([]byte, string, error) {
+	var lastErr error
+	for _, name := range []string{"Release", "InRelease"} {
+		reader, err := r.Open(ctx, path.Join("dists", dist, name))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "не удалось прочитать %s дистрибутива %s", name, dist)
+		}
+		sum := sha256.Sum256(data)
+
+		return data, hex.EncodeToString(sum[:]), nil
+	}
+
+	return nil, "", errors.Wrapf(lastErr, "не найден Release дистрибутива %s", dist)
+}
+
+// FilterDist отбирает фильтром keep записи индексов Packages дистрибутива dist
+// репозитория r. Для индексов, из которых исключена хотя бы одна запись, формируются
+// Packages и Packages.gz, а Release переписывается: контрольные суммы изменённых
+// индексов заменяются, их прочие варианты (.xz, Packages.diff) и Acquire-By-Hash
+// убираются.
+func FilterDist(ctx context.Context, r models.Repoes, dist string, keep IndexFilter) (*FilteredDist, error) {
+	data, sum, err := ReadReleaseData(ctx, r, dist)
+	if err != nil {
+		return nil, err
+	}
+	release, err := deb.ReadRelease(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrapf(err, "не удалось разобрать Release дистрибутива %s", dist)
+	}
+
+	result := &FilteredDist{
+		Dist:    dist,
+		Source:  sum,
+		Removed: make([]CatalogEntry, 0),
+		Files:   make(map[string][]byte),
+		indexes: make([]string, 0),
+	}
+
+	repoName := r.Metadata().Name
+	for _, index := range packagesIndexes(release) {
+		component, _, _ := strings.Cut(index, "/")
+		indexPath := path.Join("dists", dist, index)
+		reader, err := OpenIndex(ctx, r, indexPath)
+		if errors.Is(err, ErrPackageNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var kept bytes.Buffer
+		removed := len(result.Removed)
+		err = deb.ReadParagraphs(reader, func(p deb.Paragraph) error {
+			meta := deb.PackageMeta{Package: p.Get("Package"), Version: p.Get("Version"), Extra: map[string]string{"Source": p.Get("Source")}}
+			source, sourceVersion := packageSource(&meta)
+			e := CatalogEntry{
+				Name:          meta.Package,
+				Version:       meta.Version,
+				Arch:          p.Get("Architecture"),
+				Repo:          repoName,
+				Suite:         dist,
+				Component:     component,
+				Filename:      p.Get("Filename"),
+				index:         indexPath,
+				source:        source,
+				sourceVersion: sourceVersion,
+			}
+			if !keep(e, p) {
+				result.Removed = append(result.Removed, e)
+				return nil
+			}
+			_, err := p.WriteTo(&kept)
+			return err
+		})
+		reader.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "индекс %s", indexPath)
+		}
+		if len(result.Removed) == removed {
+			continue
+		}
+
+		var gz bytes.Buffer
+		gw := gzip.NewWriter(&gz)
+		if _, err := gw.Write(kept.Bytes()); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if err := gw.Close(); err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		result.indexes = append(result.indexes, indexPath)
+		result.Files[indexPath] = kept.Bytes()
+		result.Files[indexPath+".gz"] = gz.Bytes()
+	}
+
+	if len(result.Removed) > 0 {
+		if result.Release, err = result.rewriteRelease(data); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// rewriteRelease переписывает списки файлов Release data под изменённые индексы.
+func (m *FilteredDist) rewriteRelease(data []byte) ([]byte, error) {
+	p, err := deb.NewReader(bytes.NewReader(data)).Next()
+	if err != nil {
+		return nil, errors.Wrapf(err, "не удалось разобрать Release дистрибутива %s", m.Dist)
+	}
+
+	prefix := "dists/" + m.Dist + "/"
+	result := make(deb.Paragraph, 0, len(p))
+	for _, f := range p {
+		// Индексы by-hash отдаются только для исходных файлов — запрос по имени надёжнее
+		if strings.EqualFold(f.Name, "Acquire-By-Hash") {
+			continue
+		}
+
+		for _, field := range releaseChecksumFields {
+			if !strings.EqualFold(f.Name, field.name) {
+				continue
+			}
+
+			lines := make([]string, 0)
+			for _, line := range strings.Split(f.Value, "\n") {
+				parts := strings.Fields(line)
+				if len(parts) != 3 || m.changed(prefix+parts[2]) {
+					continue
+				}
+				lines = append(lines, line)
+			}
+			for _, index := range m.indexes {
+				for _, name := range []string{index, index + ".gz"} {
+					h := field.hash()
+					h.Write(m.Files[name])
+					lines = append(lines, fmt.Sprintf("%x %16d %s", h.Sum(nil), len(m.Files[name]), strings.TrimPrefix(name, prefix)))
+				}
+			}
+			f.Value = "\n" + strings.Join(lines, "\n")
+		}
+
+		result = append(result, f)
+	}
+
+	var buf bytes.Buffer
+	if _, err := result.WriteTo(&buf); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// changed проверяет, относится ли путь p (от корня репозитория) к изменённому индексу:
+// сам индекс, его сжатые варианты, Packages.diff и by-hash его директории.
+func (m *FilteredDist) changed(p string) bool {
+	for _, index := range m.indexes {
+		if p == index || strings.HasPrefix(p, index+".") || strings.HasPrefix(p, path.Dir(index)+"/by-hash/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Lookup возвращает файл p (путь от корня репозитория) отфильтрованного дистрибутива.
+// found — файл сформирован заново; hidden — исходный файл нельзя отдавать, так как он
+// содержит исключённые записи.
+func (m *FilteredDist) Lookup(p string) (data []byte, found, hidden bool) {
+	if data, ok := m.Files[p]; ok {
+		return data, true, false
+	}
+
+	return nil, false, m.changed(p)
+}
@@ -0,0 +1,124 @@
+package repo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kirsrus/iso2repo/pkg/deb"
+	"golang.org/x/exp/slog"
+)
+
+func TestFilterDist(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a.iso")
+	packages := "Package: app\nVersion: 1.0\nArchitecture: amd64\nFilename: pool/main/a/app/app_1.0_amd64.deb\n\n" +
+		"Package: bad\nVersion: 2.0\nArchitecture: amd64\nSource: badsrc (1.9)\nFilename: pool/main/b/bad/bad_2.0_amd64.deb\n\n"
+	installer := "Package: app-udeb\nVersion: 1.0\nArchitecture: amd64\nFilename: pool/main/a/app/app-udeb_1.0_amd64.udeb\n\n"
+	files := map[string]string{
+		"dists/stable/main/binary-amd64/Packages":                    packages,
+		"dists/stable/main/binary-amd64/Packages.xz":                 "xz",
+		"dists/stable/main/debian-installer/binary-amd64/Packages":   installer,
+		"dists/stable/main/binary-amd64/by-hash/SHA256/0123456789ab": packages,
+	}
+	var release strings.Builder
+	release.WriteString("Origin: Test\nSuite: stable\nComponents: main\nArchitectures: amd64\nAcquire-By-Hash: yes\nSHA256:\n")
+	for _, name := range []string{
+		"dists/stable/main/binary-amd64/Packages",
+		"dists/stable/main/binary-amd64/Packages.xz",
+		"dists/stable/main/debian-installer/binary-amd64/Packages",
+	} {
+		fmt.Fprintf(&release, " %x %d %s\n", sha256.Sum256([]byte(files[name])), len(files[name]), strings.TrimPrefix(name, "dists/stable/"))
+	}
+	files["dists/stable/Release"] = release.String()
+	for name, data := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := NewRepoExtracted(dir, slog.New(slog.NewTextHandler(io.Discard)))
+	fd, err := FilterDist(context.Background(), r, "stable", func(e CatalogEntry, _ deb.Paragraph) bool {
+		return e.source != "badsrc"
+	})
+	if err != nil {
+		t.Fatalf("FilterDist() error = %v", err)
+	}
+
+	if len(fd.Removed) != 1 || fd.Removed[0].Name != "bad" || fd.Removed[0].Filename != "pool/main/b/bad/bad_2.0_amd64.deb" {
+		t.Fatalf("FilterDist() removed = %+v", fd.Removed)
+	}
+
+	index := "dists/stable/main/binary-amd64/Packages"
+	want := "Package: app\nVersion: 1.0\nArchitecture: amd64\nFilename: pool/main/a/app/app_1.0_amd64.deb\n\n"
+	if got := string(fd.Files[index]); got != want {
+		t.Errorf("FilterDist() Packages = %q, want %q", got, want)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(fd.Files[index+".gz"]))
+	if err != nil {
+		t.Fatalf("FilterDist() Packages.gz: %v", err)
+	}
+	if data, _ := io.ReadAll(gz); string(data) != want {
+		t.Errorf("FilterDist() Packages.gz = %q, want %q", data, want)
+	}
+
+	// В Release — суммы новых индексов, без вариантов исходного индекса и by-hash
+	rel, err := deb.NewReader(bytes.NewReader(fd.Release)).Next()
+	if err != nil {
+		t.Fatalf("FilterDist() Release: %v", err)
+	}
+	if rel.Get("Acquire-By-Hash") != "" || rel.Get("Origin") != "Test" {
+		t.Errorf("FilterDist() Release = %s", fd.Release)
+	}
+	got := make([]string, 0)
+	for _, line := range strings.Split(strings.TrimSpace(rel.Get("SHA256")), "\n") {
+		parts := strings.Fields(line)
+		name := "dists/stable/" + parts[2]
+		data, ok := fd.Files[name]
+		if !ok {
+			data = []byte(files[name])
+		}
+		if fmt.Sprintf("%x", sha256.Sum256(data)) != parts[0] || fmt.Sprint(len(data)) != parts[1] {
+			t.Errorf("FilterDist() Release line %q does not match %s", line, name)
+		}
+		got = append(got, parts[2])
+	}
+	wantNames := []string{"main/debian-installer/binary-amd64/Packages", "main/binary-amd64/Packages", "main/binary-amd64/Packages.gz"}
+	if !reflect.DeepEqual(got, wantNames) {
+		t.Errorf("FilterDist() Release files = %v, want %v", got, wantNames)
+	}
+
+	lookups := []struct {
+		path          string
+		found, hidden bool
+	}{
+		{path: index, found: true},
+		{path: index + ".xz", hidden: true},
+		{path: "dists/stable/main/binary-amd64/by-hash/SHA256/0123456789ab", hidden: true},
+		{path: "dists/stable/main/debian-installer/binary-amd64/Packages"},
+	}
+	for _, tt := range lookups {
+		if _, found, hidden := fd.Lookup(tt.path); found != tt.found || hidden != tt.hidden {
+			t.Errorf("Lookup(%s) = %v, %v, want %v, %v", tt.path, found, hidden, tt.found, tt.hidden)
+		}
+	}
+
+	unchanged, err := FilterDist(context.Background(), r, "stable", func(CatalogEntry, deb.Paragraph) bool { return true })
+	if err != nil {
+		t.Fatalf("FilterDist() error = %v", err)
+	}
+	if len(unchanged.Removed) != 0 || unchanged.Release != nil || len(unchanged.Files) != 0 || unchanged.Source != fd.Source {
+		t.Errorf("FilterDist() without removals = %+v", unchanged)
+	}
+}
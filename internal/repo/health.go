@@ -0,0 +1,262 @@
+package repo
+
+import (
+	"sort"
+
+	"github.com/cockroachdb/errors"
+	"github.com/kirsrus/iso2repo/pkg/deb"
+)
+
+// healthFields поля отношений, обязательные для установки пакета.
+var healthFields = []string{"Pre-Depends", "Depends"}
+
+// HealthQuery условия проверки зависимостей репозитория.
+type HealthQuery struct {
+	Repo string
+	// Дополнительные репозитории, пакеты которых (любого дистрибутива) могут
+	// удовлетворять зависимости пакетов Repo.
+	With []string
+}
+
+// HealthReport отчёт о целостности зависимостей репозитория по дистрибутивам.
+type HealthReport struct {
+	Repo   string        `json:"repo"`
+	With   []string      `json:"with"`
+	Suites []SuiteHealth `json:"suites"`
+}
+
+// SuiteHealth проблемы зависимостей одного дистрибутива репозитория.
+type SuiteHealth struct {
+	Suite string `json:"suite"`
+	// Количество пакетов в дистрибутиве.
+	Packages int `json:"packages"`
+	// Зависимости Depends и Pre-Depends, не удовлетворённые ни одним пакетом.
+	Unmet []UnmetDependency `json:"unmet"`
+	// Некорректные поля Provides.
+	BrokenProvides []BrokenProvides `json:"broken_provides"`
+	// Версии пакетов, представленные в дистрибутиве разными файлами.
+	Conflicts []VersionConflict `json:"conflicts"`
+}
+
+// UnmetDependency неудовлетворённая зависимость пакета.
+type UnmetDependency struct {
+	CatalogEntry
+	Field string `json:"field"`
+	// Группа альтернатив; при ошибке разбора — исходное значение поля.
+	Relation string `json:"relation"`
+	// Ошибка разбора поля, если оно некорректно.
+	Reason string `json:"reason,omitempty"`
+}
+
+// BrokenProvides некорректное поле Provides пакета.
+type BrokenProvides struct {
+	CatalogEntry
+	Provides string `json:"provides"`
+	Reason   string `json:"reason"`
+}
+
+// VersionConflict версия пакета, представленная в дистрибутиве разными файлами:
+// apt не сможет проверить контрольную сумму одного из них.
+type VersionConflict struct {
+	Name    string         `json:"name"`
+	Version string         `json:"version"`
+	Arch    string         `json:"arch"`
+	Entries []CatalogEntry `json:"entries"`
+}
+
+// Problems возвращает количество найденных в дистрибутиве проблем.
+func (m *SuiteHealth) Problems() int {
+	return len(m.Unmet) + len(m.BrokenProvides) + len(m.Conflicts)
+}
+
+// Problems возвращает количество найденных в репозитории проблем.
+func (m *HealthReport) Problems() int {
+	result := 0
+	for i := range m.Suites {
+		result += m.Suites[i].Problems()
+	}
+
+	return result
+}
+
+// Repos возвращает отсортированный список имён репозиториев каталога.
+func (m *Catalog) Repos() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]string, 0, len(m.repos))
+	for name := range m.repos {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// Health проверяет зависимости пакетов репозитория q.Repo: для каждого дистрибутива
+// находит Depends и Pre-Depends, которые не удовлетворяются пакетами того же
+// дистрибутива и репозиториев q.With, некорректные Provides и версии пакетов,
+// представленные разными файлами.
+func (m *Catalog) Health(q HealthQuery) (*HealthReport, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	repo, ok := m.repos[q.Repo]
+	if !ok {
+		return nil, errors.Wrapf(ErrPackageNotFound, "репозиторий %s", q.Repo)
+	}
+
+	report := &HealthReport{Repo: q.Repo, With: make([]string, 0), Suites: make([]SuiteHealth, 0)}
+	extra := make([]*catalogRepo, 0, len(q.With))
+	for _, name := range q.With {
+		if name == q.Repo {
+			continue
+		}
+		r, ok := m.repos[name]
+		if !ok {
+			return nil, errors.Wrapf(ErrPackageNotFound, "репозиторий %s", name)
+		}
+		extra = append(extra, r)
+		report.With = append(report.With, name)
+	}
+
+	suites := make(map[string]*SuiteHealth)
+	for i, e := range repo.entries {
+		suite, ok := suites[e.Suite]
+		if !ok {
+			suite = &SuiteHealth{
+				Suite:          e.Suite,
+				Unmet:          make([]UnmetDependency, 0),
+				BrokenProvides: make([]BrokenProvides, 0),
+				Conflicts:      make([]VersionConflict, 0),
+			}
+			suites[e.Suite] = suite
+		}
+		suite.Packages++
+
+		for _, field := range healthFields {
+			value := repo.relations[i].Get(field)
+			if value == "" {
+				continue
+			}
+			groups, err := deb.ParseRelations(value)
+			if err != nil {
+				suite.Unmet = append(suite.Unmet, UnmetDependency{CatalogEntry: e, Field: field, Relation: value, Reason: err.Error()})
+				continue
+			}
+			for _, alts := range groups {
+				if !repo.satisfied(e, alts, extra) {
+					suite.Unmet = append(suite.Unmet, UnmetDependency{CatalogEntry: e, Field: field, Relation: alts.String()})
+				}
+			}
+		}
+
+		suite.BrokenProvides = append(suite.BrokenProvides, checkProvides(e, repo.relations[i].Get("Provides"))...)
+	}
+
+	for _, name := range sortedKeys(repo.byName) {
+		// Записи пакета по дистрибутиву, версии и архитектуре
+		groups := make(map[[3]string][]CatalogEntry)
+		keys := make([][3]string, 0)
+		for _, i := range repo.byName[name] {
+			e := repo.entries[i]
+			key := [3]string{e.Suite, e.Version, e.Arch}
+			if _, ok := groups[key]; !ok {
+				keys = append(keys, key)
+			}
+			groups[key] = append(groups[key], e)
+		}
+
+		for _, key := range keys {
+			if entries := distinctEntries(groups[key]); len(entries) > 1 {
+				sortCatalogEntries(entries)
+				suites[key[0]].Conflicts = append(suites[key[0]].Conflicts, VersionConflict{Name: name, Version: key[1], Arch: key[2], Entries: entries})
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(suites) {
+		suite := suites[name]
+		sort.SliceStable(suite.Unmet, func(i, j int) bool {
+			return catalogEntryLess(suite.Unmet[i].CatalogEntry, suite.Unmet[j].CatalogEntry)
+		})
+		sort.SliceStable(suite.BrokenProvides, func(i, j int) bool {
+			return catalogEntryLess(suite.BrokenProvides[i].CatalogEntry, suite.BrokenProvides[j].CatalogEntry)
+		})
+		report.Suites = append(report.Suites, *suite)
+	}
+
+	return report, nil
+}
+
+// satisfied проверяет, удовлетворяется ли группа альтернатив alts пакета from пакетами
+// того же дистрибутива репозитория или любыми пакетами репозиториев extra.
+func (m *catalogRepo) satisfied(from CatalogEntry, alts deb.Alternatives, extra []*catalogRepo) bool {
+	for _, r := range alts {
+		for _, i := range m.resolve(from, r) {
+			if m.entries[i].Suite == from.Suite {
+				return true
+			}
+		}
+		for _, x := range extra {
+			if len(x.resolve(from, r)) > 0 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// checkProvides проверяет поле Provides пакета e: допускаются только имена пакетов,
+// возможно с версией после оператора "=".
+func checkProvides(e CatalogEntry, value string) []BrokenProvides {
+	result := make([]BrokenProvides, 0)
+	if value == "" {
+		return result
+	}
+
+	groups, err := deb.ParseRelations(value)
+	if err != nil {
+		return append(result, BrokenProvides{CatalogEntry: e, Provides: value, Reason: err.Error()})
+	}
+	for _, alts := range groups {
+		switch {
+		case len(alts) > 1:
+			result = append(result, BrokenProvides{CatalogEntry: e, Provides: alts.String(), Reason: "альтернативы в Provides недопустимы"})
+		case alts[0].Op != "" && alts[0].Op != deb.OpEqual:
+			result = append(result, BrokenProvides{CatalogEntry: e, Provides: alts.String(), Reason: "в Provides допустим только оператор ="})
+		case alts[0].Name == e.Name:
+			result = append(result, BrokenProvides{CatalogEntry: e, Provides: alts.String(), Reason: "пакет предоставляет сам себя"})
+		}
+	}
+
+	return result
+}
+
+// distinctEntries возвращает записи entries без повторов одного и того же файла
+// (пакет может быть указан в нескольких индексах дистрибутива).
+func distinctEntries(entries []CatalogEntry) []CatalogEntry {
+	result := make([]CatalogEntry, 0, len(entries))
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		if seen[e.Filename] {
+			continue
+		}
+		seen[e.Filename] = true
+		result = append(result, e)
+	}
+
+	return result
+}
+
+// sortedKeys возвращает отсортированные ключи карты m.
+func sortedKeys[V any](m map[string]V) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+
+	return result
+}
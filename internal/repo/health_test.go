@@ -0,0 +1,124 @@
+package repo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/kirsrus/iso2repo/pkg/deb"
+)
+
+func TestCatalog_Health(t *testing.T) {
+	catalog := newTestDependsCatalog([]testPackage{
+		{"app", "1.0", "amd64", deb.Paragraph{
+			{Name: "Depends", Value: "libc6, libext1 | libext2, mail-transport-agent (>= 1)"},
+			{Name: "Recommends", Value: "app-doc"},
+		}},
+		{"libc6", "2.36", "amd64", deb.Paragraph{{Name: "Depends", Value: "libc6 ((>= 1)"}}},
+		{"mta", "1.0", "amd64", deb.Paragraph{{Name: "Provides", Value: "mail-transport-agent (>= 1), mta, smtp | mail"}}},
+	})
+	// libext1 из другого дистрибутива не удовлетворяет зависимость app из stable
+	other := catalog.repos["a.iso"]
+	other.add(CatalogEntry{Name: "libext1", Version: "1.0", Arch: "amd64", Repo: "a.iso", Suite: "testing", Filename: "pool/libext1_1.0.deb"}, nil)
+	other.add(CatalogEntry{Name: "libext1", Version: "1.0", Arch: "amd64", Repo: "a.iso", Suite: "testing", Filename: "pool/libext1_1.0+rebuild.deb"}, nil)
+	other.add(CatalogEntry{Name: "libext1", Version: "1.0", Arch: "amd64", Repo: "a.iso", Suite: "testing", Component: "contrib", Filename: "pool/libext1_1.0.deb"}, nil)
+
+	ext := newCatalogRepo(nil)
+	ext.add(CatalogEntry{Name: "libext2", Version: "2.0", Arch: "amd64", Repo: "b.iso", Suite: "bookworm"}, nil)
+	catalog.repos["b.iso"] = ext
+
+	type suiteResult struct {
+		suite     string
+		packages  int
+		unmet     []string
+		provides  []string
+		conflicts []string
+	}
+	summarize := func(report *HealthReport) []suiteResult {
+		result := make([]suiteResult, 0)
+		for _, s := range report.Suites {
+			r := suiteResult{suite: s.Suite, packages: s.Packages}
+			for _, u := range s.Unmet {
+				r.unmet = append(r.unmet, u.Name+": "+u.Relation)
+			}
+			for _, p := range s.BrokenProvides {
+				r.provides = append(r.provides, p.Name+": "+p.Provides)
+			}
+			for _, c := range s.Conflicts {
+				r.conflicts = append(r.conflicts, c.Name+" "+c.Version+" "+c.Entries[0].Filename+" "+c.Entries[1].Filename)
+			}
+			result = append(result, r)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name    string
+		query   HealthQuery
+		want    []suiteResult
+		wantErr bool
+	}{
+		{
+			name:  "single repo",
+			query: HealthQuery{Repo: "a.iso"},
+			want: []suiteResult{
+				{
+					suite:    "stable",
+					packages: 3,
+					unmet: []string{
+						"app: libext1 | libext2",
+						"app: mail-transport-agent (>= 1)",
+						"libc6: libc6 ((>= 1)",
+					},
+					provides: []string{"mta: mail-transport-agent (>= 1)", "mta: mta", "mta: smtp | mail"},
+				},
+				{
+					suite:     "testing",
+					packages:  3,
+					conflicts: []string{"libext1 1.0 pool/libext1_1.0.deb pool/libext1_1.0+rebuild.deb"},
+				},
+			},
+		},
+		{
+			name:  "with other repo",
+			query: HealthQuery{Repo: "a.iso", With: []string{"b.iso", "a.iso"}},
+			want: []suiteResult{
+				{
+					suite:    "stable",
+					packages: 3,
+					unmet: []string{
+						"app: mail-transport-agent (>= 1)",
+						"libc6: libc6 ((>= 1)",
+					},
+					provides: []string{"mta: mail-transport-agent (>= 1)", "mta: mta", "mta: smtp | mail"},
+				},
+				{
+					suite:     "testing",
+					packages:  3,
+					conflicts: []string{"libext1 1.0 pool/libext1_1.0.deb pool/libext1_1.0+rebuild.deb"},
+				},
+			},
+		},
+		{name: "unknown repo", query: HealthQuery{Repo: "c.iso"}, wantErr: true},
+		{name: "unknown extra repo", query: HealthQuery{Repo: "a.iso", With: []string{"c.iso"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := catalog.Health(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Health() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, ErrPackageNotFound) {
+					t.Errorf("Health() error = %v, want ErrPackageNotFound", err)
+				}
+				return
+			}
+
+			if got := summarize(report); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Health() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package repo
+
+import (
+	"context"
+	"io"
+	"path"
+
+	"github.com/cockroachdb/errors"
+	"github.com/kirsrus/iso2repo/models"
+	"github.com/kirsrus/iso2repo/pkg/deb"
+)
+
+// indexVariants варианты сжатия индексных файлов в порядке предпочтения.
+var indexVariants = []string{"", ".xz", ".gz", ".zst", ".bz2"}
+
+// Distributions возвращает имена дистрибутивов репозитория r — поддиректорий dists/,
+// в которых есть Release или InRelease.
+func Distributions(ctx context.Context, r models.Repoes) ([]string, error) {
+	dists, err := r.List(ctx, "dists")
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]string, 0, len(dists))
+	for _, d := range dists {
+		if !d.IsDir {
+			continue
+		}
+		entries, err := r.List(ctx, path.Join("dists", d.Name))
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if !e.IsDir && (e.Name == "Release" || e.Name == "InRelease") {
+				result = append(result, d.Name)
+				break
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// ReadRelease читает Release (или, при его отсутствии, InRelease) дистрибутива dist
+// репозитория r.
+func ReadRelease(ctx context.Context, r models.Repoes, dist string) (*deb.Release, error) {
+	var lastErr error
+	for _, name := range []string{"Release", "InRelease"} {
+		reader, err := r.Open(ctx, path.Join("dists", dist, name))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		release, err := deb.ReadRelease(reader)
+		reader.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "не удалось разобрать %s дистрибутива %s", name, dist)
+		}
+
+		return release, nil
+	}
+
+	return nil, errors.Wrapf(lastErr, "не найден Release дистрибутива %s", dist)
+}
+
+// OpenIndex открывает индексный файл p (путь без расширения сжатия, например
+// "dists/stable/main/binary-amd64/Packages") репозитория r, выбирая первый
+// доступный вариант: без сжатия, .xz, .gz, .zst или .bz2. Возвращает распакованный поток.
+func OpenIndex(ctx context.Context, r models.Repoes, p string) (io.ReadCloser, error) {
+	entries, err := r.List(ctx, path.Dir(p))
+	if err != nil {
+		return nil, errors.Wrapf(ErrPackageNotFound, "индекс %s: %v", p, err)
+	}
+
+	present := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		if !e.IsDir {
+			present[e.Name] = true
+		}
+	}
+
+	for _, ext := range indexVariants {
+		name := path.Base(p) + ext
+		if !present[name] {
+			continue
+		}
+
+		reader, err := r.Open(ctx, path.Join(path.Dir(p), name))
+		if err != nil {
+			return nil, err
+		}
+
+		decompressed, closeFn, err := deb.Decompress(name, reader)
+		if err != nil {
+			reader.Close()
+			return nil, errors.Wrapf(err, "не удалось распаковать %s", name)
+		}
+
+		return &indexReader{Reader: decompressed, closeFns: []func(){closeFn, func() { reader.Close() }}}, nil
+	}
+
+	return nil, errors.Wrapf(ErrPackageNotFound, "индекс %s не найден", p)
+}
+
+// ReadPackagesIndex читает индекс Packages по пути p (см. OpenIndex), вызывая fn для
+// каждой записи. Если fn вернёт io.EOF, чтение прекращается без ошибки.
+func ReadPackagesIndex(ctx context.Context, r models.Repoes, p string, fn func(*deb.IndexPackage) error) error {
+	reader, err := OpenIndex(ctx, r, p)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	return errors.Wrapf(deb.ReadPackages(reader, fn), "индекс %s", p)
+}
+
+// ReadSourcesIndex читает индекс Sources по пути p (см. OpenIndex), вызывая fn для
+// каждой записи. Если fn вернёт io.EOF, чтение прекращается без ошибки.
+func ReadSourcesIndex(ctx context.Context, r models.Repoes, p string, fn func(*deb.SourcePackage) error) error {
+	reader, err := OpenIndex(ctx, r, p)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	return errors.Wrapf(deb.ReadSources(reader, fn), "индекс %s", p)
+}
+
+// indexReader распакованный поток индексного файла, закрывающий распаковщик и файл.
+type indexReader struct {
+	io.Reader
+	closeFns []func()
+}
+
+// Close освобождает распаковщик и закрывает исходный файл.
+func (m *indexReader) Close() error {
+	for _, fn := range m.closeFns {
+		fn()
+	}
+
+	return nil
+}
+
+// containsString проверяет, входит ли s в список list.
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
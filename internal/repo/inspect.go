@@ -0,0 +1,94 @@
+package repo
+
+import (
+	"context"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/kirsrus/iso2repo/models"
+	"github.com/kirsrus/iso2repo/pkg/deb"
+)
+
+// IsPackageFile проверяет по расширению, является ли файл name бинарным пакетом
+// (.deb, .udeb, .ddeb).
+func IsPackageFile(name string) bool {
+	_, ok := debKindByName(name)
+
+	return ok
+}
+
+// InspectPackage возвращает полное содержимое пакета (.deb, .udeb, .ddeb) по пути p
+// внутри репозитория r: поля control, md5sums, conffiles, triggers, сценарии
+// сопровождающего и список файлов (см. deb.Inspect).
+func InspectPackage(ctx context.Context, r models.Repoes, p string) (*deb.Inspection, error) {
+	reader, err := openPackage(ctx, r, p)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	inspection, err := deb.Inspect(reader)
+	if err != nil {
+		return nil, errors.Wrapf(err, "не удалось разобрать %s", p)
+	}
+
+	return inspection, nil
+}
+
+// ReadPackageFile читает файл name из пакета по пути p внутри репозитория r
+// (см. deb.ReadPackageFile). Файлы больше limit байт не читаются.
+func ReadPackageFile(ctx context.Context, r models.Repoes, p, name string, limit int64) ([]byte, error) {
+	reader, err := openPackage(ctx, r, p)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	data, err := deb.ReadPackageFile(reader, name, limit)
+	if errors.Is(err, deb.ErrFileNotFound) {
+		return nil, errors.Wrapf(ErrPackageNotFound, "%s в %s", name, p)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "не удалось прочитать %s из %s", name, p)
+	}
+
+	return data, nil
+}
+
+// openPackage открывает файл пакета по пути p внутри репозитория r, предварительно
+// проверяя, что это пакет и что он есть в репозитории.
+func openPackage(ctx context.Context, r models.Repoes, p string) (io.ReadCloser, error) {
+	p = strings.Trim(p, "/")
+	if !IsPackageFile(p) {
+		return nil, errors.Wrapf(ErrPackageNotFound, "%s не является пакетом", p)
+	}
+
+	dir := path.Dir(p)
+	if dir == "." {
+		dir = ""
+	}
+
+	entries, err := r.List(ctx, dir)
+	if err != nil {
+		return nil, errors.Wrapf(ErrPackageNotFound, "%s: %v", p, err)
+	}
+	found := false
+	for _, e := range entries {
+		if !e.IsDir && e.Name == path.Base(p) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, errors.Wrapf(ErrPackageNotFound, "%s в %s", p, r.Metadata().Name)
+	}
+
+	reader, err := r.Open(ctx, p)
+	if err != nil {
+		return nil, errors.Wrapf(err, "не удалось открыть %s", p)
+	}
+
+	return reader, nil
+}
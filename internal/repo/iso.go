@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/kirsrus/iso2repo/models"
+	"github.com/kirsrus/iso2repo/pkg/deb"
 	"github.com/kirsrus/iso2repo/pkg/sevenz"
 	"golang.org/x/exp/slog"
 )
@@ -105,7 +106,7 @@ func (m *RepoIso) IsRepo() bool {
 //  1. Ищем в корне ISO каталог /dists.
 //  2. Внутри /dists ищем первую поддиректорию — это имя дистрибутива (например, "1.7_x86-64").
 //  3. Внутри директории дистрибутива ищем файл "Release".
-//  4. Читаем файл Release, ищем строку с префиксом "components:".
+//  4. Читаем файл Release и берём из него поле Components.
 //  5. Извлекаем список компонентов (contrib, main, non-free и т.д.).
 //  6. Проверяем, что для каждого компонента существует соответствующая поддиректория
 //     внутри /dists/<distributeName>/.
@@ -173,51 +174,29 @@ func (m *RepoIso) RepoString() string {
 	}
 	defer reader.Close()
 
-	releaseData, err := io.ReadAll(reader)
+	release, err := deb.ReadRelease(reader)
 	if err != nil {
-		m.log.Warn("не удалось прочитать файл Release", slog.String("error", err.Error()))
+		m.log.Warn("не удалось разобрать файл Release", slog.String("error", err.Error()))
 		return ""
 	}
 
-	// 5. Ищем строку с префиксом "components:"
-	findPrefix := "components:"
-	components := make([]string, 0)
-	for _, line := range strings.Split(string(releaseData), "\n") {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(strings.ToLower(line), findPrefix) {
-			// Убираем префикс и разбиваем остаток по пробелам/табуляциям
-			lineClean := strings.ReplaceAll(line, "\t", " ")
-			linePart := strings.Split(lineClean[len(findPrefix):], " ")
-			for _, p := range linePart {
-				pClean := strings.TrimSpace(p)
-				if pClean == "" {
-					continue
-				}
-				// 6. Проверяем, что для компонента существует директория
-				//    /dists/<distributeName>/<component>
-				compEntries, err := m.List(context.Background(), "/dists/"+distributeName+"/"+pClean)
-				if err != nil || len(compEntries) == 0 {
-					// Директория не найдена — пропускаем компонент
-					continue
-				}
-				// Проверяем, не дубликат ли это
-				alreadyExists := false
-				for _, c := range components {
-					if c == pClean {
-						alreadyExists = true
-						break
-					}
-				}
-				if !alreadyExists {
-					components = append(components, pClean)
-				}
-			}
-			break
+	// 5. Берём компоненты из поля Components
+	components := make([]string, 0, len(release.Components))
+	for _, component := range release.Components {
+		// 6. Проверяем, что для компонента существует директория
+		//    /dists/<distributeName>/<component>
+		compEntries, err := m.List(context.Background(), "/dists/"+distributeName+"/"+component)
+		if err != nil || len(compEntries) == 0 {
+			// Директория не найдена — пропускаем компонент
+			continue
+		}
+		if !containsString(components, component) {
+			components = append(components, component)
 		}
 	}
 
 	if len(components) == 0 {
-		m.log.Warn(fmt.Sprintf("в файле '%s' не найден блок '%s'", releasePath, findPrefix))
+		m.log.Warn(fmt.Sprintf("в файле '%s' не найдены компоненты", releasePath))
 		return ""
 	}
 
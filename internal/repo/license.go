@@ -0,0 +1,237 @@
+package repo
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/kirsrus/iso2repo/models"
+	"github.com/kirsrus/iso2repo/pkg/deb"
+)
+
+// maxCopyrightSize максимальный размер читаемого файла copyright.
+const maxCopyrightSize = 1 << 20
+
+var (
+	// dep5OrRe и dep5AndRe разделители выражения лицензии DEP-5 ("GPL-2+ or Artistic").
+	dep5OrRe  = regexp.MustCompile(`(?i)\s+or\s+`)
+	dep5AndRe = regexp.MustCompile(`(?i)\s+and\s+`)
+	// dep5FamilyRe семейства лицензий GNU с версией ("GPL-2", "LGPL-2.1").
+	dep5FamilyRe = regexp.MustCompile(`(?i)^(A?GPL|LGPL|GFDL)(?:-?(\d+(?:\.\d+)?))?$`)
+	// spdxRefInvalidRe символы, недопустимые в идентификаторе LicenseRef.
+	spdxRefInvalidRe = regexp.MustCompile(`[^A-Za-z0-9.]+`)
+)
+
+// spdxFamilyMinVersion версия семейства GNU, подразумеваемая при её отсутствии.
+var spdxFamilyMinVersion = map[string]string{"GPL": "1.0", "LGPL": "2.0", "AGPL": "1.0", "GFDL": "1.1"}
+
+// spdxLicenseIDs идентификаторы SPDX, которые в copyright встречаются в том же написании
+// (с точностью до регистра).
+var spdxLicenseIDs = []string{
+	"0BSD", "Apache-1.0", "Apache-1.1", "Apache-2.0", "Artistic-1.0", "Artistic-1.0-Perl",
+	"Artistic-2.0", "Beerware", "BSD-2-Clause", "BSD-3-Clause", "BSD-4-Clause", "BSL-1.0",
+	"bzip2-1.0.6", "CC-BY-3.0", "CC-BY-4.0", "CC-BY-SA-3.0", "CC-BY-SA-4.0", "CC0-1.0",
+	"CDDL-1.0", "CDDL-1.1", "curl", "EPL-1.0", "EPL-2.0", "FTL", "ICU", "IJG", "ISC",
+	"Libpng", "LPPL-1.3c", "MIT", "MIT-0", "MPL-1.1", "MPL-2.0", "NTP", "OFL-1.1", "OpenSSL",
+	"PSF-2.0", "Python-2.0", "Ruby", "Sleepycat", "TCL", "Unicode-DFS-2016", "Unlicense",
+	"Vim", "W3C", "WTFPL", "X11", "Zlib", "ZPL-2.1",
+}
+
+// dep5Aliases короткие имена лицензий DEP-5, отличающиеся от идентификаторов SPDX.
+var dep5Aliases = map[string]string{
+	"expat":         "MIT",
+	"apache-2":      "Apache-2.0",
+	"artistic":      "Artistic-1.0",
+	"boost":         "BSL-1.0",
+	"cc0":           "CC0-1.0",
+	"perl":          "Artistic-1.0-Perl OR GPL-1.0-or-later",
+	"psf-2":         "PSF-2.0",
+	"public-domain": "LicenseRef-public-domain",
+	"sil-ofl-1.1":   "OFL-1.1",
+	"zope-2.1":      "ZPL-2.1",
+}
+
+// spdxByLower идентификаторы SPDX и псевдонимы DEP-5 по имени в нижнем регистре.
+var spdxByLower = func() map[string]string {
+	result := make(map[string]string, len(spdxLicenseIDs)+len(dep5Aliases))
+	for _, id := range spdxLicenseIDs {
+		result[strings.ToLower(id)] = id
+	}
+	for alias, id := range dep5Aliases {
+		result[alias] = id
+	}
+
+	return result
+}()
+
+// PackageLicense лицензия пакета, определённая по его файлу copyright.
+type PackageLicense struct {
+	// Выражение лицензии SPDX; пустое — лицензию определить не удалось.
+	Expression string
+	// Тексты лицензий, не имеющих идентификатора SPDX, по идентификатору LicenseRef-.
+	Texts map[string]string
+}
+
+// Licenses определяет лицензии пакетов по файлам copyright и хранит результат в
+// памяти: повторное построение SBOM не читает пакеты заново.
+type Licenses struct {
+	mu sync.Mutex
+	// Лицензии по ключу: репозиторий, путь и контрольная сумма пакета
+	entries map[string]PackageLicense
+}
+
+// NewLicenses конструктор Licenses.
+func NewLicenses() *Licenses {
+	return &Licenses{entries: make(map[string]PackageLicense)}
+}
+
+// Get возвращает лицензию пакета e репозитория r. checksum — контрольная сумма файла
+// пакета из индекса (часть ключа кэша). Пакеты без машиночитаемого copyright
+// получают пустую лицензию без ошибки.
+func (m *Licenses) Get(ctx context.Context, r models.Repoes, e CatalogEntry, checksum string) (PackageLicense, error) {
+	key := e.Repo + "/" + e.Filename + "/" + checksum
+	if m != nil {
+		m.mu.Lock()
+		license, ok := m.entries[key]
+		m.mu.Unlock()
+		if ok {
+			return license, nil
+		}
+	}
+
+	license, err := readPackageLicense(ctx, r, e)
+	if err != nil {
+		return PackageLicense{}, err
+	}
+
+	if m != nil {
+		m.mu.Lock()
+		m.entries[key] = license
+		m.mu.Unlock()
+	}
+
+	return license, nil
+}
+
+// readPackageLicense читает usr/share/doc/<пакет>/copyright из файла пакета e.
+func readPackageLicense(ctx context.Context, r models.Repoes, e CatalogEntry) (PackageLicense, error) {
+	reader, err := r.Open(ctx, e.Filename)
+	if err != nil {
+		return PackageLicense{}, err
+	}
+	defer reader.Close()
+
+	data, err := deb.ReadPackageFile(reader, "/usr/share/doc/"+e.Name+"/copyright", maxCopyrightSize)
+	if errors.Is(err, deb.ErrFileNotFound) || errors.Is(err, deb.ErrFileTooLarge) {
+		return PackageLicense{}, nil
+	}
+	if err != nil {
+		return PackageLicense{}, errors.Wrapf(err, "не удалось прочитать copyright из %s", e.Filename)
+	}
+
+	copyright, err := deb.ParseCopyright(data)
+	if err != nil {
+		// Copyright в произвольном формате или с ошибками — лицензия не определена
+		return PackageLicense{}, nil
+	}
+
+	return copyrightLicense(copyright), nil
+}
+
+// copyrightLicense составляет выражение SPDX из лицензий абзацев Files: разные
+// лицензии частей пакета объединяются через AND.
+func copyrightLicense(c *deb.Copyright) PackageLicense {
+	result := PackageLicense{Texts: make(map[string]string)}
+
+	refs := make(map[string]string)
+	parts := make([]string, 0, len(c.Licenses))
+	for _, name := range c.Licenses {
+		expr := spdxLicense(name, refs)
+		if !containsString(parts, expr) {
+			parts = append(parts, expr)
+		}
+	}
+
+	if len(parts) == 1 {
+		result.Expression = parts[0]
+	} else {
+		for i := range parts {
+			parts[i] = spdxGroup(parts[i])
+		}
+		result.Expression = strings.Join(parts, " AND ")
+	}
+
+	for ref, name := range refs {
+		text := c.Texts[name]
+		if text == "" {
+			text = name
+		}
+		result.Texts[ref] = text
+	}
+
+	return result
+}
+
+// spdxLicense переводит выражение лицензии DEP-5 ("GPL-2+ or Artistic") в выражение
+// SPDX ("GPL-2.0-or-later OR Artistic-1.0"). Лицензии без идентификатора SPDX
+// становятся LicenseRef-<имя>; в refs записывается исходное имя каждой из них.
+func spdxLicense(name string, refs map[string]string) string {
+	alternatives := dep5OrRe.Split(strings.TrimSpace(name), -1)
+	for i, alt := range alternatives {
+		terms := dep5AndRe.Split(alt, -1)
+		for j, term := range terms {
+			terms[j] = spdxTerm(strings.TrimSpace(term), refs)
+			if len(terms) > 1 {
+				terms[j] = spdxGroup(terms[j])
+			}
+		}
+		alternatives[i] = strings.Join(terms, " AND ")
+	}
+
+	return strings.Join(alternatives, " OR ")
+}
+
+// spdxTerm переводит одно имя лицензии DEP-5 в идентификатор SPDX.
+func spdxTerm(term string, refs map[string]string) string {
+	base, orLater := strings.TrimSuffix(term, "+"), strings.HasSuffix(term, "+")
+
+	if m := dep5FamilyRe.FindStringSubmatch(base); m != nil {
+		family, version := strings.ToUpper(m[1]), m[2]
+		if version == "" {
+			version, orLater = spdxFamilyMinVersion[family], true
+		}
+		if !strings.Contains(version, ".") {
+			version += ".0"
+		}
+		if orLater {
+			return family + "-" + version + "-or-later"
+		}
+		return family + "-" + version + "-only"
+	}
+
+	if id, ok := spdxByLower[strings.ToLower(base)]; ok {
+		if strings.HasPrefix(id, "LicenseRef-") {
+			refs[id] = term
+		}
+		if orLater && !strings.Contains(id, " ") {
+			return id + "+"
+		}
+		return id
+	}
+
+	ref := "LicenseRef-" + strings.Trim(spdxRefInvalidRe.ReplaceAllString(term, "-"), "-")
+	refs[ref] = term
+
+	return ref
+}
+
+// spdxGroup заключает в скобки составное выражение SPDX.
+func spdxGroup(expr string) string {
+	if strings.Contains(expr, " ") {
+		return "(" + expr + ")"
+	}
+
+	return expr
+}
@@ -0,0 +1,228 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kirsrus/iso2repo/pkg/deb"
+)
+
+// LintSeverity важность найденной проблемы.
+type LintSeverity string
+
+const (
+	// LintError пакет не будет корректно работать у клиентов.
+	LintError LintSeverity = "error"
+	// LintWarning пакет подозрителен, но apt его примет.
+	LintWarning LintSeverity = "warning"
+)
+
+// lintRelationFields поля отношений, синтаксис которых проверяется.
+var lintRelationFields = []string{
+	"Pre-Depends", "Depends", "Recommends", "Suggests", "Enhances",
+	"Breaks", "Conflicts", "Replaces", "Provides",
+}
+
+// installedSizeTolerance допустимое расхождение Installed-Size с размером содержимого
+// пакета в килобайтах: dpkg-gencontrol учитывает также файлы DEBIAN и Extra-Size.
+const installedSizeTolerance = 16
+
+// LintFinding проблема, найденная проверкой пакета пользовательского репозитория.
+type LintFinding struct {
+	// Имя файла пакета.
+	File     string       `json:"file"`
+	Severity LintSeverity `json:"severity"`
+	// Идентификатор проверки, например "missing-field".
+	Check   string `json:"check"`
+	Message string `json:"message"`
+}
+
+// Lint возвращает проблемы, найденные в пакетах репозитория при последнем сканировании
+// (включая пакеты на карантине). Сначала идут ошибки, затем предупреждения.
+func (m *RepoCustom) Lint() []LintFinding {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]LintFinding, len(m.lint))
+	copy(result, m.lint)
+
+	return result
+}
+
+// updateLint собирает проблемы пакетов репозитория: найденные при разборе каждого
+// файла и проверки, затрагивающие несколько файлов (дубликаты версий).
+func (m *RepoCustom) updateLint() {
+	files := make([]debFileInfo, 0, len(m.debFiles)+len(m.stagedFiles))
+	files = append(files, m.debFiles...)
+	files = append(files, m.stagedFiles...)
+
+	result := make([]LintFinding, 0)
+	for _, d := range files {
+		result = append(result, d.Lint...)
+	}
+	result = append(result, lintDuplicates(files)...)
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Severity != result[j].Severity {
+			return result[i].Severity == LintError
+		}
+		return result[i].File < result[j].File
+	})
+
+	m.lint = result
+}
+
+// lintPackage проверяет файл пакета filePath разновидности kind.
+func lintPackage(filePath string, kind debKind) []LintFinding {
+	inspection, err := inspectFile(filePath)
+
+	return lintInspection(filepath.Base(filePath), inspection, err, kind)
+}
+
+// inspectFile разбирает файл пакета filePath (см. deb.Inspect).
+func inspectFile(filePath string) (*deb.Inspection, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return deb.Inspect(f)
+}
+
+// lintInspection проверяет разобранный пакет name разновидности kind. inspectErr —
+// ошибка разбора пакета; если она не nil, пакет считается нечитаемым.
+func lintInspection(name string, inspection *deb.Inspection, inspectErr error, kind debKind) []LintFinding {
+	result := make([]LintFinding, 0)
+	add := func(severity LintSeverity, check, format string, args ...any) {
+		result = append(result, LintFinding{
+			File:     name,
+			Severity: severity,
+			Check:    check,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	if inspectErr != nil {
+		add(LintError, "unreadable-package", "не удалось прочитать пакет: %v", inspectErr)
+		return result
+	}
+	control := inspection.Control
+
+	for _, field := range []string{"Package", "Version", "Architecture"} {
+		if control.Get(field) == "" {
+			add(LintError, "missing-field", "отсутствует обязательное поле %s", field)
+		}
+	}
+	for _, field := range []string{"Maintainer", "Description"} {
+		if control.Get(field) == "" {
+			add(LintWarning, "missing-field", "отсутствует поле %s", field)
+		}
+	}
+
+	if version := control.Get("Version"); version != "" {
+		if _, err := deb.ParseVersion(version); err != nil {
+			add(LintError, "invalid-version", "некорректная версия %q: %v", version, err)
+		}
+	}
+
+	if arch := control.Get("Architecture"); arch != "" {
+		if arch != customArch && arch != "all" {
+			add(LintWarning, "unsupported-architecture", "архитектура %s не видна клиентам %s", arch, customArch)
+		}
+		parts := strings.Split(strings.TrimSuffix(name, filepath.Ext(name)), "_")
+		if len(parts) == 3 && parts[2] != arch {
+			add(LintWarning, "filename-architecture", "архитектура в имени файла (%s) не совпадает с полем Architecture (%s)", parts[2], arch)
+		}
+	}
+
+	for _, field := range lintRelationFields {
+		value := control.Get(field)
+		if value == "" {
+			continue
+		}
+		if _, err := deb.ParseRelations(value); err != nil {
+			add(LintError, "malformed-relation", "некорректное поле %s: %v", field, err)
+		}
+	}
+
+	// Для udeb Installed-Size не проверяем: debian-installer его не использует
+	if kind != debKindUdeb {
+		lintInstalledSize(control.Get("Installed-Size"), inspection.InstalledSize(), add)
+	}
+
+	return result
+}
+
+// lintInstalledSize сравнивает значение поля Installed-Size с размером содержимого пакета.
+func lintInstalledSize(value string, actual int64, add func(severity LintSeverity, check, format string, args ...any)) {
+	if value == "" {
+		add(LintWarning, "installed-size", "отсутствует поле Installed-Size (размер содержимого %d КиБ)", actual)
+		return
+	}
+
+	declared, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || declared < 0 {
+		add(LintError, "installed-size", "некорректное значение Installed-Size %q", value)
+		return
+	}
+
+	diff := declared - actual
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff > installedSizeTolerance && diff*10 > actual {
+		add(LintWarning, "installed-size", "Installed-Size %d КиБ не соответствует размеру содержимого %d КиБ", declared, actual)
+	}
+}
+
+// lintDuplicates находит файлы с одинаковыми пакетом, версией и архитектурой.
+// Разное содержимое — ошибка (клиенты получат несовпадение контрольных сумм),
+// одинаковое — предупреждение.
+func lintDuplicates(files []debFileInfo) []LintFinding {
+	groups := make(map[string][]debFileInfo)
+	keys := make([]string, 0)
+	for _, d := range files {
+		if d.Meta == nil || d.Meta.Package == "" || d.Meta.Version == "" {
+			continue
+		}
+		key := d.Meta.Package + "_" + d.Meta.Version + "_" + d.Meta.Architecture
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], d)
+	}
+
+	result := make([]LintFinding, 0)
+	for _, key := range keys {
+		group := groups[key]
+		if len(group) < 2 {
+			continue
+		}
+
+		same := true
+		names := make([]string, 0, len(group))
+		for _, d := range group {
+			names = append(names, d.Name)
+			same = same && d.SHA256Sum == group[0].SHA256Sum
+		}
+
+		for _, d := range group {
+			finding := LintFinding{File: d.Name, Check: "duplicate-version"}
+			if same {
+				finding.Severity = LintWarning
+				finding.Message = fmt.Sprintf("%s %s (%s) дублируется в файлах %s", d.Meta.Package, d.Meta.Version, d.Meta.Architecture, strings.Join(names, ", "))
+			} else {
+				finding.Severity = LintError
+				finding.Message = fmt.Sprintf("%s %s (%s) с разным содержимым в файлах %s", d.Meta.Package, d.Meta.Version, d.Meta.Architecture, strings.Join(names, ", "))
+			}
+			result = append(result, finding)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,97 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kirsrus/iso2repo/pkg/deb"
+)
+
+func TestLintInstalledSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		actual   int64
+		severity LintSeverity
+	}{
+		{name: "exact", value: "120", actual: 120},
+		{name: "within tolerance of small package", value: "12", actual: 1},
+		{name: "within ten percent of large package", value: "10500", actual: 10000},
+		{name: "missing", value: "", actual: 10, severity: LintWarning},
+		{name: "not a number", value: "12K", actual: 12, severity: LintError},
+		{name: "negative", value: "-1", actual: 0, severity: LintError},
+		{name: "bytes instead of kilobytes", value: "1048576", actual: 1024, severity: LintWarning},
+		{name: "far too small", value: "100", actual: 5000, severity: LintWarning},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []LintSeverity
+			lintInstalledSize(tt.value, tt.actual, func(severity LintSeverity, check, format string, args ...any) {
+				got = append(got, severity)
+			})
+
+			var want []LintSeverity
+			if tt.severity != "" {
+				want = []LintSeverity{tt.severity}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("lintInstalledSize(%q, %d) = %v, want %v", tt.value, tt.actual, got, want)
+			}
+		})
+	}
+}
+
+func TestLintDuplicates(t *testing.T) {
+	file := func(name, pkg, version, sha string) debFileInfo {
+		return debFileInfo{
+			Name:      name,
+			SHA256Sum: sha,
+			Meta:      &deb.PackageMeta{Package: pkg, Version: version, Architecture: "amd64"},
+		}
+	}
+
+	files := []debFileInfo{
+		file("foo_1.0_amd64.deb", "foo", "1.0", "aaa"),
+		file("foo_1.0_amd64.copy.deb", "foo", "1.0", "aaa"),
+		file("bar_1.0_amd64.deb", "bar", "1.0", "bbb"),
+		file("bar_1.0_amd64.rebuild.deb", "bar", "1.0", "ccc"),
+		file("bar_2.0_amd64.deb", "bar", "2.0", "ddd"),
+		{Name: "broken.deb"},
+	}
+
+	got := make(map[string]LintSeverity)
+	for _, f := range lintDuplicates(files) {
+		if f.Check != "duplicate-version" {
+			t.Errorf("unexpected check %q", f.Check)
+		}
+		got[f.File] = f.Severity
+	}
+
+	want := map[string]LintSeverity{
+		"foo_1.0_amd64.deb":         LintWarning,
+		"foo_1.0_amd64.copy.deb":    LintWarning,
+		"bar_1.0_amd64.deb":         LintError,
+		"bar_1.0_amd64.rebuild.deb": LintError,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lintDuplicates() = %v, want %v", got, want)
+	}
+}
+
+func TestLintPackage_unreadable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken_1.0_amd64.deb")
+	if err := os.WriteFile(path, []byte("not a package"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	findings := lintPackage(path, debKindDeb)
+	if len(findings) != 1 || findings[0].Check != "unreadable-package" || findings[0].Severity != LintError {
+		t.Errorf("lintPackage() = %+v, want single unreadable-package error", findings)
+	}
+	if findings[0].File != "broken_1.0_amd64.deb" {
+		t.Errorf("File = %q, want %q", findings[0].File, "broken_1.0_amd64.deb")
+	}
+}
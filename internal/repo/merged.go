@@ -0,0 +1,416 @@
+package repo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/kirsrus/iso2repo/models"
+	"github.com/kirsrus/iso2repo/pkg/deb"
+	"golang.org/x/exp/slog"
+	"gopkg.in/yaml.v3"
+)
+
+// mergedComponent единственный компонент объединённого репозитория.
+const mergedComponent = "main"
+
+// ErrInvalidMerged некорректный файл объединённых репозиториев.
+var ErrInvalidMerged = errors.New("некорректный файл объединённых репозиториев")
+
+// MergedSource репозиторий-источник объединённого репозитория.
+type MergedSource struct {
+	// Имя репозитория (ISO-образа, распакованного или пользовательского).
+	Repo string `yaml:"repo" json:"repo"`
+	// Дистрибутивы источника; пустой список — все.
+	Dists []string `yaml:"dists,omitempty" json:"dists,omitempty"`
+	// Приоритет источника: из версий пакета берётся версия источника с наибольшим
+	// приоритетом, при равных приоритетах — наибольшая версия.
+	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
+}
+
+// MergedConfig описание объединённого репозитория.
+type MergedConfig struct {
+	// Имя репозитория в адресе /merged/<имя>/.
+	Name string `yaml:"name" json:"name"`
+	// Дистрибутив (имя директории в dists/); по умолчанию совпадает с именем.
+	Suite       string `yaml:"suite,omitempty" json:"suite"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	// Архитектуры; пустой список — все архитектуры пакетов источников.
+	Architectures []string `yaml:"architectures,omitempty" json:"architectures,omitempty"`
+	// Источники в порядке предпочтения при равных приоритетах и версиях.
+	Sources []MergedSource `yaml:"sources" json:"sources"`
+}
+
+// ParseMerged разбирает файл объединённых репозиториев в формате YAML.
+func ParseMerged(data []byte) ([]MergedConfig, error) {
+	var file struct {
+		Merged []MergedConfig `yaml:"merged"`
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil && err != io.EOF {
+		return nil, errors.Wrapf(ErrInvalidMerged, "%v", err)
+	}
+
+	result := make([]MergedConfig, 0, len(file.Merged))
+	names := make(map[string]bool)
+	for i, cfg := range file.Merged {
+		if cfg.Name == "" {
+			return nil, errors.Wrapf(ErrInvalidMerged, "репозиторий %d: не задано имя", i+1)
+		}
+		if strings.ContainsAny(cfg.Name, "/ ") || cfg.Name == "." || cfg.Name == ".." {
+			return nil, errors.Wrapf(ErrInvalidMerged, "некорректное имя репозитория %q", cfg.Name)
+		}
+		if names[cfg.Name] {
+			return nil, errors.Wrapf(ErrInvalidMerged, "репозиторий %s описан несколько раз", cfg.Name)
+		}
+		names[cfg.Name] = true
+
+		if cfg.Suite == "" {
+			cfg.Suite = cfg.Name
+		}
+		if strings.ContainsAny(cfg.Suite, "/ ") || cfg.Suite == "." || cfg.Suite == ".." {
+			return nil, errors.Wrapf(ErrInvalidMerged, "репозиторий %s: некорректный дистрибутив %q", cfg.Name, cfg.Suite)
+		}
+		if len(cfg.Sources) == 0 {
+			return nil, errors.Wrapf(ErrInvalidMerged, "репозиторий %s: не задано ни одного источника", cfg.Name)
+		}
+		for _, s := range cfg.Sources {
+			if s.Repo == "" {
+				return nil, errors.Wrapf(ErrInvalidMerged, "репозиторий %s: у источника не задан repo", cfg.Name)
+			}
+		}
+		result = append(result, cfg)
+	}
+
+	return result, nil
+}
+
+// MergedFile файл объединённых репозиториев, перечитываемый при изменении.
+// Нулевое значение (nil) допустимо — объединённых репозиториев тогда нет.
+type MergedFile struct {
+	log  *slog.Logger
+	path string
+
+	mu sync.Mutex
+	// Действующие описания (последняя корректная версия файла)
+	configs []MergedConfig
+	// Номер версии описаний: увеличивается при каждой их смене
+	generation int
+	// Размер и время изменения прочитанного файла
+	size    int64
+	modTime time.Time
+	// Ошибка разбора текущей версии файла
+	err error
+}
+
+// NewMergedFile конструктор MergedFile для файла path. Отсутствующий файл означает
+// отсутствие объединённых репозиториев.
+func NewMergedFile(path string, log *slog.Logger) *MergedFile {
+	return &MergedFile{
+		log:  log.With(slog.String("module", "merged")),
+		path: path,
+	}
+}
+
+// Path возвращает путь к файлу объединённых репозиториев.
+func (m *MergedFile) Path() string {
+	if m == nil {
+		return ""
+	}
+
+	return m.path
+}
+
+// Configs возвращает действующие описания объединённых репозиториев, номер их версии
+// и ошибку разбора файла, перечитывая файл, если он изменился. При ошибке разбора
+// продолжает действовать предыдущая корректная версия.
+func (m *MergedFile) Configs() ([]MergedConfig, int, error) {
+	if m == nil {
+		return nil, 0, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stat, err := os.Stat(m.path)
+	if os.IsNotExist(err) {
+		if m.configs != nil || !m.modTime.IsZero() {
+			m.log.Info("файл объединённых репозиториев удалён", slog.String("path", m.path))
+			m.configs, m.size, m.modTime, m.err = nil, 0, time.Time{}, nil
+			m.generation++
+		}
+		return nil, m.generation, nil
+	}
+	if err != nil {
+		return m.configs, m.generation, errors.Wrap(err, "не удалось прочитать файл объединённых репозиториев")
+	}
+	if stat.Size() == m.size && stat.ModTime().Equal(m.modTime) {
+		return m.configs, m.generation, m.err
+	}
+	m.size, m.modTime = stat.Size(), stat.ModTime()
+
+	data, err := os.ReadFile(m.path)
+	if err == nil {
+		var configs []MergedConfig
+		if configs, err = ParseMerged(data); err == nil {
+			m.configs, m.err = configs, nil
+			m.generation++
+			m.log.Info("загружены объединённые репозитории", slog.String("path", m.path), slog.Int("repos", len(configs)))
+			return m.configs, m.generation, nil
+		}
+	}
+
+	m.err = errors.Wrapf(err, "файл объединённых репозиториев %s", m.path)
+	m.log.Error("файл объединённых репозиториев не применён, действует предыдущая версия", m.err, slog.String("path", m.path))
+
+	return m.configs, m.generation, m.err
+}
+
+// Config возвращает действующее описание объединённого репозитория name.
+func (m *MergedFile) Config(name string) (MergedConfig, bool) {
+	configs, _, _ := m.Configs()
+	for _, cfg := range configs {
+		if cfg.Name == name {
+			return cfg, true
+		}
+	}
+
+	return MergedConfig{}, false
+}
+
+// MergedRepo объединённый репозиторий: индексы Packages и Release единственного
+// дистрибутива, сформированные из пакетов источников. Файлы пакетов остаются в
+// источниках и адресуются как pool/<репозиторий>/<путь в репозитории>.
+type MergedRepo struct {
+	Config MergedConfig `json:"config"`
+	// Архитектуры индексов.
+	Architectures []string `json:"architectures"`
+	// Пакеты, вошедшие в индексы, по имени и архитектуре.
+	Packages []CatalogEntry `json:"packages"`
+	// Источники, отсутствующие в каталоге.
+	Missing []string `json:"missing,omitempty"`
+	// Release (без подписи).
+	Release []byte `json:"-"`
+	// Индексы Packages и Packages.gz по пути относительно корня репозитория.
+	Files map[string][]byte `json:"-"`
+
+	// Пакеты по пути файла относительно корня репозитория
+	pool map[string]CatalogEntry
+}
+
+// MergedFilename возвращает путь файла пакета e в объединённом репозитории.
+func MergedFilename(e CatalogEntry) string {
+	return "pool/" + e.Repo + "/" + e.Filename
+}
+
+// Merge формирует объединённый репозиторий cfg из пакетов каталога. Для каждого
+// имени и архитектуры берётся пакет источника с наибольшим приоритетом, при равных
+// приоритетах — с наибольшей версией, при равных версиях — из источника, указанного
+// раньше. Не включаются пакеты debian-installer, отладочных компонентов (main-dbg)
+// и пакеты, для которых exclude (может быть nil) возвращает true. Пакеты "all"
+// попадают в индексы всех архитектур.
+func (m *Catalog) Merge(ctx context.Context, cfg MergedConfig, exclude func(CatalogEntry) bool) (*MergedRepo, error) {
+	type packageKey struct{ name, arch string }
+	type candidate struct {
+		entry    CatalogEntry
+		priority int
+	}
+
+	result := &MergedRepo{
+		Config:        cfg,
+		Architectures: make([]string, 0),
+		Packages:      make([]CatalogEntry, 0),
+		Files:         make(map[string][]byte),
+		pool:          make(map[string]CatalogEntry),
+	}
+
+	m.mu.RLock()
+	sources := make(map[string]models.Repoes)
+	chosen := make(map[packageKey]candidate)
+	for _, s := range cfg.Sources {
+		repo, ok := m.repos[s.Repo]
+		if !ok {
+			if !containsString(result.Missing, s.Repo) {
+				result.Missing = append(result.Missing, s.Repo)
+			}
+			continue
+		}
+		sources[s.Repo] = repo.repo
+
+		for _, e := range repo.entries {
+			if len(s.Dists) > 0 && !containsString(s.Dists, e.Suite) {
+				continue
+			}
+			if strings.Contains(e.index, "/debian-installer/") || strings.HasSuffix(e.Component, "-dbg") {
+				continue
+			}
+			if len(cfg.Architectures) > 0 && e.Arch != "all" && !containsString(cfg.Architectures, e.Arch) {
+				continue
+			}
+			if exclude != nil && exclude(e) {
+				continue
+			}
+
+			key := packageKey{e.Name, e.Arch}
+			prev, ok := chosen[key]
+			if ok && (prev.priority > s.Priority || prev.priority == s.Priority && deb.CompareVersions(e.Version, prev.entry.Version) <= 0) {
+				continue
+			}
+			chosen[key] = candidate{entry: e, priority: s.Priority}
+		}
+	}
+	m.mu.RUnlock()
+
+	entries := make([]CatalogEntry, 0, len(chosen))
+	for _, c := range chosen {
+		entries = append(entries, c.entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Name != entries[j].Name {
+			return entries[i].Name < entries[j].Name
+		}
+		return entries[i].Arch < entries[j].Arch
+	})
+
+	// Записи индексов с путями файлов в объединённом репозитории
+	controls := m.readControls(ctx, entries, sources)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	archs := cfg.Architectures
+	if len(archs) == 0 {
+		archs = make([]string, 0)
+		for _, e := range entries {
+			if e.Arch != "all" && !containsString(archs, e.Arch) {
+				archs = append(archs, e.Arch)
+			}
+		}
+		sort.Strings(archs)
+	}
+	if len(archs) == 0 {
+		archs = []string{"all"}
+	}
+	result.Architectures = archs
+
+	buffers := make(map[string]*bytes.Buffer, len(archs))
+	for _, arch := range archs {
+		buffers[arch] = &bytes.Buffer{}
+	}
+	for i, e := range entries {
+		if controls[i] == nil {
+			m.log.Warn("пакет не найден в индексе источника", slog.String("merged", cfg.Name), slog.String("repo", e.Repo), slog.String("file", e.Filename))
+			continue
+		}
+
+		p := make(deb.Paragraph, len(controls[i]))
+		copy(p, controls[i])
+		for j := range p {
+			if strings.EqualFold(p[j].Name, "Filename") {
+				p[j].Value = MergedFilename(e)
+			}
+		}
+		for arch, buf := range buffers {
+			if e.Arch != arch && e.Arch != "all" {
+				continue
+			}
+			if _, err := p.WriteTo(buf); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+
+		result.Packages = append(result.Packages, e)
+		result.pool[MergedFilename(e)] = e
+	}
+
+	prefix := path.Join("dists", cfg.Suite) + "/"
+	for arch, buf := range buffers {
+		index := path.Join(mergedComponent, "binary-"+arch, "Packages")
+
+		var gz bytes.Buffer
+		gw := gzip.NewWriter(&gz)
+		if _, err := gw.Write(buf.Bytes()); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if err := gw.Close(); err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		result.Files[prefix+index] = buf.Bytes()
+		result.Files[prefix+index+".gz"] = gz.Bytes()
+	}
+	result.Release = result.release(prefix)
+
+	return result, nil
+}
+
+// release формирует Release объединённого репозитория с контрольными суммами
+// индексов Files.
+func (m *MergedRepo) release(prefix string) []byte {
+	names := sortedKeys(m.Files)
+	description := m.Config.Description
+	if description == "" {
+		description = "Merged apt repository " + m.Config.Name
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Origin: iso2repo\n")
+	fmt.Fprintf(&buf, "Label: %s\n", m.Config.Name)
+	fmt.Fprintf(&buf, "Suite: %s\n", m.Config.Suite)
+	fmt.Fprintf(&buf, "Codename: %s\n", m.Config.Suite)
+	fmt.Fprintf(&buf, "Date: %s\n", time.Now().UTC().Format("Mon, 02 Jan 2006 15:04:05 MST"))
+	fmt.Fprintf(&buf, "Architectures: %s\n", strings.Join(m.Architectures, " "))
+	fmt.Fprintf(&buf, "Components: %s\n", mergedComponent)
+	fmt.Fprintf(&buf, "Description: %s\n", description)
+	fmt.Fprintf(&buf, "MD5Sum:\n")
+	for _, name := range names {
+		fmt.Fprintf(&buf, " %x %d %s\n", md5.Sum(m.Files[name]), len(m.Files[name]), strings.TrimPrefix(name, prefix))
+	}
+	fmt.Fprintf(&buf, "SHA1:\n")
+	for _, name := range names {
+		fmt.Fprintf(&buf, " %x %d %s\n", sha1.Sum(m.Files[name]), len(m.Files[name]), strings.TrimPrefix(name, prefix))
+	}
+	fmt.Fprintf(&buf, "SHA256:\n")
+	for _, name := range names {
+		fmt.Fprintf(&buf, " %x %d %s\n", sha256.Sum256(m.Files[name]), len(m.Files[name]), strings.TrimPrefix(name, prefix))
+	}
+
+	return buf.Bytes()
+}
+
+// Lookup возвращает файл p (путь от корня репозитория) индексов объединённого
+// репозитория: Release или индекс Packages.
+func (m *MergedRepo) Lookup(p string) ([]byte, bool) {
+	if p == path.Join("dists", m.Config.Suite, "Release") {
+		return m.Release, true
+	}
+	data, ok := m.Files[p]
+
+	return data, ok
+}
+
+// Pool возвращает пакет, файл которого в объединённом репозитории расположен по пути p.
+func (m *MergedRepo) Pool(p string) (CatalogEntry, bool) {
+	e, ok := m.pool[p]
+
+	return e, ok
+}
+
+// RepoString возвращает строку sources.list объединённого репозитория с адресом
+// сервера 0.0.0.0.
+func (m MergedConfig) RepoString() string {
+	return fmt.Sprintf("deb [trusted=yes] http://0.0.0.0/merged/%s %s %s", m.Name, m.Suite, mergedComponent)
+}
@@ -0,0 +1,174 @@
+package repo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kirsrus/iso2repo/pkg/deb"
+	"golang.org/x/exp/slog"
+)
+
+func TestParseMerged(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []MergedConfig
+		wantErr bool
+	}{
+		{
+			name: "suite defaults to name",
+			data: "merged:\n  - name: all\n    sources:\n      - repo: a.iso\n      - repo: custom.iso\n        priority: 10\n",
+			want: []MergedConfig{{Name: "all", Suite: "all", Sources: []MergedSource{{Repo: "a.iso"}, {Repo: "custom.iso", Priority: 10}}}},
+		},
+		{name: "empty file", data: "", want: []MergedConfig{}},
+		{name: "missing name", data: "merged:\n  - sources:\n      - repo: a.iso\n", wantErr: true},
+		{name: "name with slash", data: "merged:\n  - name: a/b\n    sources:\n      - repo: a.iso\n", wantErr: true},
+		{name: "duplicate name", data: "merged:\n  - name: a\n    sources: [{repo: a.iso}]\n  - name: a\n    sources: [{repo: b.iso}]\n", wantErr: true},
+		{name: "no sources", data: "merged:\n  - name: a\n", wantErr: true},
+		{name: "unknown field", data: "merged:\n  - name: a\n    repos: [a.iso]\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMerged([]byte(tt.data))
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidMerged) {
+					t.Errorf("ParseMerged() error = %v, want ErrInvalidMerged", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMerged() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMerged() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// writeMergedTestRepo создаёт распакованный репозиторий dir с дистрибутивом stable
+// и пакетами packages ("имя версия архитектура"); пакеты "all" — в индексе amd64.
+func writeMergedTestRepo(t *testing.T, dir string, packages ...string) {
+	t.Helper()
+
+	indexes := make(map[string]*strings.Builder)
+	for _, p := range packages {
+		fields := strings.Fields(p)
+		name, version, arch := fields[0], fields[1], fields[2]
+		index := "dists/stable/main/binary-amd64/Packages"
+		if arch != "all" {
+			index = "dists/stable/main/binary-" + arch + "/Packages"
+		}
+		if indexes[index] == nil {
+			indexes[index] = &strings.Builder{}
+		}
+		file := fmt.Sprintf("pool/main/%s_%s_%s.deb", name, version, arch)
+		fmt.Fprintf(indexes[index], "Package: %s\nVersion: %s\nArchitecture: %s\nFilename: %s\nSize: 1\n\n", name, version, arch, file)
+	}
+
+	files := map[string]string{"dists/stable/Release": "Suite: stable\nComponents: main\nArchitectures: amd64 arm64\n"}
+	for index, data := range indexes {
+		files[index] = data.String()
+	}
+	for name, data := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCatalog_Merge(t *testing.T) {
+	root := t.TempDir()
+	writeMergedTestRepo(t, filepath.Join(root, "a.iso"), "app 1.0 amd64", "lib 2.0 all", "tool 3.0 amd64", "blocked 1.0 amd64")
+	writeMergedTestRepo(t, filepath.Join(root, "b.iso"), "app 1.1 amd64", "app 1.1 arm64", "lib 2.0 all")
+	writeMergedTestRepo(t, filepath.Join(root, "c.iso"), "tool 1.0 amd64")
+
+	log := slog.New(slog.NewTextHandler(io.Discard))
+	catalog := NewCatalog(log)
+	for _, name := range []string{"a.iso", "b.iso", "c.iso"} {
+		if err := catalog.Update(context.Background(), NewRepoExtracted(filepath.Join(root, name), log)); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+	}
+
+	cfg := MergedConfig{Name: "all", Suite: "all", Sources: []MergedSource{
+		{Repo: "a.iso"}, {Repo: "b.iso"}, {Repo: "c.iso", Priority: 10}, {Repo: "missing.iso"},
+	}}
+	merged, err := catalog.Merge(context.Background(), cfg, func(e CatalogEntry) bool {
+		return e.Name == "blocked"
+	})
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+
+	// Версия — наибольшая, но приоритет источника важнее версии; при равных версиях
+	// берётся источник, указанный раньше
+	got := make([]string, 0)
+	for _, e := range merged.Packages {
+		got = append(got, fmt.Sprintf("%s %s %s %s", e.Name, e.Version, e.Arch, e.Repo))
+	}
+	want := []string{"app 1.1 amd64 b.iso", "app 1.1 arm64 b.iso", "lib 2.0 all a.iso", "tool 1.0 amd64 c.iso"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge() packages = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(merged.Architectures, []string{"amd64", "arm64"}) {
+		t.Errorf("Merge() architectures = %v", merged.Architectures)
+	}
+	if !reflect.DeepEqual(merged.Missing, []string{"missing.iso"}) {
+		t.Errorf("Merge() missing = %v", merged.Missing)
+	}
+
+	index, ok := merged.Lookup("dists/all/main/binary-arm64/Packages")
+	if !ok {
+		t.Fatalf("Lookup(Packages) not found")
+	}
+	wantIndex := "Package: app\nVersion: 1.1\nArchitecture: arm64\nFilename: pool/b.iso/pool/main/app_1.1_arm64.deb\nSize: 1\n\n" +
+		"Package: lib\nVersion: 2.0\nArchitecture: all\nFilename: pool/a.iso/pool/main/lib_2.0_all.deb\nSize: 1\n\n"
+	if string(index) != wantIndex {
+		t.Errorf("Packages = %q, want %q", index, wantIndex)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(merged.Files["dists/all/main/binary-arm64/Packages.gz"]))
+	if err != nil {
+		t.Fatalf("Packages.gz: %v", err)
+	}
+	if data, _ := io.ReadAll(gz); string(data) != wantIndex {
+		t.Errorf("Packages.gz = %q, want %q", data, wantIndex)
+	}
+
+	data, ok := merged.Lookup("dists/all/Release")
+	if !ok {
+		t.Fatalf("Lookup(Release) not found")
+	}
+	release, err := deb.ReadRelease(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if release.Suite != "all" || !reflect.DeepEqual(release.Architectures, []string{"amd64", "arm64"}) || len(release.SHA256) != 4 {
+		t.Errorf("Release = %s", data)
+	}
+	if f, ok := release.File("main/binary-arm64/Packages"); !ok || f.Hash != fmt.Sprintf("%x", sha256.Sum256(index)) {
+		t.Errorf("Release checksum of Packages = %+v", f)
+	}
+
+	e, ok := merged.Pool("pool/a.iso/pool/main/lib_2.0_all.deb")
+	if !ok || e.Repo != "a.iso" || e.Filename != "pool/main/lib_2.0_all.deb" {
+		t.Errorf("Pool() = %+v, %v", e, ok)
+	}
+	if _, ok := merged.Pool("pool/a.iso/pool/main/blocked_1.0_amd64.deb"); ok {
+		t.Errorf("Pool() returned excluded package")
+	}
+}
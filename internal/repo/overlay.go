@@ -0,0 +1,575 @@
+package repo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/kirsrus/iso2repo/models"
+	"github.com/kirsrus/iso2repo/pkg/deb"
+	"golang.org/x/exp/slog"
+)
+
+var _ models.Repoes = (*RepoOverlay)(nil)
+
+const (
+	// overlaySuffix суффикс директории дополнения: vendor.iso + vendor.iso.d/.
+	overlaySuffix = ".d"
+
+	// overlayPool директория пула (относительно корня репозитория), в которой
+	// публикуются пакеты дополнения.
+	overlayPool = "pool/overlay"
+)
+
+// binaryIndexRe индекс Packages бинарных пакетов компонента (без debian-installer)
+// относительно директории дистрибутива.
+var binaryIndexRe = regexp.MustCompile(`^[^/]+/binary-([^/]+)/Packages$`)
+
+// RepoOverlay репозиторий ISO-образа (или распакованного образа), дополненный
+// пакетами из соседней директории <имя>.d: например, vendor.iso и vendor.iso.d/
+// с исправлениями. Индексы Packages дистрибутивов образа объединяются с пакетами
+// дополнения, которые публикуются в pool/overlay/. Пакет дополнения заменяет
+// одноимённый пакет образа той же архитектуры независимо от версии.
+//
+// Release дистрибутивов переписывается под изменённые индексы, поэтому подпись
+// образа (InRelease, Release.gpg) не отдаётся. Пока в дополнении нет пакетов,
+// репозиторий отдаёт файлы образа без изменений.
+type RepoOverlay struct {
+	log  *slog.Logger
+	base models.Repoes
+	// Пакеты дополнения сканируются так же, как пользовательский репозиторий
+	overlay *RepoCustom
+
+	mu sync.Mutex
+	// Объединённые индексы; nil — ещё не построены или устарели
+	current *overlayState
+}
+
+// overlayState объединённые индексы дистрибутивов образа и пакетов дополнения.
+type overlayState struct {
+	// Пакеты дополнения (по одному файлу на имя и архитектуру)
+	packages []debFileInfo
+	// Изменённые индексы и Release по дистрибутивам
+	dists map[string]*FilteredDist
+	// Сформированные файлы по пути от корня репозитория
+	files map[string][]byte
+	// Дерево сформированных файлов и пула дополнения для List
+	tree []models.Entry
+}
+
+// NewRepoOverlay конструктор RepoOverlay. base — репозиторий образа, dir — абсолютный
+// путь к директории дополнения, options — параметры пользовательского репозитория
+// пакетов дополнения. Пакеты дополнения публикуются под именем образа, а после их
+// изменения объединённые индексы строятся заново до вызова options.OnChange.
+func NewRepoOverlay(base models.Repoes, dir string, log *slog.Logger, options CustomOptions) *RepoOverlay {
+	if log == nil {
+		log = slog.New(slog.NewTextHandler(io.Discard))
+	}
+
+	m := &RepoOverlay{
+		log:  log.With("sub", "overlay"),
+		base: base,
+	}
+
+	onChange := options.OnChange
+	options.OnChange = func(r *RepoCustom) {
+		m.reset()
+		if onChange != nil {
+			onChange(r)
+		}
+	}
+	m.overlay = NewRepoCustom(dir, log, options)
+	m.overlay.name = base.Metadata().Name
+
+	return m
+}
+
+// withOverlay возвращает r, дополненный директорией <путь>.d, если она существует.
+func withOverlay(r models.Repoes, log *slog.Logger, options CustomOptions) models.Repoes {
+	dir := r.Metadata().Path + overlaySuffix
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return r
+	}
+
+	return NewRepoOverlay(r, dir, log, options)
+}
+
+// Base возвращает репозиторий образа.
+func (m *RepoOverlay) Base() models.Repoes {
+	return m.base
+}
+
+// Overlay возвращает пользовательский репозиторий пакетов дополнения: через него
+// пакеты публикуются, удаляются и проходят карантин.
+func (m *RepoOverlay) Overlay() *RepoCustom {
+	return m.overlay
+}
+
+// OverlayPath возвращает путь к директории дополнения.
+func (m *RepoOverlay) OverlayPath() string {
+	return m.overlay.path
+}
+
+// Refresh повторно сканирует директорию дополнения; объединённые индексы строятся
+// заново при следующем обращении.
+func (m *RepoOverlay) Refresh() {
+	m.overlay.Refresh()
+	m.reset()
+}
+
+// reset сбрасывает объединённые индексы.
+func (m *RepoOverlay) reset() {
+	m.mu.Lock()
+	m.current = nil
+	m.mu.Unlock()
+}
+
+// Metadata возвращает метаданные репозитория образа.
+func (m *RepoOverlay) Metadata() models.Repo {
+	return m.base.Metadata()
+}
+
+// IsRepo возвращает true, если образ является репозиторием.
+func (m *RepoOverlay) IsRepo() bool {
+	return m.base.IsRepo()
+}
+
+// RepoString возвращает строку sources.list образа. Если в дополнении есть пакеты,
+// подпись образа недействительна, и в строку добавляется trusted=yes.
+func (m *RepoOverlay) RepoString() string {
+	result := m.base.RepoString()
+
+	state, err := m.state()
+	if err != nil || len(state.packages) == 0 {
+		return result
+	}
+
+	return withSourceOption(result, "trusted=yes")
+}
+
+// withSourceOption добавляет параметр option в список параметров строки sources.list
+// ("deb [arch=amd64] <адрес> <дистрибутив> <компоненты>"), создавая список, если
+// его нет. Пустая строка и строка, уже содержащая параметр, не изменяются.
+func withSourceOption(line, option string) string {
+	kind, rest, ok := strings.Cut(strings.TrimSpace(line), " ")
+	if !ok {
+		return line
+	}
+	rest = strings.TrimSpace(rest)
+
+	var options []string
+	if inner, ok := strings.CutPrefix(rest, "["); ok {
+		list, tail, ok := strings.Cut(inner, "]")
+		if !ok {
+			return line
+		}
+		options, rest = strings.Fields(list), strings.TrimSpace(tail)
+	}
+	for _, v := range options {
+		if v == option {
+			return line
+		}
+	}
+	options = append(options, option)
+
+	return kind + " [" + strings.Join(options, " ") + "] " + rest
+}
+
+// List возвращает список записей по пути p: записи образа с учётом изменённых
+// индексов и пакеты дополнения в pool/overlay.
+func (m *RepoOverlay) List(ctx context.Context, p string) ([]models.Entry, error) {
+	p = strings.Trim(p, "/")
+
+	state, err := m.state()
+	if err != nil {
+		return nil, err
+	}
+	if len(state.packages) == 0 {
+		return m.base.List(ctx, p)
+	}
+
+	extra := lookupEntries(state.tree, p)
+	entries, err := m.base.List(ctx, p)
+	if err != nil {
+		if len(extra) == 0 {
+			return nil, err
+		}
+		entries = nil
+	}
+
+	return state.merge(p, entries, extra), nil
+}
+
+// Open открывает файл p: сформированный индекс или Release, пакет дополнения или
+// файл образа.
+func (m *RepoOverlay) Open(ctx context.Context, p string) (io.ReadCloser, error) {
+	p = strings.Trim(p, "/")
+
+	state, err := m.state()
+	if err != nil {
+		return nil, err
+	}
+	if len(state.packages) == 0 {
+		return m.base.Open(ctx, p)
+	}
+
+	if data, ok := state.files[p]; ok {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	if name, ok := strings.CutPrefix(p, overlayPool+"/"); ok {
+		for _, d := range state.packages {
+			if d.Name != name {
+				continue
+			}
+			file, err := os.Open(d.Path)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			return file, nil
+		}
+		return nil, errors.Wrapf(ErrPackageNotFound, "файл %s", p)
+	}
+	if state.hidden(p) {
+		return nil, errors.Wrapf(ErrPackageNotFound, "файл %s заменён дополнением", p)
+	}
+
+	return m.base.Open(ctx, p)
+}
+
+// state возвращает объединённые индексы, при необходимости строя их заново.
+func (m *RepoOverlay) state() (*overlayState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.current != nil {
+		return m.current, nil
+	}
+
+	// Построение не привязано к запросу: его результат нужен и другим клиентам
+	state, err := m.build(context.Background())
+	if err != nil {
+		return nil, errors.Wrapf(err, "не удалось объединить %s с дополнением", m.base.Metadata().Name)
+	}
+	m.current = state
+
+	return state, nil
+}
+
+// build объединяет индексы всех дистрибутивов образа с пакетами дополнения.
+func (m *RepoOverlay) build(ctx context.Context) (*overlayState, error) {
+	state := &overlayState{
+		packages: m.overlayPackages(),
+		dists:    make(map[string]*FilteredDist),
+		files:    make(map[string][]byte),
+		tree:     make([]models.Entry, 0),
+	}
+	if len(state.packages) == 0 {
+		return state, nil
+	}
+
+	dists, err := Distributions(ctx, m.base)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	for _, dist := range dists {
+		fd, err := m.buildDist(ctx, dist, state.packages)
+		if err != nil {
+			return nil, err
+		}
+		if fd == nil {
+			continue
+		}
+
+		state.dists[dist] = fd
+		state.files[path.Join("dists", dist, "Release")] = fd.Release
+		for p, data := range fd.Files {
+			state.files[p] = data
+		}
+	}
+
+	for _, p := range sortedKeys(state.files) {
+		segments := strings.Split(p, "/")
+		insertEntry(&state.tree, segments[:len(segments)-1], models.Entry{
+			Name:     segments[len(segments)-1],
+			FilePath: p,
+			Size:     int64(len(state.files[p])),
+			CreateAt: now,
+			Children: make([]models.Entry, 0),
+		})
+	}
+	for _, d := range state.packages {
+		insertEntry(&state.tree, strings.Split(overlayPool, "/"), models.Entry{
+			Name:     d.Name,
+			FilePath: overlayPool + "/" + d.Name,
+			Size:     d.Size,
+			CreateAt: d.FileTime,
+			Children: make([]models.Entry, 0),
+		})
+	}
+
+	m.log.Info("индексы образа объединены с дополнением", slog.String("repo", m.base.Metadata().Name),
+		slog.Int("packages", len(state.packages)), slog.Int("dists", len(state.dists)))
+
+	return state, nil
+}
+
+// overlayPackages возвращает пакеты дополнения: только .deb с прочитанными
+// метаданными, для каждого имени и архитектуры — наибольшая версия.
+func (m *RepoOverlay) overlayPackages() []debFileInfo {
+	m.overlay.mu.RLock()
+	defer m.overlay.mu.RUnlock()
+
+	selected := make(map[string]debFileInfo)
+	for _, d := range m.overlay.debFiles {
+		if d.Kind != debKindDeb || d.Meta == nil {
+			continue
+		}
+		key := d.Meta.Package + "/" + d.Meta.Architecture
+		if prev, ok := selected[key]; ok && deb.CompareVersions(prev.Meta.Version, d.Meta.Version) >= 0 {
+			continue
+		}
+		selected[key] = d
+	}
+
+	result := make([]debFileInfo, 0, len(selected))
+	for _, key := range sortedKeys(selected) {
+		result = append(result, selected[key])
+	}
+
+	return result
+}
+
+// buildDist объединяет индексы дистрибутива dist с пакетами packages. Пакеты
+// добавляются в индекс компонента main (или первого компонента) своей архитектуры,
+// пакеты "all" — во все архитектуры дистрибутива; одноимённые пакеты образа
+// исключаются из индексов всех компонентов. Возвращает nil, если дистрибутив
+// не затронут.
+func (m *RepoOverlay) buildDist(ctx context.Context, dist string, packages []debFileInfo) (*FilteredDist, error) {
+	data, sum, err := ReadReleaseData(ctx, m.base, dist)
+	if err != nil {
+		return nil, err
+	}
+	release, err := deb.ReadRelease(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrapf(err, "не удалось разобрать Release дистрибутива %s", dist)
+	}
+	if len(release.Components) == 0 {
+		return nil, nil
+	}
+	component := release.Components[0]
+	if containsString(release.Components, "main") {
+		component = "main"
+	}
+
+	// Пакеты дополнения по архитектурам дистрибутива
+	added := make(map[string][]debFileInfo)
+	for _, arch := range release.Architectures {
+		if arch == "all" || arch == "source" {
+			continue
+		}
+		for _, d := range packages {
+			if d.Meta.Architecture == arch || d.Meta.Architecture == "all" {
+				added[arch] = append(added[arch], d)
+			}
+		}
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	indexes := make([]string, 0)
+	for _, index := range packagesIndexes(release) {
+		if binaryIndexRe.MatchString(index) {
+			indexes = append(indexes, index)
+		}
+	}
+	for arch := range added {
+		if target := path.Join(component, "binary-"+arch, "Packages"); !containsString(indexes, target) {
+			indexes = append(indexes, target)
+		}
+	}
+	sort.Strings(indexes)
+
+	result := &FilteredDist{
+		Dist:    dist,
+		Source:  sum,
+		Removed: make([]CatalogEntry, 0),
+		Files:   make(map[string][]byte),
+		indexes: make([]string, 0),
+	}
+
+	repoName := m.base.Metadata().Name
+	for _, index := range indexes {
+		arch := binaryIndexRe.FindStringSubmatch(index)[1]
+		if len(added[arch]) == 0 {
+			continue
+		}
+		names := make(map[string]bool, len(added[arch]))
+		for _, d := range added[arch] {
+			names[d.Meta.Package] = true
+		}
+
+		indexPath := path.Join("dists", dist, index)
+		var merged bytes.Buffer
+		removed := len(result.Removed)
+		reader, err := OpenIndex(ctx, m.base, indexPath)
+		switch {
+		case errors.Is(err, ErrPackageNotFound):
+		case err != nil:
+			return nil, err
+		default:
+			err = deb.ReadParagraphs(reader, func(p deb.Paragraph) error {
+				if !names[p.Get("Package")] {
+					_, err := p.WriteTo(&merged)
+					return err
+				}
+
+				e := CatalogEntry{
+					Name:      p.Get("Package"),
+					Version:   p.Get("Version"),
+					Arch:      p.Get("Architecture"),
+					Repo:      repoName,
+					Suite:     dist,
+					Component: strings.Split(index, "/")[0],
+					Filename:  p.Get("Filename"),
+				}
+				result.Removed = append(result.Removed, e)
+				m.log.Info("пакет образа заменён пакетом из дополнения", slog.String("repo", repoName), slog.String("dist", dist),
+					slog.String("package", e.Name), slog.String("version", e.Version), slog.String("arch", e.Arch))
+
+				return nil
+			})
+			reader.Close()
+			if err != nil {
+				return nil, errors.Wrapf(err, "индекс %s", indexPath)
+			}
+		}
+
+		target := strings.HasPrefix(index, component+"/")
+		if !target && len(result.Removed) == removed {
+			continue
+		}
+		if target {
+			for _, d := range added[arch] {
+				writeOverlayStanza(&merged, d)
+			}
+		}
+
+		var gz bytes.Buffer
+		gw := gzip.NewWriter(&gz)
+		if _, err := gw.Write(merged.Bytes()); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if err := gw.Close(); err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		result.indexes = append(result.indexes, indexPath)
+		result.Files[indexPath] = merged.Bytes()
+		result.Files[indexPath+".gz"] = gz.Bytes()
+	}
+
+	if result.Release, err = result.rewriteRelease(data); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// writeOverlayStanza записывает в buf запись индекса Packages для пакета дополнения d.
+func writeOverlayStanza(buf *bytes.Buffer, d debFileInfo) {
+	for _, f := range packageStanza(d) {
+		if f.Name == "Filename" {
+			f.Value = overlayPool + "/" + d.Name
+		}
+		buf.WriteString(deb.FormatField(f.Name, f.Value))
+	}
+	buf.WriteString("\n")
+}
+
+// hidden проверяет, скрыт ли файл образа p: подпись изменённого Release и прочие
+// варианты изменённых индексов.
+func (m *overlayState) hidden(p string) bool {
+	for dist, fd := range m.dists {
+		prefix := path.Join("dists", dist) + "/"
+		if p == prefix+"InRelease" || p == prefix+"Release.gpg" {
+			return true
+		}
+		if _, _, hidden := fd.Lookup(p); hidden {
+			return true
+		}
+	}
+
+	return false
+}
+
+// merge объединяет записи образа entries директории dir со сформированными
+// записями extra: размеры заменённых файлов обновляются, скрытые файлы убираются,
+// недостающие записи добавляются. Вложенные записи объединяются рекурсивно.
+func (m *overlayState) merge(dir string, entries, extra []models.Entry) []models.Entry {
+	result := make([]models.Entry, 0, len(entries)+len(extra))
+	seen := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		p := path.Join(dir, e.Name)
+		x, ok := findEntry(extra, e.Name, e.IsDir)
+		switch {
+		case e.IsDir && ok && len(e.Children) > 0:
+			e.Children = m.merge(p, e.Children, x.Children)
+		case e.IsDir && len(e.Children) > 0:
+			e.Children = m.merge(p, e.Children, nil)
+		case !e.IsDir && ok:
+			e.Size = x.Size
+		case !e.IsDir && m.hidden(p):
+			continue
+		}
+		seen[e.Name] = true
+		result = append(result, e)
+	}
+
+	for _, x := range extra {
+		if !seen[x.Name] {
+			result = append(result, x)
+		}
+	}
+
+	return result
+}
+
+// findEntry ищет в entries запись с именем name и тем же признаком директории.
+func findEntry(entries []models.Entry, name string, isDir bool) (models.Entry, bool) {
+	for _, e := range entries {
+		if e.Name == name && e.IsDir == isDir {
+			return e, true
+		}
+	}
+
+	return models.Entry{}, false
+}
+
+// lookupEntries возвращает содержимое директории p дерева tree или nil, если её нет.
+func lookupEntries(tree []models.Entry, p string) []models.Entry {
+	if p == "" {
+		return tree
+	}
+
+	for _, segment := range strings.Split(p, "/") {
+		e, ok := findEntry(tree, segment, true)
+		if !ok {
+			return nil
+		}
+		tree = e.Children
+	}
+
+	return tree
+}
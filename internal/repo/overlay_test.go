@@ -0,0 +1,250 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/kirsrus/iso2repo/pkg/deb"
+	"github.com/kirsrus/iso2repo/pkg/deb/debtest"
+	"golang.org/x/exp/slog"
+)
+
+func TestRepoOverlay(t *testing.T) {
+	root := t.TempDir()
+	base := filepath.Join(root, "vendor.iso")
+	writeMergedTestRepo(t, base, "app 1.0 amd64", "lib 2.0 all", "tool 3.0 amd64", "app 1.0 arm64")
+
+	// Release образа с контрольными суммами и подписью
+	var release strings.Builder
+	release.WriteString("Suite: stable\nComponents: main\nArchitectures: amd64 arm64\nAcquire-By-Hash: yes\nSHA256:\n")
+	for _, arch := range []string{"amd64", "arm64"} {
+		data, err := os.ReadFile(filepath.Join(base, "dists/stable/main/binary-"+arch+"/Packages"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&release, " %x %d main/binary-%s/Packages\n", sha256.Sum256(data), len(data), arch)
+		fmt.Fprintf(&release, " %x %d main/binary-%s/Packages.xz\n", sha256.Sum256(nil), 0, arch)
+	}
+	for name, data := range map[string]string{"Release": release.String(), "InRelease": "signed " + release.String()} {
+		if err := os.WriteFile(filepath.Join(base, "dists/stable", name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard))
+	overlayDir := base + overlaySuffix
+
+	// Пока директории дополнения нет, образ не оборачивается
+	if r := withOverlay(NewRepoExtracted(base, log), log, CustomOptions{}); reflect.TypeOf(r) != reflect.TypeOf(&RepoExtracted{}) {
+		t.Fatalf("withOverlay() without directory = %T", r)
+	}
+
+	// Версия пакета дополнения ниже, чем в образе, но дополнение важнее
+	debtest.Write(t, overlayDir, "app_0.9_amd64.deb", debtest.Package(t, "app", "0.9", "amd64", nil))
+	debtest.Write(t, overlayDir, "tool_3.1_amd64.deb", debtest.Package(t, "tool", "3.1", "amd64", nil))
+	debtest.Write(t, overlayDir, "tool_3.2_amd64.deb", debtest.Package(t, "tool", "3.2", "amd64", nil))
+	debtest.Write(t, overlayDir, "hotfix_1.0_all.deb", debtest.Package(t, "hotfix", "1.0", "all", nil))
+
+	r, ok := withOverlay(NewRepoExtracted(base, log), log, CustomOptions{}).(*RepoOverlay)
+	if !ok {
+		t.Fatalf("withOverlay() did not return RepoOverlay")
+	}
+
+	catalog := NewCatalog(log)
+	if err := catalog.Update(context.Background(), r); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	entries, err := catalog.Search(CatalogQuery{})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	got := make([]string, 0, len(entries))
+	for _, e := range entries {
+		got = append(got, fmt.Sprintf("%s %s %s %s", e.Name, e.Version, e.Arch, e.Filename))
+	}
+	sort.Strings(got)
+	// Пакет "all" дополнения попадает в индексы обеих архитектур
+	want := []string{
+		"app 0.9 amd64 pool/overlay/app_0.9_amd64.deb",
+		"app 1.0 arm64 pool/main/app_1.0_arm64.deb",
+		"hotfix 1.0 all pool/overlay/hotfix_1.0_all.deb",
+		"hotfix 1.0 all pool/overlay/hotfix_1.0_all.deb",
+		"lib 2.0 all pool/main/lib_2.0_all.deb",
+		"tool 3.2 amd64 pool/overlay/tool_3.2_amd64.deb",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("catalog = %v, want %v", got, want)
+	}
+
+	read := func(p string) (string, error) {
+		reader, err := r.Open(context.Background(), p)
+		if err != nil {
+			return "", err
+		}
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		return string(data), err
+	}
+
+	// Release описывает новые индексы, их прочие варианты и by-hash убраны
+	data, err := read("dists/stable/Release")
+	if err != nil {
+		t.Fatalf("Open(Release) error = %v", err)
+	}
+	parsed, err := deb.ReadRelease(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	index, err := read("dists/stable/main/binary-amd64/Packages")
+	if err != nil {
+		t.Fatalf("Open(Packages) error = %v", err)
+	}
+	if f, ok := parsed.File("main/binary-amd64/Packages"); !ok || f.Hash != fmt.Sprintf("%x", sha256.Sum256([]byte(index))) {
+		t.Errorf("Release checksum of Packages = %+v", f)
+	}
+	if strings.Contains(data, "Packages.xz") || strings.Contains(data, "Acquire-By-Hash") {
+		t.Errorf("Release = %s", data)
+	}
+	if _, err := read("dists/stable/InRelease"); !errors.Is(err, ErrPackageNotFound) {
+		t.Errorf("Open(InRelease) error = %v", err)
+	}
+
+	entriesList, err := r.List(context.Background(), "dists/stable")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	for _, e := range entriesList {
+		if e.Name == "InRelease" {
+			t.Errorf("List() contains InRelease")
+		}
+		if e.Name == "Release" && e.Size != int64(len(data)) {
+			t.Errorf("List() Release size = %d, want %d", e.Size, len(data))
+		}
+	}
+
+	if data, err := read("pool/overlay/hotfix_1.0_all.deb"); err != nil || !strings.HasPrefix(data, "!<arch>") {
+		t.Errorf("Open(pool/overlay) = %q, %v", data, err)
+	}
+	if _, err := read("pool/overlay/missing.deb"); !errors.Is(err, ErrPackageNotFound) {
+		t.Errorf("Open(missing) error = %v", err)
+	}
+	if f, err := FindPackage(context.Background(), r, "hotfix", "", ""); err != nil || f.Path != "pool/overlay/hotfix_1.0_all.deb" {
+		t.Errorf("FindPackage() = %+v, %v", f, err)
+	}
+
+	// Пустое дополнение — образ отдаётся без изменений
+	if err := os.RemoveAll(overlayDir); err != nil {
+		t.Fatal(err)
+	}
+	r.Refresh()
+	if data, err := read("dists/stable/InRelease"); err != nil || !strings.HasPrefix(data, "signed ") {
+		t.Errorf("Open(InRelease) after refresh = %q, %v", data, err)
+	}
+}
+
+func TestRepoOverlay_publish(t *testing.T) {
+	root := t.TempDir()
+	base := filepath.Join(root, "vendor.iso")
+	writeMergedTestRepo(t, base, "app 1.0 amd64")
+	overlayDir := base + overlaySuffix
+	if err := os.MkdirAll(overlayDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard))
+	var changed []*RepoCustom
+	r := NewRepoOverlay(NewRepoExtracted(base, log), overlayDir, log, CustomOptions{
+		OnChange: func(c *RepoCustom) { changed = append(changed, c) },
+	})
+
+	// Пакеты дополнения публикуются под именем образа
+	if got := r.Overlay().Metadata().Name; got != "vendor.iso" {
+		t.Errorf("Overlay().Metadata().Name = %s, want vendor.iso", got)
+	}
+	if got := r.RepoString(); strings.Contains(got, "trusted=yes") {
+		t.Errorf("RepoString() of empty overlay = %s", got)
+	}
+
+	u, err := r.Overlay().ReceiveUpload("app_0.9_amd64.deb", bytes.NewReader(debtest.Package(t, "app", "0.9", "amd64", nil)))
+	if err != nil {
+		t.Fatalf("ReceiveUpload() error = %v", err)
+	}
+	if _, err := r.Overlay().Publish([]*Upload{u}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if len(changed) != 1 || changed[0] != r.Overlay() {
+		t.Errorf("OnChange calls = %v, want one with overlay repository", changed)
+	}
+
+	// Объединённые индексы построены заново без Refresh
+	if f, err := FindPackage(context.Background(), r, "app", "", "amd64"); err != nil || f.Path != "pool/overlay/app_0.9_amd64.deb" {
+		t.Errorf("FindPackage() after publish = %+v, %v", f, err)
+	}
+	want := "deb [arch=amd64 trusted=yes] http://0.0.0.0/repo/vendor.iso stable main"
+	if got := r.RepoString(); got != want {
+		t.Errorf("RepoString() = %s, want %s", got, want)
+	}
+
+	if _, err := r.Overlay().RemovePackage("app", "0.9", ""); err != nil {
+		t.Fatalf("RemovePackage() error = %v", err)
+	}
+	if f, err := FindPackage(context.Background(), r, "app", "", "amd64"); err != nil || f.Path != "pool/main/app_1.0_amd64.deb" {
+		t.Errorf("FindPackage() after remove = %+v, %v", f, err)
+	}
+}
+
+func TestWithSourceOption(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{
+			name: "adds to existing options",
+			line: "deb [arch=amd64] http://0.0.0.0/repo/vendor.iso stable main",
+			want: "deb [arch=amd64 trusted=yes] http://0.0.0.0/repo/vendor.iso stable main",
+		},
+		{
+			name: "several options",
+			line: "deb [arch=amd64,arm64 signed-by=/etc/key.gpg] http://0.0.0.0/repo/vendor.iso stable main contrib",
+			want: "deb [arch=amd64,arm64 signed-by=/etc/key.gpg trusted=yes] http://0.0.0.0/repo/vendor.iso stable main contrib",
+		},
+		{
+			name: "creates option list",
+			line: "deb http://0.0.0.0/repo/vendor.iso stable main",
+			want: "deb [trusted=yes] http://0.0.0.0/repo/vendor.iso stable main",
+		},
+		{
+			name: "option already present",
+			line: "deb [trusted=yes arch=amd64] http://0.0.0.0/repo/vendor.iso stable main",
+			want: "deb [trusted=yes arch=amd64] http://0.0.0.0/repo/vendor.iso stable main",
+		},
+		{
+			name: "empty line",
+			line: "",
+			want: "",
+		},
+		{
+			name: "unterminated option list",
+			line: "deb [arch=amd64 http://0.0.0.0/repo/vendor.iso stable main",
+			want: "deb [arch=amd64 http://0.0.0.0/repo/vendor.iso stable main",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := withSourceOption(tt.line, "trusted=yes"); got != tt.want {
+				t.Errorf("withSourceOption() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
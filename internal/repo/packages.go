@@ -0,0 +1,245 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/kirsrus/iso2repo/models"
+	"github.com/kirsrus/iso2repo/pkg/deb"
+	"golang.org/x/exp/slog"
+)
+
+var (
+	// ErrPackageNotFound пакет не найден в репозитории.
+	ErrPackageNotFound = errors.New("пакет не найден")
+
+	// ErrPackageAmbiguous под условия поиска подходит несколько файлов пакета.
+	ErrPackageAmbiguous = errors.New("найдено несколько подходящих пакетов, уточните версию или архитектуру")
+)
+
+// PackageFile описывает файл бинарного пакета внутри репозитория.
+type PackageFile struct {
+	// Путь к файлу относительно корня репозитория (например, "pool/main/f/foo/foo_1.0_amd64.deb").
+	Path string
+	// Имя файла.
+	Name    string
+	Package string
+	Version string
+	Arch    string
+}
+
+// FindPackage ищет в репозитории r файл пакета name. Пустые version и arch означают
+// любую версию и архитектуру; если при этом подходящих файлов несколько, возвращается
+// ErrPackageAmbiguous. Версия сравнивается полностью, с эпохой.
+//
+// Для пользовательского репозитория используются метаданные из control, для остальных —
+// записи индексов Packages всех дистрибутивов.
+func FindPackage(ctx context.Context, r models.Repoes, name, version, arch string) (PackageFile, error) {
+	candidates := make([]PackageFile, 0)
+
+	if custom, ok := r.(*RepoCustom); ok {
+		custom.mu.RLock()
+		for _, d := range custom.debFiles {
+			if d.Meta == nil || d.Meta.Package != name {
+				continue
+			}
+			candidates = append(candidates, PackageFile{
+				Path:    d.Kind.poolDir() + "/" + d.Name,
+				Name:    d.Name,
+				Package: d.Meta.Package,
+				Version: d.Meta.Version,
+				Arch:    d.Meta.Architecture,
+			})
+		}
+		custom.mu.RUnlock()
+	} else {
+		var err error
+		candidates, err = indexPackageFiles(ctx, r, name)
+		if err != nil {
+			return PackageFile{}, err
+		}
+	}
+
+	matched := make([]PackageFile, 0, len(candidates))
+	for _, f := range candidates {
+		if version != "" && f.Version != version {
+			continue
+		}
+		if arch != "" && f.Arch != arch {
+			continue
+		}
+		matched = append(matched, f)
+	}
+
+	switch len(matched) {
+	case 0:
+		return PackageFile{}, errors.Wrapf(ErrPackageNotFound, "%s в %s", packageSpec(name, version, arch), r.Metadata().Name)
+	case 1:
+		return matched[0], nil
+	}
+
+	variants := make([]string, 0, len(matched))
+	for _, f := range matched {
+		variants = append(variants, f.Version+" "+f.Arch)
+	}
+	sort.Strings(variants)
+
+	return PackageFile{}, errors.Wrapf(ErrPackageAmbiguous, "%s: %s", name, strings.Join(variants, ", "))
+}
+
+// indexPackageFiles возвращает файлы пакета name из индексов Packages всех
+// дистрибутивов репозитория r. Файл, упомянутый в нескольких индексах (например,
+// пакет "all" в индексах разных архитектур), возвращается один раз.
+func indexPackageFiles(ctx context.Context, r models.Repoes, name string) ([]PackageFile, error) {
+	dists, err := Distributions(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]PackageFile, 0)
+	seen := make(map[string]bool)
+	for _, dist := range dists {
+		release, err := ReadRelease(ctx, r, dist)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, index := range packagesIndexes(release) {
+			err := ReadPackagesIndex(ctx, r, path.Join("dists", dist, index), func(p *deb.IndexPackage) error {
+				if p.Package != name || p.Filename == "" || seen[p.Filename] {
+					return nil
+				}
+				seen[p.Filename] = true
+				result = append(result, PackageFile{
+					Path:    p.Filename,
+					Name:    path.Base(p.Filename),
+					Package: p.Package,
+					Version: p.Version,
+					Arch:    p.Architecture,
+				})
+				return nil
+			})
+			if errors.Is(err, ErrPackageNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// packageSpec формирует описание пакета для сообщений об ошибках, пропуская пустые части.
+func packageSpec(name, version, arch string) string {
+	return strings.Join(strings.Fields(name+" "+version+" "+arch), " ")
+}
+
+// parsePoolFileName разбирает имя файла пакета вида "<пакет>_<версия>_<архитектура>.<ext>".
+func parsePoolFileName(name string) (PackageFile, bool) {
+	if _, ok := debKindByName(name); !ok {
+		return PackageFile{}, false
+	}
+
+	parts := strings.Split(strings.TrimSuffix(name, path.Ext(name)), "_")
+	if len(parts) != 3 {
+		return PackageFile{}, false
+	}
+
+	version, err := url.PathUnescape(parts[1])
+	if err != nil {
+		version = parts[1]
+	}
+
+	return PackageFile{
+		Name:    name,
+		Package: parts[0],
+		Version: version,
+		Arch:    parts[2],
+	}, true
+}
+
+// RemovePackage удаляет из директории репозитория файлы пакета name версии version.
+// Пустая arch означает любую архитектуру. Возвращает имена удалённых файлов.
+func (m *RepoCustom) RemovePackage(name, version, arch string) ([]string, error) {
+	if name == "" || version == "" {
+		return nil, errors.Wrap(ErrUploadInvalid, "не указаны имя и версия пакета")
+	}
+
+	m.publishMu.Lock()
+	defer m.publishMu.Unlock()
+
+	m.mu.RLock()
+	targets := make([]debFileInfo, 0)
+	for _, d := range m.debFiles {
+		if d.Meta == nil || d.Meta.Package != name || d.Meta.Version != version {
+			continue
+		}
+		if arch != "" && d.Meta.Architecture != arch {
+			continue
+		}
+		targets = append(targets, d)
+	}
+	m.mu.RUnlock()
+
+	if len(targets) == 0 {
+		return nil, errors.Wrapf(ErrPackageNotFound, "%s в %s", packageSpec(name, version, arch), m.name)
+	}
+
+	removed := make([]string, 0, len(targets))
+	for _, d := range targets {
+		if err := os.Remove(d.Path); err != nil {
+			m.Refresh()
+			if len(removed) > 0 {
+				m.notifyChange()
+			}
+			return removed, errors.Wrapf(err, "не удалось удалить %s", d.Name)
+		}
+		removed = append(removed, d.Name)
+		m.log.Info(fmt.Sprintf("удалён пакет %s", d.Name), slog.String("repo", m.name))
+	}
+
+	m.Refresh()
+	m.notifyChange()
+
+	return removed, nil
+}
+
+// ImportPackage копирует пакет из репозитория src (любого типа) в пользовательский
+// репозиторий. Пакет проходит те же проверки, что и при загрузке через API.
+// Возвращает описание исходного файла и итог публикации.
+func (m *RepoCustom) ImportPackage(ctx context.Context, src models.Repoes, name, version, arch string) (PackageFile, PublishResult, error) {
+	file, err := FindPackage(ctx, src, name, version, arch)
+	if err != nil {
+		return PackageFile{}, PublishResult{}, err
+	}
+
+	reader, err := src.Open(ctx, file.Path)
+	if err != nil {
+		return file, PublishResult{}, errors.Wrapf(err, "не удалось открыть %s в %s", file.Path, src.Metadata().Name)
+	}
+
+	upload, err := m.ReceiveUpload(file.Name, reader)
+	closeErr := reader.Close()
+	if err != nil {
+		return file, PublishResult{}, err
+	}
+	if closeErr != nil {
+		upload.Discard()
+		return file, PublishResult{}, errors.Wrapf(closeErr, "ошибка чтения %s из %s", file.Path, src.Metadata().Name)
+	}
+
+	stanzas, err := m.Publish([]*Upload{upload})
+	if err != nil {
+		return file, PublishResult{}, err
+	}
+
+	return file, stanzas, nil
+}
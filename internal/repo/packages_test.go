@@ -0,0 +1,160 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/kirsrus/iso2repo/pkg/deb/debtest"
+	"golang.org/x/exp/slog"
+)
+
+// writeIndexTestRepo создаёт распакованный репозиторий с дистрибутивами stable и
+// testing, в индексах которых указан один и тот же файл пакета app с эпохой в версии
+// и именем, не соответствующим соглашению Debian, а также пакет lib двух версий.
+func writeIndexTestRepo(t *testing.T, dir string) *RepoExtracted {
+	t.Helper()
+
+	app := debtest.Package(t, "app", "1:1.0-1", "amd64", nil)
+	debtest.Write(t, filepath.Join(dir, "pool/main/a/app"), "app-latest.deb", app)
+
+	index := fmt.Sprintf("Package: app\nVersion: 1:1.0-1\nArchitecture: amd64\nFilename: pool/main/a/app/app-latest.deb\nSize: %d\n\n", len(app)) +
+		"Package: lib\nVersion: 1.0\nArchitecture: amd64\nFilename: pool/main/l/lib/lib_1.0_amd64.deb\nSize: 1\n\n" +
+		"Package: lib\nVersion: 2.0\nArchitecture: amd64\nFilename: pool/main/l/lib/lib_2.0_amd64.deb\nSize: 1\n"
+	for _, dist := range []string{"stable", "testing"} {
+		files := map[string]string{
+			"Release":                    "Suite: " + dist + "\nComponents: main\nArchitectures: amd64\n",
+			"main/binary-amd64/Packages": index,
+		}
+		for name, data := range files {
+			debtest.Write(t, filepath.Join(dir, "dists", dist, filepath.Dir(name)), filepath.Base(name), []byte(data))
+		}
+	}
+
+	return NewRepoExtracted(dir, slog.New(slog.NewTextHandler(io.Discard)))
+}
+
+func TestFindPackage(t *testing.T) {
+	r := writeIndexTestRepo(t, filepath.Join(t.TempDir(), "vendor.iso"))
+
+	tests := []struct {
+		name    string
+		pkg     string
+		version string
+		arch    string
+		want    string
+		wantErr error
+	}{
+		{name: "any version", pkg: "app", want: "pool/main/a/app/app-latest.deb"},
+		{name: "version with epoch", pkg: "app", version: "1:1.0-1", arch: "amd64", want: "pool/main/a/app/app-latest.deb"},
+		{name: "version without epoch", pkg: "app", version: "1.0-1", wantErr: ErrPackageNotFound},
+		{name: "other arch", pkg: "app", arch: "arm64", wantErr: ErrPackageNotFound},
+		{name: "several versions", pkg: "lib", wantErr: ErrPackageAmbiguous},
+		{name: "exact version", pkg: "lib", version: "2.0", want: "pool/main/l/lib/lib_2.0_amd64.deb"},
+		{name: "unknown package", pkg: "missing", wantErr: ErrPackageNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FindPackage(context.Background(), r, tt.pkg, tt.version, tt.arch)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("FindPackage() error = %v, want %v", err, tt.wantErr)
+			}
+			if got.Path != tt.want {
+				t.Errorf("FindPackage() = %+v, want path %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepoCustom_ImportPackage(t *testing.T) {
+	root := t.TempDir()
+	src := writeIndexTestRepo(t, filepath.Join(root, "vendor.iso"))
+
+	changed := 0
+	dst := NewRepoCustom(filepath.Join(root, "custom.iso"), nil, CustomOptions{OnChange: func(*RepoCustom) { changed++ }})
+
+	file, result, err := dst.ImportPackage(context.Background(), src, "app", "", "")
+	if err != nil {
+		t.Fatalf("ImportPackage() error = %v", err)
+	}
+	if file.Path != "pool/main/a/app/app-latest.deb" || len(result.Packages) != 1 {
+		t.Errorf("ImportPackage() = %+v, %+v", file, result)
+	}
+	if _, err := os.Stat(filepath.Join(root, "custom.iso", "app-latest.deb")); err != nil {
+		t.Errorf("imported file: %v", err)
+	}
+	if changed != 1 {
+		t.Errorf("OnChange called %d times, want 1", changed)
+	}
+
+	if _, _, err := dst.ImportPackage(context.Background(), src, "missing", "", ""); !errors.Is(err, ErrPackageNotFound) {
+		t.Errorf("ImportPackage(missing) error = %v", err)
+	}
+	if changed != 1 {
+		t.Errorf("OnChange called %d times after failed import, want 1", changed)
+	}
+}
+
+func TestRepoCustom_RemovePackage(t *testing.T) {
+	tests := []struct {
+		name    string
+		pkg     string
+		version string
+		arch    string
+		want    []string
+		wantErr error
+	}{
+		{name: "all architectures", pkg: "app", version: "1.0", want: []string{"app_1.0_amd64.deb", "app_1.0_arm64.deb"}},
+		{name: "one architecture", pkg: "app", version: "1.0", arch: "arm64", want: []string{"app_1.0_arm64.deb"}},
+		{name: "other version", pkg: "app", version: "2.0", wantErr: ErrPackageNotFound},
+		{name: "no version", pkg: "app", wantErr: ErrUploadInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "custom.iso")
+			debtest.Write(t, dir, "app_1.0_amd64.deb", debtest.Package(t, "app", "1.0", "amd64", nil))
+			debtest.Write(t, dir, "app_1.0_arm64.deb", debtest.Package(t, "app", "1.0", "arm64", nil))
+			debtest.Write(t, dir, "lib_1.0_amd64.deb", debtest.Package(t, "lib", "1.0", "amd64", nil))
+
+			changed := 0
+			r := NewRepoCustom(dir, nil, CustomOptions{OnChange: func(*RepoCustom) { changed++ }})
+
+			removed, err := r.RemovePackage(tt.pkg, tt.version, tt.arch)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RemovePackage() error = %v, want %v", err, tt.wantErr)
+			}
+			sort.Strings(removed)
+			if len(removed) != len(tt.want) || (len(tt.want) > 0 && !reflect.DeepEqual(removed, tt.want)) {
+				t.Errorf("RemovePackage() = %v, want %v", removed, tt.want)
+			}
+
+			for _, name := range tt.want {
+				if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+					t.Errorf("%s still exists", name)
+				}
+			}
+			if _, err := FindPackage(context.Background(), r, "lib", "", ""); err != nil {
+				t.Errorf("FindPackage(lib) error = %v", err)
+			}
+			if _, err := FindPackage(context.Background(), r, tt.pkg, tt.version, tt.arch); tt.wantErr == nil && !errors.Is(err, ErrPackageNotFound) {
+				t.Errorf("FindPackage() after removal error = %v", err)
+			}
+
+			wantChanged := 1
+			if tt.wantErr != nil {
+				wantChanged = 0
+			}
+			if changed != wantChanged {
+				t.Errorf("OnChange called %d times, want %d", changed, wantChanged)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package repo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/kirsrus/iso2repo/pkg/pdiff"
+	"golang.org/x/exp/slog"
+)
+
+const (
+	// pdiffDir суффикс директории с разностными обновлениями индекса Packages.
+	pdiffDir = ".diff"
+
+	// pdiffIndex имя индекса разностных обновлений внутри директории Packages.diff.
+	pdiffIndex = "Index"
+)
+
+// pdiffHistory история изменений одного индекса Packages.
+// Хранится только в памяти: после перезапуска клиенты один раз скачают индекс целиком.
+type pdiffHistory struct {
+	// Текущее содержимое индекса
+	current []byte
+
+	// Патчи от старых к новым: i-й патч переводит i-ю версию индекса в (i+1)-ю,
+	// последний — в текущую
+	patches []pdiffPatch
+}
+
+// pdiffPatch ed-скрипт, переводящий одну версию индекса Packages в следующую.
+type pdiffPatch struct {
+	// Имя патча (метка времени создания)
+	name string
+
+	// Контрольная сумма и размер версии индекса, к которой применяется патч
+	fromSHA256 string
+	fromSize   int
+
+	// Ed-скрипт и он же, сжатый gzip
+	script []byte
+	gz     []byte
+}
+
+// updatePDiffs сравнивает новые индексы Packages из files с предыдущими версиями,
+// пополняет историю патчей и добавляет в files индексы Packages.diff/Index с патчами.
+// Глубина истории задаётся CustomOptions.PDiffDepth, ноль отключает PDiff.
+func (m *RepoCustom) updatePDiffs(files map[string][]byte) {
+	if m.options.PDiffDepth <= 0 {
+		return
+	}
+	if m.pdiffs == nil {
+		m.pdiffs = make(map[string]*pdiffHistory)
+	}
+
+	// Индексы, которые перестали публиковаться, забываем вместе с историей
+	for p := range m.pdiffs {
+		if _, ok := files[p]; !ok {
+			delete(m.pdiffs, p)
+		}
+	}
+
+	indexes := make([]string, 0, len(files))
+	for p := range files {
+		if path.Base(p) == "Packages" {
+			indexes = append(indexes, p)
+		}
+	}
+
+	now := time.Now().UTC()
+	for _, p := range indexes {
+		content := files[p]
+		h, ok := m.pdiffs[p]
+		if !ok {
+			m.pdiffs[p] = &pdiffHistory{current: content}
+			continue
+		}
+
+		if !bytes.Equal(h.current, content) {
+			m.appendPDiff(p, h, content, now)
+		}
+
+		// Пустой индекс не публикуется в Release, и патчи к нему не нужны
+		if len(h.patches) > 0 && len(content) > 0 {
+			writePDiffIndex(files, p, h)
+		}
+	}
+}
+
+// appendPDiff добавляет в историю h патч от текущей версии индекса p к content.
+func (m *RepoCustom) appendPDiff(p string, h *pdiffHistory, content []byte, now time.Time) {
+	script, err := pdiff.EdScript(h.current, content)
+	if err == nil {
+		// Патч проверяем сразу: клиент, получивший неверный патч, не сможет обновиться
+		var result []byte
+		if result, err = pdiff.Apply(h.current, script); err == nil && !bytes.Equal(result, content) {
+			err = fmt.Errorf("результат применения патча не совпадает с индексом")
+		}
+	}
+	if err != nil {
+		m.log.Warn("не удалось сформировать PDiff, история сброшена", slog.String("repo", m.name), slog.String("index", p), slog.Any("error", err))
+		h.current, h.patches = content, nil
+		return
+	}
+
+	// Метка времени в формате архивов Debian; при совпадении добавляем номер
+	stamp := now.Format("2006-01-02-1504.05")
+	name := stamp
+	for i := 2; h.hasPatch(name); i++ {
+		name = fmt.Sprintf("%s.%d", stamp, i)
+	}
+
+	h.patches = append(h.patches, pdiffPatch{
+		name:       name,
+		fromSHA256: fmt.Sprintf("%x", sha256.Sum256(h.current)),
+		fromSize:   len(h.current),
+		script:     script,
+		gz:         gzipBytes(script),
+	})
+	if extra := len(h.patches) - m.options.PDiffDepth; extra > 0 {
+		h.patches = append([]pdiffPatch(nil), h.patches[extra:]...)
+	}
+	h.current = content
+
+	m.log.Debug("сформирован PDiff", slog.String("repo", m.name), slog.String("index", p), slog.String("patch", name), slog.Int("size", len(script)))
+}
+
+// hasPatch сообщает, есть ли в истории патч с именем name.
+func (h *pdiffHistory) hasPatch(name string) bool {
+	for _, patch := range h.patches {
+		if patch.name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writePDiffIndex добавляет в files индекс <p>.diff/Index и сжатые патчи истории h
+// в формате, который ожидает apt.
+func writePDiffIndex(files map[string][]byte, p string, h *pdiffHistory) {
+	dir := p + pdiffDir + "/"
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "SHA256-Current: %x %d\n", sha256.Sum256(h.current), len(h.current))
+	fmt.Fprintf(&buf, "SHA256-History:\n")
+	for _, patch := range h.patches {
+		fmt.Fprintf(&buf, " %s %d %s\n", patch.fromSHA256, patch.fromSize, patch.name)
+	}
+	fmt.Fprintf(&buf, "SHA256-Patches:\n")
+	for _, patch := range h.patches {
+		fmt.Fprintf(&buf, " %x %d %s\n", sha256.Sum256(patch.script), len(patch.script), patch.name)
+	}
+	fmt.Fprintf(&buf, "SHA256-Download:\n")
+	for _, patch := range h.patches {
+		fmt.Fprintf(&buf, " %x %d %s.gz\n", sha256.Sum256(patch.gz), len(patch.gz), patch.name)
+		files[dir+patch.name+".gz"] = patch.gz
+	}
+
+	files[dir+pdiffIndex] = buf.Bytes()
+}
+
+// isPDiffPatch сообщает, является ли индексный файл патчем PDiff. Патчи не
+// перечисляются в Release: их контрольные суммы содержатся в Packages.diff/Index.
+func isPDiffPatch(p string) bool {
+	dir, base := path.Split(p)
+
+	return strings.HasSuffix(dir, pdiffDir+"/") && base != pdiffIndex
+}
@@ -0,0 +1,287 @@
+package repo
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"golang.org/x/exp/slog"
+)
+
+const (
+	// approvedFile список одобренных пакетов в директории репозитория:
+	// по строке "<sha256> <имя файла>" на пакет.
+	approvedFile = ".approved"
+
+	// rejectedDir директория внутри репозитория, куда переносятся отклонённые пакеты.
+	rejectedDir = ".rejected"
+)
+
+// ErrQuarantineDisabled операция доступна только в режиме карантина.
+var ErrQuarantineDisabled = errors.New("режим карантина не включён")
+
+// StagedPackage пакет на карантине, ожидающий одобрения.
+type StagedPackage struct {
+	// Имя файла.
+	Name string `json:"name"`
+	// Размер и контрольные суммы файла.
+	Size      int64  `json:"size"`
+	MD5Sum    string `json:"md5"`
+	SHA1Sum   string `json:"sha1"`
+	SHA256Sum string `json:"sha256"`
+	// Время изменения файла.
+	Time time.Time `json:"time"`
+	// Поля из control-файла пакета.
+	Control Stanza `json:"control"`
+}
+
+// newStagedPackage формирует описание пакета на карантине.
+func newStagedPackage(d debFileInfo) StagedPackage {
+	return StagedPackage{
+		Name:      d.Name,
+		Size:      d.Size,
+		MD5Sum:    d.MD5Sum,
+		SHA1Sum:   d.SHA1Sum,
+		SHA256Sum: d.SHA256Sum,
+		Time:      d.FileTime,
+		Control:   controlStanza(d),
+	}
+}
+
+// Package возвращает имя, версию и архитектуру пакета из control.
+func (p StagedPackage) Package() (name, version, arch string) {
+	for _, f := range p.Control {
+		switch f.Name {
+		case "Package":
+			name = f.Value
+		case "Version":
+			version = f.Value
+		case "Architecture":
+			arch = f.Value
+		}
+	}
+
+	return name, version, arch
+}
+
+// Quarantined сообщает, включён ли для репозитория режим карантина.
+func (m *RepoCustom) Quarantined() bool {
+	return m.options.Quarantine
+}
+
+// Staged возвращает пакеты на карантине, отсортированные по имени файла.
+func (m *RepoCustom) Staged() []StagedPackage {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]StagedPackage, 0, len(m.stagedFiles))
+	for _, d := range m.stagedFiles {
+		result = append(result, newStagedPackage(d))
+	}
+
+	return result
+}
+
+// ApprovePackage одобряет пакет на карантине: его контрольная сумма заносится
+// в список одобренных, и пакет попадает в индексы репозитория. Одобрение привязано
+// к содержимому: если файл будет заменён, он снова окажется на карантине.
+func (m *RepoCustom) ApprovePackage(fileName string) (StagedPackage, error) {
+	m.publishMu.Lock()
+	defer m.publishMu.Unlock()
+
+	staged, err := m.findStaged(fileName)
+	if err != nil {
+		return StagedPackage{}, err
+	}
+
+	approved, _, err := m.loadApproved()
+	if err != nil {
+		return StagedPackage{}, err
+	}
+	approved[staged.SHA256Sum] = staged.Name
+
+	// Записи об удалённых с диска пакетах не сохраняем
+	m.mu.RLock()
+	present := make(map[string]bool, len(m.debFiles)+len(m.stagedFiles))
+	for _, d := range append(append([]debFileInfo{}, m.debFiles...), m.stagedFiles...) {
+		present[d.SHA256Sum] = true
+	}
+	m.mu.RUnlock()
+	for sum := range approved {
+		if !present[sum] {
+			delete(approved, sum)
+		}
+	}
+
+	if err := m.saveApproved(approved); err != nil {
+		return StagedPackage{}, err
+	}
+
+	m.log.Info(fmt.Sprintf("одобрен пакет %s", staged.Name), slog.String("repo", m.name))
+	m.Refresh()
+	m.notifyChange()
+
+	return newStagedPackage(staged), nil
+}
+
+// RejectPackage отклоняет пакет на карантине, перенося его файл в поддиректорию .rejected.
+func (m *RepoCustom) RejectPackage(fileName string) (StagedPackage, error) {
+	m.publishMu.Lock()
+	defer m.publishMu.Unlock()
+
+	staged, err := m.findStaged(fileName)
+	if err != nil {
+		return StagedPackage{}, err
+	}
+
+	dir := filepath.Join(m.path, rejectedDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return StagedPackage{}, errors.Wrap(err, "не удалось создать директорию отклонённых пакетов")
+	}
+	if err := os.Rename(staged.Path, filepath.Join(dir, staged.Name)); err != nil {
+		return StagedPackage{}, errors.Wrapf(err, "не удалось перенести %s", staged.Name)
+	}
+
+	m.log.Info(fmt.Sprintf("отклонён пакет %s", staged.Name), slog.String("repo", m.name))
+	m.Refresh()
+	m.notifyChange()
+
+	return newStagedPackage(staged), nil
+}
+
+// findStaged ищет пакет на карантине по имени файла и проверяет, что содержимое
+// файла не изменилось с момента сканирования.
+func (m *RepoCustom) findStaged(fileName string) (debFileInfo, error) {
+	if !m.options.Quarantine {
+		return debFileInfo{}, ErrQuarantineDisabled
+	}
+
+	var (
+		staged debFileInfo
+		found  bool
+	)
+	m.mu.RLock()
+	for _, d := range m.stagedFiles {
+		if d.Name == fileName {
+			staged, found = d, true
+			break
+		}
+	}
+	m.mu.RUnlock()
+
+	if !found {
+		return debFileInfo{}, errors.Wrapf(ErrPackageNotFound, "%s нет на карантине в %s", fileName, m.name)
+	}
+
+	_, _, sum, err := m.computeHashes(staged.Path)
+	if err != nil {
+		return debFileInfo{}, errors.Wrapf(err, "не удалось прочитать %s", staged.Name)
+	}
+	if sum != staged.SHA256Sum {
+		m.Refresh()
+		return debFileInfo{}, errors.Wrapf(ErrUploadConflict, "%s изменился после сканирования, повторите операцию", staged.Name)
+	}
+
+	return staged, nil
+}
+
+// splitStaged переносит из debFiles в stagedFiles пакеты, контрольных сумм которых
+// нет в списке одобренных. Если списка ещё нет (карантин включён впервые), все
+// имеющиеся пакеты считаются одобренными, чтобы не снять с публикации уже
+// работающий репозиторий. Если список не читается, на карантине остаются все
+// пакеты, о чём сообщается в лог. Вызывается при удержании m.mu.
+func (m *RepoCustom) splitStaged() {
+	approved, exists, err := m.loadApproved()
+	if err != nil {
+		m.log.Error("не удалось прочитать список одобренных пакетов, все пакеты оставлены на карантине", err,
+			slog.String("repo", m.name), slog.Int("packages", len(m.debFiles)), slog.Any("error", err))
+		approved = make(map[string]string)
+	}
+
+	if !exists && err == nil {
+		for _, d := range m.debFiles {
+			approved[d.SHA256Sum] = d.Name
+		}
+		if err := m.saveApproved(approved); err != nil {
+			m.log.Warn("не удалось сохранить список одобренных пакетов", slog.String("repo", m.name), slog.Any("error", err))
+		} else {
+			m.log.Info("включён карантин, имеющиеся пакеты одобрены", slog.String("repo", m.name), slog.Int("packages", len(approved)))
+		}
+	}
+
+	published := make([]debFileInfo, 0, len(m.debFiles))
+	for _, d := range m.debFiles {
+		if _, ok := approved[d.SHA256Sum]; ok && d.SHA256Sum != "" {
+			published = append(published, d)
+			continue
+		}
+		m.stagedFiles = append(m.stagedFiles, d)
+	}
+	m.debFiles = published
+}
+
+// loadApproved читает список одобренных пакетов: контрольная сумма → имя файла.
+// Второе значение равно false, если файла со списком нет.
+func (m *RepoCustom) loadApproved() (map[string]string, bool, error) {
+	approved := make(map[string]string)
+
+	f, err := os.Open(filepath.Join(m.path, approvedFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return approved, false, nil
+		}
+		return approved, false, errors.WithStack(err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		sum, name, _ := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		if sum != "" && !strings.HasPrefix(sum, "#") {
+			approved[sum] = strings.TrimSpace(name)
+		}
+	}
+
+	return approved, true, errors.WithStack(scanner.Err())
+}
+
+// saveApproved атомарно перезаписывает список одобренных пакетов.
+func (m *RepoCustom) saveApproved(approved map[string]string) error {
+	sums := make([]string, 0, len(approved))
+	for sum := range approved {
+		sums = append(sums, sum)
+	}
+	sort.Slice(sums, func(i, j int) bool {
+		if approved[sums[i]] != approved[sums[j]] {
+			return approved[sums[i]] < approved[sums[j]]
+		}
+		return sums[i] < sums[j]
+	})
+
+	lines := make([]string, 0, len(sums))
+	for _, sum := range sums {
+		lines = append(lines, sum+" "+approved[sum])
+	}
+
+	tmp, err := os.CreateTemp(m.path, approvedFile+"-*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "не удалось сохранить список одобренных пакетов")
+	}
+	if _, err := tmp.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+		tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return errors.Wrap(err, "не удалось сохранить список одобренных пакетов")
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return errors.Wrap(err, "не удалось сохранить список одобренных пакетов")
+	}
+
+	return errors.Wrap(os.Rename(tmp.Name(), filepath.Join(m.path, approvedFile)),
+		"не удалось сохранить список одобренных пакетов")
+}
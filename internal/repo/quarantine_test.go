@@ -0,0 +1,154 @@
+package repo
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kirsrus/iso2repo/pkg/deb/debtest"
+	"golang.org/x/exp/slog"
+)
+
+// publishedNames возвращает имена опубликованных пакетов и пакетов на карантине.
+func publishedNames(r *RepoCustom) (published, staged []string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	published, staged = make([]string, 0), make([]string, 0)
+	for _, d := range r.debFiles {
+		published = append(published, d.Name)
+	}
+	for _, d := range r.stagedFiles {
+		staged = append(staged, d.Name)
+	}
+
+	return published, staged
+}
+
+func TestRepoCustom_splitStaged(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "custom.iso")
+	debtest.Write(t, dir, "app_1.0_amd64.deb", debtest.Package(t, "app", "1.0", "amd64", nil))
+
+	// Первое включение карантина: имеющиеся пакеты одобряются автоматически
+	r := NewRepoCustom(dir, nil, CustomOptions{Quarantine: true})
+	published, staged := publishedNames(r)
+	if !reflect.DeepEqual(published, []string{"app_1.0_amd64.deb"}) || len(staged) != 0 {
+		t.Fatalf("first enable: published %v, staged %v", published, staged)
+	}
+	if _, err := os.Stat(filepath.Join(dir, approvedFile)); err != nil {
+		t.Fatalf("approved list not saved: %v", err)
+	}
+
+	// Новый пакет остаётся на карантине
+	debtest.Write(t, dir, "lib_1.0_amd64.deb", debtest.Package(t, "lib", "1.0", "amd64", nil))
+	r.Refresh()
+	published, staged = publishedNames(r)
+	if !reflect.DeepEqual(published, []string{"app_1.0_amd64.deb"}) || !reflect.DeepEqual(staged, []string{"lib_1.0_amd64.deb"}) {
+		t.Errorf("new package: published %v, staged %v", published, staged)
+	}
+
+	// Заменённый файл одобренного пакета снова попадает на карантин
+	debtest.Write(t, dir, "app_1.0_amd64.deb", debtest.Package(t, "app", "1.0", "amd64", map[string]string{"/usr/bin/app": "app"}))
+	r.Refresh()
+	if published, _ := publishedNames(r); len(published) != 0 {
+		t.Errorf("replaced package published: %v", published)
+	}
+}
+
+func TestRepoCustom_splitStaged_loadFailure(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "custom.iso")
+	debtest.Write(t, dir, "app_1.0_amd64.deb", debtest.Package(t, "app", "1.0", "amd64", nil))
+	// Список одобренных не читается: на его месте директория
+	if err := os.MkdirAll(filepath.Join(dir, approvedFile), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	var logs bytes.Buffer
+	r := NewRepoCustom(dir, slog.New(slog.NewTextHandler(&logs)), CustomOptions{Quarantine: true})
+
+	published, staged := publishedNames(r)
+	if len(published) != 0 || !reflect.DeepEqual(staged, []string{"app_1.0_amd64.deb"}) {
+		t.Errorf("published %v, staged %v", published, staged)
+	}
+	if !strings.Contains(logs.String(), "все пакеты оставлены на карантине") {
+		t.Errorf("failure not logged: %s", logs.String())
+	}
+}
+
+func TestRepoCustom_ApprovePackage(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "custom.iso")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := 0
+	r := NewRepoCustom(dir, nil, CustomOptions{Quarantine: true, OnChange: func(*RepoCustom) { changed++ }})
+	debtest.Write(t, dir, "app_1.0_amd64.deb", debtest.Package(t, "app", "1.0", "amd64", nil))
+	r.Refresh()
+
+	if _, err := r.ApprovePackage("missing.deb"); !errors.Is(err, ErrPackageNotFound) {
+		t.Errorf("ApprovePackage(missing) error = %v", err)
+	}
+
+	p, err := r.ApprovePackage("app_1.0_amd64.deb")
+	if err != nil {
+		t.Fatalf("ApprovePackage() error = %v", err)
+	}
+	if name, version, arch := p.Package(); name != "app" || version != "1.0" || arch != "amd64" {
+		t.Errorf("ApprovePackage() = %s %s %s", name, version, arch)
+	}
+	published, staged := publishedNames(r)
+	if !reflect.DeepEqual(published, []string{"app_1.0_amd64.deb"}) || len(staged) != 0 {
+		t.Errorf("published %v, staged %v", published, staged)
+	}
+	if changed != 1 {
+		t.Errorf("OnChange called %d times, want 1", changed)
+	}
+
+	// Одобрение сохраняется в списке и переживает повторное создание репозитория
+	r = NewRepoCustom(dir, nil, CustomOptions{Quarantine: true})
+	if published, _ := publishedNames(r); len(published) != 1 {
+		t.Errorf("after restart published %v", published)
+	}
+
+	if _, err := NewRepoCustom(dir, nil, CustomOptions{}).ApprovePackage("app_1.0_amd64.deb"); !errors.Is(err, ErrQuarantineDisabled) {
+		t.Errorf("ApprovePackage() without quarantine error = %v", err)
+	}
+}
+
+func TestRepoCustom_RejectPackage(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "custom.iso")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := 0
+	r := NewRepoCustom(dir, nil, CustomOptions{Quarantine: true, OnChange: func(*RepoCustom) { changed++ }})
+	debtest.Write(t, dir, "app_1.0_amd64.deb", debtest.Package(t, "app", "1.0", "amd64", nil))
+	r.Refresh()
+
+	if _, err := r.RejectPackage("app_1.0_amd64.deb"); err != nil {
+		t.Fatalf("RejectPackage() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "app_1.0_amd64.deb")); !os.IsNotExist(err) {
+		t.Errorf("rejected package left in repository: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, rejectedDir, "app_1.0_amd64.deb")); err != nil {
+		t.Errorf("rejected package not moved: %v", err)
+	}
+	published, staged := publishedNames(r)
+	if len(published) != 0 || len(staged) != 0 {
+		t.Errorf("published %v, staged %v", published, staged)
+	}
+	if changed != 1 {
+		t.Errorf("OnChange called %d times, want 1", changed)
+	}
+
+	if _, err := r.RejectPackage("app_1.0_amd64.deb"); !errors.Is(err, ErrPackageNotFound) {
+		t.Errorf("second RejectPackage() error = %v", err)
+	}
+}
@@ -37,6 +37,9 @@ type Repo struct {
 
 	// Канал передачи события обнаружения/порте репозиториев.
 	changeRepos chan<- models.RepoEvent
+
+	// Параметры создаваемых пользовательских репозиториев.
+	customOptions CustomOptions
 }
 
 // Config конфигурирует конструктор NewRepo.
@@ -48,6 +51,9 @@ type Config struct {
 
 	// Канал передачи события обнаружения/порте репозиториев.
 	ChangeRepos chan<- models.RepoEvent
+
+	// Параметры пользовательских репозиториев (режим карантина и т.п.).
+	Custom CustomOptions
 }
 
 // Newrepo конструктор Repo.
@@ -68,10 +74,12 @@ func NewRepo(config *Config) (*Repo, error) {
 	}
 
 	m := &Repo{
-		log:         log.With(slog.String("module", "repo")),
-		changeFiles: changeFiles,
-		changeRepos: changeRepos,
+		log:           log.With(slog.String("module", "repo")),
+		changeFiles:   changeFiles,
+		changeRepos:   changeRepos,
+		customOptions: config.Custom,
 	}
+	m.customOptions.OnChange = m.customChanged
 
 	return m, nil
 }
@@ -110,6 +118,11 @@ func (m *Repo) Run(ctx context.Context) error {
 // он проверяет, является ли этот файл репозиторием (или входи в его состав) и или
 // добавляет, или удаляет новый репозиторий.
 func (m *Repo) syncRepos(ctx context.Context, fileEvent models.FileEvent) error {
+	// Файл из директории дополнения образа (vendor.iso.d/) обновляет сам образ.
+	if overlayDir := m.overlayDirInPath(fileEvent.File.Path); overlayDir != "" {
+		return m.syncOverlay(ctx, fileEvent.File.Path, overlayDir)
+	}
+
 	// Обработка добавляемого файла.
 	if fileEvent.EventType == models.FileFound {
 		// Если файл имеет суффикс .iso, проверяем, не создано ли ещё репозитория
@@ -127,12 +140,17 @@ func (m *Repo) syncRepos(ctx context.Context, fileEvent models.FileEvent) error
 				customRepo.Refresh()
 				m.sendEvent(ctx, models.RepoEvent{
 					Repo:      customRepo,
-					EventType: models.RepoFound,
+					EventType: models.RepoUpdate,
 				})
 				m.log.Info(fmt.Sprintf("обновлён репозиторий %s (типа пользовательской папки)", customRepo.Metadata().Name))
+			} else if inDists(existingRepo, fileEvent.File.Path) {
+				// RepoExtracted читает файлы с диска при каждом запросе List(), но
+				// построенные по его индексам каталог и кэши нужно обновить.
+				m.sendEvent(ctx, models.RepoEvent{
+					Repo:      existingRepo,
+					EventType: models.RepoUpdate,
+				})
 			}
-			// Для RepoExtracted обновление не требуется — он читает файлы с диска
-			// при каждом запросе List().
 			return nil
 		}
 
@@ -143,9 +161,10 @@ func (m *Repo) syncRepos(ctx context.Context, fileEvent models.FileEvent) error
 			// не определяется, оставляем его как составной репозиторий.
 			repoIsoDir := NewRepoExtracted(m.isoDirFullPath(fileEvent.File.Path, isoDir), m.log)
 			if repoIsoDir.IsRepo() { // Репозиторий оказался распакованным ISO.
-				m.repos.Store(repoIsoDir.Metadata().Name, repoIsoDir)
+				repo := withOverlay(repoIsoDir, m.log, m.customOptions)
+				m.repos.Store(repo.Metadata().Name, repo)
 				m.sendEvent(ctx, models.RepoEvent{
-					Repo:      repoIsoDir,
+					Repo:      repo,
 					EventType: models.RepoFound,
 				})
 
@@ -155,7 +174,7 @@ func (m *Repo) syncRepos(ctx context.Context, fileEvent models.FileEvent) error
 			}
 
 			// Репозиторий считаем составным, пользовательским репозиторием.
-			repoDir := NewRepoCustom(m.isoDirFullPath(fileEvent.File.Path, isoDir), m.log)
+			repoDir := NewRepoCustom(m.isoDirFullPath(fileEvent.File.Path, isoDir), m.log, m.customOptions)
 			m.repos.Store(repoDir.Metadata().Name, repoDir)
 			m.sendEvent(ctx, models.RepoEvent{
 				Repo:      repoDir,
@@ -178,9 +197,10 @@ func (m *Repo) syncRepos(ctx context.Context, fileEvent models.FileEvent) error
 				return errors.New("iso-образ не является репозиторием")
 			}
 
-			m.repos.Store(repo.Metadata().Name, repo)
+			withRepo := withOverlay(repo, m.log, m.customOptions)
+			m.repos.Store(withRepo.Metadata().Name, withRepo)
 			m.sendEvent(ctx, models.RepoEvent{
-				Repo:      repo,
+				Repo:      withRepo,
 				EventType: models.RepoFound,
 			})
 
@@ -203,12 +223,15 @@ func (m *Repo) syncRepos(ctx context.Context, fileEvent models.FileEvent) error
 				customRepo.Refresh()
 				m.sendEvent(ctx, models.RepoEvent{
 					Repo:      customRepo,
-					EventType: models.RepoFound,
+					EventType: models.RepoUpdate,
 				})
 				m.log.Info(fmt.Sprintf("обновлён репозиторий %s после удаления файла (типа пользовательской папки)", customRepo.Metadata().Name))
+			} else if inDists(existingRepo, fileEvent.File.Path) {
+				m.sendEvent(ctx, models.RepoEvent{
+					Repo:      existingRepo,
+					EventType: models.RepoUpdate,
+				})
 			}
-			// Для RepoExtracted обновление не требуется — он читает файлы с диска
-			// при каждом запросе List().
 			return nil
 		}
 
@@ -239,6 +262,47 @@ func (m *Repo) syncRepos(ctx context.Context, fileEvent models.FileEvent) error
 	return errors.New("not implement")
 }
 
+// syncOverlay обрабатывает появление или удаление файла path в директории дополнения
+// overlayDir: подключает дополнение к уже обнаруженному образу или пересканирует его.
+// Если образ ещё не обнаружен, событие пропускается — дополнение будет подключено
+// вместе с образом.
+func (m *Repo) syncOverlay(ctx context.Context, path string, overlayDir string) error {
+	name := strings.TrimSuffix(overlayDir, overlaySuffix)
+	value, ok := m.repos.Load(name)
+	if !ok {
+		m.log.Debug("файл дополнения пропущен: образ не обнаружен", slog.String("path", path))
+		return nil
+	}
+
+	fullPath := m.isoDirFullPath(path, overlayDir)
+	switch repo := value.(type) {
+	case *RepoOverlay:
+		if repo.OverlayPath() != fullPath {
+			return nil
+		}
+		repo.Refresh()
+		m.sendEvent(ctx, models.RepoEvent{
+			Repo:      repo,
+			EventType: models.RepoUpdate,
+		})
+		m.log.Info(fmt.Sprintf("обновлено дополнение репозитория %s", name))
+	case *RepoIso, *RepoExtracted:
+		base := repo.(models.Repoes)
+		if base.Metadata().Path+overlaySuffix != fullPath {
+			return nil
+		}
+		overlay := NewRepoOverlay(base, fullPath, m.log, m.customOptions)
+		m.repos.Store(name, overlay)
+		m.sendEvent(ctx, models.RepoEvent{
+			Repo:      overlay,
+			EventType: models.RepoFound,
+		})
+		m.log.Info(fmt.Sprintf("к репозиторию %s подключено дополнение", name))
+	}
+
+	return nil
+}
+
 // repoAlreadyExist проверяет в локальной базе наличие репозитория на основе файла, полученного из fileEvent.
 func (m *Repo) repoAlreadyExist(fileEvent models.FileEvent) bool {
 	found := false
@@ -283,6 +347,13 @@ func (m *Repo) findExistingDirRepo(fileEvent models.FileEvent) models.Repoes {
 	return found
 }
 
+// inDists проверяет, находится ли файл path в директории индексов dists/ репозитория r.
+func inDists(r models.Repoes, path string) bool {
+	rel, err := filepath.Rel(r.Metadata().Path, path)
+
+	return err == nil && strings.HasPrefix(filepath.ToSlash(rel), "dists/")
+}
+
 // iso2dirInPath находит первое вхождение имени директории с суфиксом ".iso" в путь path.
 // Если директория обнаружена, она возвращается в ответе, если нет, возвращается пустая строка.
 func (m *Repo) iso2dirInPath(path string) string {
@@ -296,6 +367,23 @@ func (m *Repo) iso2dirInPath(path string) string {
 	return ""
 }
 
+// overlayDirInPath находит в пути path директорию дополнения образа (с суффиксом
+// ".iso.d"). Если раньше в пути встречается директория с суффиксом ".iso", файл
+// относится к ней, и возвращается пустая строка.
+func (m *Repo) overlayDirInPath(path string) string {
+	dir := filepath.Dir(filepath.Clean(path))
+	parts := strings.Split(dir, string(filepath.Separator))
+	for _, part := range parts {
+		if strings.HasSuffix(part, ".iso") {
+			return ""
+		}
+		if strings.HasSuffix(part, ".iso"+overlaySuffix) {
+			return part
+		}
+	}
+	return ""
+}
+
 // isoDirFullPath получает полный путь к директории isoDir из абсолютного пути
 // path.
 func (m *Repo) isoDirFullPath(path string, isoDir string) string {
@@ -307,6 +395,42 @@ func (m *Repo) isoDirFullPath(path string, isoDir string) string {
 	return before + isoDir
 }
 
+// customChanged отправляет событие об изменении пользовательского репозитория r
+// его методами (публикация, удаление, карантин), чтобы потребители событий
+// обновили свои данные о нём. Изменения репозиториев, которые уже не
+// отслеживаются, пропускаются. Для пакетов дополнения событие отправляется
+// об изменении дополненного ими образа.
+func (m *Repo) customChanged(r *RepoCustom) {
+	current, ok := m.repos.Load(r.Metadata().Name)
+	if !ok {
+		return
+	}
+
+	var changed models.Repoes
+	switch repo := current.(type) {
+	case *RepoCustom:
+		if repo != r {
+			return
+		}
+		changed = repo
+	case *RepoOverlay:
+		if repo.Overlay() != r {
+			return
+		}
+		changed = repo
+	default:
+		return
+	}
+
+	err := m.sendEvent(context.Background(), models.RepoEvent{
+		Repo:      changed,
+		EventType: models.RepoUpdate,
+	})
+	if err != nil {
+		m.log.Warn(fmt.Sprintf("не удалось отправить событие об изменении репозитория %s: %s", r.Metadata().Name, err.Error()))
+	}
+}
+
 // sendEvent безопасно отправляет событие в канал changeRepos.
 func (m *Repo) sendEvent(ctx context.Context, event models.RepoEvent) error {
 	select {
@@ -1,8 +1,14 @@
 package repo
 
 import (
+	"context"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
+
+	"github.com/kirsrus/iso2repo/models"
+	"github.com/kirsrus/iso2repo/pkg/deb/debtest"
 )
 
 func TestRepo_isoInPath(t *testing.T) {
@@ -138,3 +144,190 @@ func TestRepo_isoDirFullPath(t *testing.T) {
 		})
 	}
 }
+
+func TestRepo_overlayDirInPath(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{
+			name: "overlay directory found",
+			path: "/mnt/data/vendor.iso.d/hotfix_1.0_amd64.deb",
+			want: "vendor.iso.d",
+		},
+		{
+			name: "nested file in overlay directory",
+			path: "/mnt/data/vendor.iso.d/sub/hotfix_1.0_amd64.deb",
+			want: "vendor.iso.d",
+		},
+		{
+			name: "overlay-like directory inside .iso directory belongs to it",
+			path: "/mnt/data/custom.iso/vendor.iso.d/hotfix_1.0_amd64.deb",
+			want: "",
+		},
+		{
+			name: "iso file itself",
+			path: "/mnt/data/vendor.iso",
+			want: "",
+		},
+		{
+			name: "directory with .d suffix only",
+			path: "/mnt/data/conf.d/file.deb",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Repo{}
+			if got := m.overlayDirInPath(filepath.FromSlash(tt.path)); got != tt.want {
+				t.Errorf("overlayDirInPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepo_customChanged(t *testing.T) {
+	events := make(chan models.RepoEvent, 1)
+	m, err := NewRepo(&Config{ChangeRepos: events})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "custom.iso")
+	debtest.Write(t, dir, "app_1.0_amd64.deb", debtest.Package(t, "app", "1.0", "amd64", nil))
+	debtest.Write(t, dir, "lib_1.0_amd64.deb", debtest.Package(t, "lib", "1.0", "amd64", nil))
+	r := NewRepoCustom(dir, nil, m.customOptions)
+
+	// Репозиторий ещё не отслеживается — событие не отправляется
+	if _, err := r.RemovePackage("lib", "1.0", ""); err != nil {
+		t.Fatalf("RemovePackage() error = %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("event sent for untracked repository: %+v", <-events)
+	}
+
+	m.repos.Store(r.Metadata().Name, r)
+	if _, err := r.RemovePackage("app", "1.0", ""); err != nil {
+		t.Fatalf("RemovePackage() error = %v", err)
+	}
+	select {
+	case e := <-events:
+		if e.EventType != models.RepoUpdate || e.Repo != r {
+			t.Errorf("event = %+v, want RepoUpdate of %s", e, r.Metadata().Name)
+		}
+	default:
+		t.Errorf("no event after RemovePackage()")
+	}
+
+	// Изменение пакетов дополнения — событие об образе с дополнением
+	base := filepath.Join(t.TempDir(), "vendor.iso")
+	writeMergedTestRepo(t, base, "app 1.0 amd64")
+	debtest.Write(t, base+overlaySuffix, "tool_1.0_amd64.deb", debtest.Package(t, "tool", "1.0", "amd64", nil))
+	overlay := NewRepoOverlay(NewRepoExtracted(base, nil), base+overlaySuffix, nil, m.customOptions)
+	m.repos.Store(overlay.Metadata().Name, overlay)
+	if _, err := overlay.Overlay().RemovePackage("tool", "1.0", ""); err != nil {
+		t.Fatalf("RemovePackage() error = %v", err)
+	}
+	select {
+	case e := <-events:
+		if e.EventType != models.RepoUpdate || e.Repo != overlay {
+			t.Errorf("event = %+v, want RepoUpdate of overlay %s", e, overlay.Metadata().Name)
+		}
+	default:
+		t.Errorf("no event after RemovePackage() in overlay")
+	}
+}
+
+func TestRepo_syncReposExtracted(t *testing.T) {
+	events := make(chan models.RepoEvent, 1)
+	m, err := NewRepo(&Config{ChangeRepos: events})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "debian.iso")
+	r := NewRepoExtracted(dir, nil)
+	m.repos.Store(r.Metadata().Name, r)
+
+	tests := []struct {
+		name  string
+		event models.FileEventType
+		path  string
+		want  bool
+	}{
+		{name: "index found", event: models.FileFound, path: "dists/stable/main/binary-amd64/Packages.gz", want: true},
+		{name: "index lost", event: models.FileLost, path: "dists/stable/InRelease", want: true},
+		{name: "pool file found", event: models.FileFound, path: "pool/main/f/foo/foo_1.0_amd64.deb"},
+		{name: "pool file lost", event: models.FileLost, path: "pool/main/f/foo/foo_1.0_amd64.deb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, filepath.FromSlash(tt.path))
+			err := m.syncRepos(context.Background(), models.FileEvent{
+				File:      models.File{Name: filepath.Base(path), Path: path},
+				EventType: tt.event,
+			})
+			if err != nil {
+				t.Fatalf("syncRepos() error = %v", err)
+			}
+
+			select {
+			case e := <-events:
+				if !tt.want || e.EventType != models.RepoUpdate || e.Repo != r {
+					t.Errorf("event = %+v, want update %v", e, tt.want)
+				}
+			default:
+				if tt.want {
+					t.Errorf("no RepoUpdate event")
+				}
+			}
+		})
+	}
+}
+
+func TestRepo_syncReposOverlay(t *testing.T) {
+	events := make(chan models.RepoEvent, 1)
+	m, err := NewRepo(&Config{ChangeRepos: events})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := filepath.Join(t.TempDir(), "vendor.iso")
+	writeMergedTestRepo(t, base, "app 1.0 amd64")
+	m.repos.Store("vendor.iso", NewRepoExtracted(base, nil))
+
+	// Первый файл подключает дополнение, следующие обновляют его
+	tests := []struct {
+		name string
+		file string
+		want models.RepoEventType
+	}{
+		{name: "overlay attached", file: "tool_1.0_amd64.deb", want: models.RepoFound},
+		{name: "overlay refreshed", file: "lib_1.0_amd64.deb", want: models.RepoUpdate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := debtest.Write(t, base+overlaySuffix, tt.file, debtest.Package(t, strings.Split(tt.file, "_")[0], "1.0", "amd64", nil))
+			err := m.syncRepos(context.Background(), models.FileEvent{
+				File:      models.File{Name: tt.file, Path: path},
+				EventType: models.FileFound,
+			})
+			if err != nil {
+				t.Fatalf("syncRepos() error = %v", err)
+			}
+
+			select {
+			case e := <-events:
+				if _, ok := e.Repo.(*RepoOverlay); !ok || e.EventType != tt.want {
+					t.Errorf("event = %v of %T, want %v of overlay", e.EventType, e.Repo, tt.want)
+				}
+			default:
+				t.Errorf("no %v event", tt.want)
+			}
+		})
+	}
+}
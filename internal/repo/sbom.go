@@ -0,0 +1,459 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/uuid"
+	"github.com/kirsrus/iso2repo/models"
+	"golang.org/x/exp/slog"
+)
+
+// Форматы SBOM.
+const (
+	// SBOMCycloneDX CycloneDX 1.5 в JSON.
+	SBOMCycloneDX = "cyclonedx"
+	// SBOMSPDX SPDX 2.3 в JSON.
+	SBOMSPDX = "spdx"
+)
+
+// spdxNoAssertion значение SPDX "сведения отсутствуют".
+const spdxNoAssertion = "NOASSERTION"
+
+var (
+	// spdxIDInvalidRe символы, недопустимые в идентификаторе SPDXRef-.
+	spdxIDInvalidRe = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+	// spdxLicenseRefRe идентификатор LicenseRef- в выражении лицензии.
+	spdxLicenseRefRe = regexp.MustCompile(`LicenseRef-[A-Za-z0-9.-]+`)
+	// purlNamespaceInvalidRe символы, заменяемые в пространстве имён purl.
+	purlNamespaceInvalidRe = regexp.MustCompile(`[^a-z0-9.-]+`)
+)
+
+// SBOMQuery условия построения SBOM.
+type SBOMQuery struct {
+	// Имя репозитория.
+	Repo string
+	// Определять лицензии пакетов по файлам copyright (требует чтения всех пакетов).
+	Licenses bool
+	// Версия iso2repo — для сведений об инструменте, создавшем документ.
+	ToolVersion string
+}
+
+// SBOMPackage пакет в перечне компонентов репозитория.
+type SBOMPackage struct {
+	CatalogEntry
+	// Исходный пакет и его версия.
+	Source        string `json:"source"`
+	SourceVersion string `json:"source_version"`
+	Maintainer    string `json:"maintainer,omitempty"`
+	Homepage      string `json:"homepage,omitempty"`
+	// Краткое описание (первая строка поля Description).
+	Description string `json:"description,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+	MD5         string `json:"md5,omitempty"`
+	SHA1        string `json:"sha1,omitempty"`
+	SHA256      string `json:"sha256,omitempty"`
+	// Выражение лицензии SPDX; пустое — лицензия не определена.
+	License string `json:"license,omitempty"`
+	// Идентификатор пакета в формате purl (pkg:deb/debian/bash@5.2.15-2?arch=amd64).
+	PURL string `json:"purl"`
+
+	// Тексты лицензий LicenseRef- из copyright
+	licenseTexts map[string]string
+}
+
+// SBOM перечень компонентов (Software Bill of Materials) репозитория.
+type SBOM struct {
+	// Уникальный идентификатор документа (UUID).
+	ID   string `json:"id"`
+	Repo string `json:"repo"`
+	// Время создания.
+	Created     time.Time `json:"created"`
+	ToolVersion string    `json:"tool_version"`
+	// Пакеты репозитория; пакет, входящий в несколько дистрибутивов, указывается один раз.
+	Packages []SBOMPackage `json:"packages"`
+}
+
+// SBOM строит перечень компонентов репозитория по условиям q. Сведения о пакетах
+// берутся из индексов Packages, лицензии — из файлов copyright пакетов через кэш
+// licenses (nil — без кэша).
+func (m *Catalog) SBOM(ctx context.Context, q SBOMQuery, licenses *Licenses) (*SBOM, error) {
+	m.mu.RLock()
+	repo, ok := m.repos[q.Repo]
+	var (
+		entries            []CatalogEntry
+		codenames, origins map[string]string
+		source             models.Repoes
+	)
+	if ok {
+		entries = distinctEntries(repo.entries)
+		codenames, origins, source = repo.codenames, repo.origins, repo.repo
+	}
+	m.mu.RUnlock()
+	if !ok {
+		return nil, errors.Wrapf(ErrPackageNotFound, "репозиторий %s", q.Repo)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return catalogEntryLess(entries[i], entries[j]) })
+	controls := m.readControls(ctx, entries, map[string]models.Repoes{q.Repo: source})
+
+	result := &SBOM{
+		ID:          uuid.New().String(),
+		Repo:        q.Repo,
+		Created:     time.Now().UTC(),
+		ToolVersion: q.ToolVersion,
+		Packages:    make([]SBOMPackage, 0, len(entries)),
+	}
+	for i, e := range entries {
+		control := controls[i]
+		description, _, _ := strings.Cut(control.Get("Description"), "\n")
+		size, _ := strconv.ParseInt(control.Get("Size"), 10, 64)
+		p := SBOMPackage{
+			CatalogEntry:  e,
+			Source:        e.source,
+			SourceVersion: e.sourceVersion,
+			Maintainer:    control.Get("Maintainer"),
+			Homepage:      control.Get("Homepage"),
+			Description:   description,
+			Size:          size,
+			MD5:           control.Get("MD5sum"),
+			SHA1:          control.Get("SHA1"),
+			SHA256:        control.Get("SHA256"),
+			PURL:          packageURL(e, origins[e.Suite], codenames[e.Suite]),
+		}
+
+		if q.Licenses {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			license, err := licenses.Get(ctx, source, e, p.SHA256+p.MD5)
+			if err != nil {
+				m.log.Warn("лицензия пакета не определена", slog.String("repo", q.Repo), slog.String("package", e.Filename), slog.String("error", err.Error()))
+			}
+			p.License, p.licenseTexts = license.Expression, license.Texts
+		}
+
+		result.Packages = append(result.Packages, p)
+	}
+
+	return result, nil
+}
+
+// packageURL возвращает идентификатор purl пакета e. Пространство имён — издатель
+// выпуска (Origin), по умолчанию debian.
+func packageURL(e CatalogEntry, origin, codename string) string {
+	namespace := strings.Trim(purlNamespaceInvalidRe.ReplaceAllString(strings.ToLower(origin), "-"), "-")
+	if namespace == "" {
+		namespace = "debian"
+	}
+
+	params := url.Values{}
+	params.Set("arch", e.Arch)
+	if codename != "" {
+		params.Set("distro", codename)
+	}
+	if e.source != "" && e.source != e.Name {
+		params.Set("upstream", e.source)
+	}
+
+	return fmt.Sprintf("pkg:deb/%s/%s@%s?%s", namespace, url.PathEscape(e.Name), url.QueryEscape(e.Version), params.Encode())
+}
+
+// Write записывает SBOM в формате format (SBOMCycloneDX или SBOMSPDX).
+func (m *SBOM) Write(w io.Writer, format string) error {
+	var doc any
+	switch format {
+	case SBOMCycloneDX:
+		doc = m.cycloneDX()
+	case SBOMSPDX:
+		doc = m.spdx()
+	default:
+		return errors.Wrapf(ErrInvalidQuery, "неизвестный формат SBOM %s", format)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+
+	return enc.Encode(doc)
+}
+
+// Структуры документа CycloneDX 1.5 (https://cyclonedx.org/docs/1.5/json/).
+type (
+	cdxDocument struct {
+		BOMFormat    string         `json:"bomFormat"`
+		SpecVersion  string         `json:"specVersion"`
+		SerialNumber string         `json:"serialNumber"`
+		Version      int            `json:"version"`
+		Metadata     cdxMetadata    `json:"metadata"`
+		Components   []cdxComponent `json:"components"`
+	}
+	cdxMetadata struct {
+		Timestamp string       `json:"timestamp"`
+		Tools     cdxTools     `json:"tools"`
+		Component cdxComponent `json:"component"`
+	}
+	cdxTools struct {
+		Components []cdxComponent `json:"components"`
+	}
+	cdxComponent struct {
+		Type        string        `json:"type"`
+		BOMRef      string        `json:"bom-ref,omitempty"`
+		Supplier    *cdxSupplier  `json:"supplier,omitempty"`
+		Name        string        `json:"name"`
+		Version     string        `json:"version,omitempty"`
+		Description string        `json:"description,omitempty"`
+		Hashes      []cdxHash     `json:"hashes,omitempty"`
+		Licenses    []cdxLicense  `json:"licenses,omitempty"`
+		PURL        string        `json:"purl,omitempty"`
+		ExtRefs     []cdxExtRef   `json:"externalReferences,omitempty"`
+		Properties  []cdxProperty `json:"properties,omitempty"`
+	}
+	cdxSupplier struct {
+		Name    string       `json:"name"`
+		Contact []cdxContact `json:"contact,omitempty"`
+	}
+	cdxContact struct {
+		Email string `json:"email"`
+	}
+	cdxHash struct {
+		Alg     string `json:"alg"`
+		Content string `json:"content"`
+	}
+	cdxLicense struct {
+		License    *cdxLicenseID `json:"license,omitempty"`
+		Expression string        `json:"expression,omitempty"`
+	}
+	cdxLicenseID struct {
+		ID string `json:"id"`
+	}
+	cdxExtRef struct {
+		Type string `json:"type"`
+		URL  string `json:"url"`
+	}
+	cdxProperty struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+)
+
+// cycloneDX формирует документ CycloneDX.
+func (m *SBOM) cycloneDX() *cdxDocument {
+	doc := &cdxDocument{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + m.ID,
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: m.Created.Format(time.RFC3339),
+			Tools: cdxTools{Components: []cdxComponent{
+				{Type: "application", Name: "iso2repo", Version: m.ToolVersion},
+			}},
+			Component: cdxComponent{Type: "operating-system", BOMRef: "repo:" + m.Repo, Name: m.Repo},
+		},
+		Components: make([]cdxComponent, 0, len(m.Packages)),
+	}
+
+	for _, p := range m.Packages {
+		c := cdxComponent{
+			Type:        "library",
+			BOMRef:      p.PURL,
+			Name:        p.Name,
+			Version:     p.Version,
+			Description: p.Description,
+			PURL:        p.PURL,
+			Properties: []cdxProperty{
+				{Name: "deb:arch", Value: p.Arch},
+				{Name: "deb:suite", Value: p.Suite},
+				{Name: "deb:component", Value: p.Component},
+				{Name: "deb:filename", Value: p.Filename},
+				{Name: "deb:source", Value: p.Source},
+			},
+		}
+		if p.Maintainer != "" {
+			name, email := splitMaintainer(p.Maintainer)
+			c.Supplier = &cdxSupplier{Name: name}
+			if email != "" {
+				c.Supplier.Contact = []cdxContact{{Email: email}}
+			}
+		}
+		for _, h := range []cdxHash{{"MD5", p.MD5}, {"SHA-1", p.SHA1}, {"SHA-256", p.SHA256}} {
+			if h.Content != "" {
+				c.Hashes = append(c.Hashes, h)
+			}
+		}
+		switch {
+		case p.License == "":
+		case strings.ContainsAny(p.License, " +") || strings.HasPrefix(p.License, "LicenseRef-"):
+			// В license.id допустим только идентификатор из списка SPDX
+			c.Licenses = []cdxLicense{{Expression: p.License}}
+		default:
+			c.Licenses = []cdxLicense{{License: &cdxLicenseID{ID: p.License}}}
+		}
+		if p.Homepage != "" {
+			c.ExtRefs = []cdxExtRef{{Type: "website", URL: p.Homepage}}
+		}
+
+		doc.Components = append(doc.Components, c)
+	}
+
+	return doc
+}
+
+// Структуры документа SPDX 2.3 (https://spdx.github.io/spdx-spec/v2.3/).
+type (
+	spdxDocument struct {
+		SPDXVersion       string             `json:"spdxVersion"`
+		DataLicense       string             `json:"dataLicense"`
+		SPDXID            string             `json:"SPDXID"`
+		Name              string             `json:"name"`
+		DocumentNamespace string             `json:"documentNamespace"`
+		CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+		Packages          []spdxPackage      `json:"packages"`
+		Relationships     []spdxRelationship `json:"relationships"`
+		ExtractedLicenses []spdxExtracted    `json:"hasExtractedLicensingInfos,omitempty"`
+	}
+	spdxCreationInfo struct {
+		Created  string   `json:"created"`
+		Creators []string `json:"creators"`
+	}
+	spdxPackage struct {
+		SPDXID           string         `json:"SPDXID"`
+		Name             string         `json:"name"`
+		VersionInfo      string         `json:"versionInfo"`
+		PackageFileName  string         `json:"packageFileName,omitempty"`
+		Supplier         string         `json:"supplier"`
+		DownloadLocation string         `json:"downloadLocation"`
+		FilesAnalyzed    bool           `json:"filesAnalyzed"`
+		Checksums        []spdxChecksum `json:"checksums,omitempty"`
+		Homepage         string         `json:"homepage,omitempty"`
+		SourceInfo       string         `json:"sourceInfo,omitempty"`
+		LicenseConcluded string         `json:"licenseConcluded"`
+		LicenseDeclared  string         `json:"licenseDeclared"`
+		CopyrightText    string         `json:"copyrightText"`
+		Summary          string         `json:"summary,omitempty"`
+		ExternalRefs     []spdxExtRef   `json:"externalRefs"`
+	}
+	spdxChecksum struct {
+		Algorithm string `json:"algorithm"`
+		Value     string `json:"checksumValue"`
+	}
+	spdxExtRef struct {
+		Category string `json:"referenceCategory"`
+		Type     string `json:"referenceType"`
+		Locator  string `json:"referenceLocator"`
+	}
+	spdxRelationship struct {
+		Element string `json:"spdxElementId"`
+		Type    string `json:"relationshipType"`
+		Related string `json:"relatedSpdxElement"`
+	}
+	spdxExtracted struct {
+		LicenseID string `json:"licenseId"`
+		Text      string `json:"extractedText"`
+	}
+)
+
+// spdx формирует документ SPDX.
+func (m *SBOM) spdx() *spdxDocument {
+	doc := &spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              m.Repo,
+		DocumentNamespace: "https://spdx.org/spdxdocs/iso2repo-" + url.PathEscape(m.Repo) + "-" + m.ID,
+		CreationInfo: spdxCreationInfo{
+			Created:  m.Created.Format(time.RFC3339),
+			Creators: []string{"Tool: iso2repo-" + m.ToolVersion},
+		},
+		Packages:      make([]spdxPackage, 0, len(m.Packages)),
+		Relationships: make([]spdxRelationship, 0, len(m.Packages)),
+	}
+
+	ids := make(map[string]bool)
+	texts := make(map[string]string)
+	for _, p := range m.Packages {
+		base := "SPDXRef-Package-" + strings.Trim(spdxIDInvalidRe.ReplaceAllString(p.Name+"-"+p.Version+"-"+p.Arch, "-"), "-")
+		id := base
+		for n := 2; ids[id]; n++ {
+			id = fmt.Sprintf("%s-%d", base, n)
+		}
+		ids[id] = true
+
+		pkg := spdxPackage{
+			SPDXID:           id,
+			Name:             p.Name,
+			VersionInfo:      p.Version,
+			PackageFileName:  p.Filename,
+			Supplier:         spdxNoAssertion,
+			DownloadLocation: spdxNoAssertion,
+			Homepage:         p.Homepage,
+			LicenseConcluded: spdxNoAssertion,
+			LicenseDeclared:  spdxNoAssertion,
+			CopyrightText:    spdxNoAssertion,
+			Summary:          p.Description,
+			ExternalRefs: []spdxExtRef{
+				{Category: "PACKAGE-MANAGER", Type: "purl", Locator: p.PURL},
+			},
+		}
+		if p.Maintainer != "" {
+			name, email := splitMaintainer(p.Maintainer)
+			pkg.Supplier = "Person: " + name
+			if email != "" {
+				pkg.Supplier += " (" + email + ")"
+			}
+		}
+		for _, c := range []spdxChecksum{{"MD5", p.MD5}, {"SHA1", p.SHA1}, {"SHA256", p.SHA256}} {
+			if c.Value != "" {
+				pkg.Checksums = append(pkg.Checksums, c)
+			}
+		}
+		if p.Source != "" && p.Source != p.Name {
+			pkg.SourceInfo = "built from source package " + p.Source + " " + p.SourceVersion
+		}
+		if p.License != "" {
+			pkg.LicenseDeclared = p.License
+			for _, ref := range spdxLicenseRefRe.FindAllString(p.License, -1) {
+				if texts[ref] == "" {
+					texts[ref] = p.licenseTexts[ref]
+				}
+			}
+		}
+
+		doc.Packages = append(doc.Packages, pkg)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			Element: "SPDXRef-DOCUMENT",
+			Type:    "DESCRIBES",
+			Related: id,
+		})
+	}
+
+	for _, ref := range sortedKeys(texts) {
+		text := texts[ref]
+		if text == "" {
+			text = strings.TrimPrefix(ref, "LicenseRef-")
+		}
+		doc.ExtractedLicenses = append(doc.ExtractedLicenses, spdxExtracted{LicenseID: ref, Text: text})
+	}
+
+	return doc
+}
+
+// splitMaintainer разделяет поле Maintainer ("Имя <адрес>") на имя и адрес.
+func splitMaintainer(maintainer string) (string, string) {
+	name, email, ok := strings.Cut(maintainer, "<")
+	if !ok {
+		return strings.TrimSpace(maintainer), ""
+	}
+
+	return strings.TrimSpace(name), strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(email), ">"))
+}
@@ -0,0 +1,195 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kirsrus/iso2repo/pkg/deb"
+	"github.com/kirsrus/iso2repo/pkg/deb/debtest"
+	"golang.org/x/exp/slog"
+)
+
+func TestSpdxLicense(t *testing.T) {
+	tests := []struct {
+		name     string
+		want     string
+		wantRefs []string
+	}{
+		{name: "GPL-2+", want: "GPL-2.0-or-later"},
+		{name: "GPL-3", want: "GPL-3.0-only"},
+		{name: "LGPL-2.1+", want: "LGPL-2.1-or-later"},
+		{name: "GPL", want: "GPL-1.0-or-later"},
+		{name: "Expat", want: "MIT"},
+		{name: "BSD-3-clause", want: "BSD-3-Clause"},
+		{name: "Apache-2.0+", want: "Apache-2.0+"},
+		{name: "GPL-2+ or Artistic", want: "GPL-2.0-or-later OR Artistic-1.0"},
+		{name: "MIT and Zlib or ISC", want: "MIT AND Zlib OR ISC"},
+		{name: "Perl and BSD-2-clause", want: "(Artistic-1.0-Perl OR GPL-1.0-or-later) AND BSD-2-Clause"},
+		{name: "public-domain", want: "LicenseRef-public-domain", wantRefs: []string{"LicenseRef-public-domain"}},
+		{
+			name:     "GPL-2+ with OpenSSL exception",
+			want:     "LicenseRef-GPL-2-with-OpenSSL-exception",
+			wantRefs: []string{"LicenseRef-GPL-2-with-OpenSSL-exception"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refs := make(map[string]string)
+			if got := spdxLicense(tt.name, refs); got != tt.want {
+				t.Errorf("spdxLicense() = %q, want %q", got, tt.want)
+			}
+			if got := sortedKeys(refs); !reflect.DeepEqual(got, append([]string{}, tt.wantRefs...)) {
+				t.Errorf("spdxLicense() refs = %v, want %v", got, tt.wantRefs)
+			}
+		})
+	}
+}
+
+func TestCopyrightLicense(t *testing.T) {
+	c := &deb.Copyright{
+		Licenses: []string{"GPL-2+", "Expat", "GPL-2+ or Artistic", "custom"},
+		Texts:    map[string]string{"custom": "Do what you want."},
+	}
+
+	got := copyrightLicense(c)
+	want := PackageLicense{
+		Expression: "GPL-2.0-or-later AND MIT AND (GPL-2.0-or-later OR Artistic-1.0) AND LicenseRef-custom",
+		Texts:      map[string]string{"LicenseRef-custom": "Do what you want."},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("copyrightLicense() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCatalog_SBOM(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a.iso")
+	files := map[string][]byte{
+		"pool/main/a/app/app_1:1.0-1_amd64.deb": debtest.Package(t, "app", "1:1.0-1", "amd64", map[string]string{
+			"/usr/bin/app": "binary",
+			"/usr/share/doc/app/copyright": "Format: https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/\n\n" +
+				"Files: *\nLicense: GPL-2+\n\nFiles: debian/*\nLicense: Expat\n",
+		}),
+		"pool/main/l/lib/libfoo1_2.0_amd64.deb": debtest.Package(t, "libfoo1", "2.0", "amd64", map[string]string{
+			"/usr/share/doc/libfoo1/copyright": "This package was debianized by Foo.\n",
+		}),
+	}
+	var packages strings.Builder
+	for _, p := range []struct{ name, version, file, extra string }{
+		{"app", "1:1.0-1", "pool/main/a/app/app_1:1.0-1_amd64.deb", "Maintainer: App Team <app@example.org>\nHomepage: https://example.org/app\n"},
+		{"libfoo1", "2.0", "pool/main/l/lib/libfoo1_2.0_amd64.deb", "Source: foo (2.0)\n"},
+	} {
+		data := files[p.file]
+		fmt.Fprintf(&packages, "Package: %s\nVersion: %s\nArchitecture: amd64\n%s", p.name, p.version, p.extra)
+		fmt.Fprintf(&packages, "Filename: %s\nSize: %d\nSHA256: %x\nDescription: %s tool\n long text\n\n", p.file, len(data), sha256.Sum256(data), p.name)
+	}
+	files["dists/stable/main/binary-amd64/Packages"] = []byte(packages.String())
+	files["dists/stable/Release"] = []byte("Origin: Debian\nSuite: stable\nCodename: bookworm\nComponents: main\nArchitectures: amd64\n")
+	for name, data := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard))
+	catalog := NewCatalog(log)
+	if err := catalog.Update(context.Background(), NewRepoExtracted(dir, log)); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	sbom, err := catalog.SBOM(context.Background(), SBOMQuery{Repo: "a.iso", Licenses: true, ToolVersion: "1.0"}, NewLicenses())
+	if err != nil {
+		t.Fatalf("SBOM() error = %v", err)
+	}
+
+	type pkg struct{ name, license, purl, maintainer, homepage, description string }
+	got := make([]pkg, 0)
+	for _, p := range sbom.Packages {
+		got = append(got, pkg{p.Name, p.License, p.PURL, p.Maintainer, p.Homepage, p.Description})
+	}
+	want := []pkg{
+		{
+			"app", "GPL-2.0-or-later AND MIT", "pkg:deb/debian/app@1%3A1.0-1?arch=amd64&distro=bookworm",
+			"App Team <app@example.org>", "https://example.org/app", "app tool",
+		},
+		{"libfoo1", "", "pkg:deb/debian/libfoo1@2.0?arch=amd64&distro=bookworm&upstream=foo", "", "", "libfoo1 tool"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SBOM() packages = %+v, want %+v", got, want)
+	}
+
+	var cdx bytes.Buffer
+	if err := sbom.Write(&cdx, SBOMCycloneDX); err != nil {
+		t.Fatalf("Write(cyclonedx) error = %v", err)
+	}
+	var cdxDoc struct {
+		BOMFormat  string `json:"bomFormat"`
+		Components []struct {
+			Name     string `json:"name"`
+			Supplier struct {
+				Name string `json:"name"`
+			} `json:"supplier"`
+			Hashes   []map[string]string `json:"hashes"`
+			Licenses []map[string]any    `json:"licenses"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(cdx.Bytes(), &cdxDoc); err != nil {
+		t.Fatalf("Write(cyclonedx) invalid JSON: %v", err)
+	}
+	if cdxDoc.BOMFormat != "CycloneDX" || len(cdxDoc.Components) != 2 {
+		t.Fatalf("Write(cyclonedx) = %s", cdx.String())
+	}
+	app := cdxDoc.Components[0]
+	if app.Supplier.Name != "App Team" || len(app.Hashes) != 1 || app.Hashes[0]["alg"] != "SHA-256" ||
+		len(app.Licenses) != 1 || app.Licenses[0]["expression"] != "GPL-2.0-or-later AND MIT" {
+		t.Errorf("Write(cyclonedx) component = %+v", app)
+	}
+
+	var spdx bytes.Buffer
+	if err := sbom.Write(&spdx, SBOMSPDX); err != nil {
+		t.Fatalf("Write(spdx) error = %v", err)
+	}
+	var spdxDoc struct {
+		SPDXVersion string `json:"spdxVersion"`
+		Packages    []struct {
+			SPDXID          string `json:"SPDXID"`
+			Supplier        string `json:"supplier"`
+			LicenseDeclared string `json:"licenseDeclared"`
+		} `json:"packages"`
+		Relationships []map[string]string `json:"relationships"`
+	}
+	if err := json.Unmarshal(spdx.Bytes(), &spdxDoc); err != nil {
+		t.Fatalf("Write(spdx) invalid JSON: %v", err)
+	}
+	if spdxDoc.SPDXVersion != "SPDX-2.3" || len(spdxDoc.Packages) != 2 || len(spdxDoc.Relationships) != 2 {
+		t.Fatalf("Write(spdx) = %s", spdx.String())
+	}
+	if p := spdxDoc.Packages[0]; p.SPDXID != "SPDXRef-Package-app-1-1.0-1-amd64" ||
+		p.Supplier != "Person: App Team (app@example.org)" || p.LicenseDeclared != "GPL-2.0-or-later AND MIT" {
+		t.Errorf("Write(spdx) package = %+v", p)
+	}
+	if p := spdxDoc.Packages[1]; p.Supplier != spdxNoAssertion || p.LicenseDeclared != spdxNoAssertion {
+		t.Errorf("Write(spdx) package without metadata = %+v", p)
+	}
+
+	if err := sbom.Write(io.Discard, "swid"); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("Write() unknown format error = %v", err)
+	}
+	if _, err := catalog.SBOM(context.Background(), SBOMQuery{Repo: "b.iso"}, nil); !errors.Is(err, ErrPackageNotFound) {
+		t.Errorf("SBOM() unknown repo error = %v", err)
+	}
+}
@@ -0,0 +1,335 @@
+package repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/kirsrus/iso2repo/models"
+	"golang.org/x/exp/slog"
+)
+
+const (
+	// snapshotsDir директория внутри пользовательского репозитория со снимками.
+	snapshotsDir = ".snapshots"
+
+	// snapshotFile описание снимка в его директории.
+	snapshotFile = "snapshot.json"
+)
+
+// snapshotNameRe допустимое имя снимка.
+var snapshotNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+~-]*$`)
+
+// Snapshot снимок пользовательского репозитория: индексы и файлы опубликованных
+// пакетов на момент создания. Хранится в директории .snapshots/<имя> репозитория
+// как обычный APT-репозиторий; файлы пакетов — копии файлов репозитория (где файловая
+// система позволяет — с копированием при записи), поэтому ни удаление пакета из
+// репозитория, ни перезапись его файла на месте не затрагивают снимок.
+type Snapshot struct {
+	Name string `json:"name"`
+	Repo string `json:"repo"`
+	// Время создания.
+	Time time.Time `json:"time"`
+	// Количество и суммарный размер файлов пакетов.
+	Packages int   `json:"packages"`
+	Size     int64 `json:"size"`
+	// Файлы пакетов, скопированные целиком (файловая система не поддерживает
+	// копирование при записи).
+	Copied int `json:"copied,omitempty"`
+}
+
+// snapshotDir возвращает директорию снимка name, проверяя его имя.
+func (m *RepoCustom) snapshotDir(name string) (string, error) {
+	if !snapshotNameRe.MatchString(name) {
+		return "", errors.Wrapf(ErrUploadInvalid, "некорректное имя снимка %q", name)
+	}
+
+	return filepath.Join(m.path, snapshotsDir, name), nil
+}
+
+// CreateSnapshot создаёт снимок name из опубликованных пакетов репозитория:
+// индексы копируются, Release формируется заново (без истории PDiff), файлы пакетов
+// копируются. Жёсткие ссылки не используются: файл в отслеживаемой директории могут
+// перезаписать на месте, и содержимое снимка разошлось бы с его Release. Снимок
+// собирается во временной директории и появляется целиком.
+func (m *RepoCustom) CreateSnapshot(name string) (Snapshot, error) {
+	dir, err := m.snapshotDir(name)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	// Публикация и удаление пакетов ждут окончания снимка
+	m.publishMu.Lock()
+	defer m.publishMu.Unlock()
+
+	if _, err := os.Stat(dir); err == nil {
+		return Snapshot{}, errors.Wrapf(ErrUploadConflict, "снимок %s уже существует", name)
+	}
+
+	m.mu.RLock()
+	debFiles := append([]debFileInfo{}, m.debFiles...)
+	indexFiles := make(map[string][]byte, len(m.indexFiles))
+	for p, content := range m.indexFiles {
+		if !strings.Contains(p, pdiffDir+"/") {
+			indexFiles[p] = content
+		}
+	}
+	m.mu.RUnlock()
+
+	tmp := filepath.Join(m.path, snapshotsDir, "."+name+".tmp")
+	if err := os.RemoveAll(tmp); err != nil {
+		return Snapshot{}, errors.Wrap(err, "не удалось очистить временную директорию снимка")
+	}
+	defer os.RemoveAll(tmp)
+
+	snapshot := Snapshot{Name: name, Repo: m.name, Time: time.Now().UTC()}
+	files := make(map[string][]byte, len(indexFiles)+1)
+	for p, content := range indexFiles {
+		files[path.Join("dists", customCodename, p)] = content
+	}
+	files[path.Join("dists", customCodename, "Release")] = customRelease(fmt.Sprintf("Snapshot %s of %s", name, m.name), snapshot.Time, "", indexFiles)
+	for p, content := range files {
+		if err := writeSnapshotFile(filepath.Join(tmp, filepath.FromSlash(p)), content); err != nil {
+			return Snapshot{}, err
+		}
+	}
+
+	for _, d := range debFiles {
+		// Файл, изменившийся после сканирования, не соответствует индексу
+		info, err := os.Stat(d.Path)
+		if err != nil || info.Size() != d.Size || !info.ModTime().Equal(d.FileTime) {
+			m.Refresh()
+			return Snapshot{}, errors.Wrapf(ErrUploadConflict, "%s изменился после сканирования, повторите операцию", d.Name)
+		}
+
+		dst := filepath.Join(tmp, filepath.FromSlash(d.Kind.poolDir()), d.Name)
+		copied, err := cloneOrCopy(d.Path, dst)
+		if err != nil {
+			return Snapshot{}, errors.Wrapf(err, "не удалось добавить %s в снимок", d.Name)
+		}
+		if copied {
+			snapshot.Copied++
+		}
+		snapshot.Packages++
+		snapshot.Size += d.Size
+	}
+
+	meta, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return Snapshot{}, errors.WithStack(err)
+	}
+	if err := writeSnapshotFile(filepath.Join(tmp, snapshotFile), meta); err != nil {
+		return Snapshot{}, err
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return Snapshot{}, errors.Wrapf(err, "не удалось сохранить снимок %s", name)
+	}
+
+	m.log.Info(fmt.Sprintf("создан снимок %s", name), slog.String("repo", m.name), slog.Int("packages", snapshot.Packages), slog.Int("copied", snapshot.Copied))
+
+	return snapshot, nil
+}
+
+// writeSnapshotFile записывает файл снимка, создавая его директорию.
+func writeSnapshotFile(p string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return errors.Wrap(err, "не удалось создать директорию снимка")
+	}
+	if err := os.WriteFile(p, content, 0o644); err != nil {
+		return errors.Wrapf(err, "не удалось записать %s", filepath.Base(p))
+	}
+
+	return nil
+}
+
+// cloneOrCopy копирует файл src в dst: на файловых системах с копированием при
+// записи (btrfs, XFS) — клонированием без затрат места, иначе — целиком.
+// copied — файл скопирован целиком.
+func cloneOrCopy(src, dst string) (copied bool, err error) {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return false, err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return false, err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return false, err
+	}
+	if err := cloneFile(out, in); err == nil {
+		return false, out.Close()
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return false, err
+	}
+
+	return true, out.Close()
+}
+
+// Snapshots возвращает снимки репозитория в порядке создания.
+func (m *RepoCustom) Snapshots() ([]Snapshot, error) {
+	result := make([]Snapshot, 0)
+
+	dirs, err := os.ReadDir(filepath.Join(m.path, snapshotsDir))
+	if os.IsNotExist(err) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "не удалось прочитать список снимков")
+	}
+
+	for _, d := range dirs {
+		if !d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			continue
+		}
+		snapshot, err := m.readSnapshot(d.Name())
+		if err != nil {
+			m.log.Warn("снимок пропущен", slog.String("repo", m.name), slog.String("snapshot", d.Name()), slog.String("error", err.Error()))
+			continue
+		}
+		result = append(result, snapshot)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].Time.Equal(result[j].Time) {
+			return result[i].Time.Before(result[j].Time)
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// readSnapshot читает описание снимка name.
+func (m *RepoCustom) readSnapshot(name string) (Snapshot, error) {
+	dir, err := m.snapshotDir(name)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, snapshotFile))
+	if os.IsNotExist(err) {
+		return Snapshot{}, errors.Wrapf(ErrPackageNotFound, "снимок %s в %s", name, m.name)
+	}
+	if err != nil {
+		return Snapshot{}, errors.Wrapf(err, "не удалось прочитать снимок %s", name)
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return Snapshot{}, errors.Wrapf(err, "не удалось разобрать описание снимка %s", name)
+	}
+
+	return snapshot, nil
+}
+
+// DeleteSnapshot удаляет снимок name. Файлы пакетов репозитория не затрагиваются.
+func (m *RepoCustom) DeleteSnapshot(name string) (Snapshot, error) {
+	m.publishMu.Lock()
+	defer m.publishMu.Unlock()
+
+	snapshot, err := m.readSnapshot(name)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	dir, _ := m.snapshotDir(name)
+	if err := os.RemoveAll(dir); err != nil {
+		return Snapshot{}, errors.Wrapf(err, "не удалось удалить снимок %s", name)
+	}
+
+	m.log.Info(fmt.Sprintf("удалён снимок %s", name), slog.String("repo", m.name))
+
+	return snapshot, nil
+}
+
+// OpenSnapshot открывает файл p (путь от корня репозитория) снимка name.
+func (m *RepoCustom) OpenSnapshot(name, p string) (io.ReadCloser, error) {
+	if _, err := m.readSnapshot(name); err != nil {
+		return nil, err
+	}
+
+	p = path.Clean("/" + p)[1:]
+	if p == "" || p == snapshotFile {
+		return nil, errors.Wrapf(ErrPackageNotFound, "файл %s снимка %s", p, name)
+	}
+
+	dir, _ := m.snapshotDir(name)
+	filePath := filepath.Join(dir, filepath.FromSlash(p))
+	info, err := os.Stat(filePath)
+	if err != nil || info.IsDir() {
+		return nil, errors.Wrapf(ErrPackageNotFound, "файл %s снимка %s", p, name)
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return file, nil
+}
+
+// SnapshotRepo снимок пользовательского репозитория как репозиторий только для
+// чтения (для проверки его индексов и пакетов политикой раздачи). Metadata
+// возвращает имя исходного репозитория, поэтому к пакетам снимка применяются те же
+// правила, что и к пакетам репозитория.
+type SnapshotRepo struct {
+	*RepoExtracted
+	repo     string
+	line     string
+	snapshot Snapshot
+}
+
+var _ models.Repoes = (*SnapshotRepo)(nil)
+
+// SnapshotRepo возвращает снимок name как репозиторий.
+func (m *RepoCustom) SnapshotRepo(name string) (*SnapshotRepo, error) {
+	snapshot, err := m.readSnapshot(name)
+	if err != nil {
+		return nil, err
+	}
+	dir, _ := m.snapshotDir(name)
+
+	return &SnapshotRepo{
+		RepoExtracted: NewRepoExtracted(dir, m.log),
+		repo:          m.name,
+		line:          m.SnapshotString(name),
+		snapshot:      snapshot,
+	}, nil
+}
+
+// Metadata возвращает метаданные снимка с именем исходного репозитория.
+func (m *SnapshotRepo) Metadata() models.Repo {
+	metadata := m.RepoExtracted.Metadata()
+	metadata.Name = m.repo
+
+	return metadata
+}
+
+// RepoString возвращает строку sources.list снимка с адресом сервера 0.0.0.0.
+func (m *SnapshotRepo) RepoString() string {
+	return m.line
+}
+
+// Snapshot возвращает описание снимка.
+func (m *SnapshotRepo) Snapshot() Snapshot {
+	return m.snapshot
+}
+
+// SnapshotString возвращает строку sources.list снимка name с адресом сервера 0.0.0.0.
+func (m *RepoCustom) SnapshotString(name string) string {
+	return fmt.Sprintf("deb [arch=amd64 trusted=yes] http://0.0.0.0/snapshot/%s/%s %s contrib main non-free", m.name, name, customCodename)
+}
@@ -0,0 +1,117 @@
+package repo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kirsrus/iso2repo/pkg/deb"
+	"golang.org/x/exp/slog"
+)
+
+func TestRepoCustom_Snapshots(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "custom.iso")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{"app_1.0_amd64.deb": "app 1.0", "lib_2.0_all.deb": "lib 2.0"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := NewRepoCustom(dir, slog.New(slog.NewTextHandler(io.Discard)), CustomOptions{PDiffDepth: 5})
+
+	read := func(name, p string) (string, error) {
+		reader, err := r.OpenSnapshot(name, p)
+		if err != nil {
+			return "", err
+		}
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		return string(data), err
+	}
+
+	snapshot, err := r.CreateSnapshot("2024-05")
+	if err != nil {
+		t.Fatalf("CreateSnapshot() error = %v", err)
+	}
+	if snapshot.Repo != "custom.iso" || snapshot.Packages != 2 || snapshot.Size != 14 {
+		t.Errorf("CreateSnapshot() = %+v", snapshot)
+	}
+
+	// Перезапись файла пакета на месте не меняет снимок
+	if err := os.WriteFile(filepath.Join(dir, "lib_2.0_all.deb"), []byte("lib 2.0 rebuilt"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := read("2024-05", "pool/main/lib_2.0_all.deb"); err != nil || data != "lib 2.0" {
+		t.Errorf("OpenSnapshot(lib) after overwrite = %q, %v", data, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "lib_2.0_all.deb"), []byte("lib 2.0"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Пакет, удалённый из репозитория, остаётся в снимке
+	if err := os.Remove(filepath.Join(dir, "app_1.0_amd64.deb")); err != nil {
+		t.Fatal(err)
+	}
+	r.Refresh()
+	if data, err := read("2024-05", "pool/main/app_1.0_amd64.deb"); err != nil || data != "app 1.0" {
+		t.Errorf("OpenSnapshot(app) = %q, %v", data, err)
+	}
+
+	// Release снимка описывает его собственные индексы
+	data, err := read("2024-05", "dists/custom/Release")
+	if err != nil {
+		t.Fatalf("OpenSnapshot(Release) error = %v", err)
+	}
+	release, err := deb.ReadRelease(bytes.NewReader([]byte(data)))
+	if err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	index, err := read("2024-05", "dists/custom/main/binary-amd64/Packages")
+	if err != nil {
+		t.Fatalf("OpenSnapshot(Packages) error = %v", err)
+	}
+	if f, ok := release.File("main/binary-amd64/Packages"); !ok || f.Hash != fmt.Sprintf("%x", sha256.Sum256([]byte(index))) {
+		t.Errorf("Release checksum of Packages = %+v", f)
+	}
+	if !bytes.Contains([]byte(index), []byte("app_1.0_amd64.deb")) {
+		t.Errorf("Packages = %q, want app_1.0_amd64.deb", index)
+	}
+
+	if _, err := r.CreateSnapshot("2024-05"); !errors.Is(err, ErrUploadConflict) {
+		t.Errorf("CreateSnapshot() duplicate error = %v", err)
+	}
+	if _, err := r.CreateSnapshot("../x"); !errors.Is(err, ErrUploadInvalid) {
+		t.Errorf("CreateSnapshot() invalid name error = %v", err)
+	}
+	if _, err := read("2024-05", "../../lib_2.0_all.deb"); !errors.Is(err, ErrPackageNotFound) {
+		t.Errorf("OpenSnapshot() outside snapshot error = %v", err)
+	}
+
+	if _, err := r.CreateSnapshot("2024-06"); err != nil {
+		t.Fatalf("CreateSnapshot() error = %v", err)
+	}
+	snapshots, err := r.Snapshots()
+	if err != nil || len(snapshots) != 2 || snapshots[0].Name != "2024-05" || snapshots[1].Packages != 1 {
+		t.Errorf("Snapshots() = %+v, %v", snapshots, err)
+	}
+
+	if _, err := r.DeleteSnapshot("2024-05"); err != nil {
+		t.Fatalf("DeleteSnapshot() error = %v", err)
+	}
+	if _, err := read("2024-05", "dists/custom/Release"); !errors.Is(err, ErrPackageNotFound) {
+		t.Errorf("OpenSnapshot() after delete error = %v", err)
+	}
+	if _, err := r.DeleteSnapshot("2024-05"); !errors.Is(err, ErrPackageNotFound) {
+		t.Errorf("DeleteSnapshot() missing error = %v", err)
+	}
+	if data, err := read("2024-06", "pool/main/lib_2.0_all.deb"); err != nil || data != "lib 2.0" {
+		t.Errorf("OpenSnapshot(lib) = %q, %v", data, err)
+	}
+}
@@ -0,0 +1,130 @@
+package repo
+
+import (
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/kirsrus/iso2repo/pkg/deb"
+)
+
+// UpgradeQuery условия проверки обновлений установленных пакетов.
+type UpgradeQuery struct {
+	// Установленные пакеты (из файла состояния dpkg или вывода dpkg-query -W).
+	Installed []deb.InstalledPackage
+	// Репозитории, в которых ищутся обновления; пустой — все. При равных версиях
+	// предпочтение отдаётся репозиторию, указанному раньше.
+	Repos []string
+	// Архитектура пакетов, для которых она не указана; пустая — любая.
+	Arch string
+}
+
+// Upgrade кандидат на обновление установленного пакета.
+type Upgrade struct {
+	Name string `json:"name"`
+	Arch string `json:"arch"`
+	// Установленная версия.
+	Installed string `json:"installed"`
+	// Пакет с наибольшей версией в репозиториях; nil, если пакет не найден.
+	Candidate *CatalogEntry `json:"candidate"`
+	// Версия кандидата больше установленной.
+	Upgradable bool `json:"upgradable"`
+	// Дистрибутивы обновлений безопасности ("репозиторий/дистрибутив"), в которых
+	// есть версия больше установленной.
+	Security []string `json:"security"`
+}
+
+// UpgradeReport результат проверки обновлений.
+type UpgradeReport struct {
+	Packages []Upgrade `json:"packages"`
+	// Количество установленных пакетов.
+	Installed int `json:"installed"`
+	// Количество пакетов, для которых есть обновление.
+	Upgradable int `json:"upgradable"`
+	// Количество пакетов, для которых есть обновление безопасности.
+	Security int `json:"security"`
+	// Количество пакетов, не найденных ни в одном репозитории.
+	NotFound int `json:"not_found"`
+}
+
+// Upgrades находит для каждого установленного пакета кандидата на обновление —
+// пакет той же архитектуры (или "all") с наибольшей версией в репозиториях q.Repos,
+// и отмечает пакеты, обновления которых есть в дистрибутивах безопасности.
+func (m *Catalog) Upgrades(q UpgradeQuery) (*UpgradeReport, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := q.Repos
+	if len(names) == 0 {
+		names = sortedKeys(m.repos)
+	}
+	pool := make([]*catalogRepo, 0, len(names))
+	for _, name := range names {
+		repo, ok := m.repos[name]
+		if !ok {
+			return nil, errors.Wrapf(ErrPackageNotFound, "репозиторий %s", name)
+		}
+		pool = append(pool, repo)
+	}
+
+	report := &UpgradeReport{
+		Packages:  make([]Upgrade, 0, len(q.Installed)),
+		Installed: len(q.Installed),
+	}
+	for _, p := range q.Installed {
+		u := Upgrade{
+			Name:      p.Package,
+			Arch:      p.Architecture,
+			Installed: p.Version,
+			Security:  make([]string, 0),
+		}
+		if u.Arch == "" {
+			u.Arch = q.Arch
+		}
+
+		for _, repo := range pool {
+			for _, i := range repo.byName[p.Package] {
+				e := repo.entries[i]
+				if u.Arch != "" && e.Arch != u.Arch && e.Arch != "all" {
+					continue
+				}
+				newer := deb.CompareVersions(e.Version, p.Version) > 0
+				if newer && repo.security[e.Suite] {
+					if suite := e.Repo + "/" + e.Suite; !containsString(u.Security, suite) {
+						u.Security = append(u.Security, suite)
+					}
+				}
+				if u.Candidate == nil || deb.CompareVersions(e.Version, u.Candidate.Version) > 0 {
+					candidate := e
+					u.Candidate = &candidate
+				}
+			}
+		}
+
+		switch {
+		case u.Candidate == nil:
+			report.NotFound++
+		case deb.CompareVersions(u.Candidate.Version, p.Version) > 0:
+			u.Upgradable = true
+			report.Upgradable++
+		}
+		if len(u.Security) > 0 {
+			report.Security++
+		}
+		report.Packages = append(report.Packages, u)
+	}
+
+	return report, nil
+}
+
+// isSecuritySuite определяет, является ли дистрибутив dist дистрибутивом обновлений
+// безопасности: по имени (bookworm-security, bullseye/updates) или по полям Label и
+// Suite файла Release.
+func isSecuritySuite(dist string, release *deb.Release) bool {
+	for _, s := range []string{dist, release.Label, release.Suite} {
+		if strings.Contains(strings.ToLower(s), "security") {
+			return true
+		}
+	}
+
+	return strings.HasSuffix(dist, "/updates")
+}
@@ -0,0 +1,116 @@
+package repo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/kirsrus/iso2repo/pkg/deb"
+)
+
+func TestCatalog_Upgrades(t *testing.T) {
+	catalog := newTestDependsCatalog(testDependsPackages)
+	s := newCatalogRepo(nil)
+	s.add(CatalogEntry{Name: "libc6", Version: "2.36-1+deb12u1", Arch: "amd64", Repo: "s.iso", Suite: "bookworm-security"}, nil)
+	s.add(CatalogEntry{Name: "app", Version: "2.0", Arch: "amd64", Repo: "s.iso", Suite: "bookworm"}, nil)
+	s.security["bookworm-security"] = true
+	catalog.repos["s.iso"] = s
+
+	installed := []deb.InstalledPackage{
+		{Package: "libc6", Version: "2.36", Architecture: "amd64"},
+		{Package: "app", Version: "1:1.0", Architecture: "amd64"},
+		{Package: "libfoo1", Version: "1.0"},
+		{Package: "app-data", Version: "2.0", Architecture: "all"},
+		{Package: "ghost", Version: "1.0", Architecture: "amd64"},
+	}
+
+	type result struct {
+		name, candidate string
+		upgradable      bool
+		security        []string
+	}
+	tests := []struct {
+		name       string
+		query      UpgradeQuery
+		want       []result
+		wantReport [4]int // Installed, Upgradable, Security, NotFound
+		wantErr    error
+	}{
+		{
+			name:  "all repos",
+			query: UpgradeQuery{Installed: installed, Arch: "i386"},
+			want: []result{
+				{"libc6", "2.36-1+deb12u1 s.iso", true, []string{"s.iso/bookworm-security"}},
+				{"app", "2.0 a.iso", false, []string{}},
+				{"libfoo1", "1.3 a.iso", true, []string{}},
+				{"app-data", "2.0 a.iso", false, []string{}},
+				{"ghost", "", false, []string{}},
+			},
+			wantReport: [4]int{5, 2, 1, 1},
+		},
+		{
+			name:  "selected repo without arch",
+			query: UpgradeQuery{Installed: installed[:3], Repos: []string{"a.iso"}},
+			want: []result{
+				{"libc6", "2.36 a.iso", false, []string{}},
+				{"app", "2.0 a.iso", false, []string{}},
+				{"libfoo1", "1.3 a.iso", true, []string{}},
+			},
+			wantReport: [4]int{3, 1, 0, 0},
+		},
+		{name: "unknown repo", query: UpgradeQuery{Installed: installed, Repos: []string{"c.iso"}}, wantErr: ErrPackageNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := catalog.Upgrades(tt.query)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Upgrades() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Upgrades() error = %v", err)
+			}
+
+			var got []result
+			for _, u := range report.Packages {
+				r := result{name: u.Name, upgradable: u.Upgradable, security: u.Security}
+				if u.Candidate != nil {
+					r.candidate = u.Candidate.Version + " " + u.Candidate.Repo
+				}
+				got = append(got, r)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Upgrades() = %v, want %v", got, tt.want)
+			}
+			gotReport := [4]int{report.Installed, report.Upgradable, report.Security, report.NotFound}
+			if gotReport != tt.wantReport {
+				t.Errorf("Upgrades() counters = %v, want %v", gotReport, tt.wantReport)
+			}
+		})
+	}
+}
+
+func TestIsSecuritySuite(t *testing.T) {
+	tests := []struct {
+		name    string
+		dist    string
+		release deb.Release
+		want    bool
+	}{
+		{name: "security suffix", dist: "bookworm-security", want: true},
+		{name: "old layout", dist: "bullseye/updates", want: true},
+		{name: "label", dist: "stable", release: deb.Release{Label: "Debian-Security"}, want: true},
+		{name: "regular", dist: "bookworm-updates", release: deb.Release{Label: "Debian"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isSecuritySuite(tt.dist, &tt.release); got != tt.want {
+				t.Errorf("isSecuritySuite() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
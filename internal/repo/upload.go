@@ -0,0 +1,277 @@
+package repo
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/kirsrus/iso2repo/pkg/deb"
+	"golang.org/x/exp/slog"
+)
+
+var (
+	// ErrUploadInvalid загрузка отклонена из-за некорректных данных.
+	ErrUploadInvalid = errors.New("некорректная загрузка")
+
+	// ErrUploadConflict в репозитории уже есть файл с тем же именем, но другим содержимым.
+	ErrUploadConflict = errors.New("конфликт с уже опубликованным файлом")
+)
+
+// uploadDir скрытая директория внутри репозитория для временных файлов загрузок.
+// Сканирование репозитория и отслеживание директорий её пропускают, а публикация
+// остаётся атомарным переименованием в пределах одной файловой системы.
+const uploadDir = ".uploads"
+
+// renameFile переносит принятый файл в репозиторий (подменяется в тестах).
+var renameFile = os.Rename
+
+// Upload описывает принятый, но ещё не опубликованный файл. Содержимое хранится
+// во временном файле скрытой поддиректории репозитория, что позволяет
+// опубликовать его атомарным переименованием.
+type Upload struct {
+	// Имя файла, под которым он будет опубликован.
+	Name string
+
+	// Размер и контрольные суммы принятого содержимого.
+	Size      int64
+	MD5Sum    string
+	SHA1Sum   string
+	SHA256Sum string
+
+	tmpPath string
+}
+
+// PublishResult итог публикации загруженных пакетов.
+type PublishResult struct {
+	// Записи индекса Packages для опубликованных пакетов.
+	Packages []Stanza `json:"packages"`
+	// Пакеты, помещённые на карантин (в режиме карантина).
+	Staged []StagedPackage `json:"staged,omitempty"`
+}
+
+// Discard удаляет временный файл загрузки, если он ещё существует.
+func (u *Upload) Discard() {
+	if u.tmpPath != "" {
+		_ = os.Remove(u.tmpPath)
+	}
+}
+
+// ReceiveUpload сохраняет содержимое r во временный файл в скрытой поддиректории
+// репозитория,
+// одновременно вычисляя контрольные суммы. Имя файла должно быть простым
+// (без директорий) и иметь расширение .deb, .udeb, .ddeb или .changes.
+func (m *RepoCustom) ReceiveUpload(name string, r io.Reader) (*Upload, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || name != filepath.Base(name) || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return nil, errors.Wrapf(ErrUploadInvalid, "недопустимое имя файла %q", name)
+	}
+	if _, ok := debKindByName(name); !ok && !strings.HasSuffix(strings.ToLower(name), ".changes") {
+		return nil, errors.Wrapf(ErrUploadInvalid, "неподдерживаемый тип файла %q", name)
+	}
+
+	dir := filepath.Join(m.path, uploadDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, errors.Wrap(err, "не удалось создать директорию загрузок")
+	}
+	tmp, err := os.CreateTemp(dir, "upload-*.tmp")
+	if err != nil {
+		return nil, errors.Wrap(err, "не удалось создать временный файл")
+	}
+
+	md5H := md5.New()
+	sha1H := sha1.New()
+	sha256H := sha256.New()
+
+	size, err := io.Copy(io.MultiWriter(tmp, md5H, sha1H, sha256H), r)
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return nil, errors.Wrapf(err, "не удалось принять файл %s", name)
+	}
+
+	return &Upload{
+		Name:      name,
+		Size:      size,
+		MD5Sum:    hex.EncodeToString(md5H.Sum(nil)),
+		SHA1Sum:   hex.EncodeToString(sha1H.Sum(nil)),
+		SHA256Sum: hex.EncodeToString(sha256H.Sum(nil)),
+		tmpPath:   tmp.Name(),
+	}, nil
+}
+
+// Publish проверяет принятые файлы и публикует пакеты в репозитории.
+//
+// Если среди загрузок есть .changes файлы, каждый перечисленный в них бинарный пакет
+// обязан присутствовать в загрузке и совпадать по размеру и контрольным суммам,
+// а пакет, не перечисленный ни в одном из них, отклоняется.
+// Каждый пакет должен читаться через deb.ExtractMeta и содержать поля Package,
+// Version и Architecture. Публикация выполняется только если проверку прошли все файлы;
+// сам файл переносится в репозиторий атомарным переименованием. Если перенос одного
+// из файлов не удался, уже перенесённые остаются опубликованными: они возвращаются
+// в результате вместе с ошибкой.
+//
+// После публикации репозиторий пересканируется немедленно, не дожидаясь опроса директории.
+// В режиме карантина ещё не одобренные пакеты попадают не в индексы, а на карантин.
+// Временные файлы всех загрузок удаляются в любом случае.
+func (m *RepoCustom) Publish(uploads []*Upload) (PublishResult, error) {
+	defer func() {
+		for _, u := range uploads {
+			u.Discard()
+		}
+	}()
+
+	m.publishMu.Lock()
+	defer m.publishMu.Unlock()
+
+	packages := make(map[string]*Upload)
+	changes := make([]*deb.Changes, 0)
+
+	for _, u := range uploads {
+		if strings.HasSuffix(strings.ToLower(u.Name), ".changes") {
+			f, err := os.Open(u.tmpPath)
+			if err != nil {
+				return PublishResult{}, errors.Wrapf(err, "не удалось открыть %s", u.Name)
+			}
+			c, err := deb.ParseChanges(f)
+			f.Close()
+			if err != nil {
+				return PublishResult{}, errors.Wrapf(ErrUploadInvalid, "%s: %s", u.Name, err.Error())
+			}
+			changes = append(changes, c)
+
+			continue
+		}
+
+		if _, ok := packages[u.Name]; ok {
+			return PublishResult{}, errors.Wrapf(ErrUploadInvalid, "файл %s передан дважды", u.Name)
+		}
+		packages[u.Name] = u
+	}
+
+	if len(packages) == 0 {
+		return PublishResult{}, errors.Wrap(ErrUploadInvalid, "в загрузке нет пакетов")
+	}
+
+	// Сверяем пакеты с описаниями из .changes
+	listed := make(map[string]bool, len(packages))
+	for _, c := range changes {
+		for _, f := range c.Files {
+			if _, ok := debKindByName(f.Name); !ok {
+				// Исходники и прочие файлы загрузки не публикуются
+				continue
+			}
+			u, ok := packages[f.Name]
+			if !ok {
+				return PublishResult{}, errors.Wrapf(ErrUploadInvalid, "файл %s указан в .changes, но не передан", f.Name)
+			}
+			if err := verifyChangesFile(f, u); err != nil {
+				return PublishResult{}, err
+			}
+			listed[f.Name] = true
+		}
+	}
+	if len(changes) > 0 {
+		for _, name := range sortedKeys(packages) {
+			if !listed[name] {
+				return PublishResult{}, errors.Wrapf(ErrUploadInvalid, "файл %s не указан в .changes", name)
+			}
+		}
+	}
+
+	// Проверяем содержимое пакетов и возможность их публикации
+	targets := make(map[string]string, len(packages))
+	for name, u := range packages {
+		meta, err := deb.ExtractMeta(u.tmpPath)
+		if err != nil {
+			return PublishResult{}, errors.Wrapf(ErrUploadInvalid, "%s: %s", name, err.Error())
+		}
+		if meta.Package == "" || meta.Version == "" || meta.Architecture == "" {
+			return PublishResult{}, errors.Wrapf(ErrUploadInvalid, "%s: в control отсутствуют Package, Version или Architecture", name)
+		}
+
+		target := filepath.Join(m.path, name)
+		if _, err := os.Stat(target); err == nil {
+			_, _, existing, err := m.computeHashes(target)
+			if err != nil {
+				return PublishResult{}, errors.Wrapf(err, "не удалось проверить существующий файл %s", name)
+			}
+			if existing != u.SHA256Sum {
+				return PublishResult{}, errors.Wrapf(ErrUploadConflict, "%s", name)
+			}
+		}
+		targets[name] = target
+	}
+
+	// Публикуем
+	published := make(map[string]string, len(targets))
+	var publishErr error
+	for _, name := range sortedKeys(packages) {
+		u := packages[name]
+		if err := renameFile(u.tmpPath, targets[name]); err != nil {
+			publishErr = errors.Wrapf(err, "не удалось опубликовать %s", name)
+			if len(published) > 0 {
+				publishErr = errors.Wrapf(publishErr, "опубликованы только %s", strings.Join(sortedKeys(published), ", "))
+			}
+			break
+		}
+		u.tmpPath = ""
+		published[name] = targets[name]
+		m.log.Info(fmt.Sprintf("опубликован пакет %s", name), slog.String("repo", m.name))
+	}
+	if len(published) == 0 {
+		return PublishResult{}, publishErr
+	}
+
+	// Уже перенесённые файлы попадают в индексы, даже если перенос прочих не удался
+	m.Refresh()
+	m.notifyChange()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := PublishResult{Packages: make([]Stanza, 0, len(published))}
+	for _, d := range m.debFiles {
+		if _, ok := published[d.Name]; ok && d.Path == published[d.Name] {
+			result.Packages = append(result.Packages, packageStanza(d))
+		}
+	}
+	for _, d := range m.stagedFiles {
+		if _, ok := published[d.Name]; ok && d.Path == published[d.Name] {
+			result.Staged = append(result.Staged, newStagedPackage(d))
+		}
+	}
+
+	return result, publishErr
+}
+
+// verifyChangesFile сверяет принятый файл u с его описанием f из .changes.
+func verifyChangesFile(f deb.ChangesFile, u *Upload) error {
+	if f.Size != u.Size {
+		return errors.Wrapf(ErrUploadInvalid, "%s: размер %d не совпадает с .changes (%d)", u.Name, u.Size, f.Size)
+	}
+
+	sums := []struct {
+		name, want, got string
+	}{
+		{"MD5", f.MD5Sum, u.MD5Sum},
+		{"SHA1", f.SHA1Sum, u.SHA1Sum},
+		{"SHA256", f.SHA256Sum, u.SHA256Sum},
+	}
+	for _, s := range sums {
+		if s.want != "" && !strings.EqualFold(s.want, s.got) {
+			return errors.Wrapf(ErrUploadInvalid, "%s: контрольная сумма %s не совпадает с .changes", u.Name, s.name)
+		}
+	}
+
+	return nil
+}
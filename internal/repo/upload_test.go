@@ -0,0 +1,207 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kirsrus/iso2repo/pkg/deb/debtest"
+	"golang.org/x/exp/slog"
+)
+
+// testChanges формирует .changes, перечисляющий файлы files с их содержимым.
+func testChanges(files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("Source: app\nVersion: 1.0\nArchitecture: amd64\nFiles:\n")
+	for _, name := range sortedKeys(files) {
+		fmt.Fprintf(&buf, " %x %d utils optional %s\n", md5.Sum(files[name]), len(files[name]), name)
+	}
+	buf.WriteString("Checksums-Sha256:\n")
+	for _, name := range sortedKeys(files) {
+		fmt.Fprintf(&buf, " %x %d %s\n", sha256.Sum256(files[name]), len(files[name]), name)
+	}
+
+	return buf.Bytes()
+}
+
+func TestRepoCustom_Publish(t *testing.T) {
+	app := debtest.Package(t, "app", "1.0", "amd64", nil)
+	tool := debtest.Package(t, "tool", "1.0", "amd64", nil)
+	other := debtest.Package(t, "app", "1.0", "amd64", map[string]string{"/usr/bin/app": "app"})
+
+	tests := []struct {
+		name     string
+		existing map[string][]byte
+		uploads  map[string][]byte
+		wantErr  error
+		want     []string
+	}{
+		{
+			name:    "package without changes",
+			uploads: map[string][]byte{"app_1.0_amd64.deb": app},
+			want:    []string{"app"},
+		},
+		{
+			name: "package listed in changes",
+			uploads: map[string][]byte{
+				"app_1.0_amd64.deb":     app,
+				"app_1.0_amd64.changes": testChanges(map[string][]byte{"app_1.0_amd64.deb": app, "app_1.0.dsc": []byte("dsc")}),
+			},
+			want: []string{"app"},
+		},
+		{
+			name: "checksum mismatch",
+			uploads: map[string][]byte{
+				"app_1.0_amd64.deb":     app,
+				"app_1.0_amd64.changes": testChanges(map[string][]byte{"app_1.0_amd64.deb": other}),
+			},
+			wantErr: ErrUploadInvalid,
+		},
+		{
+			name: "file listed in changes is missing",
+			uploads: map[string][]byte{
+				"app_1.0_amd64.deb":     app,
+				"app_1.0_amd64.changes": testChanges(map[string][]byte{"app_1.0_amd64.deb": app, "tool_1.0_amd64.deb": tool}),
+			},
+			wantErr: ErrUploadInvalid,
+		},
+		{
+			name: "package not listed in changes",
+			uploads: map[string][]byte{
+				"app_1.0_amd64.deb":     app,
+				"tool_1.0_amd64.deb":    tool,
+				"app_1.0_amd64.changes": testChanges(map[string][]byte{"app_1.0_amd64.deb": app}),
+			},
+			wantErr: ErrUploadInvalid,
+		},
+		{
+			name:     "conflict with published file",
+			existing: map[string][]byte{"app_1.0_amd64.deb": other},
+			uploads:  map[string][]byte{"app_1.0_amd64.deb": app},
+			wantErr:  ErrUploadConflict,
+		},
+		{
+			name:     "same published file",
+			existing: map[string][]byte{"app_1.0_amd64.deb": app},
+			uploads:  map[string][]byte{"app_1.0_amd64.deb": app},
+			want:     []string{"app"},
+		},
+		{
+			name:    "not a package",
+			uploads: map[string][]byte{"app_1.0_amd64.deb": []byte("garbage")},
+			wantErr: ErrUploadInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "custom.iso")
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			for name, data := range tt.existing {
+				debtest.Write(t, dir, name, data)
+			}
+			r := NewRepoCustom(dir, slog.New(slog.NewTextHandler(io.Discard)), CustomOptions{})
+
+			uploads := make([]*Upload, 0, len(tt.uploads))
+			for _, name := range sortedKeys(tt.uploads) {
+				u, err := r.ReceiveUpload(name, bytes.NewReader(tt.uploads[name]))
+				if err != nil {
+					t.Fatalf("ReceiveUpload(%s) error = %v", name, err)
+				}
+				if filepath.Dir(u.tmpPath) != filepath.Join(dir, uploadDir) {
+					t.Errorf("temporary file %s outside %s", u.tmpPath, uploadDir)
+				}
+				uploads = append(uploads, u)
+			}
+
+			result, err := r.Publish(uploads)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Publish() error = %v, want %v", err, tt.wantErr)
+			}
+
+			got := make([]string, 0)
+			for _, p := range result.Packages {
+				for _, f := range p {
+					if f.Name == "Package" {
+						got = append(got, f.Value)
+					}
+				}
+			}
+			if len(got) != len(tt.want) || fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Publish() packages = %v, want %v", got, tt.want)
+			}
+
+			// Временные файлы удаляются в любом случае, а при ошибке ничего не публикуется
+			tmp, _ := os.ReadDir(filepath.Join(dir, uploadDir))
+			if len(tmp) != 0 {
+				t.Errorf("temporary files left: %v", tmp)
+			}
+			if tt.wantErr != nil && tt.existing == nil {
+				if _, err := os.Stat(filepath.Join(dir, "app_1.0_amd64.deb")); !os.IsNotExist(err) {
+					t.Errorf("package published despite error %v", tt.wantErr)
+				}
+			}
+		})
+	}
+}
+
+func TestRepoCustom_Publish_partial(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "custom.iso")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	changed := 0
+	r := NewRepoCustom(dir, nil, CustomOptions{OnChange: func(*RepoCustom) { changed++ }})
+
+	// Перенос второго пакета завершается ошибкой
+	failure := errors.New("disk failure")
+	renameFile = func(from, to string) error {
+		if filepath.Base(to) == "tool_1.0_amd64.deb" {
+			return failure
+		}
+		return os.Rename(from, to)
+	}
+	t.Cleanup(func() { renameFile = os.Rename })
+
+	uploads := make([]*Upload, 0, 2)
+	for _, name := range []string{"app_1.0_amd64.deb", "tool_1.0_amd64.deb"} {
+		fields := strings.Split(name, "_")
+		u, err := r.ReceiveUpload(name, bytes.NewReader(debtest.Package(t, fields[0], "1.0", "amd64", nil)))
+		if err != nil {
+			t.Fatalf("ReceiveUpload(%s) error = %v", name, err)
+		}
+		uploads = append(uploads, u)
+	}
+
+	result, err := r.Publish(uploads)
+	if !errors.Is(err, failure) {
+		t.Fatalf("Publish() error = %v, want %v", err, failure)
+	}
+	if len(result.Packages) != 1 || result.Packages[0][0] != (StanzaField{Name: "Package", Value: "app"}) {
+		t.Errorf("Publish() packages = %v, want app", result.Packages)
+	}
+
+	// Опубликованный пакет сразу попадает в индекс
+	if changed != 1 {
+		t.Errorf("OnChange called %d times, want 1", changed)
+	}
+	if _, err := FindPackage(context.Background(), r, "app", "1.0", "amd64"); err != nil {
+		t.Errorf("FindPackage(app) error = %v", err)
+	}
+	if _, err := FindPackage(context.Background(), r, "tool", "", ""); !errors.Is(err, ErrPackageNotFound) {
+		t.Errorf("FindPackage(tool) error = %v, want %v", err, ErrPackageNotFound)
+	}
+	if tmp, _ := os.ReadDir(filepath.Join(dir, uploadDir)); len(tmp) != 0 {
+		t.Errorf("temporary files left: %v", tmp)
+	}
+}
@@ -0,0 +1,157 @@
+package repo
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/kirsrus/iso2repo/internal/vuln"
+	"github.com/kirsrus/iso2repo/pkg/deb"
+)
+
+// VulnerablePackage пакет, подверженный уязвимостям.
+type VulnerablePackage struct {
+	CatalogEntry
+	Vulnerabilities []vuln.Match `json:"vulnerabilities"`
+}
+
+// VulnSummary сводка уязвимостей репозитория.
+type VulnSummary struct {
+	Repo string `json:"repo"`
+	// Количество пакетов в репозитории.
+	Total int `json:"total"`
+	// Количество пакетов, подверженных уязвимостям.
+	Vulnerable int `json:"vulnerable"`
+	// Количество различных неисправленных уязвимостей (CVE или, если их нет,
+	// идентификаторов бюллетеней).
+	CVEs int `json:"cves"`
+	// Количество неисправленных уязвимостей по важности.
+	Severity map[vuln.Severity]int `json:"severity"`
+}
+
+// VulnReport уязвимости пакетов репозитория.
+type VulnReport struct {
+	VulnSummary
+	Packages []VulnerablePackage `json:"packages"`
+}
+
+// Vulnerabilities сопоставляет пакеты репозитория repoName с базой уязвимостей db.
+func (m *Catalog) Vulnerabilities(db *vuln.Database, repoName string) (*VulnReport, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	repo, ok := m.repos[repoName]
+	if !ok {
+		return nil, errors.Wrapf(ErrPackageNotFound, "репозиторий %s", repoName)
+	}
+
+	return repo.vulnerabilities(db, repoName), nil
+}
+
+// VulnSummaries возвращает сводки уязвимостей всех репозиториев, упорядоченные по имени.
+func (m *Catalog) VulnSummaries(db *vuln.Database) []VulnSummary {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]VulnSummary, 0, len(m.repos))
+	for _, name := range sortedKeys(m.repos) {
+		result = append(result, m.repos[name].vulnerabilities(db, name).VulnSummary)
+	}
+
+	return result
+}
+
+// MatchVulnerabilities возвращает уязвимости из db, которым подвержен пакет e каталога.
+func (m *Catalog) MatchVulnerabilities(db *vuln.Database, e CatalogEntry) []vuln.Match {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	codename := ""
+	if repo, ok := m.repos[e.Repo]; ok {
+		codename = repo.codenames[e.Suite]
+	}
+
+	return db.Match(e.Name, e.Version, e.source, e.sourceVersion, codename)
+}
+
+// vulnerabilities сопоставляет пакеты репозитория с базой уязвимостей db.
+func (m *catalogRepo) vulnerabilities(db *vuln.Database, repoName string) *VulnReport {
+	report := &VulnReport{
+		VulnSummary: VulnSummary{
+			Repo:     repoName,
+			Total:    len(m.entries),
+			Severity: make(map[vuln.Severity]int),
+		},
+		Packages: make([]VulnerablePackage, 0),
+	}
+
+	// Важность каждой уязвимости: CVE, упомянутая в нескольких бюллетенях, считается один раз
+	open := make(map[string]vuln.Severity)
+	for _, e := range m.entries {
+		matches := db.Match(e.Name, e.Version, e.source, e.sourceVersion, m.codenames[e.Suite])
+		if len(matches) == 0 {
+			continue
+		}
+		report.Packages = append(report.Packages, VulnerablePackage{CatalogEntry: e, Vulnerabilities: matches})
+
+		for _, match := range matches {
+			keys := match.CVEs
+			if len(keys) == 0 {
+				keys = []string{match.ID}
+			}
+			for _, key := range keys {
+				if s, ok := open[key]; !ok || match.Severity.Rank() > s.Rank() {
+					open[key] = match.Severity
+				}
+			}
+		}
+	}
+
+	report.Vulnerable = len(report.Packages)
+	report.CVEs = len(open)
+	for _, s := range open {
+		report.Severity[s]++
+	}
+	for _, s := range vuln.Severities {
+		if _, ok := report.Severity[s]; !ok {
+			report.Severity[s] = 0
+		}
+	}
+
+	sort.SliceStable(report.Packages, func(i, j int) bool {
+		return catalogEntryLess(report.Packages[i].CatalogEntry, report.Packages[j].CatalogEntry)
+	})
+
+	return report
+}
+
+// packageSource возвращает исходный пакет и его версию из поля Source ("имя" или
+// "имя (версия)"). Без поля Source исходный пакет совпадает с бинарным.
+func packageSource(meta *deb.PackageMeta) (string, string) {
+	source := meta.Extra["Source"]
+	if source == "" {
+		return meta.Package, meta.Version
+	}
+
+	name, version, _ := strings.Cut(source, " ")
+	if version = strings.Trim(version, " ()"); version == "" {
+		version = meta.Version
+	}
+
+	return name, version
+}
+
+// releaseCodename возвращает кодовое имя выпуска дистрибутива dist (bookworm для
+// bookworm-security, stable с Codename: bookworm и т.п.) для сопоставления с базами
+// уязвимостей.
+func releaseCodename(dist string, release *deb.Release) string {
+	codename := release.Codename
+	if codename == "" {
+		codename = dist
+	}
+	if i := strings.IndexAny(codename, "-/"); i > 0 {
+		codename = codename[:i]
+	}
+
+	return strings.ToLower(codename)
+}
@@ -0,0 +1,96 @@
+package repo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/kirsrus/iso2repo/internal/vuln"
+	"github.com/kirsrus/iso2repo/pkg/deb"
+)
+
+func TestCatalog_Vulnerabilities(t *testing.T) {
+	catalog := newTestCatalog(nil)
+	r := newCatalogRepo(nil)
+	r.codenames["stable"] = "bookworm"
+	for _, e := range []CatalogEntry{
+		{Name: "libssl3", Version: "3.0.11-1~deb12u1", source: "openssl", sourceVersion: "3.0.11-1~deb12u1"},
+		{Name: "openssl", Version: "3.0.13-1~deb12u1", source: "openssl", sourceVersion: "3.0.13-1~deb12u1"},
+		{Name: "zlib1g", Version: "1:1.2.13.dfsg-1", source: "zlib", sourceVersion: "1:1.2.13.dfsg-1"},
+		{Name: "bash", Version: "5.2.15-2", source: "bash", sourceVersion: "5.2.15-2"},
+	} {
+		e.Arch, e.Repo, e.Suite = "amd64", "a.iso", "stable"
+		r.add(e, nil)
+	}
+	catalog.repos["a.iso"] = r
+
+	db := vuln.NewDatabase(&vuln.Feed{Name: "feed", Advisories: []vuln.Advisory{
+		{ID: "CVE-1", CVEs: []string{"CVE-1"}, Severity: vuln.SeverityHigh, Affected: []vuln.Affected{
+			{Package: "openssl", Release: "bookworm", Fixed: "3.0.13-1~deb12u1"},
+			{Package: "openssl", Release: "sid", Fixed: "3.1.4-1"},
+		}},
+		{ID: "DSA-1", CVEs: []string{"CVE-1", "CVE-2"}, Severity: vuln.SeverityMedium, Affected: []vuln.Affected{
+			{Package: "libssl3", Fixed: "3.0.12"},
+		}},
+		{ID: "TEMP-1", Severity: vuln.SeverityLow, Affected: []vuln.Affected{{Package: "zlib", Release: "bookworm"}}},
+	}})
+
+	report, err := catalog.Vulnerabilities(db, "a.iso")
+	if err != nil {
+		t.Fatalf("Vulnerabilities() error = %v", err)
+	}
+
+	got := make([]string, 0)
+	for _, p := range report.Packages {
+		for _, m := range p.Vulnerabilities {
+			got = append(got, p.Name+" "+m.ID)
+		}
+	}
+	want := []string{"libssl3 CVE-1", "libssl3 DSA-1", "zlib1g TEMP-1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Vulnerabilities() = %v, want %v", got, want)
+	}
+	if report.Total != 4 || report.Vulnerable != 2 || report.CVEs != 3 {
+		t.Errorf("Vulnerabilities() total = %d, vulnerable = %d, cves = %d", report.Total, report.Vulnerable, report.CVEs)
+	}
+	wantSeverity := map[vuln.Severity]int{
+		vuln.SeverityCritical: 0, vuln.SeverityHigh: 1, vuln.SeverityMedium: 1,
+		vuln.SeverityLow: 1, vuln.SeverityNegligible: 0, vuln.SeverityUnknown: 0,
+	}
+	if !reflect.DeepEqual(report.Severity, wantSeverity) {
+		t.Errorf("Vulnerabilities() severity = %v, want %v", report.Severity, wantSeverity)
+	}
+
+	if _, err := catalog.Vulnerabilities(db, "b.iso"); !errors.Is(err, ErrPackageNotFound) {
+		t.Errorf("Vulnerabilities() unknown repo error = %v", err)
+	}
+}
+
+func TestPackageSource(t *testing.T) {
+	tests := []struct {
+		name                    string
+		meta                    deb.PackageMeta
+		wantSource, wantVersion string
+	}{
+		{name: "no source", meta: deb.PackageMeta{Package: "bash", Version: "5.2"}, wantSource: "bash", wantVersion: "5.2"},
+		{
+			name:       "source name",
+			meta:       deb.PackageMeta{Package: "libssl3", Version: "3.0.11-1", Extra: map[string]string{"Source": "openssl"}},
+			wantSource: "openssl", wantVersion: "3.0.11-1",
+		},
+		{
+			name:       "binNMU",
+			meta:       deb.PackageMeta{Package: "binpkg", Version: "1.0-1+b1", Extra: map[string]string{"Source": "srcpkg (1.0-1)"}},
+			wantSource: "srcpkg", wantVersion: "1.0-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, version := packageSource(&tt.meta)
+			if source != tt.wantSource || version != tt.wantVersion {
+				t.Errorf("packageSource() = %s %s, want %s %s", source, version, tt.wantSource, tt.wantVersion)
+			}
+		})
+	}
+}
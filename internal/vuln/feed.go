@@ -0,0 +1,501 @@
+package vuln
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/kirsrus/iso2repo/pkg/deb"
+)
+
+// ErrInvalidFeed файл не является базой уязвимостей известного формата.
+var ErrInvalidFeed = errors.New("некорректная база уязвимостей")
+
+// ErrFeedNotFound база уязвимостей не найдена в хранилище.
+var ErrFeedNotFound = errors.New("база уязвимостей не найдена")
+
+const (
+	// FormatDebian данные Debian Security Tracker (security-tracker.debian.org/tracker/data/json).
+	FormatDebian = "debian"
+	// FormatOVAL определения OVAL (Debian, Ubuntu и производные).
+	FormatOVAL = "oval"
+	// FormatOSV выгрузка OSV: один бюллетень, массив или zip-архив файлов JSON.
+	FormatOSV = "osv"
+)
+
+// osvEcosystems экосистемы OSV, версии пакетов которых сравниваются по правилам dpkg.
+var osvEcosystems = map[string]bool{
+	"Debian": true,
+	"Ubuntu": true,
+}
+
+// osvReleases кодовые имена выпусков экосистем OSV ("Debian:12", "Ubuntu:22.04:LTS").
+var osvReleases = map[string]string{
+	"Debian:8":     "jessie",
+	"Debian:9":     "stretch",
+	"Debian:10":    "buster",
+	"Debian:11":    "bullseye",
+	"Debian:12":    "bookworm",
+	"Debian:13":    "trixie",
+	"Ubuntu:16.04": "xenial",
+	"Ubuntu:18.04": "bionic",
+	"Ubuntu:20.04": "focal",
+	"Ubuntu:22.04": "jammy",
+	"Ubuntu:24.04": "noble",
+}
+
+// Parse читает базу уязвимостей name, определяя формат по содержимому.
+func Parse(name string, data []byte) (*Feed, error) {
+	var (
+		feed *Feed
+		err  error
+	)
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		feed, err = parseOSVZip(data)
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		feed, err = parseOVAL(trimmed)
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		feed, err = parseOSVList(trimmed)
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		feed, err = parseJSON(trimmed)
+	default:
+		err = errors.New("неизвестный формат")
+	}
+	if err != nil {
+		return nil, errors.Wrapf(ErrInvalidFeed, "%s: %v", name, err)
+	}
+
+	feed.Name = name
+	return feed, nil
+}
+
+// parseJSON разбирает объект JSON: бюллетень OSV или данные Debian Security Tracker.
+func parseJSON(data []byte) (*Feed, error) {
+	var probe struct {
+		ID       string          `json:"id"`
+		Affected json.RawMessage `json:"affected"`
+	}
+	if err := json.Unmarshal(data, &probe); err == nil && probe.ID != "" && probe.Affected != nil {
+		return parseOSVList(append(append([]byte("["), data...), ']'))
+	}
+
+	return parseDebian(data)
+}
+
+// debianIssue описание уязвимости в данных Debian Security Tracker.
+type debianIssue struct {
+	Description string `json:"description"`
+	Releases    map[string]struct {
+		Status       string `json:"status"`
+		FixedVersion string `json:"fixed_version"`
+		Urgency      string `json:"urgency"`
+	} `json:"releases"`
+}
+
+// parseDebian разбирает данные Debian Security Tracker: исходный пакет → уязвимость →
+// выпуск. Уязвимость со статусом open или undetermined затрагивает все версии выпуска.
+func parseDebian(data []byte) (*Feed, error) {
+	var tracker map[string]map[string]debianIssue
+	if err := json.Unmarshal(data, &tracker); err != nil {
+		return nil, err
+	}
+
+	feed := &Feed{Format: FormatDebian, Advisories: make([]Advisory, 0)}
+	byID := make(map[string]int)
+	for _, pkg := range sortedKeys(tracker) {
+		for _, id := range sortedKeys(tracker[pkg]) {
+			issue := tracker[pkg][id]
+			i, ok := byID[id]
+			if !ok {
+				i = len(feed.Advisories)
+				byID[id] = i
+				a := Advisory{ID: id, Summary: issue.Description, Severity: SeverityUnknown}
+				if strings.HasPrefix(id, "CVE-") {
+					a.CVEs = []string{id}
+				}
+				feed.Advisories = append(feed.Advisories, a)
+			}
+			a := &feed.Advisories[i]
+
+			for _, release := range sortedKeys(issue.Releases) {
+				r := issue.Releases[release]
+				if s := ParseSeverity(r.Urgency); s.Rank() > a.Severity.Rank() {
+					a.Severity = s
+				}
+				aff := Affected{Package: pkg, Release: release}
+				switch r.Status {
+				case "resolved":
+					if r.FixedVersion == "" {
+						continue
+					}
+					aff.Fixed = r.FixedVersion
+				case "open", "undetermined":
+				default:
+					continue
+				}
+				a.Affected = append(a.Affected, aff)
+			}
+		}
+	}
+
+	// Уязвимости, не затрагивающие ни один выпуск, не нужны
+	advisories := feed.Advisories[:0]
+	for _, a := range feed.Advisories {
+		if len(a.Affected) > 0 {
+			advisories = append(advisories, a)
+		}
+	}
+	feed.Advisories = advisories
+
+	return feed, nil
+}
+
+// osvAdvisory бюллетень в формате OSV (ossf.github.io/osv-schema).
+type osvAdvisory struct {
+	ID       string   `json:"id"`
+	Aliases  []string `json:"aliases"`
+	Summary  string   `json:"summary"`
+	Details  string   `json:"details"`
+	Affected []struct {
+		Package struct {
+			Ecosystem string `json:"ecosystem"`
+			Name      string `json:"name"`
+		} `json:"package"`
+		Ranges []struct {
+			Type   string              `json:"type"`
+			Events []map[string]string `json:"events"`
+		} `json:"ranges"`
+		Versions          []string       `json:"versions"`
+		EcosystemSpecific map[string]any `json:"ecosystem_specific"`
+		DatabaseSpecific  map[string]any `json:"database_specific"`
+	} `json:"affected"`
+	DatabaseSpecific map[string]any `json:"database_specific"`
+}
+
+// parseOSVZip разбирает zip-архив бюллетеней OSV (по одному в файле .json).
+func parseOSVZip(data []byte) (*Feed, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	feed := &Feed{Format: FormatOSV, Advisories: make([]Advisory, 0)}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || !strings.HasSuffix(f.Name, ".json") {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s", f.Name)
+		}
+		var a osvAdvisory
+		err = json.NewDecoder(r).Decode(&a)
+		r.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s", f.Name)
+		}
+		if advisory, ok := a.advisory(); ok {
+			feed.Advisories = append(feed.Advisories, advisory)
+		}
+	}
+
+	return feed, nil
+}
+
+// parseOSVList разбирает массив бюллетеней OSV.
+func parseOSVList(data []byte) (*Feed, error) {
+	var list []osvAdvisory
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+
+	feed := &Feed{Format: FormatOSV, Advisories: make([]Advisory, 0, len(list))}
+	for _, a := range list {
+		if advisory, ok := a.advisory(); ok {
+			feed.Advisories = append(feed.Advisories, advisory)
+		}
+	}
+
+	return feed, nil
+}
+
+// advisory преобразует бюллетень OSV, оставляя пакеты экосистем osvEcosystems.
+// Возвращает false, если таких пакетов нет.
+func (a *osvAdvisory) advisory() (Advisory, bool) {
+	result := Advisory{ID: a.ID, Summary: a.Summary, Severity: ParseSeverity(stringField(a.DatabaseSpecific, "severity"))}
+	if result.Summary == "" {
+		result.Summary, _, _ = strings.Cut(a.Details, "\n")
+	}
+	for _, id := range append([]string{a.ID}, a.Aliases...) {
+		if strings.HasPrefix(id, "CVE-") && !containsString(result.CVEs, id) {
+			result.CVEs = append(result.CVEs, id)
+		}
+	}
+
+	for _, aff := range a.Affected {
+		ecosystem, version, _ := strings.Cut(aff.Package.Ecosystem, ":")
+		if !osvEcosystems[ecosystem] || aff.Package.Name == "" {
+			continue
+		}
+		version, _, _ = strings.Cut(version, ":")
+		release := osvReleases[ecosystem+":"+version]
+		for _, field := range []string{"urgency", "severity"} {
+			for _, m := range []map[string]any{aff.EcosystemSpecific, aff.DatabaseSpecific} {
+				if s := ParseSeverity(stringField(m, field)); s.Rank() > result.Severity.Rank() {
+					result.Severity = s
+				}
+			}
+		}
+
+		ranges := 0
+		for _, r := range aff.Ranges {
+			if r.Type != "ECOSYSTEM" {
+				continue
+			}
+			for _, span := range osvSpans(r.Events) {
+				span.Package = aff.Package.Name
+				span.Release = release
+				result.Affected = append(result.Affected, span)
+				ranges++
+			}
+		}
+		if ranges == 0 && len(aff.Versions) > 0 {
+			result.Affected = append(result.Affected, Affected{Package: aff.Package.Name, Release: release, Versions: aff.Versions})
+		}
+	}
+
+	return result, len(result.Affected) > 0
+}
+
+// osvSpans разбивает события диапазона OSV на отрезки уязвимых версий: от introduced
+// до ближайшего следующего fixed или last_affected.
+func osvSpans(events []map[string]string) []Affected {
+	type event struct{ kind, version string }
+	list := make([]event, 0, len(events))
+	for _, e := range events {
+		for _, kind := range []string{"introduced", "fixed", "last_affected"} {
+			if v, ok := e[kind]; ok {
+				list = append(list, event{kind, v})
+			}
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].version == "0" || list[j].version == "0" {
+			return list[i].version == "0" && list[j].version != "0"
+		}
+		return deb.CompareVersions(list[i].version, list[j].version) < 0
+	})
+
+	result := make([]Affected, 0)
+	var open *Affected
+	for _, e := range list {
+		switch e.kind {
+		case "introduced":
+			if open == nil {
+				open = &Affected{}
+				if e.version != "0" {
+					open.Introduced = e.version
+				}
+			}
+		case "fixed":
+			if open != nil {
+				open.Fixed = e.version
+				result = append(result, *open)
+				open = nil
+			}
+		case "last_affected":
+			if open != nil {
+				open.LastAffected = e.version
+				result = append(result, *open)
+				open = nil
+			}
+		}
+	}
+	if open != nil {
+		result = append(result, *open)
+	}
+
+	return result
+}
+
+// ovalDefinitions документ OVAL: определения уязвимостей и проверки версий пакетов
+// dpkginfo, на которые они ссылаются.
+type ovalDefinitions struct {
+	Definitions []struct {
+		ID       string `xml:"id,attr"`
+		Class    string `xml:"class,attr"`
+		Metadata struct {
+			Title       string `xml:"title"`
+			Description string `xml:"description"`
+			References  []struct {
+				Source string `xml:"source,attr"`
+				RefID  string `xml:"ref_id,attr"`
+			} `xml:"reference"`
+			Severity []string `xml:"advisory>severity"`
+		} `xml:"metadata"`
+		Criteria ovalCriteria `xml:"criteria"`
+	} `xml:"definitions>definition"`
+	Tests []struct {
+		ID     string `xml:"id,attr"`
+		Object struct {
+			Ref string `xml:"object_ref,attr"`
+		} `xml:"object"`
+		State struct {
+			Ref string `xml:"state_ref,attr"`
+		} `xml:"state"`
+	} `xml:"tests>dpkginfo_test"`
+	Objects []struct {
+		ID   string `xml:"id,attr"`
+		Name struct {
+			Value  string `xml:",chardata"`
+			VarRef string `xml:"var_ref,attr"`
+		} `xml:"name"`
+	} `xml:"objects>dpkginfo_object"`
+	States []struct {
+		ID  string `xml:"id,attr"`
+		EVR struct {
+			Value     string `xml:",chardata"`
+			Operation string `xml:"operation,attr"`
+		} `xml:"evr"`
+	} `xml:"states>dpkginfo_state"`
+	Variables []struct {
+		ID     string   `xml:"id,attr"`
+		Values []string `xml:"value"`
+	} `xml:"variables>constant_variable"`
+}
+
+// ovalCriteria условия определения OVAL: ссылки на проверки и вложенные условия.
+type ovalCriteria struct {
+	Criterions []struct {
+		TestRef string `xml:"test_ref,attr"`
+	} `xml:"criterion"`
+	Criteria []ovalCriteria `xml:"criteria"`
+}
+
+// testRefs возвращает ссылки на все проверки условий, включая вложенные.
+func (c *ovalCriteria) testRefs() []string {
+	result := make([]string, 0, len(c.Criterions))
+	for _, criterion := range c.Criterions {
+		result = append(result, criterion.TestRef)
+	}
+	for i := range c.Criteria {
+		result = append(result, c.Criteria[i].testRefs()...)
+	}
+
+	return result
+}
+
+// parseOVAL разбирает определения OVAL класса vulnerability и patch. Каждая проверка
+// dpkginfo определения считается отдельным диапазоном: пакет уязвим, если его версия
+// меньше указанной в состоянии проверки ("less than"), а без состояния — в любой
+// версии. Проверки выпуска ОС и логические операторы условий не учитываются: файлы
+// OVAL выпускаются для каждого выпуска отдельно.
+func parseOVAL(data []byte) (*Feed, error) {
+	var doc ovalDefinitions
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Definitions) == 0 {
+		return nil, errors.New("нет определений OVAL")
+	}
+
+	variables := make(map[string][]string)
+	for _, v := range doc.Variables {
+		variables[v.ID] = v.Values
+	}
+	objects := make(map[string][]string)
+	for _, o := range doc.Objects {
+		if o.Name.VarRef != "" {
+			objects[o.ID] = variables[o.Name.VarRef]
+		} else if name := strings.TrimSpace(o.Name.Value); name != "" {
+			objects[o.ID] = []string{name}
+		}
+	}
+	states := make(map[string]string)
+	for _, s := range doc.States {
+		if s.EVR.Operation == "less than" {
+			states[s.ID] = strings.TrimSpace(s.EVR.Value)
+		}
+	}
+	type test struct{ object, state string }
+	tests := make(map[string]test)
+	for _, t := range doc.Tests {
+		tests[t.ID] = test{t.Object.Ref, t.State.Ref}
+	}
+
+	feed := &Feed{Format: FormatOVAL, Advisories: make([]Advisory, 0, len(doc.Definitions))}
+	for _, d := range doc.Definitions {
+		if d.Class != "vulnerability" && d.Class != "patch" {
+			continue
+		}
+		a := Advisory{ID: d.ID, Summary: strings.TrimSpace(d.Metadata.Description), Severity: SeverityUnknown}
+		for _, ref := range d.Metadata.References {
+			if ref.Source == "CVE" && !containsString(a.CVEs, ref.RefID) {
+				a.CVEs = append(a.CVEs, ref.RefID)
+			}
+		}
+		if len(a.CVEs) > 0 && d.Class == "vulnerability" {
+			a.ID = a.CVEs[0]
+		} else if title, _, _ := strings.Cut(strings.TrimSpace(d.Metadata.Title), " "); title != "" {
+			a.ID = strings.TrimSuffix(title, ":")
+		}
+		for _, s := range d.Metadata.Severity {
+			if severity := ParseSeverity(s); severity.Rank() > a.Severity.Rank() {
+				a.Severity = severity
+			}
+		}
+
+		for _, ref := range d.Criteria.testRefs() {
+			t, ok := tests[ref]
+			if !ok {
+				continue
+			}
+			fixed, ok := states[t.state]
+			if t.state != "" && !ok {
+				continue
+			}
+			for _, name := range objects[t.object] {
+				a.Affected = append(a.Affected, Affected{Package: name, Fixed: fixed})
+			}
+		}
+		if len(a.Affected) > 0 {
+			feed.Advisories = append(feed.Advisories, a)
+		}
+	}
+
+	return feed, nil
+}
+
+// stringField возвращает строковое значение key из m.
+func stringField(m map[string]any, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+// containsString проверяет наличие строки s в list.
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sortedKeys возвращает отсортированные ключи m.
+func sortedKeys[V any](m map[string]V) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+
+	return result
+}
@@ -0,0 +1,232 @@
+package vuln
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const testDebianFeed = `{
+  "openssl": {
+    "CVE-2023-5678": {
+      "description": "Excessive time spent in DH check",
+      "releases": {
+        "bookworm": {"status": "resolved", "fixed_version": "3.0.13-1~deb12u1", "urgency": "low"},
+        "bullseye": {"status": "open", "urgency": "not yet assigned"},
+        "sid": {"status": "resolved", "fixed_version": "3.1.4-1", "urgency": "medium"}
+      }
+    },
+    "CVE-2010-0001": {
+      "releases": {"bookworm": {"status": "resolved", "fixed_version": "0", "urgency": "unimportant"}}
+    }
+  },
+  "zlib": {
+    "TEMP-0000000-ABCDEF": {
+      "releases": {"bookworm": {"status": "undetermined", "urgency": "unimportant"}}
+    }
+  }
+}`
+
+const testOVALFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<oval_definitions xmlns="http://oval.mitre.org/XMLSchema/oval-definitions-5"
+    xmlns:linux-def="http://oval.mitre.org/XMLSchema/oval-definitions-5#linux">
+  <definitions>
+    <definition class="patch" id="oval:com.ubuntu.jammy:def:1" version="1">
+      <metadata>
+        <title>USN-6543-1 -- OpenSSL vulnerabilities</title>
+        <reference source="USN" ref_id="USN-6543-1"/>
+        <reference source="CVE" ref_id="CVE-2023-5678"/>
+        <description>Several security issues were fixed in OpenSSL.</description>
+        <advisory><severity>Medium</severity></advisory>
+      </metadata>
+      <criteria operator="AND">
+        <criterion test_ref="oval:com.ubuntu.jammy:tst:100" comment="Ubuntu 22.04 is installed"/>
+        <criteria operator="OR">
+          <criterion test_ref="oval:com.ubuntu.jammy:tst:1" comment="openssl packages"/>
+        </criteria>
+      </criteria>
+    </definition>
+    <definition class="vulnerability" id="oval:org.debian:def:2" version="1">
+      <metadata>
+        <title>CVE-2024-0001 zlib</title>
+        <reference source="CVE" ref_id="CVE-2024-0001"/>
+        <description>zlib issue</description>
+      </metadata>
+      <criteria><criterion test_ref="oval:org.debian:tst:2" comment="zlib is installed"/></criteria>
+    </definition>
+    <definition class="inventory" id="oval:org.debian:def:3" version="1">
+      <metadata><title>Debian 12</title></metadata>
+    </definition>
+  </definitions>
+  <tests>
+    <linux-def:dpkginfo_test id="oval:com.ubuntu.jammy:tst:1" check="at least one">
+      <linux-def:object object_ref="oval:com.ubuntu.jammy:obj:1"/>
+      <linux-def:state state_ref="oval:com.ubuntu.jammy:ste:1"/>
+    </linux-def:dpkginfo_test>
+    <linux-def:dpkginfo_test id="oval:org.debian:tst:2" check="all">
+      <linux-def:object object_ref="oval:org.debian:obj:2"/>
+    </linux-def:dpkginfo_test>
+  </tests>
+  <objects>
+    <linux-def:dpkginfo_object id="oval:com.ubuntu.jammy:obj:1" version="1">
+      <linux-def:name var_ref="oval:com.ubuntu.jammy:var:1" var_check="at least one"/>
+    </linux-def:dpkginfo_object>
+    <linux-def:dpkginfo_object id="oval:org.debian:obj:2" version="1">
+      <linux-def:name>zlib1g</linux-def:name>
+    </linux-def:dpkginfo_object>
+  </objects>
+  <states>
+    <linux-def:dpkginfo_state id="oval:com.ubuntu.jammy:ste:1" version="1">
+      <linux-def:evr datatype="debian_evr_string" operation="less than">0:3.0.2-0ubuntu1.12</linux-def:evr>
+    </linux-def:dpkginfo_state>
+  </states>
+  <variables>
+    <constant_variable id="oval:com.ubuntu.jammy:var:1" version="1" datatype="string">
+      <value>libssl3</value>
+      <value>openssl</value>
+    </constant_variable>
+  </variables>
+</oval_definitions>`
+
+const testOSVAdvisory = `{
+  "id": "DSA-5532-1",
+  "aliases": ["CVE-2023-5363"],
+  "details": "Incorrect cipher key length handling\nMore details.",
+  "affected": [
+    {
+      "package": {"ecosystem": "Debian:12", "name": "openssl"},
+      "ranges": [{"type": "ECOSYSTEM", "events": [{"fixed": "3.0.11-1~deb12u2"}, {"introduced": "0"}]}],
+      "ecosystem_specific": {"urgency": "high"}
+    },
+    {
+      "package": {"ecosystem": "PyPI", "name": "openssl"},
+      "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}]}]
+    }
+  ]
+}`
+
+func TestParse(t *testing.T) {
+	var zipped bytes.Buffer
+	zw := zip.NewWriter(&zipped)
+	w, _ := zw.Create("DSA-5532-1.json")
+	_, _ = w.Write([]byte(testOSVAdvisory))
+	w, _ = zw.Create("PYSEC-1.json")
+	_, _ = w.Write([]byte(`{"id": "PYSEC-1", "affected": [{"package": {"ecosystem": "PyPI", "name": "requests"}}]}`))
+	_ = zw.Close()
+
+	osv := []Advisory{{
+		ID:       "DSA-5532-1",
+		CVEs:     []string{"CVE-2023-5363"},
+		Summary:  "Incorrect cipher key length handling",
+		Severity: SeverityHigh,
+		Affected: []Affected{{Package: "openssl", Release: "bookworm", Fixed: "3.0.11-1~deb12u2"}},
+	}}
+
+	tests := []struct {
+		name       string
+		data       []byte
+		wantFormat string
+		want       []Advisory
+		wantErr    bool
+	}{
+		{
+			name:       "debian security tracker",
+			data:       []byte(testDebianFeed),
+			wantFormat: FormatDebian,
+			want: []Advisory{
+				{
+					ID:       "CVE-2010-0001",
+					CVEs:     []string{"CVE-2010-0001"},
+					Severity: SeverityNegligible,
+					Affected: []Affected{{Package: "openssl", Release: "bookworm", Fixed: "0"}},
+				},
+				{
+					ID:       "CVE-2023-5678",
+					CVEs:     []string{"CVE-2023-5678"},
+					Summary:  "Excessive time spent in DH check",
+					Severity: SeverityMedium,
+					Affected: []Affected{
+						{Package: "openssl", Release: "bookworm", Fixed: "3.0.13-1~deb12u1"},
+						{Package: "openssl", Release: "bullseye"},
+						{Package: "openssl", Release: "sid", Fixed: "3.1.4-1"},
+					},
+				},
+				{
+					ID:       "TEMP-0000000-ABCDEF",
+					Severity: SeverityNegligible,
+					Affected: []Affected{{Package: "zlib", Release: "bookworm"}},
+				},
+			},
+		},
+		{
+			name:       "oval",
+			data:       []byte(testOVALFeed),
+			wantFormat: FormatOVAL,
+			want: []Advisory{
+				{
+					ID:       "USN-6543-1",
+					CVEs:     []string{"CVE-2023-5678"},
+					Summary:  "Several security issues were fixed in OpenSSL.",
+					Severity: SeverityMedium,
+					Affected: []Affected{
+						{Package: "libssl3", Fixed: "0:3.0.2-0ubuntu1.12"},
+						{Package: "openssl", Fixed: "0:3.0.2-0ubuntu1.12"},
+					},
+				},
+				{
+					ID:       "CVE-2024-0001",
+					CVEs:     []string{"CVE-2024-0001"},
+					Summary:  "zlib issue",
+					Severity: SeverityUnknown,
+					Affected: []Affected{{Package: "zlib1g"}},
+				},
+			},
+		},
+		{name: "osv advisory", data: []byte(testOSVAdvisory), wantFormat: FormatOSV, want: osv},
+		{name: "osv list", data: []byte("[" + testOSVAdvisory + "]"), wantFormat: FormatOSV, want: osv},
+		{name: "osv zip", data: zipped.Bytes(), wantFormat: FormatOSV, want: osv},
+		{name: "unknown format", data: []byte("Package: foo\n"), wantErr: true},
+		{name: "broken json", data: []byte(`{"openssl": [`), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := Parse("feed", tt.data)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidFeed) {
+					t.Fatalf("Parse() error = %v, want ErrInvalidFeed", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if feed.Format != tt.wantFormat {
+				t.Errorf("Parse() format = %s, want %s", feed.Format, tt.wantFormat)
+			}
+			if !reflect.DeepEqual(feed.Advisories, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", feed.Advisories, tt.want)
+			}
+		})
+	}
+}
+
+func TestOSVSpans(t *testing.T) {
+	got := osvSpans([]map[string]string{
+		{"introduced": "0"},
+		{"fixed": "1.0-2"},
+		{"introduced": "2.0-1"},
+		{"last_affected": "2.1-1"},
+		{"introduced": "3.0-1"},
+	})
+	want := []Affected{
+		{Fixed: "1.0-2"},
+		{Introduced: "2.0-1", LastAffected: "2.1-1"},
+		{Introduced: "3.0-1"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("osvSpans() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,232 @@
+package vuln
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"golang.org/x/exp/slog"
+)
+
+// FeedInfo сведения о файле базы уязвимостей.
+type FeedInfo struct {
+	Name string `json:"name"`
+	// Формат: debian, oval или osv; пустой, если файл не удалось разобрать.
+	Format string `json:"format,omitempty"`
+	// Количество уязвимостей, затрагивающих пакеты.
+	Advisories int       `json:"advisories"`
+	Size       int64     `json:"size"`
+	Modified   time.Time `json:"modified"`
+	// Ошибка разбора файла.
+	Error string `json:"error,omitempty"`
+}
+
+// storeFeed файл базы, прочитанный хранилищем.
+type storeFeed struct {
+	info FeedInfo
+	feed *Feed
+}
+
+// Store хранилище баз уязвимостей: каждый файл директории — отдельная база. Файлы
+// можно импортировать через Import или положить в директорию вручную: изменения
+// подхватываются при следующем обращении. Безопасно для параллельного использования.
+// Нулевое значение (nil) допустимо — хранилище тогда пусто.
+type Store struct {
+	log *slog.Logger
+	dir string
+
+	mu sync.Mutex
+	// Прочитанные файлы по имени
+	feeds map[string]*storeFeed
+	// Объединённая база; nil — требуется перестроить
+	db *Database
+}
+
+// NewStore создаёт хранилище баз уязвимостей в директории dir. Директория создаётся
+// при необходимости.
+func NewStore(dir string, log *slog.Logger) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, errors.Wrap(err, "не удалось создать директорию баз уязвимостей")
+	}
+
+	return &Store{
+		log:   log.With(slog.String("module", "vuln")),
+		dir:   dir,
+		feeds: make(map[string]*storeFeed),
+	}, nil
+}
+
+// Dir возвращает директорию хранилища.
+func (m *Store) Dir() string {
+	if m == nil {
+		return ""
+	}
+
+	return m.dir
+}
+
+// Import проверяет базу уязвимостей из r и сохраняет её в хранилище под именем name,
+// заменяя файл с тем же именем.
+func (m *Store) Import(name string, r io.Reader) (*FeedInfo, error) {
+	if m == nil {
+		return nil, errors.New("хранилище баз уязвимостей не настроено")
+	}
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return nil, errors.Wrapf(ErrInvalidFeed, "недопустимое имя файла %q", name)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "не удалось прочитать базу уязвимостей")
+	}
+	feed, err := Parse(name, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Запись через временный файл: читающий директорию не увидит файл наполовину
+	tmp, err := os.CreateTemp(m.dir, ".import-*")
+	if err != nil {
+		return nil, errors.Wrap(err, "не удалось создать временный файл")
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return nil, errors.Wrap(err, "не удалось записать базу уязвимостей")
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, errors.Wrap(err, "не удалось записать базу уязвимостей")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	target := filepath.Join(m.dir, name)
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return nil, errors.Wrap(err, "не удалось сохранить базу уязвимостей")
+	}
+	stat, err := os.Stat(target)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	f := &storeFeed{info: newFeedInfo(stat, feed), feed: feed}
+	m.feeds[name] = f
+	m.db = nil
+	m.log.Info("импортирована база уязвимостей", slog.String("name", name), slog.String("format", feed.Format), slog.Int("advisories", len(feed.Advisories)))
+
+	info := f.info
+	return &info, nil
+}
+
+// Database возвращает объединённую базу всех файлов хранилища и сведения о них,
+// перечитывая изменённые с прошлого обращения файлы.
+func (m *Store) Database() (*Database, []FeedInfo) {
+	if m == nil {
+		return NewDatabase(), []FeedInfo{}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		m.log.Warn("не удалось прочитать директорию баз уязвимостей", slog.String("dir", m.dir), slog.String("error", err.Error()))
+		entries = nil
+	}
+
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		stat, err := e.Info()
+		if err != nil {
+			continue
+		}
+		seen[e.Name()] = true
+
+		if f, ok := m.feeds[e.Name()]; ok && f.info.Size == stat.Size() && f.info.Modified.Equal(stat.ModTime()) {
+			continue
+		}
+		m.feeds[e.Name()] = m.load(stat)
+		m.db = nil
+	}
+	for name := range m.feeds {
+		if !seen[name] {
+			delete(m.feeds, name)
+			m.db = nil
+		}
+	}
+
+	feeds := make([]*Feed, 0, len(m.feeds))
+	infos := make([]FeedInfo, 0, len(m.feeds))
+	for _, name := range sortedKeys(m.feeds) {
+		f := m.feeds[name]
+		infos = append(infos, f.info)
+		if f.feed != nil {
+			feeds = append(feeds, f.feed)
+		}
+	}
+	if m.db == nil {
+		m.db = NewDatabase(feeds...)
+	}
+
+	return m.db, infos
+}
+
+// load читает и разбирает файл базы stat. Ошибка разбора сохраняется в сведениях о
+// файле, чтобы файл не перечитывался до изменения.
+func (m *Store) load(stat os.FileInfo) *storeFeed {
+	data, err := os.ReadFile(filepath.Join(m.dir, stat.Name()))
+	var feed *Feed
+	if err == nil {
+		feed, err = Parse(stat.Name(), data)
+	}
+	if err != nil {
+		m.log.Warn("база уязвимостей пропущена", slog.String("name", stat.Name()), slog.String("error", err.Error()))
+		info := newFeedInfo(stat, nil)
+		info.Error = err.Error()
+		return &storeFeed{info: info}
+	}
+
+	m.log.Debug("прочитана база уязвимостей", slog.String("name", stat.Name()), slog.String("format", feed.Format), slog.Int("advisories", len(feed.Advisories)))
+	return &storeFeed{info: newFeedInfo(stat, feed), feed: feed}
+}
+
+// newFeedInfo возвращает сведения о файле stat с базой feed (может быть nil).
+func newFeedInfo(stat os.FileInfo, feed *Feed) FeedInfo {
+	info := FeedInfo{Name: stat.Name(), Size: stat.Size(), Modified: stat.ModTime()}
+	if feed != nil {
+		info.Format = feed.Format
+		info.Advisories = len(feed.Advisories)
+	}
+
+	return info
+}
+
+// Remove удаляет файл базы name из хранилища.
+func (m *Store) Remove(name string) error {
+	if m == nil || name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return errors.Wrapf(ErrFeedNotFound, "%s", name)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.Remove(filepath.Join(m.dir, name)); err != nil {
+		if os.IsNotExist(err) {
+			return errors.Wrapf(ErrFeedNotFound, "%s", name)
+		}
+		return errors.Wrap(err, "не удалось удалить базу уязвимостей")
+	}
+	delete(m.feeds, name)
+	m.db = nil
+	m.log.Info("удалена база уязвимостей", slog.String("name", name))
+
+	return nil
+}
@@ -0,0 +1,246 @@
+// Package vuln сопоставляет пакеты с локальными (офлайн) базами уязвимостей:
+// данными Debian Security Tracker в JSON, определениями OVAL и выгрузками OSV.
+package vuln
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/kirsrus/iso2repo/pkg/deb"
+)
+
+// Severity важность уязвимости.
+type Severity string
+
+const (
+	// Критическая.
+	SeverityCritical Severity = "critical"
+	// Высокая.
+	SeverityHigh Severity = "high"
+	// Средняя.
+	SeverityMedium Severity = "medium"
+	// Низкая.
+	SeverityLow Severity = "low"
+	// Незначительная (unimportant в Debian).
+	SeverityNegligible Severity = "negligible"
+	// Не указана в базе.
+	SeverityUnknown Severity = "unknown"
+)
+
+// Severities уровни важности по убыванию.
+var Severities = []Severity{SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow, SeverityNegligible, SeverityUnknown}
+
+// Rank возвращает вес уровня важности: чем важнее, тем больше.
+func (s Severity) Rank() int {
+	for i, v := range Severities {
+		if v == s {
+			return len(Severities) - i
+		}
+	}
+
+	return 0
+}
+
+// ParseSeverity приводит обозначение важности из базы уязвимостей (urgency Debian,
+// severity Ubuntu и OSV) к одному из уровней Severity.
+func ParseSeverity(s string) Severity {
+	s = strings.ToLower(strings.TrimRight(strings.TrimSpace(s), "*"))
+	switch s {
+	case "critical":
+		return SeverityCritical
+	case "high", "important":
+		return SeverityHigh
+	case "medium", "moderate":
+		return SeverityMedium
+	case "low":
+		return SeverityLow
+	case "negligible", "unimportant":
+		return SeverityNegligible
+	}
+
+	return SeverityUnknown
+}
+
+// Affected диапазон уязвимых версий пакета.
+type Affected struct {
+	// Имя пакета (исходного или бинарного).
+	Package string
+	// Кодовое имя выпуска (bookworm), к которому относится диапазон; пустое — к любому.
+	Release string
+	// Первая уязвимая версия; пустая — все версии до Fixed.
+	Introduced string
+	// Версия, в которой уязвимость исправлена; пустая — не исправлена.
+	Fixed string
+	// Последняя уязвимая версия, если исправления нет.
+	LastAffected string
+	// Отдельные уязвимые версии. Если диапазон не задан, уязвимы только они.
+	Versions []string
+}
+
+// Affects проверяет, входит ли версия version в диапазон уязвимых.
+func (a *Affected) Affects(version string) bool {
+	for _, v := range a.Versions {
+		if deb.CompareVersions(v, version) == 0 {
+			return true
+		}
+	}
+	if len(a.Versions) > 0 && a.Introduced == "" && a.Fixed == "" && a.LastAffected == "" {
+		return false
+	}
+
+	if a.Introduced != "" && deb.CompareVersions(version, a.Introduced) < 0 {
+		return false
+	}
+	if a.Fixed != "" && deb.CompareVersions(version, a.Fixed) >= 0 {
+		return false
+	}
+	if a.LastAffected != "" && deb.CompareVersions(version, a.LastAffected) > 0 {
+		return false
+	}
+
+	return true
+}
+
+// Advisory уязвимость (бюллетень безопасности) из базы.
+type Advisory struct {
+	// Идентификатор в базе: CVE, DSA, USN и т.п.
+	ID string
+	// Идентификаторы CVE уязвимости (в том числе сам ID, если это CVE).
+	CVEs     []string
+	Summary  string
+	Severity Severity
+	Affected []Affected
+}
+
+// Feed база уязвимостей, прочитанная из одного файла.
+type Feed struct {
+	Name string
+	// Формат файла: debian, oval или osv.
+	Format     string
+	Advisories []Advisory
+}
+
+// Match уязвимость, которой подвержена версия пакета.
+type Match struct {
+	ID       string   `json:"id"`
+	CVEs     []string `json:"cves"`
+	Summary  string   `json:"summary,omitempty"`
+	Severity Severity `json:"severity"`
+	// Версия с исправлением; пустая — исправления нет.
+	Fixed string `json:"fixed,omitempty"`
+	// Имя файла базы.
+	Feed string `json:"feed"`
+}
+
+// advisoryRef уязвимость базы с её файлом.
+type advisoryRef struct {
+	feed     string
+	advisory *Advisory
+}
+
+// Database объединённые базы уязвимостей, проиндексированные по именам пакетов.
+// Только для чтения; безопасна для параллельного использования.
+type Database struct {
+	// Уязвимости, затрагивающие пакет, по его имени
+	byPackage map[string][]advisoryRef
+	// Выпуски, упомянутые в диапазонах уязвимостей
+	releases map[string]bool
+}
+
+// NewDatabase строит базу из файлов feeds.
+func NewDatabase(feeds ...*Feed) *Database {
+	result := &Database{
+		byPackage: make(map[string][]advisoryRef),
+		releases:  make(map[string]bool),
+	}
+	for _, f := range feeds {
+		for i := range f.Advisories {
+			a := &f.Advisories[i]
+			seen := make(map[string]bool)
+			for _, aff := range a.Affected {
+				if aff.Release != "" {
+					result.releases[aff.Release] = true
+				}
+				if seen[aff.Package] {
+					continue
+				}
+				seen[aff.Package] = true
+				result.byPackage[aff.Package] = append(result.byPackage[aff.Package], advisoryRef{feed: f.Name, advisory: a})
+			}
+		}
+	}
+
+	return result
+}
+
+// Match возвращает уязвимости, которым подвержена версия version бинарного пакета
+// name, собранного из исходного пакета source версии sourceVersion, в выпуске
+// release. Базы Debian указывают исходные пакеты, Ubuntu — бинарные, поэтому
+// проверяются оба имени. Если выпуск неизвестен базе (например, у пользовательского
+// репозитория), учитываются диапазоны всех выпусков. Результат упорядочен по убыванию
+// важности.
+func (m *Database) Match(name, version, source, sourceVersion, release string) []Match {
+	result := make([]Match, 0)
+	if m == nil {
+		return result
+	}
+
+	if !m.releases[release] {
+		release = ""
+	}
+	byID := make(map[string]int)
+	check := func(pkg, ver string) {
+		for _, ref := range m.byPackage[pkg] {
+			aff, ok := matchAffected(ref.advisory, pkg, ver, release)
+			if !ok {
+				continue
+			}
+			match := Match{
+				ID:       ref.advisory.ID,
+				CVEs:     ref.advisory.CVEs,
+				Summary:  ref.advisory.Summary,
+				Severity: ref.advisory.Severity,
+				Fixed:    aff.Fixed,
+				Feed:     ref.feed,
+			}
+			// Одна уязвимость из нескольких баз: берётся наибольшая важность
+			if i, ok := byID[match.ID]; ok {
+				if match.Severity.Rank() > result[i].Severity.Rank() {
+					result[i] = match
+				}
+				continue
+			}
+			byID[match.ID] = len(result)
+			result = append(result, match)
+		}
+	}
+
+	check(name, version)
+	if source != "" && source != name {
+		check(source, sourceVersion)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if ri, rj := result[i].Severity.Rank(), result[j].Severity.Rank(); ri != rj {
+			return ri > rj
+		}
+		return result[i].ID < result[j].ID
+	})
+
+	return result
+}
+
+// matchAffected ищет диапазон уязвимости a, в который входит версия version пакета
+// pkg выпуска release. Пустой release — любой выпуск.
+func matchAffected(a *Advisory, pkg, version, release string) (Affected, bool) {
+	for _, aff := range a.Affected {
+		if aff.Package != pkg || (release != "" && aff.Release != "" && aff.Release != release) {
+			continue
+		}
+		if aff.Affects(version) {
+			return aff, true
+		}
+	}
+
+	return Affected{}, false
+}
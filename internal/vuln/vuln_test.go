@@ -0,0 +1,87 @@
+package vuln
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAffected_Affects(t *testing.T) {
+	tests := []struct {
+		name     string
+		affected Affected
+		version  string
+		want     bool
+	}{
+		{name: "not fixed", affected: Affected{}, version: "1.0", want: true},
+		{name: "before fix", affected: Affected{Fixed: "1.0-2"}, version: "1.0-1", want: true},
+		{name: "fixed", affected: Affected{Fixed: "1.0-2"}, version: "1.0-2", want: false},
+		{name: "epoch", affected: Affected{Fixed: "0:1.0-2"}, version: "1:0.9", want: false},
+		{name: "not affected", affected: Affected{Fixed: "0"}, version: "0.1", want: false},
+		{name: "before introduced", affected: Affected{Introduced: "2.0", Fixed: "2.5"}, version: "1.9", want: false},
+		{name: "last affected", affected: Affected{LastAffected: "2.1"}, version: "2.1", want: true},
+		{name: "after last affected", affected: Affected{LastAffected: "2.1"}, version: "2.1+b1", want: false},
+		{name: "listed version", affected: Affected{Versions: []string{"1.0-1", "1.0-2"}}, version: "1.0-2", want: true},
+		{name: "unlisted version", affected: Affected{Versions: []string{"1.0-1"}}, version: "1.0-3", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.affected.Affects(tt.version); got != tt.want {
+				t.Errorf("Affects(%s) = %v, want %v", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDatabase_Match(t *testing.T) {
+	debian, err := Parse("debian.json", []byte(testDebianFeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	osv, err := Parse("osv.json", []byte(testOSVAdvisory))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := NewDatabase(debian, osv)
+
+	tests := []struct {
+		name                                         string
+		pkg, version, source, sourceVersion, release string
+		want                                         []string
+	}{
+		{
+			name: "binary from source", pkg: "libssl3", version: "3.0.11-1~deb12u1",
+			source: "openssl", sourceVersion: "3.0.11-1~deb12u1", release: "bookworm",
+			want: []string{"DSA-5532-1 high 3.0.11-1~deb12u2", "CVE-2023-5678 medium 3.0.13-1~deb12u1"},
+		},
+		{
+			name: "fixed in release", pkg: "openssl", version: "3.0.13-1~deb12u1", release: "bookworm",
+			want: []string{},
+		},
+		{
+			name: "open in other release", pkg: "openssl", version: "1.1.1w-0+deb11u1", release: "bullseye",
+			want: []string{"CVE-2023-5678 medium "},
+		},
+		{
+			name: "unknown release uses all ranges", pkg: "openssl", version: "3.1.0-1", release: "custom",
+			want: []string{"CVE-2023-5678 medium "},
+		},
+		{
+			name: "unknown release before any fix", pkg: "openssl", version: "3.0.11-1~deb12u1", release: "custom",
+			want: []string{"DSA-5532-1 high 3.0.11-1~deb12u2", "CVE-2023-5678 medium 3.0.13-1~deb12u1"},
+		},
+		{name: "not in database", pkg: "bash", version: "5.2", release: "bookworm", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			for _, m := range db.Match(tt.pkg, tt.version, tt.source, tt.sourceVersion, tt.release) {
+				got = append(got, m.ID+" "+string(m.Severity)+" "+m.Fixed)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -155,8 +155,12 @@ func (m *Watcher) collectFiles() (map[string]struct{}, error) {
 			return nil
 		}
 
-		// Пропускаем директории, нас интересуют только файлы.
+		// Пропускаем директории, нас интересуют только файлы. Скрытые директории
+		// (в том числе служебная .iso2repo) не отслеживаются вовсе.
 		if d.IsDir() {
+			if path != m.rootDir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 
@@ -0,0 +1,439 @@
+package web
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/kirsrus/iso2repo/internal/audit"
+	"github.com/kirsrus/iso2repo/internal/repo"
+	"github.com/kirsrus/iso2repo/models"
+	"github.com/spf13/cast"
+	"golang.org/x/exp/slog"
+)
+
+// apiError отдаёт ошибку API в едином JSON-формате.
+func apiError(c *gin.Context, status int, err error) {
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
+// findRepo ищет репозиторий по имени среди обслуживаемых.
+func (m *Web) findRepo(name string) (models.Repoes, bool) {
+	value, ok := m.repos.Load(name)
+	if !ok {
+		return nil, false
+	}
+
+	repo, ok := value.(models.Repoes)
+
+	return repo, ok
+}
+
+// findCustomRepo ищет пользовательский репозиторий по имени. Если репозиторий
+// не найден или имеет другой тип, в ответ записывается ошибка и возвращается false.
+func (m *Web) findCustomRepo(c *gin.Context, name string) (*repo.RepoCustom, bool) {
+	r, ok := m.findRepo(name)
+	if !ok {
+		apiError(c, http.StatusNotFound, errors.Newf("репозиторий %s не найден", name))
+		return nil, false
+	}
+
+	custom, ok := r.(*repo.RepoCustom)
+	if !ok {
+		apiError(c, http.StatusBadRequest, errors.Newf("репозиторий %s не является пользовательским", name))
+		return nil, false
+	}
+
+	return custom, true
+}
+
+// findPackageRepo ищет репозиторий, пакетами которого можно управлять через API:
+// пользовательский репозиторий или образ с дополнением. Для образа с дополнением
+// возвращается пользовательский репозиторий пакетов дополнения. Если репозиторий
+// не найден или имеет другой тип, в ответ записывается ошибка и возвращается false.
+func (m *Web) findPackageRepo(c *gin.Context, name string) (*repo.RepoCustom, bool) {
+	r, ok := m.findRepo(name)
+	if !ok {
+		apiError(c, http.StatusNotFound, errors.Newf("репозиторий %s не найден", name))
+		return nil, false
+	}
+
+	switch r := r.(type) {
+	case *repo.RepoCustom:
+		return r, true
+	case *repo.RepoOverlay:
+		return r.Overlay(), true
+	default:
+		apiError(c, http.StatusBadRequest, errors.Newf("репозиторий %s не является пользовательским и не имеет дополнения", name))
+		return nil, false
+	}
+}
+
+// handleUploadPackages обработчик маршрута PUT/POST /api/repos/:name/packages.
+// Принимает пакеты (.deb, .udeb, .ddeb) и описания загрузок .changes:
+//   - multipart/form-data — любое количество файлов в одном запросе;
+//   - тело запроса целиком — один файл, имя которого задаётся параметром
+//     ?filename= или заголовком Content-Disposition.
+//
+// Пакеты публикуются в пользовательском репозитории, в ответ возвращаются
+// записи индекса Packages для опубликованных пакетов. Тело запроса больше
+// maxUploadSize отклоняется с кодом 413.
+func (m *Web) handleUploadPackages(c *gin.Context) {
+	custom, ok := m.findPackageRepo(c, c.Param("name"))
+	if !ok {
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, m.maxUploadSize)
+
+	uploads := make([]*repo.Upload, 0)
+	discard := func() {
+		for _, u := range uploads {
+			u.Discard()
+		}
+	}
+
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		reader, err := c.Request.MultipartReader()
+		if err != nil {
+			apiError(c, http.StatusBadRequest, err)
+			return
+		}
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				discard()
+				apiError(c, bodyErrorStatus(err, http.StatusBadRequest), err)
+				return
+			}
+			if part.FileName() == "" {
+				part.Close()
+				continue
+			}
+
+			u, err := custom.ReceiveUpload(part.FileName(), part)
+			part.Close()
+			if err != nil {
+				discard()
+				apiError(c, repoErrorStatus(err), err)
+				return
+			}
+			uploads = append(uploads, u)
+		}
+	} else {
+		fileName := c.Query("filename")
+		if fileName == "" {
+			if _, params, err := mime.ParseMediaType(c.GetHeader("Content-Disposition")); err == nil {
+				fileName = params["filename"]
+			}
+		}
+
+		u, err := custom.ReceiveUpload(fileName, c.Request.Body)
+		if err != nil {
+			apiError(c, repoErrorStatus(err), err)
+			return
+		}
+		uploads = append(uploads, u)
+	}
+
+	entry := audit.Entry{
+		Action: audit.ActionUpload,
+		Repo:   custom.Metadata().Name,
+	}
+	for _, u := range uploads {
+		entry.Files = append(entry.Files, u.Name)
+	}
+
+	result, err := custom.Publish(uploads)
+	m.recordAudit(c, entry, err)
+	if err != nil && len(result.Packages)+len(result.Staged) > 0 {
+		// Часть пакетов опубликована до ошибки — сообщаем и о них
+		response := publishResponse(custom, result)
+		response["error"] = err.Error()
+		c.JSON(repoErrorStatus(err), response)
+		return
+	}
+	if err != nil {
+		apiError(c, repoErrorStatus(err), err)
+		return
+	}
+
+	// Пакеты, ушедшие на карантин, приняты, но ещё не опубликованы
+	status := http.StatusCreated
+	if len(result.Staged) > 0 {
+		status = http.StatusAccepted
+	}
+
+	c.JSON(status, publishResponse(custom, result))
+}
+
+// publishResponse формирует ответ API с итогом публикации пакетов.
+func publishResponse(custom *repo.RepoCustom, result repo.PublishResult) gin.H {
+	response := gin.H{
+		"repo":     custom.Metadata().Name,
+		"packages": result.Packages,
+	}
+	if len(result.Staged) > 0 {
+		response["staged"] = result.Staged
+	}
+
+	return response
+}
+
+// repoErrorStatus подбирает HTTP-статус для ошибки операции с репозиторием.
+func repoErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, repo.ErrUploadInvalid), errors.Is(err, repo.ErrPackageAmbiguous),
+		errors.Is(err, repo.ErrQuarantineDisabled), errors.Is(err, repo.ErrInvalidQuery):
+		return http.StatusBadRequest
+	case errors.Is(err, repo.ErrPackageNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errPackageDenied):
+		return http.StatusForbidden
+	case errors.Is(err, repo.ErrUploadConflict):
+		return http.StatusConflict
+	}
+
+	return bodyErrorStatus(err, http.StatusInternalServerError)
+}
+
+// bodyErrorStatus возвращает код 413, если err вызвана превышением допустимого
+// размера тела запроса, иначе — fallback.
+func bodyErrorStatus(err error, fallback int) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+
+	return fallback
+}
+
+// recordAudit записывает операцию, выполненную через API, в журнал аудита.
+// Ошибка записи в журнал не прерывает обработку запроса, но попадает в лог.
+func (m *Web) recordAudit(c *gin.Context, entry audit.Entry, opErr error) {
+	entry.Source = "api"
+	entry.Actor = c.ClientIP()
+	if opErr != nil {
+		entry.Error = opErr.Error()
+	}
+
+	if err := m.audit.Record(entry); err != nil {
+		m.log.Warn("не удалось записать операцию в журнал аудита", slog.Any("error", err))
+	}
+}
+
+// packageOpRequest тело запроса копирования и продвижения пакета.
+type packageOpRequest struct {
+	// Репозиторий-источник.
+	From string `json:"from" binding:"required"`
+	// Имя пакета.
+	Package string `json:"package" binding:"required"`
+	// Версия и архитектура (необязательны, если пакет определяется однозначно).
+	Version string `json:"version"`
+	Arch    string `json:"arch"`
+}
+
+// handleRemovePackage обработчик маршрута DELETE /api/repos/:name/packages/:package.
+// Удаляет версию пакета (?version=, обязательно) из пользовательского репозитория;
+// параметр ?arch= ограничивает удаление одной архитектурой.
+func (m *Web) handleRemovePackage(c *gin.Context) {
+	custom, ok := m.findPackageRepo(c, c.Param("name"))
+	if !ok {
+		return
+	}
+
+	entry := audit.Entry{
+		Action:  audit.ActionRemove,
+		Repo:    custom.Metadata().Name,
+		Package: c.Param("package"),
+		Version: c.Query("version"),
+		Arch:    c.Query("arch"),
+	}
+
+	removed, err := custom.RemovePackage(entry.Package, entry.Version, entry.Arch)
+	entry.Files = removed
+	m.recordAudit(c, entry, err)
+	if err != nil {
+		apiError(c, repoErrorStatus(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"repo":    custom.Metadata().Name,
+		"removed": removed,
+	})
+}
+
+// handleCopyPackage обработчик маршрута POST /api/repos/:name/copy.
+// Копирует пакет из любого обслуживаемого репозитория (в том числе из ISO-образа)
+// в пользовательский репозиторий :name.
+func (m *Web) handleCopyPackage(c *gin.Context) {
+	m.importPackage(c, audit.ActionCopy)
+}
+
+// handlePromotePackage обработчик маршрута POST /api/repos/:name/promote.
+// Продвигает конкретную версию пакета из одного пользовательского репозитория
+// в другой (например, testing.iso → stable.iso). Исходный репозиторий не изменяется.
+func (m *Web) handlePromotePackage(c *gin.Context) {
+	m.importPackage(c, audit.ActionPromote)
+}
+
+// importPackage общая часть копирования и продвижения пакета.
+func (m *Web) importPackage(c *gin.Context, action audit.Action) {
+	var req packageOpRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apiError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	custom, ok := m.findPackageRepo(c, c.Param("name"))
+	if !ok {
+		return
+	}
+
+	var src models.Repoes
+	if action == audit.ActionPromote {
+		if req.Version == "" {
+			apiError(c, http.StatusBadRequest, errors.New("для продвижения необходимо указать версию пакета"))
+			return
+		}
+		if src, ok = m.findCustomRepo(c, req.From); !ok {
+			return
+		}
+	} else if src, ok = m.findRepo(req.From); !ok {
+		apiError(c, http.StatusNotFound, errors.Newf("репозиторий %s не найден", req.From))
+		return
+	}
+
+	entry := audit.Entry{
+		Action:   action,
+		FromRepo: req.From,
+		Repo:     custom.Metadata().Name,
+		Package:  req.Package,
+		Version:  req.Version,
+		Arch:     req.Arch,
+	}
+
+	file, result, err := custom.ImportPackage(c.Request.Context(), src, req.Package, req.Version, req.Arch)
+	if file.Name != "" {
+		entry.Files = []string{file.Name}
+	}
+	m.recordAudit(c, entry, err)
+	if err != nil {
+		apiError(c, repoErrorStatus(err), err)
+		return
+	}
+
+	response := publishResponse(custom, result)
+	response["from"] = src.Metadata().Name
+
+	c.JSON(http.StatusOK, response)
+}
+
+// handleStaging обработчик маршрута GET /api/repos/:name/staging.
+// Возвращает пакеты на карантине с метаданными из control и контрольными суммами.
+func (m *Web) handleStaging(c *gin.Context) {
+	custom, ok := m.findPackageRepo(c, c.Param("name"))
+	if !ok {
+		return
+	}
+	if !custom.Quarantined() {
+		apiError(c, http.StatusBadRequest, repo.ErrQuarantineDisabled)
+		return
+	}
+
+	c.JSON(http.StatusOK, custom.Staged())
+}
+
+// handleApprove обработчик маршрута POST /api/repos/:name/staging/:file/approve.
+// Публикует пакет с карантина.
+func (m *Web) handleApprove(c *gin.Context) {
+	m.reviewStaged(c, audit.ActionApprove)
+}
+
+// handleReject обработчик маршрута POST /api/repos/:name/staging/:file/reject.
+// Переносит пакет с карантина в директорию отклонённых.
+func (m *Web) handleReject(c *gin.Context) {
+	m.reviewStaged(c, audit.ActionReject)
+}
+
+// reviewStaged общая часть одобрения и отклонения пакета на карантине.
+func (m *Web) reviewStaged(c *gin.Context, action audit.Action) {
+	custom, ok := m.findPackageRepo(c, c.Param("name"))
+	if !ok {
+		return
+	}
+
+	review := custom.ApprovePackage
+	if action == audit.ActionReject {
+		review = custom.RejectPackage
+	}
+
+	staged, err := review(c.Param("file"))
+
+	entry := audit.Entry{
+		Action: action,
+		Repo:   custom.Metadata().Name,
+		Files:  []string{c.Param("file")},
+	}
+	entry.Package, entry.Version, entry.Arch = staged.Package()
+	m.recordAudit(c, entry, err)
+	if err != nil {
+		apiError(c, repoErrorStatus(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"repo":    custom.Metadata().Name,
+		"action":  action,
+		"package": staged,
+	})
+}
+
+// handleAudit обработчик маршрута GET /api/audit.
+// Возвращает последние записи журнала аудита (?limit=, по умолчанию 100).
+func (m *Web) handleAudit(c *gin.Context) {
+	limit := cast.ToInt(c.DefaultQuery("limit", "100"))
+
+	entries, err := m.audit.Read(limit)
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// handleInspect обработчик маршрута GET /api/repos/:name/inspect/*path.
+// Возвращает полное содержимое пакета по пути path внутри любого обслуживаемого
+// репозитория: поля control, список файлов, md5sums, conffiles, triggers и
+// сценарии сопровождающего.
+func (m *Web) handleInspect(c *gin.Context) {
+	r, ok := m.findRepo(c.Param("name"))
+	if !ok {
+		apiError(c, http.StatusNotFound, errors.Newf("репозиторий %s не найден", c.Param("name")))
+		return
+	}
+
+	if err := m.checkPackage(c, r, c.Param("path")); err != nil {
+		apiError(c, repoErrorStatus(err), err)
+		return
+	}
+
+	inspection, err := repo.InspectPackage(c.Request.Context(), r, c.Param("path"))
+	if err != nil {
+		status := repoErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			m.log.Error("не удалось разобрать пакет", err, slog.String("path", c.Param("path")))
+		}
+		apiError(c, status, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, inspection)
+}
@@ -0,0 +1,93 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kirsrus/iso2repo/internal/repo"
+	"github.com/spf13/cast"
+	"golang.org/x/exp/slog"
+)
+
+// queryList возвращает значения параметра запроса name: параметр может повторяться,
+// каждое значение может содержать список через запятую.
+func queryList(c *gin.Context, name string) []string {
+	result := make([]string, 0)
+	for _, value := range c.QueryArray(name) {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				result = append(result, item)
+			}
+		}
+	}
+
+	return result
+}
+
+// handleAPIBundle обработчик маршрута GET /api/bundle.
+// Собирает запрошенные пакеты со всеми зависимостями в комплект для переноса в
+// изолированную сеть. Параметры: packages — пакеты через запятую (имя или имя=версия),
+// repos — репозитории-источники через запятую (по умолчанию все), arch — архитектура,
+// recommends — включать Recommends, format — tar (по умолчанию), zip или json (только
+// состав комплекта, без файлов).
+func (m *Web) handleAPIBundle(c *gin.Context) {
+	format := c.DefaultQuery("format", "tar")
+	if format != "tar" && format != "zip" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("неизвестный формат комплекта %s", format)})
+		return
+	}
+
+	q := repo.BundleQuery{
+		Packages:   queryList(c, "packages"),
+		Repos:      queryList(c, "repos"),
+		Arch:       c.Query("arch"),
+		Recommends: cast.ToBool(c.Query("recommends")),
+	}
+	bundle, err := m.catalog.ResolveBundle(q)
+	if err != nil {
+		apiError(c, repoErrorStatus(err), err)
+		return
+	}
+
+	// Комплект, в который вошёл запрещённый политикой пакет, не отдаётся
+	for _, p := range bundle.Packages {
+		r, ok := m.findRepo(p.Repo)
+		if !ok {
+			continue
+		}
+		if err := m.checkPackage(c, r, p.Filename); err != nil {
+			apiError(c, repoErrorStatus(err), err)
+			return
+		}
+	}
+
+	if format == "json" {
+		c.JSON(http.StatusOK, bundle)
+		return
+	}
+
+	// Ответ передаётся потоком: после начала передачи ошибку можно только записать в лог,
+	// клиент получит оборванный архив
+	name, _, _ := strings.Cut(q.Packages[0], "=")
+	fileName := fmt.Sprintf("bundle-%s-%s.%s", name, bundle.Arch, format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", fileName))
+	c.Header("X-Bundle-Packages", fmt.Sprint(len(bundle.Packages)))
+	c.Header("X-Bundle-Unmet", fmt.Sprint(len(bundle.Unmet)))
+
+	var w repo.BundleWriter
+	if format == "zip" {
+		c.Header("Content-Type", "application/zip")
+		w = repo.NewZipBundleWriter(c.Writer)
+	} else {
+		c.Header("Content-Type", "application/x-tar")
+		w = repo.NewTarBundleWriter(c.Writer)
+	}
+	c.Status(http.StatusOK)
+
+	if err := m.catalog.WriteBundle(c.Request.Context(), bundle, w); err != nil {
+		m.log.Error("не удалось передать комплект пакетов", err, slog.String("file", fileName))
+		c.Abort()
+	}
+}
@@ -0,0 +1,134 @@
+package web
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kirsrus/iso2repo/internal/repo"
+	"github.com/kirsrus/iso2repo/internal/vuln"
+	"github.com/spf13/cast"
+	"golang.org/x/exp/slog"
+)
+
+// packageInfoData модель данных для шаблона package.html.
+type packageInfoData struct {
+	Name     string
+	Versions []packageVersionView
+	// Пакеты, предоставляющие Name как виртуальный пакет.
+	ProvidedBy     []repo.CatalogEntry
+	ReverseDepends []repo.ReverseDependency
+}
+
+// packageVersionView модель для отображения одной версии пакета.
+type packageVersionView struct {
+	repo.CatalogEntry
+	// Поля control в текстовом виде.
+	Control string
+	Depends []repo.Dependency
+	// Уязвимости версии из локальных баз.
+	Vulnerabilities []vuln.Match
+	// Ссылки на скачивание пакета, просмотр его содержимого и граф зависимостей.
+	DownloadURL string
+	BrowseURL   string
+	GraphURL    string
+}
+
+// handlePackageInfo обработчик маршрута GET /package/:name.
+// Страница пакета: все версии во всех обслуживаемых репозиториях с полями control,
+// зависимостями и обратными зависимостями.
+func (m *Web) handlePackageInfo(c *gin.Context) {
+	info, err := m.catalog.Package(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		status := repoErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			m.log.Error("не удалось получить сведения о пакете", err, slog.String("package", c.Param("name")))
+		}
+		c.String(status, "%s\n", err.Error())
+		return
+	}
+	m.packageVulnerabilities(info)
+
+	data := packageInfoData{
+		Name:           info.Name,
+		Versions:       make([]packageVersionView, 0, len(info.Versions)),
+		ProvidedBy:     info.ProvidedBy,
+		ReverseDepends: info.ReverseDepends,
+	}
+	for _, v := range info.Versions {
+		control := new(strings.Builder)
+		if v.Control != nil {
+			_, _ = v.Control.WriteTo(control)
+		}
+
+		graph := url.Values{}
+		graph.Set("repo", v.Repo)
+		graph.Set("version", v.Version)
+		graph.Set("arch", v.Arch)
+
+		data.Versions = append(data.Versions, packageVersionView{
+			CatalogEntry:    v.CatalogEntry,
+			Control:         control.String(),
+			Depends:         v.Depends,
+			Vulnerabilities: v.Vulnerabilities,
+			DownloadURL:     "/repo/" + v.Repo + "/" + v.Filename,
+			BrowseURL:       "/repo/" + v.Repo + "/" + v.Filename + "/",
+			GraphURL:        "/package/" + url.PathEscape(info.Name) + "/graph.svg?" + graph.Encode(),
+		})
+	}
+
+	c.HTML(http.StatusOK, "package.html", data)
+}
+
+// graphQuery формирует условия построения графа зависимостей пакета name из
+// параметров запроса repo, version, arch, depth и recommends.
+func graphQuery(c *gin.Context, name string) repo.GraphQuery {
+	return repo.GraphQuery{
+		Repo:       c.Query("repo"),
+		Name:       name,
+		Version:    c.Query("version"),
+		Arch:       c.Query("arch"),
+		Depth:      cast.ToInt(c.Query("depth")),
+		Recommends: cast.ToBool(c.Query("recommends")),
+	}
+}
+
+// handlePackageGraph обработчик маршрута GET /package/:name/graph.svg.
+// Отдаёт граф зависимостей пакета в репозитории repo в формате SVG.
+func (m *Web) handlePackageGraph(c *gin.Context) {
+	graph, err := m.catalog.DependencyGraph(graphQuery(c, c.Param("name")))
+	if err != nil {
+		c.String(repoErrorStatus(err), "%s\n", err.Error())
+		return
+	}
+
+	c.Data(http.StatusOK, "image/svg+xml", renderGraphSVG(graph))
+}
+
+// handleAPIPackage обработчик маршрута GET /api/packages/:name.
+// Возвращает все версии пакета во всех обслуживаемых репозиториях с полями control,
+// разрешёнными зависимостями и обратными зависимостями.
+func (m *Web) handleAPIPackage(c *gin.Context) {
+	info, err := m.catalog.Package(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		apiError(c, repoErrorStatus(err), err)
+		return
+	}
+	m.packageVulnerabilities(info)
+
+	c.JSON(http.StatusOK, info)
+}
+
+// handleAPIPackageGraph обработчик маршрута GET /api/packages/:name/graph.
+// Возвращает граф зависимостей пакета в репозитории repo; параметры те же, что
+// у /package/:name/graph.svg.
+func (m *Web) handleAPIPackageGraph(c *gin.Context) {
+	graph, err := m.catalog.DependencyGraph(graphQuery(c, c.Param("name")))
+	if err != nil {
+		apiError(c, repoErrorStatus(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, graph)
+}
@@ -0,0 +1,105 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kirsrus/iso2repo/internal/repo"
+)
+
+// diffRow строка таблицы различий с размерами в человекочитаемом виде.
+type diffRow struct {
+	repo.DiffPackage
+	OldSizeText string
+	NewSizeText string
+}
+
+// diffData модель данных для шаблона diff.html.
+type diffData struct {
+	// Репозитории для выбора.
+	Repos []string
+	A, B  string
+	Diff  *repo.RepoDiff
+	// Изменённые пакеты по видам изменения.
+	Added, Removed, Upgraded, Downgraded []diffRow
+	SizeA, SizeB                         string
+	// Адреса отчёта в форматах JSON и текст.
+	JSONURL, TextURL string
+	Error            string
+}
+
+// handleDiff обработчик маршрута GET /diff.
+// Сравнивает индексы Packages репозиториев a (исходный) и b (новый): добавленные,
+// удалённые, обновлённые и пониженные пакеты. Параметр format — html (по умолчанию),
+// json или text.
+func (m *Web) handleDiff(c *gin.Context) {
+	a, b := c.Query("a"), c.Query("b")
+	format := c.DefaultQuery("format", "html")
+	if format != "html" && format != repo.DiffJSON && format != repo.DiffText {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("неизвестный формат отчёта %s", format)})
+		return
+	}
+
+	var (
+		diff *repo.RepoDiff
+		err  error
+	)
+	if a != "" && b != "" {
+		diff, err = m.catalog.Diff(c.Request.Context(), a, b)
+	}
+
+	if format != "html" {
+		if diff == nil && err == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "не заданы сравниваемые репозитории a и b"})
+			return
+		}
+		if err != nil {
+			apiError(c, repoErrorStatus(err), err)
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := diff.Write(&buf, format); err != nil {
+			apiError(c, repoErrorStatus(err), err)
+			return
+		}
+		contentType := "application/json; charset=utf-8"
+		if format == repo.DiffText {
+			contentType = "text/plain; charset=utf-8"
+		}
+		c.Data(http.StatusOK, contentType, buf.Bytes())
+		return
+	}
+
+	data := diffData{Repos: m.catalog.Repos(), A: a, B: b, Diff: diff}
+	if err != nil {
+		data.Error = err.Error()
+	}
+	if diff != nil {
+		for _, p := range diff.Packages {
+			row := diffRow{DiffPackage: p, OldSizeText: formatSize(p.OldSize), NewSizeText: formatSize(p.NewSize)}
+			switch p.Change {
+			case repo.DiffAdded:
+				data.Added = append(data.Added, row)
+			case repo.DiffRemoved:
+				data.Removed = append(data.Removed, row)
+			case repo.DiffUpgraded:
+				data.Upgraded = append(data.Upgraded, row)
+			case repo.DiffDowngraded:
+				data.Downgraded = append(data.Downgraded, row)
+			}
+		}
+		data.SizeA, data.SizeB = formatSize(diff.SizeA), formatSize(diff.SizeB)
+
+		query := url.Values{"a": {a}, "b": {b}}
+		query.Set("format", repo.DiffJSON)
+		data.JSONURL = "/diff?" + query.Encode()
+		query.Set("format", repo.DiffText)
+		data.TextURL = "/diff?" + query.Encode()
+	}
+
+	c.HTML(http.StatusOK, "diff.html", data)
+}
@@ -0,0 +1,133 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/url"
+	"unicode/utf8"
+
+	"github.com/kirsrus/iso2repo/internal/repo"
+)
+
+// Размеры элементов SVG-графа зависимостей в пикселях.
+const (
+	graphMargin    = 20
+	graphNodeH     = 40
+	graphHGap      = 16
+	graphVGap      = 60
+	graphCharWidth = 7
+)
+
+// graphBox положение вершины графа на рисунке.
+type graphBox struct {
+	x, y, w int
+}
+
+// renderGraphSVG рисует граф зависимостей g в формате SVG: вершины располагаются
+// по уровням (расстоянию от корня), рёбра Pre-Depends выделяются толщиной,
+// Recommends — пунктиром, неудовлетворённые зависимости — красным.
+func renderGraphSVG(g *repo.DependencyGraph) []byte {
+	layers := make([][]int, 0)
+	for i, n := range g.Nodes {
+		for len(layers) <= n.Depth {
+			layers = append(layers, nil)
+		}
+		layers[n.Depth] = append(layers[n.Depth], i)
+	}
+
+	boxes := make([]graphBox, len(g.Nodes))
+	width := 0
+	for _, layer := range layers {
+		w := 0
+		for _, i := range layer {
+			n := g.Nodes[i]
+			chars := utf8.RuneCountInString(n.Name)
+			if c := utf8.RuneCountInString(n.Version); c > chars {
+				chars = c
+			}
+			boxes[i].w = chars*graphCharWidth + 24
+			w += boxes[i].w + graphHGap
+		}
+		if w > width {
+			width = w
+		}
+	}
+	width += 2 * graphMargin
+
+	for d, layer := range layers {
+		w := -graphHGap
+		for _, i := range layer {
+			w += boxes[i].w + graphHGap
+		}
+		x := (width - w) / 2
+		for _, i := range layer {
+			boxes[i].x = x
+			boxes[i].y = graphMargin + d*(graphNodeH+graphVGap)
+			x += boxes[i].w + graphHGap
+		}
+	}
+
+	height := 2*graphMargin + len(layers)*(graphNodeH+graphVGap) - graphVGap
+	if g.Truncated {
+		height += 24
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n", width, height, width, height)
+	buf.WriteString(`<defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="6" markerHeight="6" orient="auto-start-reverse"><path d="M 0 0 L 10 5 L 0 10 z" fill="#888"/></marker></defs>` + "\n")
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#ffffff"/>`+"\n", width, height)
+
+	for _, e := range g.Edges {
+		from, to := boxes[e.From], boxes[e.To]
+		x1, y1 := from.x+from.w/2, from.y+graphNodeH
+		x2, y2 := to.x+to.w/2, to.y
+		// Ребро к вершине того же или верхнего уровня (цикл) огибает её снизу
+		c2 := y2 - graphVGap/2
+		if g.Nodes[e.To].Depth <= g.Nodes[e.From].Depth {
+			y2 = to.y + graphNodeH
+			c2 = y2 + graphVGap/2
+		}
+
+		style := `stroke-width="1"`
+		switch e.Field {
+		case "Pre-Depends":
+			style = `stroke-width="2"`
+		case "Recommends":
+			style = `stroke-width="1" stroke-dasharray="4 3"`
+		}
+		fmt.Fprintf(&buf, `<path d="M %d %d C %d %d %d %d %d %d" fill="none" stroke="#888" %s marker-end="url(#arrow)"><title>%s</title></path>`+"\n",
+			x1, y1, x1, y1+graphVGap/2, x2, c2, x2, y2, style, template.HTMLEscapeString(e.Field))
+	}
+
+	for i, n := range g.Nodes {
+		b := boxes[i]
+		fill, stroke, dash := "#ffffff", "#888888", ""
+		switch {
+		case n.Missing:
+			fill, stroke, dash = "#fdecec", "#cc3333", ` stroke-dasharray="4 3"`
+		case i == 0:
+			fill, stroke = "#eef4ff", "#5577aa"
+		}
+
+		if !n.Missing {
+			fmt.Fprintf(&buf, `<a xlink:href="/package/%s">`, template.HTMLEscapeString(url.PathEscape(n.Name)))
+		}
+		fmt.Fprintf(&buf, `<rect x="%d" y="%d" width="%d" height="%d" rx="4" fill="%s" stroke="%s"%s/>`, b.x, b.y, b.w, graphNodeH, fill, stroke, dash)
+		if n.Missing {
+			fmt.Fprintf(&buf, `<text x="%d" y="%d" text-anchor="middle" fill="#cc3333">%s</text>`, b.x+b.w/2, b.y+24, template.HTMLEscapeString(n.Name))
+		} else {
+			fmt.Fprintf(&buf, `<text x="%d" y="%d" text-anchor="middle" font-weight="bold">%s</text>`, b.x+b.w/2, b.y+17, template.HTMLEscapeString(n.Name))
+			fmt.Fprintf(&buf, `<text x="%d" y="%d" text-anchor="middle" fill="#888888" font-size="11">%s</text>`, b.x+b.w/2, b.y+32, template.HTMLEscapeString(n.Version))
+			buf.WriteString(`</a>`)
+		}
+		buf.WriteString("\n")
+	}
+
+	if g.Truncated {
+		fmt.Fprintf(&buf, `<text x="%d" y="%d" fill="#888888">Граф усечён: больше вершин, чем можно показать.</text>`+"\n", graphMargin, height-graphMargin/2)
+	}
+	buf.WriteString("</svg>\n")
+
+	return buf.Bytes()
+}
@@ -1,6 +1,7 @@
 package web
 
 import (
+	"bytes"
 	"fmt"
 	"io/fs"
 	"net"
@@ -13,6 +14,7 @@ import (
 	"sync"
 
 	"github.com/gin-gonic/gin"
+	repoPkg "github.com/kirsrus/iso2repo/internal/repo"
 	"github.com/kirsrus/iso2repo/models"
 	"golang.org/x/exp/slog"
 )
@@ -74,6 +76,10 @@ type entryView struct {
 	URL   string
 	IsDir bool
 	Size  string
+	// Файл является пакетом, содержимое которого можно просмотреть.
+	IsPackage bool
+	// Цель символической ссылки (для файлов внутри пакета).
+	Link string
 }
 
 // crumb модель элемента "хлебных крошек".
@@ -82,11 +88,33 @@ type crumb struct {
 	URL  string
 }
 
+// stagedView модель для отображения пакета на карантине.
+type stagedView struct {
+	File    string
+	Package string
+	Version string
+	Arch    string
+	Size    string
+	SHA256  string
+	// Поля control-файла в текстовом виде.
+	Control string
+}
+
 // repoData модель данных для шаблона repo.html.
 type repoData struct {
 	RepoName    string
 	Breadcrumbs []crumb
 	Entries     []entryView
+	// Включён ли карантин (только для пользовательских репозиториев).
+	Quarantine bool
+	// Пакеты на карантине; показываются в корне репозитория.
+	Staged []stagedView
+	// Проблемы пакетов пользовательского репозитория; показываются в его корне.
+	Lint []repoPkg.LintFinding
+	// Корень просматриваемого пакета .deb (ссылка на скачивание и поля control).
+	Package *packageView
+	// Просматриваемый файл из пакета .deb.
+	File *packageFileView
 }
 
 // staticData модель данных для шаблона static.html.
@@ -127,6 +155,9 @@ func newIndexData(repos *sync.Map, rootDir, copyright, version string, port int)
 			typeLabel = "Пользовательский"
 			typeStr = "Custom"
 		}
+		if _, ok := repo.(*repoPkg.RepoOverlay); ok {
+			typeLabel += " + дополнение"
+		}
 
 		data.Repos = append(data.Repos, repoView{
 			Name:      meta.Name,
@@ -203,9 +234,10 @@ func getServerIP(c *gin.Context, address string) string {
 	return "127.0.0.1"
 }
 
-// newSourcesTxtData создаёт sourcesTxtData из sync.Map репозиториев.
+// newSourcesTxtData создаёт sourcesTxtData из sync.Map репозиториев и строк merged
+// объединённых репозиториев.
 // Формирует список строк RepoString() с заменой 0.0.0.0 на указанный address:port.
-func newSourcesTxtData(repos *sync.Map, address string, port int, version, serverIP string) sourcesTxtData {
+func newSourcesTxtData(repos *sync.Map, merged []string, address string, port int, version, serverIP string) sourcesTxtData {
 	data := sourcesTxtData{
 		CustomComment: "",
 		Repos:         make([]string, 0),
@@ -232,6 +264,9 @@ func newSourcesTxtData(repos *sync.Map, address string, port int, version, serve
 
 		return true
 	})
+	for _, repoStr := range merged {
+		data.Repos = append(data.Repos, strings.ReplaceAll(repoStr, "0.0.0.0", fmt.Sprintf("%s:%d", address, port)))
+	}
 
 	// Сортируем строки для стабильного вывода
 	sort.Strings(data.Repos)
@@ -324,6 +359,45 @@ func (m *Web) registerRoutes() {
 	m.router.GET("/sources.list", m.handleSources)
 	m.router.GET("/repo/*path", m.handleRepo)
 	m.router.GET("/static/*path", m.handleStatic)
+	m.router.GET("/changelogs/:name/*path", m.handleChangelog)
+	m.router.GET("/search", m.handleSearch)
+	m.router.GET("/package/:name", m.handlePackageInfo)
+	m.router.GET("/package/:name/graph.svg", m.handlePackageGraph)
+	m.router.GET("/health", m.handleHealth)
+	m.router.GET("/vulns", m.handleVulns)
+	m.router.GET("/diff", m.handleDiff)
+	m.router.GET("/signing-key.asc", m.handleSigningKey)
+	m.router.GET("/merged/:name/*path", m.handleMerged)
+	m.router.GET("/snapshot/:name/:snapshot/*path", m.handleSnapshot)
+
+	m.router.POST("/api/repos/:name/packages", m.handleUploadPackages)
+	m.router.PUT("/api/repos/:name/packages", m.handleUploadPackages)
+	m.router.DELETE("/api/repos/:name/packages/:package", m.handleRemovePackage)
+	m.router.POST("/api/repos/:name/copy", m.handleCopyPackage)
+	m.router.POST("/api/repos/:name/promote", m.handlePromotePackage)
+	m.router.GET("/api/repos/:name/staging", m.handleStaging)
+	m.router.POST("/api/repos/:name/staging/:file/approve", m.handleApprove)
+	m.router.POST("/api/repos/:name/staging/:file/reject", m.handleReject)
+	m.router.GET("/api/repos/:name/inspect/*path", m.handleInspect)
+	m.router.GET("/api/repos/:name/health", m.handleAPIHealth)
+	m.router.GET("/api/repos/:name/vulns", m.handleAPIRepoVulns)
+	m.router.GET("/api/repos/:name/sbom", m.handleAPISBOM)
+	m.router.GET("/api/repos/:name/snapshots", m.handleSnapshots)
+	m.router.POST("/api/repos/:name/snapshots", m.handleCreateSnapshot)
+	m.router.DELETE("/api/repos/:name/snapshots/:snapshot", m.handleDeleteSnapshot)
+	m.router.GET("/api/audit", m.handleAudit)
+	m.router.GET("/api/search", m.handleAPISearch)
+	m.router.GET("/api/contents", m.handleAPIContents)
+	m.router.GET("/api/packages/:name", m.handleAPIPackage)
+	m.router.GET("/api/packages/:name/graph", m.handleAPIPackageGraph)
+	m.router.GET("/api/bundle", m.handleAPIBundle)
+	m.router.POST("/api/upgrades", m.handleAPIUpgrades)
+	m.router.GET("/api/vulns", m.handleAPIVulns)
+	m.router.POST("/api/vulns", m.handleImportVulns)
+	m.router.DELETE("/api/vulns/:name", m.handleRemoveVulns)
+	m.router.GET("/api/policy", m.handleAPIPolicy)
+	m.router.GET("/api/merged", m.handleAPIMerged)
+	m.router.GET("/api/merged/:name", m.handleAPIMergedRepo)
 }
 
 // handleIndex обработчик корневого маршрута.
@@ -338,7 +412,7 @@ func (m *Web) handleIndex(c *gin.Context) {
 
 	if isCurlOrWget {
 		serverIP := getServerIP(c, "repo.loc")
-		data := newSourcesTxtData(&m.repos, "repo.loc", m.port, m.version, serverIP)
+		data := newSourcesTxtData(&m.repos, m.mergedRepoStrings(), "repo.loc", m.port, m.version, serverIP)
 
 		// Рендерим шаблон sources.txt в буфер и отдаём как text/plain
 		buf := new(strings.Builder)
@@ -396,7 +470,7 @@ func (m *Web) handleSources(c *gin.Context) {
 
 	if isCurlOrWget {
 		serverIP := getServerIP(c, address)
-		data := newSourcesTxtData(&m.repos, address, m.port, m.version, serverIP)
+		data := newSourcesTxtData(&m.repos, m.mergedRepoStrings(), address, m.port, m.version, serverIP)
 
 		// Рендерим шаблон sources.txt в буфер и отдаём как text/plain
 		buf := new(strings.Builder)
@@ -430,6 +504,9 @@ func (m *Web) handleSources(c *gin.Context) {
 
 		return true
 	})
+	for _, repoStr := range m.mergedRepoStrings() {
+		lines = append(lines, strings.ReplaceAll(repoStr, "0.0.0.0", fmt.Sprintf("%s:%d", address, m.port)))
+	}
 
 	// Сортируем строки для стабильного вывода
 	sort.Strings(lines)
@@ -476,10 +553,35 @@ func (m *Web) handleRepo(c *gin.Context) {
 		return
 	}
 
-	// Определяем путь внутри репозитория (без имени репозитория)
+	// Путь, продолжающийся внутрь пакета (".../foo.deb/..."), — просмотр содержимого пакета
+	if len(parts) > 1 {
+		if pkgPath, inner, ok := splitPackagePath(parts[1]); ok {
+			m.handlePackage(c, repo, repoName, pkgPath, inner)
+			return
+		}
+	}
+
+	// Определяем путь внутри репозитория (без имени репозитория) в каноническом
+	// виде: иначе пути вида "dists//..." обходят переписанные политикой индексы
 	innerPath := ""
 	if len(parts) > 1 {
-		innerPath = strings.TrimSuffix(parts[1], "/")
+		innerPath = strings.TrimPrefix(path.Clean("/"+parts[1]), "/")
+	}
+
+	// Индексы и пакеты, затронутые политикой раздачи
+	if m.servePolicy(c, repo, innerPath) {
+		return
+	}
+
+	// В Release пользовательского репозитория подставляется адрес сервера в шаблон
+	// Changelogs; Release прочих репозиториев отдаются без изменений
+	if _, custom := repo.(*repoPkg.RepoCustom); custom {
+		if dist := releaseDist(innerPath); dist != "" {
+			if release, _, err := repoPkg.ReadReleaseData(c.Request.Context(), repo, dist); err == nil {
+				m.serveRelease(c, repo, innerPath, release)
+				return
+			}
+		}
 	}
 
 	// Получаем список содержимого директории
@@ -523,6 +625,7 @@ func (m *Web) handleRepo(c *gin.Context) {
 		reader, err := repo.Open(c.Request.Context(), innerPath)
 		if err == nil && reader != nil {
 			defer reader.Close()
+
 			// Определяем имя файла из пути
 			c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", fileName))
 			c.DataFromReader(http.StatusOK, -1, "application/octet-stream", reader, nil)
@@ -546,9 +649,131 @@ func (m *Web) handleRepo(c *gin.Context) {
 		Entries:     entryViews,
 	}
 
+	// В корне пользовательского репозитория показываем проблемы пакетов, а при
+	// включённом карантине — ожидающие одобрения пакеты
+	if custom, ok := repo.(*repoPkg.RepoCustom); ok && innerPath == "" {
+		data.Lint = custom.Lint()
+		if custom.Quarantined() {
+			data.Quarantine = true
+			data.Staged = makeStagedViews(custom.Staged())
+		}
+	}
+
 	c.HTML(http.StatusOK, "repo.html", data)
 }
 
+// releaseDist возвращает дистрибутив, если innerPath — файл Release, InRelease или
+// Release.gpg ("dists/<дистрибутив>/Release"), иначе пустую строку.
+func releaseDist(innerPath string) string {
+	dir, file := path.Split(innerPath)
+	if file != "Release" && file != "InRelease" && file != "Release.gpg" {
+		return ""
+	}
+
+	dist := strings.TrimSuffix(strings.TrimPrefix(dir, "dists/"), "/")
+	if dist == "" || dist == strings.TrimSuffix(dir, "/") {
+		return ""
+	}
+
+	return dist
+}
+
+// serveRelease отдаёт файл Release, InRelease или Release.gpg (innerPath) репозитория
+// r, сформированный из Release release. В Release пользовательского репозитория
+// подставляется адрес сервера в шаблон Changelogs. InRelease и Release.gpg
+// подписываются ключом iso2repo, причём только те, что есть в репозитории:
+// неподписанный репозиторий остаётся неподписанным, как и любой Release при
+// отсутствии ключа.
+func (m *Web) serveRelease(c *gin.Context, r models.Repoes, innerPath string, release []byte) {
+	file := path.Base(innerPath)
+	if file != "Release" && (m.signer == nil || !repoHasFile(c.Request.Context(), r, innerPath)) {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	data, err := m.releaseForAddress(r, release), error(nil)
+	switch file {
+	case "InRelease":
+		data, err = m.signer.ClearSign(data)
+	case "Release.gpg":
+		data, err = m.signer.DetachSign(data)
+	}
+	if err != nil {
+		m.log.Error("не удалось подписать Release", err, slog.String("repo", r.Metadata().Name), slog.String("path", innerPath))
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", file))
+	c.Data(http.StatusOK, "application/octet-stream", data)
+}
+
+// releaseForAddress подставляет адрес сервера для клиентов (--address) в шаблон
+// Changelogs файла Release пользовательского репозитория r. Release прочих
+// репозиториев не изменяется.
+func (m *Web) releaseForAddress(r models.Repoes, content []byte) []byte {
+	if _, ok := r.(*repoPkg.RepoCustom); !ok {
+		return content
+	}
+
+	return bytes.ReplaceAll(content, []byte("http://0.0.0.0/"), []byte(fmt.Sprintf("http://%s:%d/", m.address, m.port)))
+}
+
+// handleChangelog обработчик маршрута /changelogs/:name/*path, на который указывает
+// поле Changelogs в Release. Отдаёт журнал изменений пакета для "apt changelog".
+func (m *Web) handleChangelog(c *gin.Context) {
+	value, ok := m.repos.Load(c.Param("name"))
+	if !ok {
+		c.String(http.StatusNotFound, "репозиторий %s не найден\n", c.Param("name"))
+		return
+	}
+
+	repo, ok := value.(models.Repoes)
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	data, err := m.changelogs.Get(c.Request.Context(), repo, c.Param("path"), func(file string) error {
+		return m.checkPackage(c, repo, file)
+	})
+	if err != nil {
+		status := repoErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			m.log.Error("не удалось получить журнал изменений", err, slog.String("path", c.Param("path")))
+		}
+		c.String(status, "%s\n", err.Error())
+		return
+	}
+
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", data)
+}
+
+// makeStagedViews формирует список stagedView для шаблона.
+func makeStagedViews(staged []repoPkg.StagedPackage) []stagedView {
+	result := make([]stagedView, 0, len(staged))
+	for _, p := range staged {
+		name, version, arch := p.Package()
+
+		control := new(strings.Builder)
+		for _, f := range p.Control {
+			fmt.Fprintf(control, "%s: %s\n", f.Name, f.Value)
+		}
+
+		result = append(result, stagedView{
+			File:    p.Name,
+			Package: name,
+			Version: version,
+			Arch:    arch,
+			Size:    formatSize(p.Size),
+			SHA256:  p.SHA256Sum,
+			Control: control.String(),
+		})
+	}
+
+	return result
+}
+
 // makeBreadcrumbs формирует список "хлебных крошек" для навигации.
 // Имя репозитория всегда является кликабельной ссылкой на /repo/<repoName>/.
 func makeBreadcrumbs(repoName, innerPath string) []crumb {
@@ -599,15 +824,18 @@ func makeEntryViews(repoName, innerPath string, entries []models.Entry) []entryV
 
 	for _, e := range entries {
 		url := baseURL + "/" + e.Name
-		if e.IsDir {
+		isPackage := !e.IsDir && repoPkg.IsPackageFile(e.Name)
+		// Директории и пакеты открываются как директории
+		if e.IsDir || isPackage {
 			url += "/"
 		}
 
 		ev := entryView{
-			Name:  e.Name,
-			URL:   url,
-			IsDir: e.IsDir,
-			Size:  formatSize(e.Size),
+			Name:      e.Name,
+			URL:       url,
+			IsDir:     e.IsDir,
+			Size:      formatSize(e.Size),
+			IsPackage: isPackage,
 		}
 
 		if e.IsDir {
@@ -0,0 +1,66 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kirsrus/iso2repo/internal/repo"
+)
+
+func TestWeb_release(t *testing.T) {
+	m, _ := newPolicyTestWeb(t)
+
+	// Образ с подписью поставщика
+	base := filepath.Join(t.TempDir(), "vendor.iso")
+	files := map[string]string{
+		"dists/stable/Release":                    "Origin: Vendor\nSuite: stable\nComponents: main\nArchitectures: amd64\nChangelogs: https://vendor.example/@CHANGEPATH@\n",
+		"dists/stable/InRelease":                  "-----BEGIN PGP SIGNED MESSAGE-----\nvendor\n",
+		"dists/stable/Release.gpg":                "vendor signature\n",
+		"dists/stable/main/binary-amd64/Packages": "Package: tool\nVersion: 1.0\nArchitecture: amd64\nFilename: pool/main/tool_1.0_amd64.deb\n\n",
+	}
+	for name, data := range files {
+		p := filepath.Join(base, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	vendor := repo.NewRepoExtracted(base, nil)
+	m.repos.Store(vendor.Metadata().Name, vendor)
+
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{name: "vendor Release", url: "/repo/vendor.iso/dists/stable/Release", want: files["dists/stable/Release"]},
+		{name: "vendor InRelease", url: "/repo/vendor.iso/dists/stable/InRelease", want: files["dists/stable/InRelease"]},
+		{name: "vendor Release.gpg", url: "/repo/vendor.iso/dists/stable/Release.gpg", want: files["dists/stable/Release.gpg"]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := get(m, tt.url)
+			if rec.Code != http.StatusOK || rec.Body.String() != tt.want {
+				t.Errorf("GET %s = %d\n%s\nwant unchanged\n%s", tt.url, rec.Code, rec.Body, tt.want)
+			}
+		})
+	}
+
+	// Адрес в Changelogs пользовательского репозитория не зависит от заголовка Host
+	req := httptest.NewRequest(http.MethodGet, "/repo/custom.iso/dists/custom/Release", nil)
+	req.Host = "attacker.example"
+	rec := httptest.NewRecorder()
+	m.router.ServeHTTP(rec, req)
+	if want := "Changelogs: http://repo.loc:4309/changelogs/custom.iso/@CHANGEPATH@\n"; rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("GET custom Release = %d\n%s\nwant %q", rec.Code, rec.Body, want)
+	}
+	if strings.Contains(rec.Body.String(), "attacker.example") {
+		t.Errorf("custom Release contains Host header:\n%s", rec.Body)
+	}
+}
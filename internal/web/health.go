@@ -0,0 +1,99 @@
+package web
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kirsrus/iso2repo/internal/repo"
+	"golang.org/x/exp/slog"
+)
+
+// healthData модель данных для шаблона health.html.
+type healthData struct {
+	// Все репозитории каталога с отметкой выбранных дополнительных.
+	Repos []healthRepoOption
+	// Отчёты по всем репозиториям (сводка) или по выбранному.
+	Reports []*repo.HealthReport
+	// Выбран один репозиторий — показывается подробный отчёт.
+	Detail bool
+	// Адрес подробного отчёта в формате JSON.
+	APIURL string
+	Error  string
+}
+
+// healthRepoOption репозиторий в форме выбора дополнительных репозиториев.
+type healthRepoOption struct {
+	Name     string
+	Selected bool
+}
+
+// healthQuery формирует условия проверки зависимостей репозитория name из
+// параметров запроса with (может повторяться).
+func healthQuery(c *gin.Context, name string) repo.HealthQuery {
+	return repo.HealthQuery{
+		Repo: name,
+		With: c.QueryArray("with"),
+	}
+}
+
+// handleHealth обработчик маршрута GET /health.
+// Отчёт о целостности зависимостей: без параметров — сводка по всем репозиториям,
+// с параметром repo — подробный отчёт по репозиторию, в том числе с учётом
+// дополнительных репозиториев with.
+func (m *Web) handleHealth(c *gin.Context) {
+	q := healthQuery(c, c.Query("repo"))
+	selected := make(map[string]bool)
+	for _, name := range q.With {
+		selected[name] = true
+	}
+
+	data := healthData{
+		Repos:   make([]healthRepoOption, 0),
+		Reports: make([]*repo.HealthReport, 0),
+		Detail:  q.Repo != "",
+	}
+	for _, name := range m.catalog.Repos() {
+		data.Repos = append(data.Repos, healthRepoOption{Name: name, Selected: selected[name]})
+	}
+
+	names := []string{q.Repo}
+	if !data.Detail {
+		names = m.catalog.Repos()
+	}
+	for _, name := range names {
+		q.Repo = name
+		report, err := m.catalog.Health(q)
+		if err != nil {
+			// Репозиторий мог пропасть между получением списка и проверкой
+			if data.Detail {
+				data.Error = err.Error()
+			}
+			m.log.Debug("отчёт о зависимостях не построен", slog.String("repo", name), slog.String("error", err.Error()))
+			continue
+		}
+		data.Reports = append(data.Reports, report)
+	}
+	if data.Detail {
+		data.APIURL = "/api/repos/" + url.PathEscape(q.Repo) + "/health"
+		if len(q.With) > 0 {
+			data.APIURL += "?" + url.Values{"with": q.With}.Encode()
+		}
+	}
+
+	c.HTML(http.StatusOK, "health.html", data)
+}
+
+// handleAPIHealth обработчик маршрута GET /api/repos/:name/health.
+// Возвращает отчёт о целостности зависимостей репозитория по дистрибутивам.
+// Параметр with (может повторяться) — дополнительные репозитории, пакеты которых
+// могут удовлетворять зависимости.
+func (m *Web) handleAPIHealth(c *gin.Context) {
+	report, err := m.catalog.Health(healthQuery(c, c.Param("name")))
+	if err != nil {
+		apiError(c, repoErrorStatus(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
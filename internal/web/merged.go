@@ -0,0 +1,220 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/kirsrus/iso2repo/internal/repo"
+	"golang.org/x/exp/slog"
+)
+
+// mergedEntry объединённый репозиторий и условия его актуальности.
+type mergedEntry struct {
+	// Версия описаний объединённых репозиториев
+	generation int
+	// Версия каталога пакетов
+	catalog int
+	// Дистрибутивы источников, отфильтрованные политикой
+	policy []*policyDist
+	merged *repo.MergedRepo
+}
+
+// mergedView состояние объединённого репозитория для GET /api/merged.
+type mergedView struct {
+	Config repo.MergedConfig `json:"config"`
+	// Строка sources.list.
+	Line          string   `json:"sources_list"`
+	Architectures []string `json:"architectures"`
+	Packages      int      `json:"packages"`
+	Missing       []string `json:"missing,omitempty"`
+	Error         string   `json:"error,omitempty"`
+}
+
+// mergedRepo возвращает объединённый репозиторий name. Результат кэшируется до
+// изменения описаний объединённых репозиториев, каталога пакетов или решений
+// политики по дистрибутивам источников.
+func (m *Web) mergedRepo(ctx context.Context, name string) (*repo.MergedRepo, error) {
+	_, generation, _ := m.merged.Configs()
+	cfg, ok := m.merged.Config(name)
+	if !ok {
+		return nil, errors.Wrapf(repo.ErrPackageNotFound, "объединённый репозиторий %s", name)
+	}
+	catalog := m.catalog.Generation()
+
+	// Пакеты, исключённые политикой, не попадают и в объединённый репозиторий
+	type distKey struct{ repo, dist string }
+	dists := make(map[distKey]*policyDist)
+	pds := make([]*policyDist, 0)
+	for _, s := range cfg.Sources {
+		r, ok := m.findRepo(s.Repo)
+		if !ok {
+			continue
+		}
+		names := s.Dists
+		if len(names) == 0 {
+			var err error
+			if names, err = m.repoDists(ctx, r); err != nil {
+				return nil, err
+			}
+		}
+		for _, dist := range names {
+			pd, err := m.filteredDist(ctx, r, dist)
+			if err != nil {
+				return nil, err
+			}
+			if pd != nil {
+				dists[distKey{s.Repo, dist}] = pd
+				pds = append(pds, pd)
+			}
+		}
+	}
+
+	m.mergedMu.Lock()
+	cached, ok := m.mergedCache[name]
+	m.mergedMu.Unlock()
+	if ok && cached.generation == generation && cached.catalog == catalog && samePolicyDists(cached.policy, pds) {
+		return cached.merged, nil
+	}
+
+	// Сборка не привязана к запросу: её результат ждут и другие клиенты
+	value, err, _ := m.mergedGroup.Do(name, func() (any, error) {
+		return m.catalog.Merge(context.Background(), cfg, func(e repo.CatalogEntry) bool {
+			pd := dists[distKey{e.Repo, e.Suite}]
+			if pd == nil {
+				return false
+			}
+			_, blocked := pd.decisions[e.Filename]
+			return blocked
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := value.(*repo.MergedRepo)
+	if len(result.Missing) > 0 {
+		m.log.Warn("источники объединённого репозитория не найдены", slog.String("merged", name), slog.String("repos", strings.Join(result.Missing, ", ")))
+	}
+
+	m.mergedMu.Lock()
+	m.mergedCache[name] = &mergedEntry{generation: generation, catalog: catalog, policy: pds, merged: result}
+	m.mergedMu.Unlock()
+
+	return result, nil
+}
+
+// samePolicyDists проверяет, что решения политики по дистрибутивам не изменились.
+func samePolicyDists(a, b []*policyDist) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// mergedRepoStrings возвращает строки sources.list объединённых репозиториев.
+func (m *Web) mergedRepoStrings() []string {
+	configs, _, _ := m.merged.Configs()
+	result := make([]string, 0, len(configs))
+	for _, cfg := range configs {
+		result = append(result, cfg.RepoString())
+	}
+
+	return result
+}
+
+// handleMerged обработчик маршрута /merged/:name/*path.
+// Отдаёт Release и индексы Packages объединённого репозитория, а файлы пакетов
+// (pool/<репозиторий>/<путь>) — из репозиториев-источников. Release не подписывается:
+// репозиторий подключается с trusted=yes, как и пользовательские.
+func (m *Web) handleMerged(c *gin.Context) {
+	name := c.Param("name")
+	innerPath := strings.Trim(c.Param("path"), "/")
+
+	merged, err := m.mergedRepo(c.Request.Context(), name)
+	if errors.Is(err, repo.ErrPackageNotFound) {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		m.log.Error("не удалось сформировать объединённый репозиторий", err, slog.String("merged", name))
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	fileName := path.Base(innerPath)
+	if data, ok := merged.Lookup(innerPath); ok {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", fileName))
+		c.Data(http.StatusOK, "application/octet-stream", data)
+		return
+	}
+
+	e, ok := merged.Pool(innerPath)
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	r, ok := m.findRepo(e.Repo)
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	reader, err := r.Open(c.Request.Context(), e.Filename)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	defer reader.Close()
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", fileName))
+	c.DataFromReader(http.StatusOK, -1, "application/octet-stream", reader, nil)
+}
+
+// handleAPIMerged обработчик маршрута GET /api/merged.
+// Возвращает описания объединённых репозиториев, их строки sources.list, количество
+// пакетов и отсутствующие источники, а также ошибку разбора файла описаний.
+func (m *Web) handleAPIMerged(c *gin.Context) {
+	configs, _, loadErr := m.merged.Configs()
+
+	views := make([]mergedView, 0, len(configs))
+	for _, cfg := range configs {
+		view := mergedView{Config: cfg, Line: cfg.RepoString()}
+		merged, err := m.mergedRepo(c.Request.Context(), cfg.Name)
+		if err != nil {
+			view.Error = err.Error()
+		} else {
+			view.Architectures = merged.Architectures
+			view.Packages = len(merged.Packages)
+			view.Missing = merged.Missing
+		}
+		views = append(views, view)
+	}
+
+	result := gin.H{"path": m.merged.Path(), "repos": views}
+	if loadErr != nil {
+		result["error"] = loadErr.Error()
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// handleAPIMergedRepo обработчик маршрута GET /api/merged/:name.
+// Возвращает пакеты, вошедшие в объединённый репозиторий, с их источниками.
+func (m *Web) handleAPIMergedRepo(c *gin.Context) {
+	merged, err := m.mergedRepo(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		apiError(c, repoErrorStatus(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, merged)
+}
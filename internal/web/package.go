@@ -0,0 +1,241 @@
+package web
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cockroachdb/errors"
+	"github.com/gin-gonic/gin"
+	repoPkg "github.com/kirsrus/iso2repo/internal/repo"
+	"github.com/kirsrus/iso2repo/models"
+	"github.com/kirsrus/iso2repo/pkg/deb"
+	"golang.org/x/exp/slog"
+)
+
+// maxInlineFile максимальный размер файла из пакета, показываемого в браузере.
+const maxInlineFile = 1 << 20
+
+// packageView модель для отображения корня пакета .deb.
+type packageView struct {
+	// Ссылка на скачивание самого пакета.
+	DownloadURL string
+	// Версия формата пакета (debian-binary).
+	Format string
+	// Поля control-файла в текстовом виде.
+	Control string
+}
+
+// packageFileView модель для отображения файла из пакета .deb.
+type packageFileView struct {
+	Name string
+	// Текст файла (сжатые gzip файлы показываются распакованными).
+	Text string
+	// Причина, по которой содержимое не показывается (двоичный или слишком большой файл).
+	Notice string
+}
+
+// splitPackagePath выделяет из пути внутри репозитория путь к пакету и путь внутри
+// пакета: "pool/main/foo.deb/usr/bin/" → "pool/main/foo.deb", "/usr/bin/".
+// Путь к самому пакету без завершающего "/" не выделяется — такой файл отдаётся
+// на скачивание.
+func splitPackagePath(p string) (pkgPath, inner string, ok bool) {
+	segments := strings.Split(p, "/")
+	for i := 0; i < len(segments)-1; i++ {
+		if repoPkg.IsPackageFile(segments[i]) {
+			return strings.Join(segments[:i+1], "/"), "/" + strings.Join(segments[i+1:], "/"), true
+		}
+	}
+
+	return "", "", false
+}
+
+// handlePackage отображает содержимое пакета pkgPath репозитория repo как директорию:
+// поля control и дерево файлов data.tar, а файлы архива control.tar — в директории
+// DEBIAN. Путь inner, оканчивающийся на "/", — директория внутри пакета, иначе —
+// файл, содержимое которого показывается в браузере.
+func (m *Web) handlePackage(c *gin.Context, repo models.Repoes, repoName, pkgPath, inner string) {
+	dir := path.Clean(inner)
+	innerPath := strings.TrimSuffix(pkgPath+dir, "/")
+
+	data := repoData{
+		RepoName:    repoName,
+		Breadcrumbs: makeBreadcrumbs(repoName, innerPath),
+	}
+
+	if err := m.checkPackage(c, repo, pkgPath); err != nil {
+		m.packageError(c, err, innerPath)
+		return
+	}
+
+	if !strings.HasSuffix(inner, "/") {
+		file, err := m.packageFile(c, repo, pkgPath, dir)
+		if err != nil {
+			m.packageError(c, err, innerPath)
+			return
+		}
+		data.File = file
+		c.HTML(http.StatusOK, "repo.html", data)
+		return
+	}
+
+	inspection, err := repoPkg.InspectPackage(c.Request.Context(), repo, pkgPath)
+	if err != nil {
+		m.packageError(c, err, innerPath)
+		return
+	}
+
+	var entries []models.Entry
+	links := make(map[string]deb.DataFile)
+	if dir == deb.ControlDir {
+		entries = make([]models.Entry, 0, len(inspection.ControlFiles))
+		for _, name := range inspection.ControlFiles {
+			entries = append(entries, models.Entry{Name: name, FilePath: deb.ControlDir + "/" + name})
+		}
+	} else {
+		entries, links = packageDirEntries(inspection.Files, dir)
+	}
+
+	if dir == "/" {
+		control := new(strings.Builder)
+		inspection.Control.WriteTo(control)
+		data.Package = &packageView{
+			DownloadURL: "/repo/" + repoName + "/" + pkgPath,
+			Format:      inspection.Format,
+			Control:     control.String(),
+		}
+		entries = append(entries, models.Entry{Name: strings.TrimPrefix(deb.ControlDir, "/"), IsDir: true})
+	} else if len(entries) == 0 {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	data.Entries = makeEntryViews(repoName, innerPath, entries)
+	for i, e := range data.Entries {
+		if link, ok := links[e.Name]; ok {
+			data.Entries[i].Link = link.LinkTarget
+			data.Entries[i].URL = packageLinkURL("/repo/"+repoName+"/"+pkgPath, inspection.Files, link)
+		}
+	}
+	c.HTML(http.StatusOK, "repo.html", data)
+}
+
+// packageFile читает файл name из пакета для показа в браузере.
+func (m *Web) packageFile(c *gin.Context, repo models.Repoes, pkgPath, name string) (*packageFileView, error) {
+	view := &packageFileView{Name: path.Base(name)}
+
+	content, err := repoPkg.ReadPackageFile(c.Request.Context(), repo, pkgPath, name, maxInlineFile)
+	if errors.Is(err, deb.ErrFileTooLarge) {
+		view.Notice = "Файл слишком большой для просмотра."
+		return view, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasSuffix(name, ".gz") {
+		content, err = gunzipInline(content)
+		if err != nil {
+			view.Notice = "Не удалось распаковать файл: " + err.Error()
+			return view, nil
+		}
+	}
+
+	if !utf8.Valid(content) || bytes.IndexByte(content, 0) >= 0 {
+		view.Notice = "Двоичный файл."
+		return view, nil
+	}
+	view.Text = string(content)
+
+	return view, nil
+}
+
+// packageError отвечает на ошибку просмотра пакета, записывая в лог внутренние ошибки.
+func (m *Web) packageError(c *gin.Context, err error, innerPath string) {
+	status := repoErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		m.log.Error("не удалось прочитать пакет", err, slog.String("path", innerPath))
+	}
+	c.String(status, "%s\n", err.Error())
+}
+
+// packageDirEntries формирует список записей директории dir пакета по списку его
+// файлов. Промежуточные директории, отсутствующие в архиве, добавляются неявно.
+// Второе значение — символические ссылки директории по имени записи.
+func packageDirEntries(files []deb.DataFile, dir string) ([]models.Entry, map[string]deb.DataFile) {
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+
+	result := make([]models.Entry, 0)
+	links := make(map[string]deb.DataFile)
+	seen := make(map[string]int)
+	for _, f := range files {
+		if !strings.HasPrefix(f.Path, prefix) {
+			continue
+		}
+
+		name, _, nested := strings.Cut(strings.TrimPrefix(f.Path, prefix), "/")
+		if name == "" {
+			continue
+		}
+
+		entry := models.Entry{
+			Name:     name,
+			FilePath: prefix + name,
+			IsDir:    nested || f.Type == "dir",
+		}
+		if !nested && f.Type == "file" {
+			entry.Size = f.Size
+		}
+		if !nested && f.Type == "symlink" {
+			links[name] = f
+		}
+
+		if i, ok := seen[name]; ok {
+			result[i].IsDir = result[i].IsDir || entry.IsDir
+			continue
+		}
+		seen[name] = len(result)
+		result = append(result, entry)
+	}
+
+	return result, links
+}
+
+// packageLinkURL возвращает адрес цели символической ссылки link внутри пакета.
+func packageLinkURL(baseURL string, files []deb.DataFile, link deb.DataFile) string {
+	target := link.LinkTarget
+	if !path.IsAbs(target) {
+		target = path.Join(path.Dir(link.Path), target)
+	}
+
+	for _, f := range files {
+		if f.Type == "dir" && f.Path == target || strings.HasPrefix(f.Path, target+"/") {
+			return baseURL + target + "/"
+		}
+	}
+
+	return baseURL + target
+}
+
+// gunzipInline распаковывает сжатый gzip файл, ограничивая размер результата.
+func gunzipInline(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	content, err := io.ReadAll(io.LimitReader(zr, maxInlineFile+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxInlineFile {
+		return nil, errors.Newf("распакованный файл больше %d байт", maxInlineFile)
+	}
+
+	return content, nil
+}
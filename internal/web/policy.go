@@ -0,0 +1,433 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/kirsrus/iso2repo/internal/audit"
+	"github.com/kirsrus/iso2repo/internal/policy"
+	"github.com/kirsrus/iso2repo/internal/repo"
+	"github.com/kirsrus/iso2repo/internal/vuln"
+	"github.com/kirsrus/iso2repo/models"
+	"github.com/kirsrus/iso2repo/pkg/deb"
+	"golang.org/x/exp/slog"
+)
+
+// errPackageDenied раздача пакета запрещена политикой.
+var errPackageDenied = errors.New("пакет запрещён политикой")
+
+// policyDist индексы дистрибутива, отфильтрованные политикой, и условия их актуальности.
+type policyDist struct {
+	// Версия политики, по которой выполнена фильтрация
+	generation int
+	// База уязвимостей, по которой выполнена фильтрация (nil — не использовалась)
+	db *vuln.Database
+	fd *repo.FilteredDist
+	// Решения по исключённым пакетам по пути файла пакета
+	decisions map[string]policy.Decision
+}
+
+// policyRepo дистрибутивы репозитория и их индексы, отфильтрованные политикой.
+// Сбрасывается при изменении репозитория (invalidatePolicy).
+type policyRepo struct {
+	// Номер версии кэша: увеличивается при каждом сбросе, чтобы не сохранять
+	// результаты, вычисленные по прежнему содержимому репозитория
+	version int
+	// Дистрибутивы репозитория (nil — ещё не прочитаны)
+	dists []string
+	// Отфильтрованные дистрибутивы по имени
+	filtered map[string]*policyDist
+}
+
+// blockedPackage пакет, раздача которого запрещена политикой.
+type blockedPackage struct {
+	repo.CatalogEntry
+	policy.Decision
+}
+
+// policyView состояние политики для GET /api/policy.
+type policyView struct {
+	Path string `json:"path"`
+	// Ошибка разбора файла политики (действует предыдущая корректная версия).
+	Error       string           `json:"error,omitempty"`
+	Rules       []policy.Rule    `json:"rules"`
+	Fingerprint string           `json:"signing_key,omitempty"`
+	Blocked     []blockedPackage `json:"blocked"`
+}
+
+// policyEntry возвращает кэш политики репозитория по ключу name (см. policyKey).
+// Вызывается под policyMu.
+func (m *Web) policyEntry(name string) *policyRepo {
+	entry, ok := m.policyCache[name]
+	if !ok {
+		entry = &policyRepo{filtered: make(map[string]*policyDist)}
+		m.policyCache[name] = entry
+	}
+
+	return entry
+}
+
+// policyKey возвращает ключ кэша политики репозитория r: имя репозитория, а для
+// снимка — ещё имя снимка и время его создания (удалённый снимок можно создать
+// заново с тем же именем, а содержимое снимка не меняется).
+func policyKey(r models.Repoes) string {
+	if snapshot, ok := r.(*repo.SnapshotRepo); ok {
+		s := snapshot.Snapshot()
+		return fmt.Sprintf("%s/%s/%d", r.Metadata().Name, s.Name, s.Time.UnixNano())
+	}
+
+	return r.Metadata().Name
+}
+
+// invalidatePolicy сбрасывает кэш политики репозитория name после его изменения.
+func (m *Web) invalidatePolicy(name string) {
+	m.policyMu.Lock()
+	defer m.policyMu.Unlock()
+
+	entry := m.policyEntry(name)
+	entry.version++
+	entry.dists = nil
+	entry.filtered = make(map[string]*policyDist)
+}
+
+// repoDists возвращает дистрибутивы репозитория r. Список кэшируется до изменения
+// репозитория.
+func (m *Web) repoDists(ctx context.Context, r models.Repoes) ([]string, error) {
+	name := policyKey(r)
+	m.policyMu.Lock()
+	entry := m.policyEntry(name)
+	dists, version := entry.dists, entry.version
+	m.policyMu.Unlock()
+	if dists != nil {
+		return dists, nil
+	}
+
+	dists, err := repo.Distributions(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	m.policyMu.Lock()
+	if entry := m.policyEntry(name); entry.version == version {
+		entry.dists = dists
+	}
+	m.policyMu.Unlock()
+
+	return dists, nil
+}
+
+// filteredDist возвращает индексы дистрибутива dist репозитория r, отфильтрованные
+// действующей политикой, или nil, если политика пуста. Результат кэшируется до
+// изменения репозитория, политики или базы уязвимостей.
+func (m *Web) filteredDist(ctx context.Context, r models.Repoes, dist string) (*policyDist, error) {
+	pol, generation, _ := m.policy.Policy()
+	if pol.Empty() {
+		return nil, nil
+	}
+
+	var db *vuln.Database
+	if pol.NeedsVulnerabilities() && m.vulns != nil {
+		db, _ = m.vulns.Database()
+	}
+
+	name := policyKey(r)
+	m.policyMu.Lock()
+	entry := m.policyEntry(name)
+	cached, version := entry.filtered[dist], entry.version
+	m.policyMu.Unlock()
+	if cached != nil && cached.generation == generation && cached.db == db {
+		return cached, nil
+	}
+
+	// Фильтрация не привязана к запросу: её результат ждут и другие клиенты
+	key := fmt.Sprintf("%s/%s/%d/%d", name, dist, version, generation)
+	value, err, _ := m.policyGroup.Do(key, func() (any, error) {
+		return m.filterDist(context.Background(), r, dist, pol, generation, db)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := value.(*policyDist)
+
+	m.policyMu.Lock()
+	if entry := m.policyEntry(name); entry.version == version {
+		entry.filtered[dist] = result
+	}
+	m.policyMu.Unlock()
+
+	return result, nil
+}
+
+// filterDist применяет политику pol к индексам дистрибутива dist репозитория r.
+// Каждое решение правила записывается в лог, исключения — и в журнал аудита.
+func (m *Web) filterDist(ctx context.Context, r models.Repoes, dist string, pol *policy.Policy, generation int, db *vuln.Database) (*policyDist, error) {
+	matchDB := db
+	if matchDB == nil {
+		matchDB = vuln.NewDatabase()
+	}
+
+	result := &policyDist{generation: generation, db: db, decisions: make(map[string]policy.Decision)}
+	fd, err := repo.FilterDist(ctx, r, dist, func(e repo.CatalogEntry, p deb.Paragraph) bool {
+		s := policy.Subject{
+			Repo:       e.Repo,
+			Name:       e.Name,
+			Version:    e.Version,
+			Arch:       e.Arch,
+			Maintainer: p.Get("Maintainer"),
+		}
+		if pol.NeedsLicenses() {
+			license, err := m.licenses.Get(ctx, r, e, p.Get("SHA256")+p.Get("MD5sum"))
+			if err != nil {
+				m.log.Warn("не удалось определить лицензию пакета", slog.String("repo", e.Repo), slog.String("file", e.Filename), slog.String("error", err.Error()))
+			}
+			s.License = license.Expression
+		}
+		if pol.NeedsVulnerabilities() {
+			s.Vulnerabilities = m.catalog.MatchVulnerabilities(matchDB, e)
+		}
+
+		d := pol.Evaluate(s)
+		if d.Rule == "" {
+			return true
+		}
+
+		attrs := []any{
+			slog.String("repo", e.Repo), slog.String("dist", dist), slog.String("package", e.Name),
+			slog.String("version", e.Version), slog.String("arch", e.Arch),
+			slog.String("rule", d.Rule), slog.String("reason", d.Reason),
+		}
+		if d.Allowed {
+			m.log.Info("пакет разрешён политикой", attrs...)
+			return true
+		}
+
+		m.log.Info("пакет исключён политикой из индекса", attrs...)
+		result.decisions[e.Filename] = d
+		m.recordPolicy(audit.Entry{
+			Action:  audit.ActionBlock,
+			Repo:    e.Repo,
+			Package: e.Name,
+			Version: e.Version,
+			Arch:    e.Arch,
+			Files:   []string{e.Filename},
+			Reason:  d.Rule + ": " + d.Reason,
+		})
+
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.fd = fd
+
+	return result, nil
+}
+
+// recordPolicy записывает решение политики в журнал аудита.
+func (m *Web) recordPolicy(entry audit.Entry) {
+	entry.Source = "policy"
+	if err := m.audit.Record(entry); err != nil {
+		m.log.Warn("не удалось записать операцию в журнал аудита", slog.Any("error", err))
+	}
+}
+
+// servePolicy отдаёт файл innerPath репозитория r с учётом политики: переписанные
+// индексы и подписанный заново Release дистрибутивов, из которых исключены пакеты,
+// и отказ (403) в загрузке исключённых пакетов. Возвращает false, если файл
+// отдаётся без изменений.
+func (m *Web) servePolicy(c *gin.Context, r models.Repoes, innerPath string) bool {
+	if pol, _, _ := m.policy.Policy(); pol.Empty() {
+		return false
+	}
+
+	ctx := c.Request.Context()
+	dists, err := m.repoDists(ctx, r)
+	if err != nil {
+		return false
+	}
+
+	for _, dist := range dists {
+		prefix := path.Join("dists", dist) + "/"
+		if !strings.HasPrefix(innerPath, prefix) {
+			continue
+		}
+
+		pd, err := m.filteredDist(ctx, r, dist)
+		if err != nil {
+			// Без решения политики индексы не отдаются: иначе запрещённые пакеты станут доступны
+			m.log.Error("не удалось применить политику к дистрибутиву", err, slog.String("repo", r.Metadata().Name), slog.String("dist", dist))
+			c.Status(http.StatusInternalServerError)
+			return true
+		}
+		if pd == nil || len(pd.fd.Removed) == 0 {
+			return false
+		}
+
+		if releaseDist(innerPath) == dist {
+			m.serveRelease(c, r, innerPath, pd.fd.Release)
+			return true
+		}
+
+		data, found, hidden := pd.fd.Lookup(innerPath)
+		if hidden {
+			c.Status(http.StatusNotFound)
+			return true
+		} else if !found {
+			return false
+		}
+
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", path.Base(innerPath)))
+		c.Data(http.StatusOK, "application/octet-stream", data)
+		return true
+	}
+
+	if !repo.IsPackageFile(path.Base(innerPath)) {
+		return false
+	}
+	if err := m.checkPackage(c, r, innerPath); err != nil {
+		c.String(repoErrorStatus(err), "%s\n", err.Error())
+		return true
+	}
+
+	return false
+}
+
+// checkPackage проверяет, разрешена ли политикой раздача файла пакета file (путь
+// внутри репозитория r): самого файла, его содержимого и извлечённых из него данных.
+// Отказ записывается в лог и журнал аудита и возвращается ошибкой errPackageDenied,
+// ошибка применения политики — в лог.
+// Все обработчики, отдающие пакеты или их содержимое, проверяют пакет этой функцией.
+func (m *Web) checkPackage(c *gin.Context, r models.Repoes, file string) error {
+	if pol, _, _ := m.policy.Policy(); pol.Empty() {
+		return nil
+	}
+
+	ctx := c.Request.Context()
+	file = strings.TrimPrefix(path.Clean("/"+file), "/")
+	dists, err := m.repoDists(ctx, r)
+	if err != nil {
+		// Без дистрибутивов нет и индексов, из которых политика исключает пакеты
+		return nil
+	}
+
+	for _, dist := range dists {
+		pd, err := m.filteredDist(ctx, r, dist)
+		if err != nil {
+			m.log.Error("не удалось применить политику к дистрибутиву", err, slog.String("repo", r.Metadata().Name), slog.String("dist", dist))
+			return errors.Wrapf(err, "не удалось применить политику к дистрибутиву %s", dist)
+		}
+		if pd == nil {
+			return nil
+		}
+
+		d, ok := pd.decisions[file]
+		if !ok {
+			continue
+		}
+
+		m.log.Info("загрузка пакета запрещена политикой", slog.String("repo", r.Metadata().Name), slog.String("file", file),
+			slog.String("client", c.ClientIP()), slog.String("rule", d.Rule), slog.String("reason", d.Reason))
+		m.recordPolicy(audit.Entry{
+			Action: audit.ActionDeny,
+			Actor:  c.ClientIP(),
+			Repo:   r.Metadata().Name,
+			Files:  []string{file},
+			Reason: d.Rule + ": " + d.Reason,
+		})
+
+		return errors.Mark(errors.Newf("пакет %s запрещён политикой: %s (%s)", path.Base(file), d.Rule, d.Reason), errPackageDenied)
+	}
+
+	return nil
+}
+
+// handleAPIPolicy обработчик маршрута GET /api/policy.
+// Возвращает действующие правила политики, ошибку разбора файла политики,
+// отпечаток ключа подписи и пакеты, исключённые политикой из всех репозиториев.
+func (m *Web) handleAPIPolicy(c *gin.Context) {
+	pol, _, loadErr := m.policy.Policy()
+
+	view := policyView{Path: m.policy.Path(), Rules: make([]policy.Rule, 0), Blocked: make([]blockedPackage, 0)}
+	if loadErr != nil {
+		view.Error = loadErr.Error()
+	}
+	if pol != nil {
+		view.Rules = pol.Rules
+	}
+	if m.signer != nil {
+		fingerprint, err := m.signer.Fingerprint()
+		if err != nil {
+			apiError(c, http.StatusInternalServerError, err)
+			return
+		}
+		view.Fingerprint = fingerprint
+	}
+
+	ctx := c.Request.Context()
+	names := make([]string, 0)
+	m.repos.Range(func(key, _ any) bool {
+		names = append(names, key.(string))
+		return true
+	})
+	sort.Strings(names)
+	for _, name := range names {
+		r, ok := m.findRepo(name)
+		if !ok {
+			continue
+		}
+		dists, err := m.repoDists(ctx, r)
+		if err != nil {
+			continue
+		}
+		for _, dist := range dists {
+			pd, err := m.filteredDist(ctx, r, dist)
+			if err != nil {
+				apiError(c, http.StatusInternalServerError, err)
+				return
+			}
+			if pd == nil {
+				continue
+			}
+			for _, e := range pd.fd.Removed {
+				view.Blocked = append(view.Blocked, blockedPackage{CatalogEntry: e, Decision: pd.decisions[e.Filename]})
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, view)
+}
+
+// handleSigningKey обработчик маршрута GET /signing-key.asc.
+// Отдаёт открытый ключ, которым подписываются переписанные политикой Release.
+func (m *Web) handleSigningKey(c *gin.Context) {
+	if m.signer == nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	key, err := m.signer.PublicKey()
+	if err != nil {
+		m.log.Error("не удалось получить ключ подписи", err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/pgp-keys", key)
+}
+
+// repoHasFile проверяет, есть ли в репозитории r файл p.
+func repoHasFile(ctx context.Context, r models.Repoes, p string) bool {
+	reader, err := r.Open(ctx, p)
+	if err != nil {
+		return false
+	}
+	reader.Close()
+
+	return true
+}
@@ -0,0 +1,158 @@
+package web
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kirsrus/iso2repo/internal/policy"
+	"github.com/kirsrus/iso2repo/internal/repo"
+	"github.com/kirsrus/iso2repo/pkg/deb/debtest"
+	"golang.org/x/exp/slog"
+)
+
+// newPolicyTestWeb создаёт веб-сервер с политикой rules и пользовательским репозиторием
+// custom.iso с пакетами app и evil, раздачу последнего политика запрещает.
+func newPolicyTestWeb(t *testing.T) (*Web, *repo.RepoCustom) {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	log := slog.New(slog.NewTextHandler(io.Discard))
+
+	policyPath := filepath.Join(dir, "policy.yaml")
+	if err := os.WriteFile(policyPath, []byte("rules:\n  - name: no-evil\n    packages: [evil*]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := NewWeb(&Config{
+		Log:    log,
+		Router: gin.New(),
+		Policy: policy.NewFile(policyPath, log),
+		Signer: policy.NewSigner(filepath.Join(dir, "signing-key.asc")),
+	})
+	if err != nil {
+		t.Fatalf("NewWeb() error = %v", err)
+	}
+
+	repoDir := filepath.Join(dir, "custom.iso")
+	debtest.Write(t, repoDir, "app_1.0_amd64.deb", debtest.Package(t, "app", "1.0", "amd64", map[string]string{
+		"/usr/bin/app":                           "app",
+		"/usr/share/doc/app/changelog.Debian.gz": gzipString(t, "app (1.0) unstable; urgency=low\n"),
+	}))
+	debtest.Write(t, repoDir, "evil_1.0_amd64.deb", debtest.Package(t, "evil", "1.0", "amd64", map[string]string{
+		"/usr/bin/evil": "evil",
+		"/usr/share/doc/evil/changelog.Debian.gz": gzipString(t, "evil (1.0) unstable; urgency=low\n"),
+	}))
+	r := repo.NewRepoCustom(repoDir, log, repo.CustomOptions{})
+	m.repos.Store(r.Metadata().Name, r)
+	if err := m.catalog.Update(context.Background(), r); err != nil {
+		t.Fatalf("catalog.Update() error = %v", err)
+	}
+
+	return m, r
+}
+
+// gzipString сжимает s в gzip.
+func gzipString(t *testing.T, s string) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.String()
+}
+
+// get выполняет запрос GET к веб-серверу m.
+func get(m *Web, url string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	m.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+
+	return rec
+}
+
+func TestWeb_policyCache(t *testing.T) {
+	m, r := newPolicyTestWeb(t)
+	const index = "/repo/custom.iso/dists/custom/main/binary-amd64/Packages"
+
+	rec := get(m, index)
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "Package: evil") || !strings.Contains(rec.Body.String(), "Package: app") {
+		t.Fatalf("GET %s = %d\n%s", index, rec.Code, rec.Body)
+	}
+
+	// Новый запрещённый пакет: до сброса кэша отдаются прежние индексы и решения
+	debtest.Write(t, r.Metadata().Path, "evil-tools_1.0_amd64.deb", debtest.Package(t, "evil-tools", "1.0", "amd64", nil))
+	r.Refresh()
+	if rec := get(m, "/repo/custom.iso/pool/main/evil-tools_1.0_amd64.deb"); rec.Code != http.StatusOK {
+		t.Errorf("GET before invalidation = %d, want cached decisions", rec.Code)
+	}
+
+	m.invalidatePolicy(r.Metadata().Name)
+	if rec := get(m, "/repo/custom.iso/pool/main/evil-tools_1.0_amd64.deb"); rec.Code != http.StatusForbidden {
+		t.Errorf("GET after invalidation = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	rec = get(m, index)
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "Package: evil") {
+		t.Errorf("GET %s after invalidation = %d\n%s", index, rec.Code, rec.Body)
+	}
+
+	// Изменённая политика применяется без сброса кэша
+	if err := os.WriteFile(m.policy.Path(), []byte("rules:\n  - packages: [app, evil*]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rec = get(m, index)
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "Package: app") {
+		t.Errorf("GET %s after policy change = %d\n%s", index, rec.Code, rec.Body)
+	}
+}
+
+func TestWeb_policyDenied(t *testing.T) {
+	m, _ := newPolicyTestWeb(t)
+
+	tests := []struct {
+		name  string
+		url   string
+		allow string
+	}{
+		{name: "package file", url: "/repo/custom.iso/pool/main/%s_1.0_amd64.deb"},
+		{name: "package file with extra slashes", url: "/repo/custom.iso/pool//main/./%s_1.0_amd64.deb"},
+		{name: "package contents", url: "/repo/custom.iso/pool/main/%s_1.0_amd64.deb/usr/bin/"},
+		{name: "file from package", url: "/repo/custom.iso/pool/main/%s_1.0_amd64.deb/usr/bin/%[1]s"},
+		{name: "inspect API", url: "/api/repos/custom.iso/inspect/pool/main/%s_1.0_amd64.deb"},
+		{name: "changelog", url: "/changelogs/custom.iso/main/%.1[1]s/%[1]s/%[1]s_1.0"},
+		{name: "bundle", url: "/api/bundle?arch=amd64&packages=%s"},
+		{name: "bundle composition", url: "/api/bundle?arch=amd64&format=json&packages=%s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := get(m, fmt.Sprintf(tt.url, "evil")); rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "no-evil") {
+				t.Errorf("GET %s = %d, want %d\n%s", fmt.Sprintf(tt.url, "evil"), rec.Code, http.StatusForbidden, rec.Body)
+			}
+			if rec := get(m, fmt.Sprintf(tt.url, "app")); rec.Code != http.StatusOK {
+				t.Errorf("GET %s = %d, want %d\n%s", fmt.Sprintf(tt.url, "app"), rec.Code, http.StatusOK, rec.Body)
+			}
+		})
+	}
+
+	// Неканонический путь к индексу не обходит фильтрацию
+	rec := get(m, "/repo/custom.iso/dists//custom/./main/binary-amd64/Packages")
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "Package: evil") {
+		t.Errorf("GET Packages with extra slashes = %d\n%s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,50 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kirsrus/iso2repo/internal/repo"
+	"github.com/spf13/cast"
+)
+
+// sbomContentTypes тип содержимого и расширение файла SBOM по формату.
+var sbomContentTypes = map[string][2]string{
+	repo.SBOMCycloneDX: {"application/vnd.cyclonedx+json", "cdx.json"},
+	repo.SBOMSPDX:      {"application/spdx+json", "spdx.json"},
+}
+
+// handleAPISBOM обработчик маршрута GET /api/repos/:name/sbom.
+// Возвращает перечень компонентов (SBOM) репозитория. Параметры: format — cyclonedx
+// (по умолчанию) или spdx, licenses — определять лицензии по файлам copyright пакетов
+// (по умолчанию да; первое построение читает все пакеты репозитория).
+func (m *Web) handleAPISBOM(c *gin.Context) {
+	format := c.DefaultQuery("format", repo.SBOMCycloneDX)
+	contentType, ok := sbomContentTypes[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("неизвестный формат SBOM %s", format)})
+		return
+	}
+
+	name := c.Param("name")
+	sbom, err := m.catalog.SBOM(c.Request.Context(), repo.SBOMQuery{
+		Repo:        name,
+		Licenses:    cast.ToBool(c.DefaultQuery("licenses", "true")),
+		ToolVersion: m.version,
+	}, m.licenses)
+	if err != nil {
+		apiError(c, repoErrorStatus(err), err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := sbom.Write(&buf, format); err != nil {
+		apiError(c, repoErrorStatus(err), err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.%s\"", name, contentType[1]))
+	c.Data(http.StatusOK, contentType[0], buf.Bytes())
+}
@@ -0,0 +1,140 @@
+package web
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kirsrus/iso2repo/internal/repo"
+	"github.com/kirsrus/iso2repo/models"
+	"github.com/spf13/cast"
+	"golang.org/x/exp/slog"
+)
+
+// searchPageLimit количество результатов поиска, показываемых на странице.
+const searchPageLimit = 200
+
+// searchData модель данных для шаблона search.html.
+type searchData struct {
+	// Строка поиска пакета (параметр q).
+	Query string
+	// Путь файла (параметр path).
+	Path string
+	Mode string
+	Arch string
+	// Результаты поиска пакетов; nil, если запрос не задан.
+	Results []repo.CatalogEntry
+	// Результаты поиска файлов; nil, если путь не задан.
+	Files []repo.ContentsEntry
+	// Результатов больше, чем показано.
+	Truncated bool
+	Error     string
+}
+
+// updateCatalog перечитывает индексы репозитория r в каталог пакетов. Если за время
+// чтения репозиторий перестал обслуживаться, его пакеты удаляются из каталога.
+func (m *Web) updateCatalog(ctx context.Context, r models.Repoes) {
+	name := r.Metadata().Name
+	if err := m.catalog.Update(ctx, r); err != nil {
+		m.log.Warn("не удалось обновить каталог пакетов", slog.String("repo", name), slog.String("error", err.Error()))
+		return
+	}
+
+	if current, ok := m.findRepo(name); !ok || current != r {
+		m.catalog.Remove(name)
+	}
+}
+
+// catalogQuery формирует условия поиска из параметров запроса q, mode, arch и limit.
+func catalogQuery(c *gin.Context) repo.CatalogQuery {
+	return repo.CatalogQuery{
+		Query: c.Query("q"),
+		Mode:  repo.CatalogMode(c.Query("mode")),
+		Arch:  c.Query("arch"),
+		Limit: cast.ToInt(c.Query("limit")),
+	}
+}
+
+// contentsQuery формирует условия поиска файлов из параметров запроса path, mode,
+// arch и limit.
+func contentsQuery(c *gin.Context) repo.ContentsQuery {
+	return repo.ContentsQuery{
+		Path:  c.Query("path"),
+		Mode:  repo.CatalogMode(c.Query("mode")),
+		Arch:  c.Query("arch"),
+		Limit: cast.ToInt(c.Query("limit")),
+	}
+}
+
+// handleSearch обработчик маршрута GET /search.
+// Страница поиска пакетов (параметр q) и файлов в пакетах (параметр path) по всем
+// обслуживаемым репозиториям.
+func (m *Web) handleSearch(c *gin.Context) {
+	q := catalogQuery(c)
+	data := searchData{
+		Query: q.Query,
+		Path:  c.Query("path"),
+		Mode:  string(q.Mode),
+		Arch:  q.Arch,
+	}
+
+	// Запрашиваем на один результат больше, чтобы узнать об усечении списка
+	limit := q.Limit
+	if limit <= 0 {
+		limit = searchPageLimit
+	}
+
+	var err error
+	switch {
+	case data.Path != "":
+		cq := contentsQuery(c)
+		cq.Limit = limit + 1
+		data.Files, err = m.catalog.SearchContents(cq)
+		if len(data.Files) > limit {
+			data.Files = data.Files[:limit]
+			data.Truncated = true
+		}
+
+	case q.Query != "":
+		q.Limit = limit + 1
+		data.Results, err = m.catalog.Search(q)
+		if len(data.Results) > limit {
+			data.Results = data.Results[:limit]
+			data.Truncated = true
+		}
+	}
+	if err != nil {
+		data.Error = err.Error()
+	}
+
+	c.HTML(http.StatusOK, "search.html", data)
+}
+
+// handleAPISearch обработчик маршрута GET /api/search.
+// Ищет пакеты по всем обслуживаемым репозиториям. Параметры: q — строка поиска,
+// mode — prefix (по умолчанию), exact или regex, arch — архитектура (пакеты all
+// подходят под любую), limit — максимальное количество результатов.
+func (m *Web) handleAPISearch(c *gin.Context) {
+	results, err := m.catalog.Search(catalogQuery(c))
+	if err != nil {
+		apiError(c, repoErrorStatus(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// handleAPIContents обработчик маршрута GET /api/contents.
+// Ищет пакеты, содержащие файл, по индексам Contents всех обслуживаемых репозиториев.
+// Параметры: path — имя файла или окончание пути (mode=exact — полный путь,
+// mode=prefix — начало пути, mode=regex — регулярное выражение), arch — архитектура,
+// limit — максимальное количество результатов.
+func (m *Web) handleAPIContents(c *gin.Context) {
+	results, err := m.catalog.SearchContents(contentsQuery(c))
+	if err != nil {
+		apiError(c, repoErrorStatus(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,139 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kirsrus/iso2repo/internal/audit"
+	"github.com/kirsrus/iso2repo/internal/repo"
+)
+
+// snapshotRequest тело запроса создания снимка.
+type snapshotRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// snapshotView снимок со строкой sources.list для GET /api/repos/:name/snapshots.
+type snapshotView struct {
+	repo.Snapshot
+	Line string `json:"sources_list"`
+}
+
+// handleSnapshots обработчик маршрута GET /api/repos/:name/snapshots.
+// Возвращает снимки пользовательского репозитория в порядке создания.
+func (m *Web) handleSnapshots(c *gin.Context) {
+	custom, ok := m.findCustomRepo(c, c.Param("name"))
+	if !ok {
+		return
+	}
+
+	snapshots, err := custom.Snapshots()
+	if err != nil {
+		apiError(c, repoErrorStatus(err), err)
+		return
+	}
+
+	views := make([]snapshotView, 0, len(snapshots))
+	for _, s := range snapshots {
+		views = append(views, snapshotView{Snapshot: s, Line: m.snapshotString(c, custom, s.Name)})
+	}
+
+	c.JSON(http.StatusOK, views)
+}
+
+// handleCreateSnapshot обработчик маршрута POST /api/repos/:name/snapshots.
+// Создаёт снимок пользовательского репозитория с именем из тела запроса.
+func (m *Web) handleCreateSnapshot(c *gin.Context) {
+	custom, ok := m.findCustomRepo(c, c.Param("name"))
+	if !ok {
+		return
+	}
+
+	var req snapshotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apiError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	snapshot, err := custom.CreateSnapshot(req.Name)
+	m.recordAudit(c, audit.Entry{
+		Action:   audit.ActionSnapshot,
+		Repo:     custom.Metadata().Name,
+		Snapshot: req.Name,
+	}, err)
+	if err != nil {
+		apiError(c, repoErrorStatus(err), err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, snapshotView{Snapshot: snapshot, Line: m.snapshotString(c, custom, snapshot.Name)})
+}
+
+// handleDeleteSnapshot обработчик маршрута DELETE /api/repos/:name/snapshots/:snapshot.
+// Удаляет снимок пользовательского репозитория.
+func (m *Web) handleDeleteSnapshot(c *gin.Context) {
+	custom, ok := m.findCustomRepo(c, c.Param("name"))
+	if !ok {
+		return
+	}
+
+	snapshot, err := custom.DeleteSnapshot(c.Param("snapshot"))
+	m.recordAudit(c, audit.Entry{
+		Action:   audit.ActionSnapshotDelete,
+		Repo:     custom.Metadata().Name,
+		Snapshot: c.Param("snapshot"),
+	}, err)
+	if err != nil {
+		apiError(c, repoErrorStatus(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// handleSnapshot обработчик маршрута /snapshot/:name/:snapshot/*path.
+// Отдаёт файлы снимка пользовательского репозитория: Release, индексы и пакеты
+// в том виде, в каком они были на момент создания снимка, с учётом действующей
+// политики раздачи.
+func (m *Web) handleSnapshot(c *gin.Context) {
+	r, ok := m.findRepo(c.Param("name"))
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	custom, ok := r.(*repo.RepoCustom)
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	snapshot, err := custom.SnapshotRepo(c.Param("snapshot"))
+	if err != nil {
+		c.Status(repoErrorStatus(err))
+		return
+	}
+
+	innerPath := strings.TrimPrefix(path.Clean("/"+c.Param("path")), "/")
+	if m.servePolicy(c, snapshot, innerPath) {
+		return
+	}
+
+	reader, err := custom.OpenSnapshot(c.Param("snapshot"), innerPath)
+	if err != nil {
+		c.Status(repoErrorStatus(err))
+		return
+	}
+	defer reader.Close()
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", path.Base(innerPath)))
+	c.DataFromReader(http.StatusOK, -1, "application/octet-stream", reader, nil)
+}
+
+// snapshotString возвращает строку sources.list снимка с адресом сервера из параметра
+// запроса address (по умолчанию repo.loc), как в /sources.list.
+func (m *Web) snapshotString(c *gin.Context, custom *repo.RepoCustom, name string) string {
+	return strings.ReplaceAll(custom.SnapshotString(name), "0.0.0.0", fmt.Sprintf("%s:%d", c.DefaultQuery("address", "repo.loc"), m.port))
+}
@@ -0,0 +1,91 @@
+package web
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kirsrus/iso2repo/pkg/deb"
+)
+
+func TestWeb_snapshotString(t *testing.T) {
+	m, _ := newPolicyTestWeb(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/repos/custom.iso/snapshots", strings.NewReader(`{"name":"s1"}`))
+	req.Host = "attacker.example"
+	rec := httptest.NewRecorder()
+	m.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST snapshots = %d\n%s", rec.Code, rec.Body)
+	}
+
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{name: "default address", url: "/api/repos/custom.iso/snapshots", want: "http://repo.loc:4309/snapshot/custom.iso/s1 "},
+		{name: "address parameter", url: "/api/repos/custom.iso/snapshots?address=10.0.0.1", want: "http://10.0.0.1:4309/snapshot/custom.iso/s1 "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			req.Host = "attacker.example"
+			rec := httptest.NewRecorder()
+			m.router.ServeHTTP(rec, req)
+
+			var views []snapshotView
+			if err := json.Unmarshal(rec.Body.Bytes(), &views); err != nil || len(views) != 1 {
+				t.Fatalf("GET %s = %d, %v\n%s", tt.url, rec.Code, err, rec.Body)
+			}
+			if !strings.Contains(views[0].Line, tt.want) {
+				t.Errorf("sources_list = %q, want %q", views[0].Line, tt.want)
+			}
+		})
+	}
+}
+
+func TestWeb_snapshotPolicy(t *testing.T) {
+	m, r := newPolicyTestWeb(t)
+	if _, err := r.CreateSnapshot("s1"); err != nil {
+		t.Fatalf("CreateSnapshot() error = %v", err)
+	}
+
+	if rec := get(m, "/snapshot/custom.iso/s1/pool/main/evil_1.0_amd64.deb"); rec.Code != http.StatusForbidden {
+		t.Errorf("GET blocked package = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if rec := get(m, "/snapshot/custom.iso/s1/pool//main/evil_1.0_amd64.deb"); rec.Code != http.StatusForbidden {
+		t.Errorf("GET blocked package with extra slashes = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if rec := get(m, "/snapshot/custom.iso/s1/pool/main/app_1.0_amd64.deb"); rec.Code != http.StatusOK {
+		t.Errorf("GET allowed package = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	packages := get(m, "/snapshot/custom.iso/s1/dists/custom/main/binary-amd64/Packages")
+	if packages.Code != http.StatusOK || strings.Contains(packages.Body.String(), "Package: evil") || !strings.Contains(packages.Body.String(), "Package: app") {
+		t.Fatalf("GET Packages = %d\n%s", packages.Code, packages.Body)
+	}
+
+	rec := get(m, "/snapshot/custom.iso/s1/dists/custom/Release")
+	release, err := deb.ReadRelease(rec.Body)
+	if err != nil {
+		t.Fatalf("GET Release = %d, %v", rec.Code, err)
+	}
+	f, ok := release.File("main/binary-amd64/Packages")
+	if sum := sha256.Sum256(packages.Body.Bytes()); !ok || f.Hash != hex.EncodeToString(sum[:]) {
+		t.Errorf("Release entry for Packages = %+v, %v", f, ok)
+	}
+	if _, ok := release.Extra["Changelogs"]; ok {
+		t.Errorf("snapshot Release has Changelogs field")
+	}
+
+	// Неподписанный снимок остаётся неподписанным
+	if rec := get(m, "/snapshot/custom.iso/s1/dists/custom/InRelease"); rec.Code != http.StatusNotFound {
+		t.Errorf("GET InRelease = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,50 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/cockroachdb/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/kirsrus/iso2repo/internal/repo"
+	"github.com/kirsrus/iso2repo/pkg/deb"
+	"github.com/spf13/cast"
+)
+
+// maxInstalledSize максимальный размер списка установленных пакетов в запросе.
+const maxInstalledSize = 64 << 20
+
+// handleAPIUpgrades обработчик маршрута POST /api/upgrades.
+// Тело запроса — файл состояния dpkg (/var/lib/dpkg/status) или вывод dpkg-query -W.
+// Для каждого установленного пакета возвращает кандидата на обновление из
+// обслуживаемых репозиториев. Параметры: repos — репозитории через запятую (по
+// умолчанию все), arch — архитектура пакетов, для которых она не указана,
+// upgradable — возвращать только пакеты, для которых есть обновление.
+func (m *Web) handleAPIUpgrades(c *gin.Context) {
+	installed, err := deb.ReadInstalled(http.MaxBytesReader(c.Writer, c.Request.Body, maxInstalledSize))
+	if err != nil {
+		apiError(c, http.StatusBadRequest, errors.Wrap(repo.ErrInvalidQuery, err.Error()))
+		return
+	}
+
+	report, err := m.catalog.Upgrades(repo.UpgradeQuery{
+		Installed: installed,
+		Repos:     queryList(c, "repos"),
+		Arch:      c.Query("arch"),
+	})
+	if err != nil {
+		apiError(c, repoErrorStatus(err), err)
+		return
+	}
+
+	if cast.ToBool(c.Query("upgradable")) {
+		packages := make([]repo.Upgrade, 0)
+		for _, u := range report.Packages {
+			if u.Upgradable {
+				packages = append(packages, u)
+			}
+		}
+		report.Packages = packages
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,174 @@
+package web
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/kirsrus/iso2repo/internal/repo"
+	"github.com/kirsrus/iso2repo/internal/vuln"
+)
+
+// maxFeedSize максимальный размер импортируемой базы уязвимостей.
+const maxFeedSize = 1 << 30
+
+// vulnsData модель данных для шаблона vulns.html.
+type vulnsData struct {
+	Feeds []vuln.FeedInfo
+	// Директория хранилища баз уязвимостей.
+	Dir string
+	// Уровни важности по убыванию (столбцы сводки).
+	Severities []vuln.Severity
+	// Сводка по всем репозиториям.
+	Summaries []repo.VulnSummary
+	// Отчёт по выбранному репозиторию (параметр repo).
+	Report *repo.VulnReport
+	// Адрес отчёта в формате JSON.
+	APIURL string
+	Error  string
+}
+
+// handleVulns обработчик маршрута GET /vulns.
+// Уязвимости пакетов по локальным базам: без параметров — сводка по всем
+// репозиториям, с параметром repo — уязвимые пакеты репозитория.
+func (m *Web) handleVulns(c *gin.Context) {
+	db, feeds := m.vulns.Database()
+	data := vulnsData{
+		Feeds:      feeds,
+		Dir:        m.vulns.Dir(),
+		Severities: vuln.Severities,
+		APIURL:     "/api/vulns",
+	}
+
+	if name := c.Query("repo"); name != "" {
+		report, err := m.catalog.Vulnerabilities(db, name)
+		if err != nil {
+			data.Error = err.Error()
+		}
+		data.Report = report
+		data.APIURL = "/api/repos/" + url.PathEscape(name) + "/vulns"
+	} else {
+		data.Summaries = m.catalog.VulnSummaries(db)
+	}
+
+	c.HTML(http.StatusOK, "vulns.html", data)
+}
+
+// handleAPIVulns обработчик маршрута GET /api/vulns.
+// Возвращает загруженные базы уязвимостей и сводку уязвимостей по репозиториям.
+func (m *Web) handleAPIVulns(c *gin.Context) {
+	db, feeds := m.vulns.Database()
+
+	c.JSON(http.StatusOK, gin.H{
+		"feeds": feeds,
+		"repos": m.catalog.VulnSummaries(db),
+	})
+}
+
+// handleAPIRepoVulns обработчик маршрута GET /api/repos/:name/vulns.
+// Возвращает уязвимые пакеты репозитория и сводку по важности уязвимостей.
+func (m *Web) handleAPIRepoVulns(c *gin.Context) {
+	db, _ := m.vulns.Database()
+	report, err := m.catalog.Vulnerabilities(db, c.Param("name"))
+	if err != nil {
+		apiError(c, repoErrorStatus(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// handleImportVulns обработчик маршрута POST /api/vulns.
+// Импортирует базы уязвимостей (данные Debian Security Tracker в JSON, OVAL, OSV):
+// multipart/form-data — любое количество файлов; тело запроса целиком — один файл,
+// имя которого задаётся параметром ?name= или заголовком Content-Disposition.
+// Файл с тем же именем заменяется.
+func (m *Web) handleImportVulns(c *gin.Context) {
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxFeedSize)
+	imported := make([]*vuln.FeedInfo, 0)
+
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		c.Request.Body = body
+		reader, err := c.Request.MultipartReader()
+		if err != nil {
+			apiError(c, http.StatusBadRequest, err)
+			return
+		}
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				apiError(c, http.StatusBadRequest, err)
+				return
+			}
+			if part.FileName() == "" {
+				part.Close()
+				continue
+			}
+			info, err := m.vulns.Import(part.FileName(), part)
+			part.Close()
+			if err != nil {
+				apiError(c, vulnErrorStatus(err), err)
+				return
+			}
+			imported = append(imported, info)
+		}
+	} else {
+		name := c.Query("name")
+		if name == "" {
+			if _, params, err := mime.ParseMediaType(c.GetHeader("Content-Disposition")); err == nil {
+				name = params["filename"]
+			}
+		}
+		info, err := m.vulns.Import(name, body)
+		if err != nil {
+			apiError(c, vulnErrorStatus(err), err)
+			return
+		}
+		imported = append(imported, info)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"feeds": imported})
+}
+
+// handleRemoveVulns обработчик маршрута DELETE /api/vulns/:name.
+// Удаляет базу уязвимостей из хранилища.
+func (m *Web) handleRemoveVulns(c *gin.Context) {
+	if err := m.vulns.Remove(c.Param("name")); err != nil {
+		apiError(c, vulnErrorStatus(err), err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// vulnErrorStatus подбирает HTTP-статус для ошибки операции с базами уязвимостей.
+func vulnErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, vuln.ErrInvalidFeed):
+		return http.StatusBadRequest
+	case errors.Is(err, vuln.ErrFeedNotFound):
+		return http.StatusNotFound
+	}
+
+	var maxBytes *http.MaxBytesError
+	if errors.As(err, &maxBytes) {
+		return http.StatusRequestEntityTooLarge
+	}
+
+	return http.StatusInternalServerError
+}
+
+// packageVulnerabilities дополняет версии пакета info уязвимостями из локальных баз.
+func (m *Web) packageVulnerabilities(info *repo.PackageInfo) {
+	db, _ := m.vulns.Database()
+	for i := range info.Versions {
+		info.Versions[i].Vulnerabilities = m.catalog.MatchVulnerabilities(db, info.Versions[i].CatalogEntry)
+	}
+}
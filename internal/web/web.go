@@ -13,10 +13,24 @@ import (
 
 	"github.com/cockroachdb/errors"
 	"github.com/gin-gonic/gin"
+	"github.com/kirsrus/iso2repo/internal/audit"
+	"github.com/kirsrus/iso2repo/internal/policy"
+	"github.com/kirsrus/iso2repo/internal/repo"
+	"github.com/kirsrus/iso2repo/internal/vuln"
 	"github.com/kirsrus/iso2repo/models"
 	"golang.org/x/exp/slog"
+	"golang.org/x/sync/singleflight"
 )
 
+// changelogCacheSize предельный размер кэша извлечённых журналов изменений.
+const changelogCacheSize = 64 << 20
+
+// DefaultMaxUploadSize максимальный размер тела запроса загрузки пакетов по умолчанию.
+const DefaultMaxUploadSize = 1 << 30
+
+// DefaultAddress адрес сервера для клиентов по умолчанию (как в /sources.list).
+const DefaultAddress = "repo.loc"
+
 //go:embed templates/*
 var templatesFS embed.FS
 
@@ -32,6 +46,9 @@ type Web struct {
 	// Порт для прослушивания
 	port int
 
+	// Адрес сервера для клиентов
+	address string
+
 	// Контекст для graceful shutdown
 	ctx context.Context
 
@@ -56,6 +73,39 @@ type Web struct {
 
 	// Версия программы.
 	version string
+
+	// Журнал аудита операций с содержимым репозиториев.
+	audit *audit.Log
+
+	// Кэш журналов изменений пакетов для "apt changelog".
+	changelogs *repo.Changelogs
+
+	// Кэш лицензий пакетов для SBOM.
+	licenses *repo.Licenses
+
+	// Сводный индекс пакетов всех репозиториев для поиска.
+	catalog *repo.Catalog
+
+	// Хранилище локальных баз уязвимостей.
+	vulns *vuln.Store
+
+	// Политика раздачи пакетов и ключ подписи переписанных ею Release.
+	policy *policy.File
+	signer *policy.Signer
+
+	// Максимальный размер тела запроса загрузки пакетов в байтах.
+	maxUploadSize int64
+
+	// Дистрибутивы и индексы, отфильтрованные политикой, по имени репозитория.
+	policyMu    sync.Mutex
+	policyCache map[string]*policyRepo
+	policyGroup singleflight.Group
+
+	// Описания объединённых репозиториев и сформированные по ним репозитории по имени.
+	merged      *repo.MergedFile
+	mergedMu    sync.Mutex
+	mergedCache map[string]*mergedEntry
+	mergedGroup singleflight.Group
 }
 
 // Config конфигурация веб-сервера
@@ -65,6 +115,10 @@ type Config struct {
 	// Порт для прослушивания
 	Port int
 
+	// Адрес сервера, по которому к нему обращаются клиенты (по умолчанию
+	// DefaultAddress). Подставляется в поле Changelogs файла Release.
+	Address string
+
 	// Router для обработки запросов
 	Router *gin.Engine
 
@@ -79,6 +133,26 @@ type Config struct {
 
 	// Версия программы.
 	Version string
+
+	// Журнал аудита операций с содержимым репозиториев (может быть nil).
+	Audit *audit.Log
+
+	// Хранилище локальных баз уязвимостей (может быть nil).
+	Vulns *vuln.Store
+
+	// Файл политики раздачи пакетов (может быть nil).
+	Policy *policy.File
+
+	// Ключ подписи Release, переписанных политикой (может быть nil — тогда они
+	// отдаются без подписи).
+	Signer *policy.Signer
+
+	// Файл описаний объединённых репозиториев (может быть nil).
+	Merged *repo.MergedFile
+
+	// Максимальный размер тела запроса загрузки пакетов в байтах
+	// (по умолчанию DefaultMaxUploadSize).
+	MaxUploadSize int64
 }
 
 // NewWeb конструктор веб-сервера
@@ -94,6 +168,16 @@ func NewWeb(config *Config) (*Web, error) {
 		port = 4309
 	}
 
+	address := config.Address
+	if address == "" {
+		address = DefaultAddress
+	}
+
+	maxUploadSize := config.MaxUploadSize
+	if maxUploadSize <= 0 {
+		maxUploadSize = DefaultMaxUploadSize
+	}
+
 	router := gin.Default()
 	if config.Router != nil {
 		router = config.Router
@@ -114,15 +198,27 @@ func NewWeb(config *Config) (*Web, error) {
 	router.SetHTMLTemplate(tmpl)
 
 	m := &Web{
-		log:         log.With(slog.String("module", "web")),
-		port:        port,
-		router:      router,
-		changeRepos: changeRepos,
-		templates:   tmpl,
-		rootDir:     config.RootDir,
-		copyright:   config.Copyright,
-		version:     config.Version,
+		log:           log.With(slog.String("module", "web")),
+		port:          port,
+		address:       address,
+		router:        router,
+		changeRepos:   changeRepos,
+		templates:     tmpl,
+		rootDir:       config.RootDir,
+		copyright:     config.Copyright,
+		version:       config.Version,
+		audit:         config.Audit,
+		vulns:         config.Vulns,
+		policy:        config.Policy,
+		signer:        config.Signer,
+		maxUploadSize: maxUploadSize,
+		policyCache:   make(map[string]*policyRepo),
+		merged:        config.Merged,
+		mergedCache:   make(map[string]*mergedEntry),
+		changelogs:    repo.NewChangelogs(changelogCacheSize),
+		licenses:      repo.NewLicenses(),
 	}
+	m.catalog = repo.NewCatalog(m.log)
 
 	// Регистрируем обработчики HTTP запросов
 	m.registerRoutes()
@@ -217,12 +313,16 @@ func (m *Web) Run(ctx context.Context) error {
 				}
 
 				switch repoEvent.EventType {
-				case models.RepoFound:
+				case models.RepoFound, models.RepoUpdate:
 					m.repos.Store(repoEvent.Repo.Metadata().Name, repoEvent.Repo)
+					m.invalidatePolicy(repoEvent.Repo.Metadata().Name)
 					m.log.Debug("репозиторий добавлен в веб-сервер", slog.String("repo", repoEvent.Repo.Metadata().Name))
+					go m.updateCatalog(ctx, repoEvent.Repo)
 
 				case models.RepoLost:
 					m.repos.Delete(repoEvent.Repo.Metadata().Name)
+					m.invalidatePolicy(repoEvent.Repo.Metadata().Name)
+					m.catalog.Remove(repoEvent.Repo.Metadata().Name)
 					m.log.Debug("репозиторий удалён из веб-сервера", slog.String("repo", repoEvent.Repo.Metadata().Name))
 				}
 
@@ -28,7 +28,7 @@ type Repo struct {
 	Type RepoType
 }
 
-// RepoEventType описывает тип события, закреплённого за репозиторием (обнаружение, потеря).
+// RepoEventType описывает тип события, закреплённого за репозиторием (обнаружение, потеря, изменение).
 type RepoEventType int
 
 const (
@@ -37,6 +37,9 @@ const (
 
 	// Репозиторий потерян.
 	RepoLost
+
+	// Содержимое обнаруженного ранее репозитория изменилось.
+	RepoUpdate
 )
 
 // RepoEvent описание события обнаружения репозитория.
@@ -0,0 +1,144 @@
+package deb
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/blakesmith/ar"
+)
+
+// maxAppStreamFile максимальный размер файла AppStream, извлекаемого из пакета.
+// Более крупные файлы (как правило, это не метаданные, а ошибка сборки) пропускаются.
+const maxAppStreamFile = 2 << 20
+
+// AppStreamFile файл из data.tar, относящийся к метаданным AppStream.
+type AppStreamFile struct {
+	Path string // Путь внутри пакета без ведущих "./" и "/"
+	Data []byte // Содержимое файла
+}
+
+// AppStream метаданные AppStream (DEP-11), извлечённые из data.tar пакета.
+type AppStream struct {
+	MetaInfo []AppStreamFile // usr/share/metainfo/*.xml (и устаревший usr/share/appdata/*.xml)
+	Desktop  []AppStreamFile // usr/share/applications/*.desktop
+	Icons    []AppStreamFile // usr/share/icons/hicolor/<размер>/apps/*.png и usr/share/pixmaps/*.png
+}
+
+// Empty сообщает, что в пакете нет описаний AppStream-компонентов.
+// Одних иконок и .desktop файлов для DEP-11 недостаточно.
+func (a *AppStream) Empty() bool {
+	return a == nil || len(a.MetaInfo) == 0
+}
+
+// ExtractAppStream читает data.tar пакета и извлекает файлы, необходимые для
+// генерации метаданных AppStream: описания компонентов, .desktop файлы и иконки.
+// Inspect собирает те же данные попутно с разбором пакета.
+func ExtractAppStream(debPath string) (*AppStream, error) {
+	result := &AppStream{}
+	if err := WalkData(debPath, result.add); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// add сохраняет запись архива data.tar, если она относится к метаданным AppStream.
+func (a *AppStream) add(hdr *tar.Header, r io.Reader) error {
+	if hdr.Typeflag != tar.TypeReg || hdr.Size > maxAppStreamFile {
+		return nil
+	}
+
+	name := strings.TrimLeft(strings.TrimPrefix(hdr.Name, "."), "/")
+	dir, base := path.Split(name)
+
+	var target *[]AppStreamFile
+	switch {
+	case (dir == "usr/share/metainfo/" || dir == "usr/share/appdata/") && strings.HasSuffix(base, ".xml"):
+		target = &a.MetaInfo
+	case dir == "usr/share/applications/" && strings.HasSuffix(base, ".desktop"):
+		target = &a.Desktop
+	case isAppStreamIcon(dir, base):
+		target = &a.Icons
+	default:
+		return nil
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения %s: %w", name, err)
+	}
+	*target = append(*target, AppStreamFile{Path: name, Data: data})
+
+	return nil
+}
+
+// isAppStreamIcon проверяет, может ли файл служить иконкой приложения.
+func isAppStreamIcon(dir, base string) bool {
+	if !strings.HasSuffix(base, ".png") {
+		return false
+	}
+	if dir == "usr/share/pixmaps/" {
+		return true
+	}
+
+	return strings.HasPrefix(dir, "usr/share/icons/hicolor/") && strings.HasSuffix(dir, "/apps/")
+}
+
+// WalkData последовательно обходит файлы архива data.tar пакета debPath, вызывая fn
+// для каждой записи. Содержимое записи доступно через r до возврата из fn.
+// Если fn вернёт io.EOF, обход прекращается без ошибки.
+func WalkData(debPath string, fn func(hdr *tar.Header, r io.Reader) error) error {
+	f, err := os.Open(strings.ReplaceAll(debPath, "\\", "/"))
+	if err != nil {
+		return fmt.Errorf("не удалось открыть .deb файл: %w", err)
+	}
+	defer f.Close()
+
+	return WalkDataReader(f, fn)
+}
+
+// WalkDataReader то же, что WalkData, но читает пакет из потока deb.
+func WalkDataReader(deb io.Reader, fn func(hdr *tar.Header, r io.Reader) error) error {
+	arR := ar.NewReader(deb)
+	for {
+		hdr, err := arR.Next()
+		if err == io.EOF {
+			return fmt.Errorf("архив data не найден в .deb файле")
+		}
+		if err != nil {
+			return fmt.Errorf("ошибка чтения архива .deb: %w", err)
+		}
+
+		name := strings.Trim(strings.TrimSpace(hdr.Name), "/")
+		if !strings.HasPrefix(name, "data.tar") {
+			continue
+		}
+
+		r, closeFn, err := decompressor(name, arR)
+		if err != nil {
+			return err
+		}
+		defer closeFn()
+
+		tr := tar.NewReader(r)
+		for {
+			th, err := tr.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("ошибка чтения архива data.tar: %w", err)
+			}
+			if err := fn(th, tr); err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+}
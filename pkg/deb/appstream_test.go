@@ -0,0 +1,101 @@
+package deb
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kirsrus/iso2repo/pkg/deb/debtest"
+)
+
+func TestExtractAppStream(t *testing.T) {
+	data := debtest.Package(t, "app", "1.0", "amd64", map[string]string{
+		"/usr/bin/app": "binary",
+		"/usr/share/metainfo/org.example.App.metainfo.xml": "<component/>",
+		"/usr/share/metainfo/README":                       "not metainfo",
+		"/usr/share/appdata/old.appdata.xml":               "<application/>",
+		"/usr/share/applications/org.example.App.desktop":  "[Desktop Entry]\n",
+		"/usr/share/applications/nested/other.desktop":     "[Desktop Entry]\n",
+		"/usr/share/icons/hicolor/64x64/apps/app.png":      "png64",
+		"/usr/share/icons/hicolor/64x64/mimetypes/x.png":   "mime",
+		"/usr/share/pixmaps/app.png":                       "pixmap",
+		"/usr/share/pixmaps/app.xpm":                       "xpm",
+	})
+	debPath := debtest.Write(t, t.TempDir(), "app_1.0_amd64.deb", data)
+
+	want := &AppStream{
+		MetaInfo: []AppStreamFile{
+			{Path: "usr/share/appdata/old.appdata.xml", Data: []byte("<application/>")},
+			{Path: "usr/share/metainfo/org.example.App.metainfo.xml", Data: []byte("<component/>")},
+		},
+		Desktop: []AppStreamFile{
+			{Path: "usr/share/applications/org.example.App.desktop", Data: []byte("[Desktop Entry]\n")},
+		},
+		Icons: []AppStreamFile{
+			{Path: "usr/share/icons/hicolor/64x64/apps/app.png", Data: []byte("png64")},
+			{Path: "usr/share/pixmaps/app.png", Data: []byte("pixmap")},
+		},
+	}
+
+	got, err := ExtractAppStream(debPath)
+	if err != nil {
+		t.Fatalf("ExtractAppStream() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractAppStream() = %+v, want %+v", got, want)
+	}
+	if got.Empty() {
+		t.Errorf("Empty() = true")
+	}
+
+	// Inspect собирает те же данные за один проход
+	inspection, err := Inspect(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Inspect() error = %v", err)
+	}
+	if !reflect.DeepEqual(inspection.AppStream, want) {
+		t.Errorf("Inspect().AppStream = %+v, want %+v", inspection.AppStream, want)
+	}
+
+	if _, err := ExtractAppStream(filepath.Join(t.TempDir(), "missing.deb")); err == nil {
+		t.Errorf("ExtractAppStream(missing) error = nil")
+	}
+}
+
+func TestAppStream_Empty(t *testing.T) {
+	tests := []struct {
+		name string
+		a    *AppStream
+		want bool
+	}{
+		{name: "nil", a: nil, want: true},
+		{name: "icons and desktop only", a: &AppStream{
+			Desktop: []AppStreamFile{{Path: "usr/share/applications/a.desktop"}},
+			Icons:   []AppStreamFile{{Path: "usr/share/pixmaps/a.png"}},
+		}, want: true},
+		{name: "metainfo", a: &AppStream{MetaInfo: []AppStreamFile{{Path: "usr/share/metainfo/a.xml"}}}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.Empty(); got != tt.want {
+				t.Errorf("Empty() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractAppStream_largeFile(t *testing.T) {
+	data := debtest.Package(t, "app", "1.0", "amd64", map[string]string{
+		"/usr/share/metainfo/big.xml": strings.Repeat("x", maxAppStreamFile+1),
+	})
+	got, err := ExtractAppStream(debtest.Write(t, t.TempDir(), "app.deb", data))
+	if err != nil {
+		t.Fatalf("ExtractAppStream() error = %v", err)
+	}
+	if !got.Empty() {
+		t.Errorf("oversized metainfo extracted")
+	}
+}
@@ -0,0 +1,83 @@
+package deb
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxChangelog максимальный размер распакованного журнала изменений.
+const maxChangelog = 16 << 20
+
+// ErrChangelogNotFound в пакете нет журнала изменений.
+var ErrChangelogNotFound = errors.New("журнал изменений не найден в пакете")
+
+// ExtractChangelog читает из потока deb журнал изменений пакета pkg:
+// usr/share/doc/<pkg>/changelog.Debian.gz, а для нативных пакетов — changelog.gz.
+// Возвращает распакованный текст журнала.
+func ExtractChangelog(deb io.Reader, pkg string) ([]byte, error) {
+	docDir := "usr/share/doc/" + pkg + "/"
+
+	var native []byte
+	var result []byte
+
+	err := WalkDataReader(deb, func(hdr *tar.Header, r io.Reader) error {
+		if hdr.Typeflag != tar.TypeReg {
+			return nil
+		}
+
+		name := strings.TrimLeft(strings.TrimPrefix(hdr.Name, "."), "/")
+		if name != docDir+"changelog.Debian.gz" && name != docDir+"changelog.gz" {
+			return nil
+		}
+
+		data, err := gunzipLimited(r)
+		if err != nil {
+			return fmt.Errorf("ошибка распаковки %s: %w", name, err)
+		}
+
+		if strings.HasSuffix(name, "changelog.gz") {
+			native = data
+			return nil
+		}
+		result = data
+
+		return io.EOF
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil {
+		result = native
+	}
+	if result == nil {
+		return nil, ErrChangelogNotFound
+	}
+
+	return result, nil
+}
+
+// gunzipLimited распаковывает gzip-поток, ограничивая размер результата.
+func gunzipLimited(r io.Reader) ([]byte, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, io.LimitReader(zr, maxChangelog+1))
+	if err != nil {
+		return nil, err
+	}
+	if n > maxChangelog {
+		return nil, fmt.Errorf("журнал изменений больше %d байт", maxChangelog)
+	}
+
+	return buf.Bytes(), nil
+}
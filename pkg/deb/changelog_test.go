@@ -0,0 +1,87 @@
+package deb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"testing"
+
+	"github.com/kirsrus/iso2repo/pkg/deb/debtest"
+)
+
+// gzipString сжимает s в gzip.
+func gzipString(t *testing.T, s string) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.String()
+}
+
+func TestExtractChangelog(t *testing.T) {
+	debian := gzipString(t, "foo (1.0-1) unstable; urgency=medium\n")
+	native := gzipString(t, "foo (1.0) unstable; urgency=low\n")
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "changelog.Debian.gz",
+			files: map[string]string{"/usr/share/doc/foo/changelog.Debian.gz": debian},
+			want:  "foo (1.0-1) unstable; urgency=medium\n",
+		},
+		{
+			name:  "native changelog.gz",
+			files: map[string]string{"/usr/share/doc/foo/changelog.gz": native},
+			want:  "foo (1.0) unstable; urgency=low\n",
+		},
+		{
+			name: "debian changelog preferred",
+			files: map[string]string{
+				"/usr/share/doc/foo/changelog.gz":        native,
+				"/usr/share/doc/foo/changelog.Debian.gz": debian,
+			},
+			want: "foo (1.0-1) unstable; urgency=medium\n",
+		},
+		{
+			name:    "changelog of another package",
+			files:   map[string]string{"/usr/share/doc/bar/changelog.Debian.gz": debian},
+			wantErr: ErrChangelogNotFound,
+		},
+		{
+			name:    "no changelog",
+			files:   map[string]string{"/usr/bin/foo": "binary"},
+			wantErr: ErrChangelogNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := debtest.Package(t, "foo", "1.0-1", "amd64", tt.files)
+			got, err := ExtractChangelog(bytes.NewReader(data), "foo")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ExtractChangelog() error = %v, want %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("ExtractChangelog() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("corrupted gzip", func(t *testing.T) {
+		data := debtest.Package(t, "foo", "1.0-1", "amd64", map[string]string{"/usr/share/doc/foo/changelog.Debian.gz": "not gzip"})
+		if _, err := ExtractChangelog(bytes.NewReader(data), "foo"); err == nil || errors.Is(err, ErrChangelogNotFound) {
+			t.Errorf("ExtractChangelog() error = %v", err)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package deb
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+// Changes — содержимое .changes файла (описание загрузки в стиле dput).
+type Changes struct {
+	Source       string        // Имя исходного пакета
+	Version      string        // Версия загрузки
+	Architecture string        // Список архитектур через пробел
+	Distribution string        // Целевой дистрибутив
+	Maintainer   string        // Сопровождающий
+	ChangedBy    string        // Автор изменений
+	Files        []ChangesFile // Файлы, входящие в загрузку
+}
+
+// ChangesFile — описание одного файла из .changes.
+type ChangesFile struct {
+	Name      string // Имя файла
+	Size      int64  // Размер в байтах
+	Section   string // Раздел (из поля Files)
+	Priority  string // Приоритет (из поля Files)
+	MD5Sum    string // Из поля Files
+	SHA1Sum   string // Из поля Checksums-Sha1
+	SHA256Sum string // Из поля Checksums-Sha256
+}
+
+// ParseChanges читает .changes файл. Если файл подписан (clearsign), подпись
+// отбрасывается без проверки — проверка подписи остаётся на стороне вызывающего кода.
+// Возвращает ошибку, если в файле нет списка файлов.
+func ParseChanges(r io.Reader) (*Changes, error) {
+	fields, err := parseControl(r)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка разбора .changes файла: %w", err)
+	}
+
+	c := &Changes{
+		Source:       fields.Get("Source"),
+		Version:      fields.Get("Version"),
+		Architecture: fields.Get("Architecture"),
+		Distribution: fields.Get("Distribution"),
+		Maintainer:   fields.Get("Maintainer"),
+		ChangedBy:    fields.Get("Changed-By"),
+	}
+
+	// Поле Files: "<md5> <size> <section> <priority> <name>"
+	for _, line := range strings.Split(fields.Get("Files"), "\n") {
+		parts := strings.Fields(line)
+		if len(parts) != 5 {
+			continue
+		}
+		c.Files = append(c.Files, ChangesFile{
+			Name:     parts[4],
+			Size:     cast.ToInt64(parts[1]),
+			Section:  parts[2],
+			Priority: parts[3],
+			MD5Sum:   parts[0],
+		})
+	}
+
+	// Поля Checksums-*: "<hash> <size> <name>". Учитываются только файлы,
+	// перечисленные в Files.
+	checksums := func(field string, set func(f *ChangesFile, sum string)) {
+		for _, line := range strings.Split(fields.Get(field), "\n") {
+			parts := strings.Fields(line)
+			if len(parts) != 3 {
+				continue
+			}
+			for i := range c.Files {
+				if c.Files[i].Name == parts[2] {
+					set(&c.Files[i], parts[0])
+				}
+			}
+		}
+	}
+	checksums("Checksums-Sha1", func(f *ChangesFile, sum string) { f.SHA1Sum = sum })
+	checksums("Checksums-Sha256", func(f *ChangesFile, sum string) { f.SHA256Sum = sum })
+
+	if len(c.Files) == 0 {
+		return nil, fmt.Errorf("в .changes файле нет списка файлов")
+	}
+
+	return c, nil
+}
@@ -0,0 +1,81 @@
+package deb
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testChanges = `Format: 1.8
+Source: foo
+Version: 1.0-1
+Architecture: amd64 all
+Distribution: stable
+Maintainer: Test <test@example.org>
+Changed-By: Dev <dev@example.org>
+Files:
+ 0123 100 utils optional foo_1.0-1_amd64.deb
+ 4567 20 utils optional foo_1.0-1.dsc
+Checksums-Sha1:
+ aaaa 100 foo_1.0-1_amd64.deb
+ bbbb 30 other.deb
+Checksums-Sha256:
+ cccc 100 foo_1.0-1_amd64.deb
+ dddd 20 foo_1.0-1.dsc
+`
+
+func TestParseChanges(t *testing.T) {
+	want := &Changes{
+		Source:       "foo",
+		Version:      "1.0-1",
+		Architecture: "amd64 all",
+		Distribution: "stable",
+		Maintainer:   "Test <test@example.org>",
+		ChangedBy:    "Dev <dev@example.org>",
+		Files: []ChangesFile{
+			{Name: "foo_1.0-1_amd64.deb", Size: 100, Section: "utils", Priority: "optional", MD5Sum: "0123", SHA1Sum: "aaaa", SHA256Sum: "cccc"},
+			{Name: "foo_1.0-1.dsc", Size: 20, Section: "utils", Priority: "optional", MD5Sum: "4567", SHA256Sum: "dddd"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    *Changes
+		wantErr bool
+	}{
+		{
+			name:  "plain",
+			input: testChanges,
+			want:  want,
+		},
+		{
+			name: "clearsigned",
+			input: "-----BEGIN PGP SIGNED MESSAGE-----\nHash: SHA512\n\n" + testChanges +
+				"-----BEGIN PGP SIGNATURE-----\n\niQEzBAEBCgAdFiEE\n-----END PGP SIGNATURE-----\n",
+			want: want,
+		},
+		{
+			name:    "no files",
+			input:   "Source: foo\nVersion: 1.0\n",
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			input:   "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseChanges(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseChanges() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseChanges() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package deb
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// maxContentsLine максимальная длина строки индекса Contents.
+const maxContentsLine = 1 << 20
+
+// ReadContents последовательно читает индекс Contents из r, вызывая fn для каждой
+// строки: path — путь файла без ведущего "/", packages — квалифицированные имена
+// пакетов ("[область/]раздел/пакет"), в которые входит файл.
+// Заголовок старого формата (текст до строки "FILE  LOCATION") пропускается.
+// Если fn вернёт io.EOF, чтение прекращается без ошибки.
+func ReadContents(r io.Reader, fn func(path string, packages []string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxContentsLine)
+
+	type bufferedLine struct {
+		text string
+		num  int
+	}
+	lines := make([]bufferedLine, 0)
+	header := true
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" {
+			continue
+		}
+
+		// Строки до заголовка накапливаем, пока не станет ясно, есть ли он
+		if header {
+			fields := strings.Fields(line)
+			if len(fields) == 2 && fields[0] == "FILE" && fields[1] == "LOCATION" {
+				header = false
+				lines = lines[:0]
+				continue
+			}
+			if len(lines) < 64 {
+				lines = append(lines, bufferedLine{line, lineNum})
+				continue
+			}
+			header = false
+		}
+
+		for _, l := range lines {
+			if err := contentsLine(l.text, l.num, fn); err != nil {
+				return noEOF(err)
+			}
+		}
+		lines = lines[:0]
+
+		if err := contentsLine(line, lineNum, fn); err != nil {
+			return noEOF(err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	for _, l := range lines {
+		if err := contentsLine(l.text, l.num, fn); err != nil {
+			return noEOF(err)
+		}
+	}
+
+	return nil
+}
+
+// contentsLine разбирает строку индекса Contents. Путь может содержать пробелы,
+// поэтому список пакетов — последнее поле строки.
+func contentsLine(line string, lineNum int, fn func(path string, packages []string) error) error {
+	i := strings.LastIndexAny(line, " \t")
+	if i < 0 {
+		return fmt.Errorf("строка %d: некорректная запись Contents %q", lineNum, line)
+	}
+
+	p := strings.TrimLeft(strings.TrimRight(line[:i], " \t"), "/")
+	packages := strings.Split(line[i+1:], ",")
+	if p == "" {
+		return fmt.Errorf("строка %d: некорректная запись Contents %q", lineNum, line)
+	}
+
+	return fn(p, packages)
+}
+
+// noEOF превращает io.EOF, которым fn прерывает чтение, в отсутствие ошибки.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return nil
+	}
+
+	return err
+}
+
+// ContentsPackageName возвращает имя пакета из квалифицированного имени индекса
+// Contents ("[область/]раздел/пакет").
+func ContentsPackageName(qualified string) string {
+	return qualified[strings.LastIndex(qualified, "/")+1:]
+}
+
+// WriteContents записывает индекс Contents: files — пути файлов (без ведущего "/")
+// с квалифицированными именами содержащих их пакетов. Строки сортируются по пути.
+func WriteContents(w io.Writer, files map[string][]string) error {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	bw := bufio.NewWriter(w)
+	for _, p := range paths {
+		packages := append([]string(nil), files[p]...)
+		sort.Strings(packages)
+		fmt.Fprintf(bw, "%-55s %s\n", p, strings.Join(packages, ","))
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,82 @@
+package deb
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadContents(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "modern format",
+			input: "usr/bin/foo                 utils/foo\nusr/lib/libbar.so.3         libs/libbar3,oldlibs/libbar-compat\n",
+			want:  []string{"usr/bin/foo=utils/foo", "usr/lib/libbar.so.3=libs/libbar3|oldlibs/libbar-compat"},
+		},
+		{
+			name:  "old format with header",
+			input: "This file maps each file available in the Debian\nsystem to the package from which it originates.\n\nFILE                                                    LOCATION\n/usr/bin/foo bar            main/utils/foo\n",
+			want:  []string{"usr/bin/foo bar=main/utils/foo"},
+		},
+		{name: "missing location", input: "usr/bin/foo\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			err := ReadContents(strings.NewReader(tt.input), func(path string, packages []string) error {
+				got = append(got, path+"="+strings.Join(packages, "|"))
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadContents() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadContents() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteContents(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteContents(&buf, map[string][]string{
+		"usr/lib/libbar.so.3": {"libs/libbar3", "libs/libbar-dev"},
+		"usr/bin/foo":         {"utils/foo"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([]string, 0)
+	err = ReadContents(&buf, func(path string, packages []string) error {
+		got = append(got, path+"="+strings.Join(packages, "|"))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"usr/bin/foo=utils/foo", "usr/lib/libbar.so.3=libs/libbar-dev|libs/libbar3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %q, want %q", got, want)
+	}
+}
+
+func TestContentsPackageName(t *testing.T) {
+	for qualified, want := range map[string]string{
+		"foo":                 "foo",
+		"utils/foo":           "foo",
+		"non-free/libs/libx1": "libx1",
+	} {
+		if got := ContentsPackageName(qualified); got != want {
+			t.Errorf("ContentsPackageName(%q) = %q, want %q", qualified, got, want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package deb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrCopyrightFormat файл copyright не в машиночитаемом формате (DEP-5).
+var ErrCopyrightFormat = errors.New("copyright не в машиночитаемом формате")
+
+// Copyright сведения машиночитаемого файла debian/copyright
+// (https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/).
+type Copyright struct {
+	// Адрес спецификации формата (поле Format).
+	Format string
+	// Имя исходного пакета (поле Upstream-Name).
+	UpstreamName string
+	// Лицензии абзацев Files — первые строки полей License ("GPL-2+ or Artistic-1.0")
+	// в порядке появления, без повторов.
+	Licenses []string
+	// Тексты лицензий по короткому имени: из отдельных абзацев License и из абзацев
+	// Files, где текст приведён вместе с именем.
+	Texts map[string]string
+}
+
+// ParseCopyright разбирает машиночитаемый файл copyright. Для файлов в
+// произвольном формате возвращает ErrCopyrightFormat.
+func ParseCopyright(data []byte) (*Copyright, error) {
+	result := &Copyright{
+		Licenses: make([]string, 0),
+		Texts:    make(map[string]string),
+	}
+
+	r := NewReader(bytes.NewReader(data))
+	// Файл в произвольном формате обычно не разбирается как deb822 уже в первом абзаце
+	p, err := r.Next()
+	if err != nil {
+		return nil, ErrCopyrightFormat
+	}
+	result.Format = p.Get("Format")
+	if !strings.Contains(result.Format, "copyright-format") && !strings.Contains(result.Format, "dep5") {
+		return nil, ErrCopyrightFormat
+	}
+	result.UpstreamName = p.Get("Upstream-Name")
+	// Поле License заголовка описывает лицензию всего пакета
+	if license, ok := p.Lookup("License"); ok {
+		result.addLicense(license, false)
+	}
+
+	for {
+		p, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ошибка разбора copyright: %w", err)
+		}
+
+		license, ok := p.Lookup("License")
+		if !ok {
+			continue
+		}
+		_, files := p.Lookup("Files")
+		result.addLicense(license, !files)
+	}
+
+	return result, nil
+}
+
+// addLicense учитывает значение поля License: первая строка — имя (выражение)
+// лицензии, остальные — её текст. Отдельные абзацы License (standalone) только
+// добавляют текст.
+func (m *Copyright) addLicense(value string, standalone bool) {
+	name, text, _ := strings.Cut(value, "\n")
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return
+	}
+
+	if text = copyrightText(text); text != "" {
+		m.Texts[name] = text
+	}
+	if standalone {
+		return
+	}
+
+	for _, l := range m.Licenses {
+		if l == name {
+			return
+		}
+	}
+	m.Licenses = append(m.Licenses, name)
+}
+
+// copyrightText восстанавливает текст многострочного поля: строка "." обозначает
+// пустую строку.
+func copyrightText(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "." {
+			lines[i] = ""
+		}
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,88 @@
+package deb
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const testCopyright = `Format: https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/
+Upstream-Name: foo
+Source: https://example.org/foo
+
+Files: *
+Copyright: 2020 Foo Authors
+License: GPL-2+
+
+Files: lib/*
+Copyright: 2021 Bar
+License: Expat
+ Permission is hereby granted, free of charge.
+ .
+ The above copyright notice shall be included.
+
+Files: debian/*
+Copyright: 2022 Maintainer
+License: GPL-2+
+
+License: GPL-2+
+ This program is free software.
+`
+
+func TestParseCopyright(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		want      []string
+		wantTexts map[string]string
+		wantErr   error
+	}{
+		{
+			name: "machine readable",
+			data: testCopyright,
+			want: []string{"GPL-2+", "Expat"},
+			wantTexts: map[string]string{
+				"Expat":  "Permission is hereby granted, free of charge.\n\nThe above copyright notice shall be included.",
+				"GPL-2+": "This program is free software.",
+			},
+		},
+		{
+			name:      "header license",
+			data:      "Format: http://dep.debian.net/deps/dep5\nLicense: MIT or Apache-2.0\n",
+			want:      []string{"MIT or Apache-2.0"},
+			wantTexts: map[string]string{},
+		},
+		{
+			name:    "free form",
+			data:    "This package was debianized by Foo.\n\nCopyright: 2001 Foo\n",
+			wantErr: ErrCopyrightFormat,
+		},
+		{
+			name:    "deb822 without format",
+			data:    "Upstream-Name: foo\n\nFiles: *\nLicense: MIT\n",
+			wantErr: ErrCopyrightFormat,
+		},
+		{name: "empty", data: "", wantErr: ErrCopyrightFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCopyright([]byte(tt.data))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ParseCopyright() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCopyright() error = %v", err)
+			}
+			if !reflect.DeepEqual(got.Licenses, tt.want) {
+				t.Errorf("ParseCopyright() licenses = %q, want %q", got.Licenses, tt.want)
+			}
+			if !reflect.DeepEqual(got.Texts, tt.wantTexts) {
+				t.Errorf("ParseCopyright() texts = %q, want %q", got.Texts, tt.wantTexts)
+			}
+		})
+	}
+}
@@ -2,7 +2,7 @@ package deb
 
 import (
 	"archive/tar"
-	"bufio"
+	"compress/bzip2"
 	"compress/gzip"
 	"fmt"
 	"io"
@@ -64,36 +64,46 @@ func ExtractMeta(debPath string) (*PackageMeta, error) {
 			continue
 		}
 
-		var r io.Reader
-		switch {
-		case strings.HasSuffix(name, ".gz"):
-			gr, err := gzip.NewReader(arR)
-			if err != nil {
-				return nil, fmt.Errorf("ошибка инициализации gzip: %w", err)
-			}
-			defer gr.Close()
-			r = gr
-		case strings.HasSuffix(name, ".xz"):
-			xr, err := xz.NewReader(arR)
-			if err != nil {
-				return nil, fmt.Errorf("ошибка инициализации xz: %w", err)
-			}
-			r = xr
-		case strings.HasSuffix(name, ".zst"):
-			zr, err := zstd.NewReader(arR)
-			if err != nil {
-				return nil, fmt.Errorf("ошибка инициализации zstd: %w", err)
-			}
-			defer zr.Close()
-			r = zr
-		default:
-			r = arR
+		r, closeFn, err := decompressor(name, arR)
+		if err != nil {
+			return nil, err
 		}
+		defer closeFn()
 
 		return parseControlTar(r)
 	}
 }
 
+// decompressor возвращает reader, распаковывающий член архива .deb с именем name
+// (control.tar.gz, data.tar.xz и т.п.) по его расширению. Возвращаемая функция
+// освобождает ресурсы распаковщика.
+func decompressor(name string, r io.Reader) (io.Reader, func(), error) {
+	switch {
+	case strings.HasSuffix(name, ".gz"):
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("ошибка инициализации gzip: %w", err)
+		}
+		return gr, func() { gr.Close() }, nil
+	case strings.HasSuffix(name, ".xz"):
+		xr, err := xz.NewReader(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("ошибка инициализации xz: %w", err)
+		}
+		return xr, func() {}, nil
+	case strings.HasSuffix(name, ".zst"):
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("ошибка инициализации zstd: %w", err)
+		}
+		return zr, zr.Close, nil
+	case strings.HasSuffix(name, ".bz2"):
+		return bzip2.NewReader(r), func() {}, nil
+	}
+
+	return r, func() {}, nil
+}
+
 // parseControlTar парсит архив control.tar.* и извлекает control-файл.
 // Принимает io.Reader для чтения архива control.
 // Возвращает структуру PackageMeta или ошибку.
@@ -116,81 +126,65 @@ func parseControlTar(r io.Reader) (*PackageMeta, error) {
 		if err != nil {
 			return nil, err
 		}
-		return fieldsToMeta(fields), nil
+		return paragraphToMeta(fields), nil
 	}
 }
 
-// parseControl парсит control-файл и извлекает ключевые-значения.
+// parseControl читает первую запись control-файла.
 // Принимает io.Reader для чтения control-файла.
-// Возвращает map[string]string с парами ключ-значение или ошибку.
-func parseControl(r io.Reader) (map[string]string, error) {
-	fields := make(map[string]string)
-	sc := bufio.NewScanner(r)
-	var key string
-	var val strings.Builder
-
-	// Вспомогательная функция для сохранения текущего поля
-	flush := func() {
-		if key != "" {
-			fields[key] = strings.TrimSpace(val.String())
-		}
+// Возвращает поля записи в исходном порядке или ошибку.
+func parseControl(r io.Reader) (Paragraph, error) {
+	p, err := NewReader(r).Next()
+	if err == io.EOF {
+		return nil, fmt.Errorf("control файл пуст")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка разбора control файла: %w", err)
 	}
 
-	for sc.Scan() {
-		line := sc.Text()
-		// Пустая строка означает конец записей
-		if line == "" {
-			flush()
-			break
-		}
-		// Обработка продолжения строки (строки, начинающиеся с пробела или табуляции)
-		if line[0] == ' ' || line[0] == '\t' {
-			val.WriteByte('\n')
-			val.WriteString(strings.TrimSpace(line))
+	return p, nil
+}
+
+// knownKeys — известные ключи control-файла: имя в нижнем регистре и его
+// каноническое написание. Имена полей deb822 не зависят от регистра, поэтому
+// при парсинге стандартные поля сопоставляются по имени в нижнем регистре.
+var knownKeys = map[string]string{
+	"package":        "Package",
+	"version":        "Version",
+	"architecture":   "Architecture",
+	"maintainer":     "Maintainer",
+	"installed-size": "Installed-Size",
+	"depends":        "Depends",
+	"pre-depends":    "Pre-Depends",
+	"recommends":     "Recommends",
+	"suggests":       "Suggests",
+	"conflicts":      "Conflicts",
+	"replaces":       "Replaces",
+	"provides":       "Provides",
+	"section":        "Section",
+	"priority":       "Priority",
+	"homepage":       "Homepage",
+	"description":    "Description",
+}
+
+// paragraphToMeta преобразует запись control-файла в структуру PackageMeta.
+// Поля из skip не попадают в Extra (например, поля файла в записи индекса Packages).
+// Имена полей сравниваются без учёта регистра; неизвестные поля сохраняются в Extra
+// в исходном написании.
+func paragraphToMeta(p Paragraph, skip ...string) *PackageMeta {
+	f := make(map[string]string, len(p))
+	extra := make(map[string]string)
+	for _, field := range p {
+		if name, ok := knownKeys[strings.ToLower(field.Name)]; ok {
+			f[name] = field.Value
 			continue
 		}
-		// Сохраняем предыдущее поле перед началом нового
-		flush()
-		// Ищем разделитель между ключом и значением
-		i := strings.IndexByte(line, ':')
-		if i < 0 {
-			key = ""
-			continue
+		if !containsFold(skip, field.Name) {
+			extra[field.Name] = field.Value
 		}
-		key = strings.TrimSpace(line[:i])
-		val.Reset()
-		val.WriteString(strings.TrimSpace(line[i+1:]))
 	}
-	flush()
-	return fields, sc.Err()
-}
-
-// knownKeys — множество известных ключей control-файла.
-// Используется для определения стандартных полей при парсинге.
-var knownKeys = map[string]bool{
-	"Package":        true,
-	"Version":        true,
-	"Architecture":   true,
-	"Maintainer":     true,
-	"Installed-Size": true,
-	"Depends":        true,
-	"Pre-Depends":    true,
-	"Recommends":     true,
-	"Suggests":       true,
-	"Conflicts":      true,
-	"Replaces":       true,
-	"Provides":       true,
-	"Section":        true,
-	"Priority":       true,
-	"Homepage":       true,
-	"Description":    true,
-}
 
-// fieldsToMeta преобразует map[string]string в структуру PackageMeta.
-// Принимает map с парами ключ-значение из control-файла.
-// Возвращает заполненную структуру PackageMeta.
-func fieldsToMeta(f map[string]string) *PackageMeta {
-	m := &PackageMeta{
+	return &PackageMeta{
 		Package:       f["Package"],
 		Version:       f["Version"],
 		Architecture:  f["Architecture"],
@@ -207,13 +201,17 @@ func fieldsToMeta(f map[string]string) *PackageMeta {
 		Priority:      f["Priority"],
 		Homepage:      f["Homepage"],
 		Description:   f["Description"],
-		Extra:         make(map[string]string),
+		Extra:         extra,
 	}
-	// Все неизвестные поля сохраняем в Extra
-	for k, v := range f {
-		if !knownKeys[k] {
-			m.Extra[k] = v
+}
+
+// containsFold сообщает, есть ли name в names без учёта регистра.
+func containsFold(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
 		}
 	}
-	return m
+
+	return false
 }
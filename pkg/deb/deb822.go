@@ -0,0 +1,175 @@
+package deb
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxDeb822Line максимальная длина строки в файле формата deb822.
+const maxDeb822Line = 16 << 20
+
+// Field поле записи deb822. Значение многострочного поля хранится построчно через "\n";
+// у строк продолжения отброшен первый пробельный символ, так что строка " ." хранится как ".".
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Paragraph запись (абзац) файла формата deb822 с исходным порядком полей.
+type Paragraph []Field
+
+// Get возвращает значение поля name (без учёта регистра имени) или пустую строку.
+func (p Paragraph) Get(name string) string {
+	value, _ := p.Lookup(name)
+
+	return value
+}
+
+// Lookup возвращает значение поля name (без учёта регистра имени) и признак его наличия.
+func (p Paragraph) Lookup(name string) (string, bool) {
+	for _, f := range p {
+		if strings.EqualFold(f.Name, name) {
+			return f.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// WriteTo записывает запись в формате deb822, завершая её пустой строкой.
+func (p Paragraph) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	for _, f := range p {
+		b.WriteString(FormatField(f.Name, f.Value))
+	}
+	b.WriteString("\n")
+
+	n, err := io.WriteString(w, b.String())
+
+	return int64(n), err
+}
+
+// FormatField формирует строку поля deb822 "Имя: значение\n", добавляя пробел
+// в начало каждой строки продолжения многострочного значения.
+func FormatField(name, value string) string {
+	if value == "" || value[0] == '\n' {
+		return name + ":" + strings.ReplaceAll(value, "\n", "\n ") + "\n"
+	}
+
+	return name + ": " + strings.ReplaceAll(value, "\n", "\n ") + "\n"
+}
+
+// Reader последовательно читает записи файла формата deb822 (control, Packages,
+// Sources, Release). Записи разделяются пустыми строками, строки-комментарии ("#")
+// пропускаются. Подпись OpenPGP у InRelease (clearsign) отбрасывается.
+type Reader struct {
+	sc   *bufio.Scanner
+	line int
+
+	// Поток — подписанное сообщение (clearsign)
+	signed bool
+	// Внутри заголовка подписанного сообщения (до первой пустой строки)
+	inSignedHeader bool
+	// Достигнут блок подписи — дальше данных нет
+	done bool
+}
+
+// NewReader создаёт Reader для потока r.
+func NewReader(r io.Reader) *Reader {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), maxDeb822Line)
+
+	return &Reader{sc: sc}
+}
+
+// Next возвращает следующую запись. По окончании данных возвращает io.EOF.
+func (r *Reader) Next() (Paragraph, error) {
+	var p Paragraph
+
+	for !r.done && r.sc.Scan() {
+		r.line++
+		line := strings.TrimRight(r.sc.Text(), "\r")
+
+		// Подписанный InRelease: пропускаем заголовок и всё, начиная с подписи
+		switch {
+		case line == "-----BEGIN PGP SIGNED MESSAGE-----":
+			r.signed, r.inSignedHeader = true, true
+			continue
+		case line == "-----BEGIN PGP SIGNATURE-----":
+			r.done = true
+			continue
+		case r.inSignedHeader:
+			if strings.TrimSpace(line) == "" {
+				r.inSignedHeader = false
+			}
+			continue
+		case r.signed && strings.HasPrefix(line, "- "):
+			// Строки, начинающиеся с "-", в подписанном сообщении экранируются
+			line = line[2:]
+		}
+
+		if strings.TrimSpace(line) == "" {
+			if len(p) > 0 {
+				return p, nil
+			}
+			continue
+		}
+		if line[0] == '#' {
+			continue
+		}
+
+		// Строка продолжения многострочного поля
+		if line[0] == ' ' || line[0] == '\t' {
+			if len(p) == 0 {
+				return nil, fmt.Errorf("строка %d: продолжение поля без самого поля", r.line)
+			}
+			p[len(p)-1].Value += "\n" + line[1:]
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("строка %d: ожидалось поле \"Имя: значение\"", r.line)
+		}
+		p = append(p, Field{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value)})
+	}
+
+	if err := r.sc.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения: %w", err)
+	}
+	if len(p) > 0 {
+		return p, nil
+	}
+
+	return nil, io.EOF
+}
+
+// ReadParagraphs вызывает fn для каждой записи потока r. Если fn вернёт io.EOF,
+// чтение прекращается без ошибки.
+func ReadParagraphs(r io.Reader, fn func(Paragraph) error) error {
+	dr := NewReader(r)
+	for {
+		p, err := dr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(p); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// Decompress возвращает reader, распаковывающий поток r по расширению имени файла
+// name (.gz, .xz, .zst, .bz2); файлы без сжатия читаются как есть. Возвращаемая
+// функция освобождает ресурсы распаковщика.
+func Decompress(name string, r io.Reader) (io.Reader, func(), error) {
+	return decompressor(name, r)
+}
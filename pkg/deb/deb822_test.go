@@ -0,0 +1,338 @@
+package deb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+const testPackages = `Package: foo
+Version: 1.0
+Architecture: amd64
+Filename: pool/main/f/foo/foo_1.0_amd64.deb
+Size: 1234
+SHA256: abc
+Description: short
+ long line
+ .
+   verbatim
+
+Package: bar
+Version: 2:0.1-1
+Architecture: all
+Filename: pool/main/b/bar/bar_0.1-1_all.deb
+Size: 10
+Description: bar
+`
+
+// testPackagesBz2 testPackages, сжатый bzip2 (в стандартной библиотеке нет упаковщика).
+const testPackagesBz2 = "QlpoOTFBWSZTWVxDPXQAADJfgAAQQAP/ECVASQC/798QMADGA1PRTUPFNqDJhpD01HqAMNDJkDIxBiZNDTAVVMjRpMU9EwEGjDI01sl7c4SpF3pOoqtlpvDBqiMWAJEA99YPSm/YOLoTW4i1SFbmuGeRSglwyBagQgFvbAMmWMvujjGYqP1TuhDBzoYukkF/aUUWata5rdFj+ScnV0aZPDXsgv1vDZkuqXVKWLN+Jq328sNSEcFJFUcEYqIFedHpdakmTyaL1K6Cz4omnqWMVcnsXckU4UJBcQz10A=="
+
+func TestReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []Paragraph
+		wantErr bool
+	}{
+		{
+			name:  "empty input",
+			input: "",
+			want:  nil,
+		},
+		{
+			name:  "several paragraphs with extra blank lines",
+			input: "\n\nA: 1\nB:2\n\n\n\nA: 3\n",
+			want: []Paragraph{
+				{{Name: "A", Value: "1"}, {Name: "B", Value: "2"}},
+				{{Name: "A", Value: "3"}},
+			},
+		},
+		{
+			name:  "multiline field keeps continuation lines",
+			input: "Description: short\n long\n .\n   indented\nNext: x\n",
+			want: []Paragraph{
+				{{Name: "Description", Value: "short\nlong\n.\n  indented"}, {Name: "Next", Value: "x"}},
+			},
+		},
+		{
+			name:  "field with empty first line",
+			input: "SHA256:\n abc 10 main/Packages\n def 20 main/Packages.gz\n",
+			want: []Paragraph{
+				{{Name: "SHA256", Value: "\nabc 10 main/Packages\ndef 20 main/Packages.gz"}},
+			},
+		},
+		{
+			name:  "comments, CRLF and whitespace-only separators",
+			input: "# comment\r\nA: 1\r\n \t \r\nB: 2\r\n",
+			want: []Paragraph{
+				{{Name: "A", Value: "1"}},
+				{{Name: "B", Value: "2"}},
+			},
+		},
+		{
+			name: "clearsigned message",
+			input: "-----BEGIN PGP SIGNED MESSAGE-----\nHash: SHA512\n\nOrigin: Debian\n- -Dashed: yes\n" +
+				"-----BEGIN PGP SIGNATURE-----\n\niQIzBAEBCgAdFiEE\n-----END PGP SIGNATURE-----\n",
+			want: []Paragraph{
+				{{Name: "Origin", Value: "Debian"}, {Name: "-Dashed", Value: "yes"}},
+			},
+		},
+		{
+			name:    "continuation without field",
+			input:   " orphan\n",
+			wantErr: true,
+		},
+		{
+			name:    "line without colon",
+			input:   "A: 1\ngarbage\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []Paragraph
+			err := ReadParagraphs(strings.NewReader(tt.input), func(p Paragraph) error {
+				got = append(got, p)
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadParagraphs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadParagraphs() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParagraph_WriteTo(t *testing.T) {
+	input := "Package: foo\nDescription: short\n long\n .\n   indented\nSHA256:\n abc 10 main/Packages\n\n"
+
+	p, err := NewReader(strings.NewReader(input)).Next()
+	if err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := p.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	if buf.String() != input {
+		t.Errorf("WriteTo() = %q, want %q", buf.String(), input)
+	}
+	if got := p.Get("package"); got != "foo" {
+		t.Errorf("Get(package) = %q, want %q", got, "foo")
+	}
+}
+
+func TestReadPackages(t *testing.T) {
+	compress := func(t *testing.T, newWriter func(io.Writer) (io.WriteCloser, error)) []byte {
+		var buf bytes.Buffer
+		w, err := newWriter(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, testPackages); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	bz2, _ := base64.StdEncoding.DecodeString(testPackagesBz2)
+
+	tests := []struct {
+		name string
+		data func(t *testing.T) []byte
+	}{
+		{
+			name: "Packages",
+			data: func(t *testing.T) []byte { return []byte(testPackages) },
+		},
+		{
+			name: "Packages.gz",
+			data: func(t *testing.T) []byte {
+				return compress(t, func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil })
+			},
+		},
+		{
+			name: "Packages.xz",
+			data: func(t *testing.T) []byte {
+				return compress(t, func(w io.Writer) (io.WriteCloser, error) { return xz.NewWriter(w) })
+			},
+		},
+		{
+			name: "Packages.zst",
+			data: func(t *testing.T) []byte {
+				return compress(t, func(w io.Writer) (io.WriteCloser, error) { return zstd.NewWriter(w) })
+			},
+		},
+		{
+			name: "Packages.bz2",
+			data: func(t *testing.T) []byte { return bz2 },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, closeFn, err := Decompress(tt.name, bytes.NewReader(tt.data(t)))
+			if err != nil {
+				t.Fatalf("Decompress() error = %v", err)
+			}
+			defer closeFn()
+
+			var got []*IndexPackage
+			err = ReadPackages(r, func(p *IndexPackage) error {
+				got = append(got, p)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("ReadPackages() error = %v", err)
+			}
+			if len(got) != 2 {
+				t.Fatalf("ReadPackages() returned %d packages, want 2", len(got))
+			}
+
+			foo := got[0]
+			if foo.Package != "foo" || foo.Version != "1.0" || foo.Size != 1234 || foo.SHA256 != "abc" ||
+				foo.Filename != "pool/main/f/foo/foo_1.0_amd64.deb" {
+				t.Errorf("first package = %+v", foo)
+			}
+			if foo.Description != "short\nlong line\n.\n  verbatim" {
+				t.Errorf("Description = %q", foo.Description)
+			}
+			if len(foo.Extra) != 0 {
+				t.Errorf("Extra = %v, want empty", foo.Extra)
+			}
+			if got[1].Package != "bar" || got[1].Version != "2:0.1-1" {
+				t.Errorf("second package = %+v", got[1])
+			}
+		})
+	}
+}
+
+func TestReadPackages_stop(t *testing.T) {
+	count := 0
+	err := ReadPackages(strings.NewReader(testPackages), func(p *IndexPackage) error {
+		count++
+		return io.EOF
+	})
+	if err != nil || count != 1 {
+		t.Errorf("ReadPackages() error = %v, count = %d, want nil and 1", err, count)
+	}
+}
+
+func TestReadRelease(t *testing.T) {
+	input := `Origin: Debian
+Label: Debian
+Suite: stable
+Version: 12.5
+Codename: bookworm
+Date: Sat, 10 Feb 2024 09:27:46 UTC
+Acquire-By-Hash: yes
+No-Support-for-Architecture-all: Packages
+Architectures: all amd64 arm64
+Components: main contrib non-free-firmware
+Description: Debian 12.5 Released 10 February 2024
+MD5Sum:
+ 0ed6d4c8891eb86358b94bb35d9e4da4  1484322 contrib/Contents-all
+SHA256:
+ d6c9c82f4e61b4662f9ba16b9ebb379c57b4943f8b7813091d1f637325ddfb79   1484322 contrib/Contents-all
+ 3957f28db16e3f28c7b34ae84f1c929c567de6970f3f1b95dac9b498dd80fe63      738242 main/binary-amd64/Packages.xz
+`
+
+	release, err := ReadRelease(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadRelease() error = %v", err)
+	}
+
+	if release.Codename != "bookworm" || release.Suite != "stable" || release.Version != "12.5" || !release.AcquireByHash {
+		t.Errorf("ReadRelease() = %+v", release)
+	}
+	if !reflect.DeepEqual(release.Components, []string{"main", "contrib", "non-free-firmware"}) {
+		t.Errorf("Components = %v", release.Components)
+	}
+	if !reflect.DeepEqual(release.Architectures, []string{"all", "amd64", "arm64"}) {
+		t.Errorf("Architectures = %v", release.Architectures)
+	}
+	if release.Extra["No-Support-for-Architecture-all"] != "Packages" {
+		t.Errorf("Extra = %v", release.Extra)
+	}
+	if len(release.MD5Sum) != 1 || len(release.SHA256) != 2 {
+		t.Fatalf("MD5Sum = %v, SHA256 = %v", release.MD5Sum, release.SHA256)
+	}
+
+	f, ok := release.File("main/binary-amd64/Packages.xz")
+	want := FileChecksum{Hash: "3957f28db16e3f28c7b34ae84f1c929c567de6970f3f1b95dac9b498dd80fe63", Size: 738242, Path: "main/binary-amd64/Packages.xz"}
+	if !ok || f != want {
+		t.Errorf("File() = %+v, %v, want %+v", f, ok, want)
+	}
+	if _, ok := release.File("main/binary-i386/Packages"); ok {
+		t.Errorf("File() found a missing file")
+	}
+
+	if _, err := ReadRelease(strings.NewReader("SHA256:\n abc notanumber main/Packages\n")); err == nil {
+		t.Errorf("ReadRelease() with invalid size: expected error")
+	}
+}
+
+func TestReadSources(t *testing.T) {
+	input := `Package: hello
+Binary: hello, hello-dbg
+Version: 2.10-3
+Maintainer: Santiago Vila <sanvila@debian.org>
+Build-Depends: debhelper-compat (= 13)
+Architecture: any
+Standards-Version: 4.6.2
+Format: 3.0 (quilt)
+Files:
+ b8a1c7ee1d9d1ae6d8e3e6bb1d7ae0a1 1183 hello_2.10-3.dsc
+ 6cd0ffea3884a4e79330338dcc2987d6 725946 hello_2.10.orig.tar.gz
+Checksums-Sha256:
+ ab4f2d5ab2c4a5e1b8a8f1b6c0a9d7e3 1183 hello_2.10-3.dsc
+Directory: pool/main/h/hello
+Priority: optional
+Section: devel
+`
+
+	var got []*SourcePackage
+	err := ReadSources(strings.NewReader(input), func(s *SourcePackage) error {
+		got = append(got, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadSources() error = %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("ReadSources() returned %d records, want 1", len(got))
+	}
+
+	src := got[0]
+	if src.Package != "hello" || src.Version != "2.10-3" || src.Directory != "pool/main/h/hello" ||
+		src.Format != "3.0 (quilt)" || src.BuildDepends != "debhelper-compat (= 13)" {
+		t.Errorf("ReadSources() = %+v", src)
+	}
+	if !reflect.DeepEqual(src.Binary, []string{"hello", "hello-dbg"}) {
+		t.Errorf("Binary = %v", src.Binary)
+	}
+	if len(src.Files) != 2 || src.Files[1].Path != "hello_2.10.orig.tar.gz" || src.Files[1].Size != 725946 {
+		t.Errorf("Files = %+v", src.Files)
+	}
+	if len(src.ChecksumsSHA256) != 1 {
+		t.Errorf("ChecksumsSHA256 = %+v", src.ChecksumsSHA256)
+	}
+	if !reflect.DeepEqual(src.Extra, map[string]string{"Standards-Version": "4.6.2"}) {
+		t.Errorf("Extra = %v", src.Extra)
+	}
+}
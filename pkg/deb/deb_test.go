@@ -0,0 +1,72 @@
+package deb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParagraphToMeta(t *testing.T) {
+	tests := []struct {
+		name string
+		p    Paragraph
+		skip []string
+		want *PackageMeta
+	}{
+		{
+			name: "canonical names",
+			p: Paragraph{
+				{Name: "Package", Value: "foo"},
+				{Name: "Version", Value: "1.0"},
+				{Name: "Installed-Size", Value: "12"},
+				{Name: "Description", Value: "short\nlong"},
+				{Name: "Multi-Arch", Value: "same"},
+			},
+			want: &PackageMeta{
+				Package:       "foo",
+				Version:       "1.0",
+				InstalledSize: "12",
+				Description:   "short\nlong",
+				Extra:         map[string]string{"Multi-Arch": "same"},
+			},
+		},
+		{
+			name: "names in other case",
+			p: Paragraph{
+				{Name: "package", Value: "foo"},
+				{Name: "VERSION", Value: "1.0"},
+				{Name: "installed-size", Value: "12"},
+				{Name: "Pre-depends", Value: "libc6"},
+				{Name: "multi-arch", Value: "same"},
+			},
+			want: &PackageMeta{
+				Package:       "foo",
+				Version:       "1.0",
+				InstalledSize: "12",
+				PreDepends:    "libc6",
+				Extra:         map[string]string{"multi-arch": "same"},
+			},
+		},
+		{
+			name: "skipped fields ignore case",
+			p: Paragraph{
+				{Name: "Package", Value: "foo"},
+				{Name: "Filename", Value: "pool/main/f/foo/foo_1.0_amd64.deb"},
+				{Name: "MD5Sum", Value: "abc"},
+				{Name: "Task", Value: "desktop"},
+			},
+			skip: []string{"Filename", "MD5sum"},
+			want: &PackageMeta{
+				Package: "foo",
+				Extra:   map[string]string{"Task": "desktop"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := paragraphToMeta(tt.p, tt.skip...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("paragraphToMeta() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+// Package debtest собирает пакеты .deb для тестов.
+package debtest
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/blakesmith/ar"
+	"github.com/ulikunitz/xz"
+)
+
+// Entry запись tar-архива пакета. Размер обычного файла берётся из Body.
+type Entry struct {
+	Header tar.Header
+	Body   string
+}
+
+// File возвращает обычный файл name (путь в архиве, например "./usr/bin/foo")
+// с содержимым body и правами 0644.
+func File(name, body string) Entry {
+	return Entry{Header: tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o644}, Body: body}
+}
+
+// Control возвращает control-файл пакета name версии version архитектуры arch
+// с полями Maintainer и Description и дополнительными полями extra (строки
+// "Поле: значение\n").
+func Control(name, version, arch, extra string) Entry {
+	return File("./control", fmt.Sprintf("Package: %s\nVersion: %s\nArchitecture: %s\nMaintainer: Test <test@example.org>\n%sDescription: %s test package\n",
+		name, version, arch, extra, name))
+}
+
+// Build собирает пакет .deb из записей control.tar.gz и data.tar.xz.
+func Build(t testing.TB, control, data []Entry) []byte {
+	t.Helper()
+
+	var controlGz bytes.Buffer
+	gw := gzip.NewWriter(&controlGz)
+	if _, err := gw.Write(makeTar(t, control)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var dataXz bytes.Buffer
+	xw, err := xz.NewWriter(&dataXz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := xw.Write(makeTar(t, data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := xw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	aw := ar.NewWriter(&out)
+	if err := aw.WriteGlobalHeader(); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []struct {
+		name string
+		data []byte
+	}{
+		{"debian-binary", []byte("2.0\n")},
+		{"control.tar.gz", controlGz.Bytes()},
+		{"data.tar.xz", dataXz.Bytes()},
+	} {
+		if err := aw.WriteHeader(&ar.Header{Name: m.name, Size: int64(len(m.data)), Mode: 0o644}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := aw.Write(m.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return out.Bytes()
+}
+
+// Package собирает пакет name версии version архитектуры arch с файлами files
+// (абсолютный путь в системе — содержимое).
+func Package(t testing.TB, name, version, arch string, files map[string]string) []byte {
+	t.Helper()
+
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	data := make([]Entry, 0, len(paths))
+	for _, p := range paths {
+		data = append(data, File("."+p, files[p]))
+	}
+
+	return Build(t, []Entry{Control(name, version, arch, "")}, data)
+}
+
+// Write записывает пакет data в файл dir/name, создавая директорию, и возвращает
+// путь к файлу.
+func Write(t testing.TB, dir, name string, data []byte) string {
+	t.Helper()
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+// makeTar собирает tar-архив из записей entries.
+func makeTar(t testing.TB, entries []Entry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := e.Header
+		if hdr.Typeflag == tar.TypeReg {
+			hdr.Size = int64(len(e.Body))
+		}
+		if err := tw.WriteHeader(&hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.Body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
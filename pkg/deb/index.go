@@ -0,0 +1,247 @@
+package deb
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// FileChecksum файл с контрольной суммой и размером из списков Release и Sources.
+type FileChecksum struct {
+	Hash string
+	Size int64
+	Path string // Путь относительно директории дистрибутива (Release) или имя файла (Sources)
+}
+
+// IndexPackage запись индекса Packages: метаданные пакета и сведения о его файле.
+type IndexPackage struct {
+	PackageMeta
+
+	Filename string // Путь к файлу пакета относительно корня репозитория
+	Size     int64  // Размер файла пакета
+	MD5Sum   string
+	SHA1     string
+	SHA256   string
+}
+
+// SourcePackage запись индекса Sources.
+type SourcePackage struct {
+	Package           string
+	Binary            []string // Бинарные пакеты, собираемые из исходного
+	Version           string
+	Maintainer        string
+	Architecture      string
+	Format            string
+	Directory         string // Директория файлов исходного пакета относительно корня репозитория
+	Section           string
+	Priority          string
+	Homepage          string
+	BuildDepends      string
+	BuildDependsIndep string
+	Files             []FileChecksum // Файлы с MD5 (поле Files)
+	ChecksumsSHA256   []FileChecksum
+	Extra             map[string]string // Все остальные поля
+}
+
+// Release содержимое файла Release (InRelease) дистрибутива.
+type Release struct {
+	Origin        string
+	Label         string
+	Suite         string
+	Codename      string
+	Version       string
+	Date          string
+	ValidUntil    string
+	Description   string
+	Architectures []string
+	Components    []string
+	AcquireByHash bool
+	MD5Sum        []FileChecksum
+	SHA1          []FileChecksum
+	SHA256        []FileChecksum
+	Extra         map[string]string // Все остальные поля
+}
+
+// File возвращает сведения об индексном файле path из списков контрольных сумм,
+// предпочитая SHA256. Второе значение равно false, если файл в Release не указан.
+func (r *Release) File(path string) (FileChecksum, bool) {
+	for _, list := range [][]FileChecksum{r.SHA256, r.SHA1, r.MD5Sum} {
+		for _, f := range list {
+			if f.Path == path {
+				return f, true
+			}
+		}
+	}
+
+	return FileChecksum{}, false
+}
+
+// ReadPackages последовательно читает индекс Packages из r, вызывая fn для каждой записи.
+// Если fn вернёт io.EOF, чтение прекращается без ошибки.
+func ReadPackages(r io.Reader, fn func(*IndexPackage) error) error {
+	return ReadParagraphs(r, func(p Paragraph) error {
+		size, err := parseSize(p.Get("Size"))
+		if err != nil {
+			return fmt.Errorf("пакет %s: %w", p.Get("Package"), err)
+		}
+
+		pkg := &IndexPackage{
+			PackageMeta: *paragraphToMeta(p, "Filename", "Size", "MD5sum", "SHA1", "SHA256"),
+			Filename:    p.Get("Filename"),
+			Size:        size,
+			MD5Sum:      p.Get("MD5sum"),
+			SHA1:        p.Get("SHA1"),
+			SHA256:      p.Get("SHA256"),
+		}
+
+		return fn(pkg)
+	})
+}
+
+// ReadSources последовательно читает индекс Sources из r, вызывая fn для каждой записи.
+// Если fn вернёт io.EOF, чтение прекращается без ошибки.
+func ReadSources(r io.Reader, fn func(*SourcePackage) error) error {
+	known := map[string]bool{
+		"package": true, "binary": true, "version": true, "maintainer": true,
+		"architecture": true, "format": true, "directory": true, "section": true,
+		"priority": true, "homepage": true, "build-depends": true,
+		"build-depends-indep": true, "files": true, "checksums-sha256": true,
+	}
+
+	return ReadParagraphs(r, func(p Paragraph) error {
+		files, err := parseChecksums(p.Get("Files"))
+		if err != nil {
+			return fmt.Errorf("исходный пакет %s: %w", p.Get("Package"), err)
+		}
+		sha256, err := parseChecksums(p.Get("Checksums-Sha256"))
+		if err != nil {
+			return fmt.Errorf("исходный пакет %s: %w", p.Get("Package"), err)
+		}
+
+		src := &SourcePackage{
+			Package:           p.Get("Package"),
+			Binary:            splitList(p.Get("Binary"), ","),
+			Version:           p.Get("Version"),
+			Maintainer:        p.Get("Maintainer"),
+			Architecture:      p.Get("Architecture"),
+			Format:            p.Get("Format"),
+			Directory:         p.Get("Directory"),
+			Section:           p.Get("Section"),
+			Priority:          p.Get("Priority"),
+			Homepage:          p.Get("Homepage"),
+			BuildDepends:      p.Get("Build-Depends"),
+			BuildDependsIndep: p.Get("Build-Depends-Indep"),
+			Files:             files,
+			ChecksumsSHA256:   sha256,
+			Extra:             make(map[string]string),
+		}
+		for _, f := range p {
+			if !known[strings.ToLower(f.Name)] {
+				src.Extra[f.Name] = f.Value
+			}
+		}
+
+		return fn(src)
+	})
+}
+
+// ReadRelease читает файл Release или InRelease (подпись не проверяется).
+func ReadRelease(r io.Reader) (*Release, error) {
+	p, err := NewReader(r).Next()
+	if err == io.EOF {
+		return nil, fmt.Errorf("файл Release пуст")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rel := &Release{Extra: make(map[string]string)}
+	for _, f := range p {
+		switch strings.ToLower(f.Name) {
+		case "origin":
+			rel.Origin = f.Value
+		case "label":
+			rel.Label = f.Value
+		case "suite":
+			rel.Suite = f.Value
+		case "codename":
+			rel.Codename = f.Value
+		case "version":
+			rel.Version = f.Value
+		case "date":
+			rel.Date = f.Value
+		case "valid-until":
+			rel.ValidUntil = f.Value
+		case "description":
+			rel.Description = f.Value
+		case "architectures":
+			rel.Architectures = strings.Fields(f.Value)
+		case "components":
+			rel.Components = strings.Fields(f.Value)
+		case "acquire-by-hash":
+			rel.AcquireByHash = strings.EqualFold(f.Value, "yes")
+		case "md5sum":
+			rel.MD5Sum, err = parseChecksums(f.Value)
+		case "sha1":
+			rel.SHA1, err = parseChecksums(f.Value)
+		case "sha256":
+			rel.SHA256, err = parseChecksums(f.Value)
+		default:
+			rel.Extra[f.Name] = f.Value
+		}
+		if err != nil {
+			return nil, fmt.Errorf("поле %s: %w", f.Name, err)
+		}
+	}
+
+	return rel, nil
+}
+
+// parseChecksums разбирает многострочный список "<хэш> <размер> <путь>".
+func parseChecksums(value string) ([]FileChecksum, error) {
+	result := make([]FileChecksum, 0)
+	for _, line := range strings.Split(value, "\n") {
+		parts := strings.Fields(line)
+		if len(parts) == 0 {
+			continue
+		}
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("некорректная строка контрольной суммы %q", strings.TrimSpace(line))
+		}
+
+		size, err := parseSize(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, FileChecksum{Hash: parts[0], Size: size, Path: parts[2]})
+	}
+
+	return result, nil
+}
+
+// parseSize разбирает размер файла; пустая строка означает ноль.
+func parseSize(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("некорректный размер %q", value)
+	}
+
+	return size, nil
+}
+
+// splitList разбивает список по разделителю sep, отбрасывая пробелы и пустые элементы.
+func splitList(value, sep string) []string {
+	result := make([]string, 0)
+	for _, item := range strings.Split(value, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
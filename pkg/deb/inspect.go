@@ -0,0 +1,398 @@
+package deb
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/blakesmith/ar"
+)
+
+// maxControlMember максимальный размер файла архива control.tar, читаемого Inspect.
+const maxControlMember = 16 << 20
+
+// maintainerScripts сценарии сопровождающего, которые Inspect сохраняет целиком.
+var maintainerScripts = map[string]bool{
+	"preinst": true, "postinst": true, "prerm": true, "postrm": true, "config": true,
+}
+
+// Inspection полное содержимое пакета .deb, собранное Inspect.
+type Inspection struct {
+	Format       string            `json:"format"`        // Версия формата из debian-binary
+	Control      Paragraph         `json:"control"`       // Поля control-файла в исходном порядке
+	ControlFiles []string          `json:"control_files"` // Все файлы архива control.tar
+	Files        []DataFile        `json:"files"`         // Файлы архива data.tar
+	MD5Sums      []MD5Sum          `json:"md5sums"`
+	Conffiles    []Conffile        `json:"conffiles"`
+	Triggers     []Trigger         `json:"triggers"`
+	Scripts      map[string]string `json:"scripts"` // Сценарии сопровождающего по имени (postinst и т.д.)
+	AppStream    *AppStream        `json:"-"`       // Файлы AppStream из data.tar (см. ExtractAppStream)
+}
+
+// Meta возвращает метаинформацию пакета из control-файла.
+func (i *Inspection) Meta() *PackageMeta {
+	return paragraphToMeta(i.Control)
+}
+
+// InstalledSize оценивает установленный размер пакета в килобайтах так же, как
+// dpkg-gencontrol: содержимое файлов и ссылок — с округлением вверх до 1 КиБ,
+// прочие объекты (директории, устройства) — по 1 КиБ, жёсткие ссылки не учитываются.
+func (i *Inspection) InstalledSize() int64 {
+	var size int64
+	for _, f := range i.Files {
+		switch f.Type {
+		case "hardlink":
+		case "file":
+			size += (f.Size + 1023) / 1024
+		case "symlink":
+			size += (int64(len(f.LinkTarget)) + 1023) / 1024
+		default:
+			size++
+		}
+	}
+
+	return size
+}
+
+// DataFile запись архива data.tar. Пути абсолютные, как после установки пакета.
+type DataFile struct {
+	Path       string    `json:"path"`
+	Type       string    `json:"type"` // file, dir, symlink, hardlink, char, block, fifo
+	Mode       string    `json:"mode"` // Права доступа в восьмеричном виде, например "0755"
+	Owner      string    `json:"owner"`
+	Group      string    `json:"group"`
+	Size       int64     `json:"size"`
+	LinkTarget string    `json:"link_target,omitempty"` // Цель символической или жёсткой ссылки
+	ModTime    time.Time `json:"mtime"`
+}
+
+// MD5Sum строка файла md5sums.
+type MD5Sum struct {
+	Path string `json:"path"`
+	Hash string `json:"md5"`
+}
+
+// Conffile строка файла conffiles: путь и необязательные флаги (например, remove-on-upgrade).
+type Conffile struct {
+	Path  string   `json:"path"`
+	Flags []string `json:"flags,omitempty"`
+}
+
+// Trigger строка файла triggers: директива (interest, activate-noawait и т.д.) и имя триггера.
+type Trigger struct {
+	Directive string `json:"directive"`
+	Name      string `json:"name"`
+}
+
+// MarshalJSON представляет запись в виде JSON-объекта с исходным порядком полей.
+func (p Paragraph) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range p {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(f.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(f.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// Inspect за один проход по потоку deb собирает полное содержимое пакета: поля
+// control-файла, md5sums, conffiles, triggers, сценарии сопровождающего и список
+// файлов архива data.tar. Из содержимого файлов data.tar сохраняются только
+// метаданные AppStream.
+func Inspect(deb io.Reader) (*Inspection, error) {
+	result := &Inspection{
+		ControlFiles: make([]string, 0),
+		Files:        make([]DataFile, 0),
+		MD5Sums:      make([]MD5Sum, 0),
+		Conffiles:    make([]Conffile, 0),
+		Triggers:     make([]Trigger, 0),
+		Scripts:      make(map[string]string),
+		AppStream:    &AppStream{},
+	}
+
+	var hasData bool
+	members := 0
+	arR := ar.NewReader(deb)
+	for {
+		hdr, err := arR.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения архива .deb: %w", err)
+		}
+		members++
+
+		name := strings.Trim(strings.TrimSpace(hdr.Name), "/")
+		switch {
+		case name == "debian-binary":
+			data, err := io.ReadAll(io.LimitReader(arR, 64))
+			if err != nil {
+				return nil, fmt.Errorf("ошибка чтения debian-binary: %w", err)
+			}
+			result.Format = strings.TrimSpace(string(data))
+		case strings.HasPrefix(name, "control.tar"):
+			if err := inspectMember(name, arR, result.addControl); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(name, "data.tar"):
+			if err := inspectMember(name, arR, result.addData); err != nil {
+				return nil, err
+			}
+			hasData = true
+		}
+	}
+
+	if members == 0 {
+		return nil, fmt.Errorf("файл не является архивом .deb")
+	}
+	if result.Control == nil {
+		return nil, fmt.Errorf("control файл не найден в .deb файле")
+	}
+	if !hasData {
+		return nil, fmt.Errorf("архив data не найден в .deb файле")
+	}
+
+	return result, nil
+}
+
+// inspectMember распаковывает член name архива .deb и вызывает fn для каждой записи tar.
+func inspectMember(name string, r io.Reader, fn func(hdr *tar.Header, r io.Reader) error) error {
+	dr, closeFn, err := decompressor(name, r)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	tr := tar.NewReader(dr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("ошибка чтения архива %s: %w", name, err)
+		}
+		if err := fn(hdr, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// addControl обрабатывает файл архива control.tar.
+func (i *Inspection) addControl(hdr *tar.Header, r io.Reader) error {
+	if hdr.Typeflag != tar.TypeReg {
+		return nil
+	}
+
+	name := strings.TrimLeft(strings.TrimPrefix(hdr.Name, "."), "/")
+	i.ControlFiles = append(i.ControlFiles, name)
+
+	if name != "control" && name != "md5sums" && name != "conffiles" && name != "triggers" && !maintainerScripts[name] {
+		return nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, maxControlMember+1))
+	if err != nil {
+		return fmt.Errorf("ошибка чтения %s: %w", name, err)
+	}
+	if len(data) > maxControlMember {
+		return fmt.Errorf("файл %s больше %d байт", name, maxControlMember)
+	}
+
+	switch name {
+	case "control":
+		i.Control, err = parseControl(bytes.NewReader(data))
+		return err
+	case "md5sums":
+		for _, line := range controlLines(data) {
+			hash, file, ok := strings.Cut(line, " ")
+			if !ok {
+				return fmt.Errorf("md5sums: некорректная строка %q", line)
+			}
+			i.MD5Sums = append(i.MD5Sums, MD5Sum{Path: installedPath(strings.TrimSpace(file)), Hash: hash})
+		}
+	case "conffiles":
+		for _, line := range controlLines(data) {
+			parts := strings.Fields(line)
+			conffile := Conffile{Path: parts[len(parts)-1]}
+			if len(parts) > 1 {
+				conffile.Flags = parts[:len(parts)-1]
+			}
+			i.Conffiles = append(i.Conffiles, conffile)
+		}
+	case "triggers":
+		for _, line := range controlLines(data) {
+			parts := strings.Fields(line)
+			if len(parts) != 2 {
+				return fmt.Errorf("triggers: некорректная строка %q", line)
+			}
+			i.Triggers = append(i.Triggers, Trigger{Directive: parts[0], Name: parts[1]})
+		}
+	default:
+		i.Scripts[name] = string(data)
+	}
+
+	return nil
+}
+
+// addData добавляет запись архива data.tar в список файлов и сохраняет её
+// содержимое, если это метаданные AppStream.
+func (i *Inspection) addData(hdr *tar.Header, r io.Reader) error {
+	if err := i.AppStream.add(hdr, r); err != nil {
+		return err
+	}
+
+	file := DataFile{
+		Path:    installedPath(hdr.Name),
+		Mode:    fmt.Sprintf("%04o", hdr.Mode&07777),
+		Owner:   hdr.Uname,
+		Group:   hdr.Gname,
+		ModTime: hdr.ModTime.UTC(),
+	}
+	if file.Owner == "" {
+		file.Owner = fmt.Sprintf("%d", hdr.Uid)
+	}
+	if file.Group == "" {
+		file.Group = fmt.Sprintf("%d", hdr.Gid)
+	}
+
+	switch hdr.Typeflag {
+	case tar.TypeReg:
+		file.Type = "file"
+		file.Size = hdr.Size
+	case tar.TypeDir:
+		file.Type = "dir"
+	case tar.TypeSymlink:
+		file.Type = "symlink"
+		file.LinkTarget = hdr.Linkname
+	case tar.TypeLink:
+		file.Type = "hardlink"
+		file.LinkTarget = installedPath(hdr.Linkname)
+	case tar.TypeChar:
+		file.Type = "char"
+	case tar.TypeBlock:
+		file.Type = "block"
+	case tar.TypeFifo:
+		file.Type = "fifo"
+	default:
+		// Служебные записи tar (расширенные заголовки и т.п.) не являются файлами пакета
+		return nil
+	}
+
+	i.Files = append(i.Files, file)
+
+	return nil
+}
+
+// controlLines возвращает непустые строки файла архива control.tar без комментариев.
+func controlLines(data []byte) []string {
+	result := make([]string, 0)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		result = append(result, line)
+	}
+
+	return result
+}
+
+// installedPath приводит путь из архива ("./usr/bin/foo", "usr/bin/foo")
+// к абсолютному пути после установки ("/usr/bin/foo").
+func installedPath(name string) string {
+	return path.Clean("/" + strings.TrimPrefix(name, "./"))
+}
+
+// ControlDir каталог, под которым ReadPackageFile доступны файлы архива control.tar
+// (как при распаковке dpkg-deb --raw-extract).
+const ControlDir = "/DEBIAN"
+
+// ErrFileNotFound файл не найден в пакете.
+var ErrFileNotFound = errors.New("файл не найден в пакете")
+
+// ErrFileTooLarge файл пакета больше допустимого для чтения размера.
+var ErrFileTooLarge = errors.New("файл слишком большой")
+
+// errStopWalk прерывает обход архива после нахождения нужного файла.
+var errStopWalk = errors.New("обход прерван")
+
+// ReadPackageFile читает из потока deb обычный файл name: абсолютный путь после
+// установки ("/usr/share/doc/foo/copyright") или файл архива control.tar под
+// ControlDir ("/DEBIAN/postinst"). Файлы больше limit байт не читаются.
+func ReadPackageFile(deb io.Reader, name string, limit int64) ([]byte, error) {
+	name = installedPath(name)
+
+	member, inner := "data.tar", name
+	if strings.HasPrefix(name, ControlDir+"/") {
+		member, inner = "control.tar", "/"+strings.TrimPrefix(name, ControlDir+"/")
+	}
+
+	var result []byte
+	find := func(hdr *tar.Header, r io.Reader) error {
+		if installedPath(hdr.Name) != inner {
+			return nil
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return fmt.Errorf("%s не является обычным файлом", name)
+		}
+		if hdr.Size > limit {
+			return fmt.Errorf("%w: %s больше %d байт", ErrFileTooLarge, name, limit)
+		}
+
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("ошибка чтения %s: %w", name, err)
+		}
+		result = data
+
+		return errStopWalk
+	}
+
+	arR := ar.NewReader(deb)
+	for {
+		hdr, err := arR.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: %s", ErrFileNotFound, name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения архива .deb: %w", err)
+		}
+
+		memberName := strings.Trim(strings.TrimSpace(hdr.Name), "/")
+		if !strings.HasPrefix(memberName, member) {
+			continue
+		}
+
+		err = inspectMember(memberName, arR, find)
+		if err == errStopWalk {
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("%w: %s", ErrFileNotFound, name)
+	}
+}
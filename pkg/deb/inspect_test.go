@@ -0,0 +1,174 @@
+package deb
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kirsrus/iso2repo/pkg/deb/debtest"
+)
+
+func TestInspect(t *testing.T) {
+	mtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	reg := func(name string, mode int64, body string) debtest.Entry {
+		return debtest.Entry{
+			Header: tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: mode, Uname: "root", Gname: "root", ModTime: mtime},
+			Body:   body,
+		}
+	}
+
+	control := []debtest.Entry{
+		{Header: tar.Header{Name: "./", Typeflag: tar.TypeDir, Mode: 0755, ModTime: mtime}},
+		reg("./control", 0644, "Package: foo\nVersion: 1.0-1\nArchitecture: amd64\nDescription: foo\n long\n"),
+		reg("./md5sums", 0644, "d41d8cd98f00b204e9800998ecf8427e  usr/bin/foo\n0cc175b9c0f1b6a831c399e269772661  etc/foo.conf\n"),
+		reg("./conffiles", 0644, "/etc/foo.conf\nremove-on-upgrade /etc/foo.old\n"),
+		reg("./triggers", 0644, "# comment\ninterest-noawait /usr/share/foo\n"),
+		reg("./postinst", 0755, "#!/bin/sh\nset -e\n"),
+		reg("./shlibs", 0644, "libfoo 1 libfoo1\n"),
+	}
+	data := []debtest.Entry{
+		{Header: tar.Header{Name: "./", Typeflag: tar.TypeDir, Mode: 0755, ModTime: mtime}},
+		{Header: tar.Header{Name: "./usr/bin/", Typeflag: tar.TypeDir, Mode: 0755, Uid: 0, Gid: 0, ModTime: mtime}},
+		reg("./usr/bin/foo", 04755, ""),
+		reg("./etc/foo.conf", 0644, "a"),
+		{Header: tar.Header{Name: "./usr/bin/foo-link", Typeflag: tar.TypeSymlink, Linkname: "foo", Mode: 0777, ModTime: mtime}},
+		{Header: tar.Header{Name: "./usr/bin/foo-hard", Typeflag: tar.TypeLink, Linkname: "./usr/bin/foo", Mode: 04755, ModTime: mtime}},
+	}
+
+	inspection, err := Inspect(bytes.NewReader(debtest.Build(t, control, data)))
+	if err != nil {
+		t.Fatalf("Inspect() error = %v", err)
+	}
+
+	if inspection.Format != "2.0" {
+		t.Errorf("Format = %q, want %q", inspection.Format, "2.0")
+	}
+	if meta := inspection.Meta(); meta.Package != "foo" || meta.Version != "1.0-1" || meta.Description != "foo\nlong" {
+		t.Errorf("Meta() = %+v", meta)
+	}
+
+	wantControlFiles := []string{"control", "md5sums", "conffiles", "triggers", "postinst", "shlibs"}
+	if !reflect.DeepEqual(inspection.ControlFiles, wantControlFiles) {
+		t.Errorf("ControlFiles = %v, want %v", inspection.ControlFiles, wantControlFiles)
+	}
+
+	wantMD5 := []MD5Sum{
+		{Path: "/usr/bin/foo", Hash: "d41d8cd98f00b204e9800998ecf8427e"},
+		{Path: "/etc/foo.conf", Hash: "0cc175b9c0f1b6a831c399e269772661"},
+	}
+	if !reflect.DeepEqual(inspection.MD5Sums, wantMD5) {
+		t.Errorf("MD5Sums = %+v, want %+v", inspection.MD5Sums, wantMD5)
+	}
+
+	wantConffiles := []Conffile{
+		{Path: "/etc/foo.conf"},
+		{Path: "/etc/foo.old", Flags: []string{"remove-on-upgrade"}},
+	}
+	if !reflect.DeepEqual(inspection.Conffiles, wantConffiles) {
+		t.Errorf("Conffiles = %+v, want %+v", inspection.Conffiles, wantConffiles)
+	}
+
+	wantTriggers := []Trigger{{Directive: "interest-noawait", Name: "/usr/share/foo"}}
+	if !reflect.DeepEqual(inspection.Triggers, wantTriggers) {
+		t.Errorf("Triggers = %+v, want %+v", inspection.Triggers, wantTriggers)
+	}
+
+	wantScripts := map[string]string{"postinst": "#!/bin/sh\nset -e\n"}
+	if !reflect.DeepEqual(inspection.Scripts, wantScripts) {
+		t.Errorf("Scripts = %v, want %v", inspection.Scripts, wantScripts)
+	}
+
+	wantFiles := []DataFile{
+		{Path: "/", Type: "dir", Mode: "0755", Owner: "0", Group: "0", ModTime: mtime},
+		{Path: "/usr/bin", Type: "dir", Mode: "0755", Owner: "0", Group: "0", ModTime: mtime},
+		{Path: "/usr/bin/foo", Type: "file", Mode: "4755", Owner: "root", Group: "root", ModTime: mtime},
+		{Path: "/etc/foo.conf", Type: "file", Mode: "0644", Owner: "root", Group: "root", Size: 1, ModTime: mtime},
+		{Path: "/usr/bin/foo-link", Type: "symlink", Mode: "0777", Owner: "0", Group: "0", LinkTarget: "foo", ModTime: mtime},
+		{Path: "/usr/bin/foo-hard", Type: "hardlink", Mode: "4755", Owner: "0", Group: "0", LinkTarget: "/usr/bin/foo", ModTime: mtime},
+	}
+	if !reflect.DeepEqual(inspection.Files, wantFiles) {
+		t.Errorf("Files = %+v\nwant %+v", inspection.Files, wantFiles)
+	}
+
+	// Две директории, файл в 1 байт и символическая ссылка; пустой файл и жёсткая ссылка не учитываются
+	if got := inspection.InstalledSize(); got != 4 {
+		t.Errorf("InstalledSize() = %d, want 4", got)
+	}
+}
+
+func TestInspect_invalid(t *testing.T) {
+	control := []debtest.Entry{{
+		Header: tar.Header{Name: "./control", Typeflag: tar.TypeReg, Mode: 0644},
+		Body:   "Package: foo\n",
+	}}
+
+	tests := []struct {
+		name string
+		deb  []byte
+	}{
+		{name: "not an ar archive", deb: []byte("garbage")},
+		{name: "no control file", deb: debtest.Build(t, nil, nil)},
+		{name: "invalid triggers", deb: debtest.Build(t, append(control, debtest.Entry{
+			Header: tar.Header{Name: "./triggers", Typeflag: tar.TypeReg, Mode: 0644},
+			Body:   "interest\n",
+		}), nil)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Inspect(bytes.NewReader(tt.deb)); err == nil {
+				t.Errorf("Inspect() expected error")
+			}
+		})
+	}
+}
+
+func TestReadPackageFile(t *testing.T) {
+	control := []debtest.Entry{
+		{Header: tar.Header{Name: "./control", Typeflag: tar.TypeReg, Mode: 0644}, Body: "Package: foo\n"},
+		{Header: tar.Header{Name: "./postinst", Typeflag: tar.TypeReg, Mode: 0755}, Body: "#!/bin/sh\n"},
+	}
+	data := []debtest.Entry{
+		{Header: tar.Header{Name: "./usr/share/doc/foo/", Typeflag: tar.TypeDir, Mode: 0755}},
+		{Header: tar.Header{Name: "./usr/share/doc/foo/copyright", Typeflag: tar.TypeReg, Mode: 0644}, Body: "MIT\n"},
+		{Header: tar.Header{Name: "./usr/bin/foo", Typeflag: tar.TypeReg, Mode: 0755}, Body: "0123456789"},
+		{Header: tar.Header{Name: "./usr/bin/bar", Typeflag: tar.TypeSymlink, Linkname: "foo"}},
+	}
+	pkg := debtest.Build(t, control, data)
+
+	tests := []struct {
+		name    string
+		file    string
+		limit   int64
+		want    string
+		wantErr bool
+		errIs   error
+	}{
+		{name: "data file", file: "/usr/share/doc/foo/copyright", limit: 100, want: "MIT\n"},
+		{name: "path without leading slash", file: "usr/share/doc/foo/copyright", limit: 100, want: "MIT\n"},
+		{name: "control file", file: "/DEBIAN/postinst", limit: 100, want: "#!/bin/sh\n"},
+		{name: "missing data file", file: "/usr/bin/baz", limit: 100, wantErr: true, errIs: ErrFileNotFound},
+		{name: "missing control file", file: "/DEBIAN/prerm", limit: 100, wantErr: true, errIs: ErrFileNotFound},
+		{name: "file over limit", file: "/usr/bin/foo", limit: 5, wantErr: true, errIs: ErrFileTooLarge},
+		{name: "directory", file: "/usr/share/doc/foo", limit: 100, wantErr: true},
+		{name: "symlink", file: "/usr/bin/bar", limit: 100, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadPackageFile(bytes.NewReader(pkg), tt.file, tt.limit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadPackageFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.errIs != nil && !errors.Is(err, tt.errIs) {
+				t.Errorf("ReadPackageFile() error = %v, want %v", err, tt.errIs)
+			}
+			if !tt.wantErr && string(got) != tt.want {
+				t.Errorf("ReadPackageFile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,297 @@
+package deb
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Операторы сравнения версий в отношениях между пакетами.
+const (
+	OpLess         = "<<"
+	OpLessEqual    = "<="
+	OpEqual        = "="
+	OpGreaterEqual = ">="
+	OpGreater      = ">>"
+)
+
+// Relation отношение к одному пакету: имя с необязательными уточнением архитектуры,
+// ограничением версии, списком архитектур и профилями сборки.
+//
+//	libc6:any (>= 2.36) [amd64 !i386] <!nocheck>
+type Relation struct {
+	Name          string     // Имя пакета
+	ArchQualifier string     // Уточнение архитектуры после ':' ("any", "native", "amd64")
+	Op            string     // Оператор сравнения версий (пустой, если версия не ограничена)
+	Version       string     // Версия в ограничении
+	Archs         []string   // Ограничение по архитектурам ("amd64", "!i386")
+	Profiles      [][]string // Профили сборки: внешний список — ИЛИ, внутренний — И
+}
+
+// Alternatives группа альтернатив "a | b": отношение выполнено, если выполнено любое из них.
+type Alternatives []Relation
+
+// ParseRelations разбирает поле отношений (Depends, Pre-Depends, Recommends, Suggests,
+// Breaks, Conflicts, Provides, Replaces, Enhances, Build-Depends и т.п.) на группы
+// альтернатив по правилам Debian Policy (7.1). Устаревшие операторы "<" и ">"
+// приводятся к "<=" и ">=". Пустые группы (например, после завершающей запятой)
+// пропускаются.
+func ParseRelations(field string) ([]Alternatives, error) {
+	result := make([]Alternatives, 0)
+
+	for _, group := range strings.Split(field, ",") {
+		if strings.TrimSpace(group) == "" {
+			continue
+		}
+
+		alts := make(Alternatives, 0, 1)
+		for _, alt := range strings.Split(group, "|") {
+			r, err := parseRelation(alt)
+			if err != nil {
+				return nil, err
+			}
+			alts = append(alts, r)
+		}
+		result = append(result, alts)
+	}
+
+	return result, nil
+}
+
+// parseRelation разбирает одно отношение вида "имя[:арх] [(оп версия)] [[архитектуры]] [<профили>]".
+func parseRelation(s string) (Relation, error) {
+	var r Relation
+	p := relationParser{s: s}
+
+	p.skipSpaces()
+	name := p.until(" \t\n(:[<")
+	if name == "" {
+		return r, fmt.Errorf("пустое имя пакета в отношении %q", strings.TrimSpace(s))
+	}
+	if !validPackageName(name) {
+		return r, fmt.Errorf("некорректное имя пакета %q", name)
+	}
+	r.Name = name
+
+	if p.peek() == ':' {
+		p.pos++
+		r.ArchQualifier = p.until(" \t\n([<")
+		if r.ArchQualifier == "" {
+			return r, fmt.Errorf("пустое уточнение архитектуры у %s", name)
+		}
+	}
+
+	p.skipSpaces()
+	if p.peek() == '(' {
+		p.pos++
+		p.skipSpaces()
+		op := p.while("<>=")
+		switch op {
+		case OpLess, OpLessEqual, OpEqual, OpGreaterEqual, OpGreater:
+		case "<":
+			op = OpLessEqual
+		case ">":
+			op = OpGreaterEqual
+		default:
+			return r, fmt.Errorf("некорректный оператор %q в отношении к %s", op, name)
+		}
+		p.skipSpaces()
+		version := p.until(" \t\n)")
+		p.skipSpaces()
+		if p.peek() != ')' {
+			return r, fmt.Errorf("не закрыта скобка в отношении к %s", name)
+		}
+		p.pos++
+		if version == "" {
+			return r, fmt.Errorf("пустая версия в отношении к %s", name)
+		}
+		if _, err := ParseVersion(version); err != nil {
+			return r, fmt.Errorf("отношение к %s: %w", name, err)
+		}
+		r.Op, r.Version = op, version
+	}
+
+	p.skipSpaces()
+	if p.peek() == '[' {
+		p.pos++
+		list := p.until("]")
+		if p.peek() != ']' {
+			return r, fmt.Errorf("не закрыт список архитектур в отношении к %s", name)
+		}
+		p.pos++
+		r.Archs = strings.Fields(list)
+		if len(r.Archs) == 0 {
+			return r, fmt.Errorf("пустой список архитектур в отношении к %s", name)
+		}
+	}
+
+	for p.skipSpaces(); p.peek() == '<'; p.skipSpaces() {
+		p.pos++
+		list := p.until(">")
+		if p.peek() != '>' {
+			return r, fmt.Errorf("не закрыт список профилей в отношении к %s", name)
+		}
+		p.pos++
+		profiles := strings.Fields(list)
+		if len(profiles) == 0 {
+			return r, fmt.Errorf("пустой список профилей в отношении к %s", name)
+		}
+		r.Profiles = append(r.Profiles, profiles)
+	}
+
+	if !p.eof() {
+		return r, fmt.Errorf("лишние символы %q в отношении к %s", strings.TrimSpace(p.s[p.pos:]), name)
+	}
+
+	return r, nil
+}
+
+// SatisfiedBy проверяет, удовлетворяет ли версия version ограничению отношения.
+// Отношение без ограничения версии удовлетворяется любой версией.
+func (r Relation) SatisfiedBy(version string) bool {
+	if r.Op == "" {
+		return true
+	}
+
+	c := CompareVersions(version, r.Version)
+	switch r.Op {
+	case OpLess:
+		return c < 0
+	case OpLessEqual:
+		return c <= 0
+	case OpEqual:
+		return c == 0
+	case OpGreaterEqual:
+		return c >= 0
+	case OpGreater:
+		return c > 0
+	}
+
+	return false
+}
+
+// String возвращает отношение в каноническом виде.
+func (r Relation) String() string {
+	var b strings.Builder
+
+	b.WriteString(r.Name)
+	if r.ArchQualifier != "" {
+		b.WriteString(":" + r.ArchQualifier)
+	}
+	if r.Op != "" {
+		b.WriteString(" (" + r.Op + " " + r.Version + ")")
+	}
+	if len(r.Archs) > 0 {
+		b.WriteString(" [" + strings.Join(r.Archs, " ") + "]")
+	}
+	for _, profiles := range r.Profiles {
+		b.WriteString(" <" + strings.Join(profiles, " ") + ">")
+	}
+
+	return b.String()
+}
+
+// String возвращает группу альтернатив в каноническом виде.
+func (a Alternatives) String() string {
+	parts := make([]string, 0, len(a))
+	for _, r := range a {
+		parts = append(parts, r.String())
+	}
+
+	return strings.Join(parts, " | ")
+}
+
+// Relations разбирает поле отношений field ("Depends", "Breaks", "Build-Depends" и т.п.)
+// из control-файла пакета. Для отсутствующего поля возвращается пустой список.
+func (m *PackageMeta) Relations(field string) ([]Alternatives, error) {
+	value := ""
+	switch field {
+	case "Depends":
+		value = m.Depends
+	case "Pre-Depends":
+		value = m.PreDepends
+	case "Recommends":
+		value = m.Recommends
+	case "Suggests":
+		value = m.Suggests
+	case "Conflicts":
+		value = m.Conflicts
+	case "Replaces":
+		value = m.Replaces
+	case "Provides":
+		value = m.Provides
+	default:
+		value = m.Extra[field]
+	}
+
+	result, err := ParseRelations(value)
+	if err != nil {
+		return nil, fmt.Errorf("поле %s: %w", field, err)
+	}
+
+	return result, nil
+}
+
+// validPackageName проверяет имя пакета: строчные буквы, цифры и "+-.",
+// не менее двух символов, начинается с буквы или цифры.
+func validPackageName(name string) bool {
+	if len(name) < 2 {
+		return false
+	}
+	for i, c := range []byte(name) {
+		switch {
+		case c >= 'a' && c <= 'z', isDigit(c):
+		case i > 0 && (c == '+' || c == '-' || c == '.'):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// relationParser простой посимвольный разборщик строки отношения.
+type relationParser struct {
+	s   string
+	pos int
+}
+
+// eof сообщает, что строка разобрана целиком.
+func (p *relationParser) eof() bool {
+	return p.pos >= len(p.s)
+}
+
+// peek возвращает текущий символ или 0 в конце строки.
+func (p *relationParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+
+	return p.s[p.pos]
+}
+
+// skipSpaces пропускает пробелы и переводы строк (поля могут быть многострочными).
+func (p *relationParser) skipSpaces() {
+	for !p.eof() && strings.IndexByte(" \t\r\n", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// until читает символы до первого из stop или до конца строки.
+func (p *relationParser) until(stop string) string {
+	start := p.pos
+	for !p.eof() && strings.IndexByte(stop, p.s[p.pos]) < 0 {
+		p.pos++
+	}
+
+	return p.s[start:p.pos]
+}
+
+// while читает символы, пока они входят в set.
+func (p *relationParser) while(set string) string {
+	start := p.pos
+	for !p.eof() && strings.IndexByte(set, p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+
+	return p.s[start:p.pos]
+}
@@ -0,0 +1,277 @@
+package deb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRelations(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   string
+		want    []Alternatives
+		wantErr bool
+	}{
+		{
+			name:  "empty field",
+			field: "",
+			want:  []Alternatives{},
+		},
+		{
+			name:  "plain names",
+			field: "libc6, zlib1g",
+			want: []Alternatives{
+				{{Name: "libc6"}},
+				{{Name: "zlib1g"}},
+			},
+		},
+		{
+			name:  "version constraints",
+			field: "a1 (<< 1.0), a2 (<= 1.0), a3 (= 1:1.0-1), a4 (>= 1.0~rc1), a5 (>> 1.0)",
+			want: []Alternatives{
+				{{Name: "a1", Op: OpLess, Version: "1.0"}},
+				{{Name: "a2", Op: OpLessEqual, Version: "1.0"}},
+				{{Name: "a3", Op: OpEqual, Version: "1:1.0-1"}},
+				{{Name: "a4", Op: OpGreaterEqual, Version: "1.0~rc1"}},
+				{{Name: "a5", Op: OpGreater, Version: "1.0"}},
+			},
+		},
+		{
+			name:  "obsolete operators",
+			field: "a1 (< 1.0), a2 (> 2.0)",
+			want: []Alternatives{
+				{{Name: "a1", Op: OpLessEqual, Version: "1.0"}},
+				{{Name: "a2", Op: OpGreaterEqual, Version: "2.0"}},
+			},
+		},
+		{
+			name:  "no spaces inside parentheses",
+			field: "libfoo(>=1.2)",
+			want:  []Alternatives{{{Name: "libfoo", Op: OpGreaterEqual, Version: "1.2"}}},
+		},
+		{
+			name:  "alternatives",
+			field: "default-mta | mail-transport-agent, exim4 (>= 4.0) | postfix",
+			want: []Alternatives{
+				{{Name: "default-mta"}, {Name: "mail-transport-agent"}},
+				{{Name: "exim4", Op: OpGreaterEqual, Version: "4.0"}, {Name: "postfix"}},
+			},
+		},
+		{
+			name:  "arch qualifiers",
+			field: "python3:any (>= 3.11), libc6:amd64, perl:native",
+			want: []Alternatives{
+				{{Name: "python3", ArchQualifier: "any", Op: OpGreaterEqual, Version: "3.11"}},
+				{{Name: "libc6", ArchQualifier: "amd64"}},
+				{{Name: "perl", ArchQualifier: "native"}},
+			},
+		},
+		{
+			name:  "arch restrictions and build profiles",
+			field: "libseccomp-dev [amd64 arm64 !hurd-i386] <!nocheck> <stage1 cross>, debhelper-compat (= 13)",
+			want: []Alternatives{
+				{{
+					Name:     "libseccomp-dev",
+					Archs:    []string{"amd64", "arm64", "!hurd-i386"},
+					Profiles: [][]string{{"!nocheck"}, {"stage1", "cross"}},
+				}},
+				{{Name: "debhelper-compat", Op: OpEqual, Version: "13"}},
+			},
+		},
+		{
+			name:  "multiline field and extra whitespace",
+			field: "foo   (  >=   1.0  ) ,\n bar\t|\n  baz ,",
+			want: []Alternatives{
+				{{Name: "foo", Op: OpGreaterEqual, Version: "1.0"}},
+				{{Name: "bar"}, {Name: "baz"}},
+			},
+		},
+		{
+			name:  "package names with special characters",
+			field: "libstdc++6, g++-12, python3.11",
+			want: []Alternatives{
+				{{Name: "libstdc++6"}},
+				{{Name: "g++-12"}},
+				{{Name: "python3.11"}},
+			},
+		},
+		{
+			name:    "empty alternative",
+			field:   "foo | , bar",
+			wantErr: true,
+		},
+		{
+			name:    "invalid operator",
+			field:   "foo (!= 1.0)",
+			wantErr: true,
+		},
+		{
+			name:    "missing operator",
+			field:   "foo (1.0)",
+			wantErr: true,
+		},
+		{
+			name:    "unclosed parenthesis",
+			field:   "foo (>= 1.0",
+			wantErr: true,
+		},
+		{
+			name:    "empty version",
+			field:   "foo (>= )",
+			wantErr: true,
+		},
+		{
+			name:    "invalid version",
+			field:   "foo (>= a.b)",
+			wantErr: true,
+		},
+		{
+			name:    "uppercase name",
+			field:   "Foo",
+			wantErr: true,
+		},
+		{
+			name:    "single character name",
+			field:   "f",
+			wantErr: true,
+		},
+		{
+			name:    "unclosed arch list",
+			field:   "foo [amd64",
+			wantErr: true,
+		},
+		{
+			name:    "empty arch list",
+			field:   "foo []",
+			wantErr: true,
+		},
+		{
+			name:    "unclosed profile list",
+			field:   "foo <!nocheck",
+			wantErr: true,
+		},
+		{
+			name:    "empty arch qualifier",
+			field:   "foo: (>= 1.0)",
+			wantErr: true,
+		},
+		{
+			name:    "trailing garbage",
+			field:   "foo (>= 1.0) bar",
+			wantErr: true,
+		},
+		{
+			name:    "arch list before version",
+			field:   "foo [amd64] (>= 1.0)",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRelations(tt.field)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRelations(%q) error = %v, wantErr %v", tt.field, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRelations(%q) = %#v, want %#v", tt.field, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlternatives_String(t *testing.T) {
+	tests := []struct {
+		field string
+		want  []string
+	}{
+		{
+			field: "foo",
+			want:  []string{"foo"},
+		},
+		{
+			field: "foo(>=1.0)|bar:any , baz [amd64  !i386]  <!nocheck>",
+			want:  []string{"foo (>= 1.0) | bar:any", "baz [amd64 !i386] <!nocheck>"},
+		},
+		{
+			field: "foo (< 1.0)",
+			want:  []string{"foo (<= 1.0)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			relations, err := ParseRelations(tt.field)
+			if err != nil {
+				t.Fatalf("ParseRelations(%q) error = %v", tt.field, err)
+			}
+			got := make([]string, 0, len(relations))
+			for _, alts := range relations {
+				got = append(got, alts.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRelation_SatisfiedBy(t *testing.T) {
+	tests := []struct {
+		relation string
+		version  string
+		want     bool
+	}{
+		{"foo", "0.1", true},
+		{"foo (<< 1.0)", "1.0~rc1", true},
+		{"foo (<< 1.0)", "1.0", false},
+		{"foo (<= 1.0)", "1.0", true},
+		{"foo (<= 1.0)", "1.0+b1", false},
+		{"foo (= 1:1.0-1)", "1:1.0-1", true},
+		{"foo (= 1:1.0-1)", "1.0-1", false},
+		{"foo (>= 1.0)", "1.0.0", true},
+		{"foo (>= 1.0)", "1.0~", false},
+		{"foo (>> 1.0)", "1.0-0", false},
+		{"foo (>> 1.0)", "1.0-1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.relation+" "+tt.version, func(t *testing.T) {
+			relations, err := ParseRelations(tt.relation)
+			if err != nil {
+				t.Fatalf("ParseRelations(%q) error = %v", tt.relation, err)
+			}
+			if got := relations[0][0].SatisfiedBy(tt.version); got != tt.want {
+				t.Errorf("SatisfiedBy(%q) = %v, want %v", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPackageMeta_Relations(t *testing.T) {
+	meta := &PackageMeta{
+		Depends: "libc6 (>= 2.36)",
+		Extra:   map[string]string{"Breaks": "foo (<< 2.0)"},
+	}
+
+	tests := []struct {
+		field string
+		want  []Alternatives
+	}{
+		{"Depends", []Alternatives{{{Name: "libc6", Op: OpGreaterEqual, Version: "2.36"}}}},
+		{"Breaks", []Alternatives{{{Name: "foo", Op: OpLess, Version: "2.0"}}}},
+		{"Enhances", []Alternatives{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			got, err := meta.Relations(tt.field)
+			if err != nil {
+				t.Fatalf("Relations(%q) error = %v", tt.field, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Relations(%q) = %#v, want %#v", tt.field, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package deb
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// installedStates состояния пакета (последнее слово поля Status), при которых
+// пакет считается установленным.
+var installedStates = map[string]bool{
+	"installed":        true,
+	"half-configured":  true,
+	"unpacked":         true,
+	"half-installed":   true,
+	"triggers-awaited": true,
+	"triggers-pending": true,
+}
+
+// InstalledPackage пакет, установленный в системе.
+type InstalledPackage struct {
+	Package      string
+	Version      string
+	Architecture string // Пустая, если архитектура неизвестна
+}
+
+// ReadInstalled читает список установленных пакетов из файла состояния dpkg
+// (/var/lib/dpkg/status) или из вывода dpkg-query -W: строк "пакет[:арх] версия
+// [арх]", разделённых пробелами или табуляцией. Формат определяется по первой
+// непустой строке: файл состояния начинается с поля Package.
+func ReadInstalled(r io.Reader) ([]InstalledPackage, error) {
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.Peek(1)
+		if err == io.EOF {
+			return []InstalledPackage{}, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения: %w", err)
+		}
+		if b[0] != '\n' && b[0] != '\r' && b[0] != ' ' && b[0] != '\t' {
+			break
+		}
+		_, _ = br.ReadByte()
+	}
+
+	prefix, _ := br.Peek(len("Package:"))
+	if bytes.EqualFold(prefix, []byte("Package:")) {
+		return readStatus(br)
+	}
+
+	return readDpkgQuery(br)
+}
+
+// readStatus читает установленные пакеты из файла состояния dpkg.
+func readStatus(r io.Reader) ([]InstalledPackage, error) {
+	result := make([]InstalledPackage, 0)
+	err := ReadParagraphs(r, func(p Paragraph) error {
+		status := strings.Fields(p.Get("Status"))
+		if len(status) == 0 || !installedStates[status[len(status)-1]] || p.Get("Version") == "" {
+			return nil
+		}
+		result = append(result, InstalledPackage{
+			Package:      p.Get("Package"),
+			Version:      p.Get("Version"),
+			Architecture: p.Get("Architecture"),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// readDpkgQuery читает установленные пакеты из вывода dpkg-query -W. Пакеты без
+// версии (известные dpkg, но не установленные) пропускаются.
+func readDpkgQuery(r io.Reader) ([]InstalledPackage, error) {
+	result := make([]InstalledPackage, 0)
+	sc := bufio.NewScanner(r)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		if len(fields) > 3 {
+			return nil, fmt.Errorf("строка %d: ожидается \"пакет версия [архитектура]\": %q", lineNo, sc.Text())
+		}
+
+		name, arch, _ := strings.Cut(fields[0], ":")
+		if len(fields) == 3 {
+			arch = fields[2]
+		}
+		if _, err := ParseVersion(fields[1]); err != nil {
+			return nil, fmt.Errorf("строка %d: %w", lineNo, err)
+		}
+		result = append(result, InstalledPackage{Package: name, Version: fields[1], Architecture: arch})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,72 @@
+package deb
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadInstalled(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []InstalledPackage
+		wantErr bool
+	}{
+		{
+			name: "dpkg status",
+			input: `
+Package: libc6
+Status: install ok installed
+Architecture: amd64
+Multi-Arch: same
+Version: 2.36-9+deb12u4
+Description: GNU C Library: Shared libraries
+ Contains the standard libraries.
+
+Package: oldpkg
+Status: deinstall ok config-files
+Architecture: amd64
+Version: 1.0-1
+
+Package: tzdata
+Status: install ok unpacked
+Architecture: all
+Version: 2024a-0+deb12u1
+`,
+			want: []InstalledPackage{
+				{Package: "libc6", Version: "2.36-9+deb12u4", Architecture: "amd64"},
+				{Package: "tzdata", Version: "2024a-0+deb12u1", Architecture: "all"},
+			},
+		},
+		{
+			name:  "dpkg-query default format",
+			input: "libc6:amd64\t2.36-9+deb12u4\nlibc6:i386\t2.36-9+deb12u4\nbash\t5.2.15-2+b2\nnotinstalled\t\n",
+			want: []InstalledPackage{
+				{Package: "libc6", Version: "2.36-9+deb12u4", Architecture: "amd64"},
+				{Package: "libc6", Version: "2.36-9+deb12u4", Architecture: "i386"},
+				{Package: "bash", Version: "5.2.15-2+b2"},
+			},
+		},
+		{
+			name:  "dpkg-query with architecture column",
+			input: "bash 5.2.15-2+b2 amd64\n",
+			want:  []InstalledPackage{{Package: "bash", Version: "5.2.15-2+b2", Architecture: "amd64"}},
+		},
+		{name: "empty", input: "\n\n", want: []InstalledPackage{}},
+		{name: "invalid version", input: "bash 5.2_1\n", wantErr: true},
+		{name: "too many fields", input: "bash 5.2 amd64 extra\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadInstalled(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadInstalled() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadInstalled() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
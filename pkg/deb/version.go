@@ -0,0 +1,199 @@
+package deb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version версия пакета Debian: [эпоха:]версия_апстрима[-ревизия].
+type Version struct {
+	Epoch    int    // Эпоха (0, если не указана)
+	Upstream string // Версия апстрима
+	Revision string // Ревизия Debian (пустая для нативных пакетов)
+}
+
+// ParseVersion разбирает и проверяет версию пакета по правилам Debian Policy (5.6.12):
+// эпоха — число, версия апстрима начинается с цифры и состоит из [A-Za-z0-9.+~-:],
+// ревизия — из [A-Za-z0-9.+~].
+func ParseVersion(s string) (Version, error) {
+	v := parseVersion(s)
+	s = strings.TrimSpace(s)
+
+	if s == "" {
+		return v, fmt.Errorf("пустая версия")
+	}
+	if strings.ContainsAny(s, " \t\n") {
+		return v, fmt.Errorf("версия %q содержит пробельные символы", s)
+	}
+	if epoch, _, ok := strings.Cut(s, ":"); ok {
+		n, err := strconv.Atoi(epoch)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("некорректная эпоха в версии %q", s)
+		}
+	}
+	if v.Upstream == "" {
+		return v, fmt.Errorf("пустая версия апстрима в %q", s)
+	}
+	if strings.HasSuffix(s, "-") {
+		return v, fmt.Errorf("пустая ревизия в версии %q", s)
+	}
+	if !isDigit(v.Upstream[0]) {
+		return v, fmt.Errorf("версия %q не начинается с цифры", s)
+	}
+	for _, c := range []byte(v.Upstream) {
+		if !isVersionChar(c) && c != '-' && c != ':' {
+			return v, fmt.Errorf("недопустимый символ %q в версии %q", c, s)
+		}
+	}
+	for _, c := range []byte(v.Revision) {
+		if !isVersionChar(c) {
+			return v, fmt.Errorf("недопустимый символ %q в ревизии %q", c, s)
+		}
+	}
+
+	return v, nil
+}
+
+// parseVersion разбирает версию без проверок: эпоха — до первого ':', ревизия —
+// после последнего '-'.
+func parseVersion(s string) Version {
+	var v Version
+	s = strings.TrimSpace(s)
+
+	if epoch, rest, ok := strings.Cut(s, ":"); ok {
+		v.Epoch, _ = strconv.Atoi(epoch)
+		s = rest
+	}
+	if i := strings.LastIndexByte(s, '-'); i >= 0 {
+		v.Revision = s[i+1:]
+		s = s[:i]
+	}
+	v.Upstream = s
+
+	return v
+}
+
+// String возвращает версию в каноническом виде (эпоха 0 не выводится).
+func (v Version) String() string {
+	s := v.Upstream
+	if v.Epoch != 0 {
+		s = strconv.Itoa(v.Epoch) + ":" + s
+	}
+	if v.Revision != "" {
+		s += "-" + v.Revision
+	}
+
+	return s
+}
+
+// Compare сравнивает версии по правилам dpkg. Возвращает -1, если v < other,
+// 0 при равенстве и 1, если v > other.
+func (v Version) Compare(other Version) int {
+	switch {
+	case v.Epoch < other.Epoch:
+		return -1
+	case v.Epoch > other.Epoch:
+		return 1
+	}
+
+	if r := compareFragment(v.Upstream, other.Upstream); r != 0 {
+		return r
+	}
+
+	return compareFragment(v.Revision, other.Revision)
+}
+
+// CompareVersions сравнивает две строки версий так же, как "dpkg --compare-versions".
+// Некорректные версии сравниваются без ошибки, как это делает dpkg.
+// Возвращает -1, если a < b, 0 при равенстве и 1, если a > b.
+func CompareVersions(a, b string) int {
+	return parseVersion(a).Compare(parseVersion(b))
+}
+
+// compareFragment сравнивает версии апстрима или ревизии алгоритмом dpkg (verrevcmp):
+// нечисловые части сравниваются посимвольно (буквы раньше прочих символов, '~' раньше
+// всего, даже конца строки), числовые — как числа.
+func compareFragment(a, b string) int {
+	i, j := 0, 0
+
+	for i < len(a) || j < len(b) {
+		for (i < len(a) && !isDigit(a[i])) || (j < len(b) && !isDigit(b[j])) {
+			ac, bc := charOrder(a, i), charOrder(b, j)
+			if ac != bc {
+				return sign(ac - bc)
+			}
+			i++
+			j++
+		}
+
+		for i < len(a) && a[i] == '0' {
+			i++
+		}
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+
+		firstDiff := 0
+		for i < len(a) && isDigit(a[i]) && j < len(b) && isDigit(b[j]) {
+			if firstDiff == 0 {
+				firstDiff = int(a[i]) - int(b[j])
+			}
+			i++
+			j++
+		}
+
+		if i < len(a) && isDigit(a[i]) {
+			return 1
+		}
+		if j < len(b) && isDigit(b[j]) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return sign(firstDiff)
+		}
+	}
+
+	return 0
+}
+
+// charOrder вес символа s[i] при сравнении нечисловых частей версии.
+func charOrder(s string, i int) int {
+	if i >= len(s) {
+		return 0
+	}
+
+	c := s[i]
+	switch {
+	case isDigit(c):
+		return 0
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		return int(c)
+	case c == '~':
+		return -1
+	}
+
+	return int(c) + 256
+}
+
+// isDigit проверяет, является ли символ десятичной цифрой.
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isVersionChar проверяет, допустим ли символ в версии апстрима и ревизии.
+func isVersionChar(c byte) bool {
+	return isDigit(c) || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '.' || c == '+' || c == '~'
+}
+
+// sign возвращает знак числа: -1, 0 или 1.
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,226 @@
+package deb
+
+import "testing"
+
+// Тестовые векторы из набора тестов dpkg (Dpkg_Version.t, t-version.c);
+// каждый проверен с помощью "dpkg --compare-versions".
+var versionVectors = []struct {
+	a, b string
+	want int
+}{
+	{"1.0", "1.0", 0},
+	{"1.0", "2.0", -1},
+	{"2.0", "1.0", 1},
+	{"1.0-1", "2.0-2", -1},
+	{"2.2~rc-4", "2.2-1", -1},
+	{"2.2-1", "2.2~rc-4", 1},
+	{"1.0000-1", "1.0-1", 0},
+	{"1", "0:1", 0},
+	{"0", "0:0-0", 0},
+	{"2:2.5", "1:7.5", 1},
+	{"1:0foo", "0foo", 1},
+	{"0:0foo", "0foo", 0},
+	{"0foo", "0foo", 0},
+	{"0foo-0", "0foo", 0},
+	{"0foo", "0foo-0", 0},
+	{"0foo", "0fo", 1},
+	{"0foo-0", "0foo+", -1},
+	{"0foo~1", "0foo", -1},
+	{"0foo~foo+Bar", "0foo~foo+bar", -1},
+	{"0foo~~", "0foo~", -1},
+	{"1~", "1", -1},
+	{"12345+that-really-is-some-ver-0", "12345+that-really-is-some-ver-10", -1},
+	{"0foo-0", "0foo-01", -1},
+	{"0foo.bar", "0foobar", 1},
+	{"0foo.bar", "0foo1bar", 1},
+	{"0foo.bar", "0foo0bar", 1},
+	{"0foo1bar-1", "0foobar-1", -1},
+	{"0foo2.0", "0foo2", 1},
+	{"0foo2.0.0", "0foo2.10.0", -1},
+	{"0foo2.0", "0foo2.0.0", -1},
+	{"0foo2.0", "0foo2.10", -1},
+	{"0foo2.1", "0foo2.10", -1},
+	{"1.09", "1.9", 0},
+	{"1.0.8+nmu1", "1.0.8", 1},
+	{"3.11", "3.10+nmu1", 1},
+	{"0.9j-20080306-4", "0.9i-20070324-2", 1},
+	{"1.2.0~b7-1", "1.2.0~b6-1", 1},
+	{"1.011-1", "1.06-2", 1},
+	{"0.0.9+dfsg1-1", "0.0.8+dfsg1-3", 1},
+	{"4.6.99+svn6582-1", "4.6.99+svn6496-1", 1},
+	{"53", "52", 1},
+	{"0.9.9~pre122-1", "0.9.9~pre111-1", 1},
+	{"2:2.3.2-2+lenny2", "2:2.3.2-2", 1},
+	{"1:3.8.1-1", "3.8.GA-1", 1},
+	{"1.0.1+gpl-1", "1.0.1-2", 1},
+	{"1a", "1000a", -1},
+	{"1.0~rc1", "1.0~rc1~1", 1},
+	{"1.0~~", "1.0~~a", -1},
+	{"1.0~", "1.0", -1},
+	{"1.0", "1.0+", -1},
+	{"1.0+", "1.0.", -1},
+	{"1.0a", "1.0+", -1},
+	{"1.0-1~bpo1", "1.0-1", -1},
+	{"1.0-1+b1", "1.0-1", 1},
+	{"1.0-1ubuntu1", "1.0-1", 1},
+	{"10", "9", 1},
+	{"1.10", "1.9", 1},
+	{"1:1.0", "2.0", 1},
+	{"0:1.0", "1.0", 0},
+	{"1.0-0", "1.0", 0},
+	{"1.0.0", "1.0", 1},
+	{"1.0-a", "1.0-0", 1},
+	{"7.6p2-4", "7.6-0", 1},
+	{"1.0.3-3", "1.0-1", 1},
+	{"1.3", "1.2.2-2", 1},
+	{"1.3", "1.2.2", 1},
+	{"0-pre", "0-pre", 0},
+	{"0-pre", "0-pree", -1},
+	{"1.1.6r2-2", "1.1.6r-1", 1},
+	{"2.6b2-1", "2.6b-2", 1},
+	{"98.1p5-1", "98.1-pre2-b6-2", -1},
+	{"0.4a6-2", "0.4-1", 1},
+	{"1:3.0.5-2", "1:3.0.5.1", -1},
+	{"1:0.4", "10.3", 1},
+	{"1:1.25-4", "1:1.25-8", -1},
+	{"0:1.18.36", "1.18.36", 0},
+	{"1.18.36", "1.18.35", 1},
+	{"0:1.18.36", "1.18.35", 1},
+	{"9:1.18.36:5.4-20", "10:0.5.1-22", -1},
+	{"9:1.18.36:5.4-20", "9:1.18.36:5.5-1", -1},
+	{"9:1.18.36:5.4-20", "9:1.18.37:4.3-22", -1},
+	{"1.18.36-0.17.35-18", "1.18.36-19", 1},
+	{"1:1.2.13-3", "1:1.2.13-3.1", -1},
+	{"2.0.7pre1-4", "2.0.7r-1", -1},
+	{"0:0-0", "0", 0},
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, tt := range versionVectors {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			if got := CompareVersions(tt.a, tt.b); got != tt.want {
+				t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+			if got := CompareVersions(tt.b, tt.a); got != -tt.want {
+				t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+			}
+		})
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		want    Version
+		wantErr bool
+	}{
+		{
+			name:    "upstream only",
+			version: "1.0",
+			want:    Version{Upstream: "1.0"},
+		},
+		{
+			name:    "epoch, upstream and revision",
+			version: "2:1.0.3-4ubuntu1",
+			want:    Version{Epoch: 2, Upstream: "1.0.3", Revision: "4ubuntu1"},
+		},
+		{
+			name:    "hyphen in upstream",
+			version: "1.0-rc1-2",
+			want:    Version{Upstream: "1.0-rc1", Revision: "2"},
+		},
+		{
+			name:    "colon in upstream",
+			version: "1:2:3-1",
+			want:    Version{Epoch: 1, Upstream: "2:3", Revision: "1"},
+		},
+		{
+			name:    "tilde and plus",
+			version: "1.0~beta+dfsg-1~bpo12+1",
+			want:    Version{Upstream: "1.0~beta+dfsg", Revision: "1~bpo12+1"},
+		},
+		{
+			name:    "surrounding spaces",
+			version: " 1.0-1 ",
+			want:    Version{Upstream: "1.0", Revision: "1"},
+		},
+		{
+			name:    "empty",
+			version: "",
+			wantErr: true,
+		},
+		{
+			name:    "empty upstream",
+			version: "1:-1",
+			wantErr: true,
+		},
+		{
+			name:    "empty revision",
+			version: "1.0-",
+			wantErr: true,
+		},
+		{
+			name:    "non-numeric epoch",
+			version: "a:1.0",
+			wantErr: true,
+		},
+		{
+			name:    "negative epoch",
+			version: "-1:1.0",
+			wantErr: true,
+		},
+		{
+			name:    "upstream starts with letter",
+			version: "a1.0",
+			wantErr: true,
+		},
+		{
+			name:    "embedded space",
+			version: "1.0 1",
+			wantErr: true,
+		},
+		{
+			name:    "invalid character in upstream",
+			version: "1.0_1",
+			wantErr: true,
+		},
+		{
+			name:    "invalid character in revision",
+			version: "1.0-1:2",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseVersion(tt.version)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseVersion(%q) error = %v, wantErr %v", tt.version, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseVersion(%q) = %+v, want %+v", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersion_String(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"1.0", "1.0"},
+		{"0:1.0-1", "1.0-1"},
+		{"3:1.0-1", "3:1.0-1"},
+		{"1.0-1-2", "1.0-1-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			if got := parseVersion(tt.version).String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
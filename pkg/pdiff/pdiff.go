@@ -0,0 +1,309 @@
+// Package pdiff формирует построчные различия между текстовыми файлами в виде
+// ed-скриптов — в формате, который использует механизм PDiff в apt (Packages.diff).
+package pdiff
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// DefaultMaxEdits ограничение на число вставленных и удалённых строк, после которого
+// поиск минимальной разницы прекращается, и изменённый участок заменяется целиком.
+const DefaultMaxEdits = 4000
+
+// ErrDotLine строка из одной точки завершает ввод текста в ed и не может быть вставлена.
+var ErrDotLine = fmt.Errorf("добавляемая строка состоит из одной точки")
+
+// hunk изменённый участок: строки [oldStart, oldEnd) старого файла заменяются
+// строками [newStart, newEnd) нового. Нумерация строк с нуля.
+type hunk struct {
+	oldStart, oldEnd int
+	newStart, newEnd int
+}
+
+// EdScript возвращает ed-скрипт, превращающий old в new (аналог "diff --ed").
+// Команды следуют от конца файла к началу, поэтому номера строк в каждой команде
+// относятся к исходному файлу. Ожидается, что оба текста завершаются переводом строки.
+func EdScript(old, new []byte) ([]byte, error) {
+	a, b := splitLines(old), splitLines(new)
+	hunks := diff(a, b, DefaultMaxEdits)
+
+	var buf bytes.Buffer
+	for i := len(hunks) - 1; i >= 0; i-- {
+		h := hunks[i]
+
+		switch {
+		case h.newStart == h.newEnd:
+			buf.WriteString(lineRange(h.oldStart+1, h.oldEnd))
+			buf.WriteString("d\n")
+			continue
+		case h.oldStart == h.oldEnd:
+			buf.WriteString(strconv.Itoa(h.oldStart))
+			buf.WriteString("a\n")
+		default:
+			buf.WriteString(lineRange(h.oldStart+1, h.oldEnd))
+			buf.WriteString("c\n")
+		}
+
+		for _, line := range b[h.newStart:h.newEnd] {
+			if string(line) == "." {
+				return nil, ErrDotLine
+			}
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(".\n")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Apply применяет ed-скрипт, сформированный EdScript, к тексту old.
+// Поддерживаются команды a, c и d — то же подмножество, что и у apt.
+func Apply(old, script []byte) ([]byte, error) {
+	lines := splitLines(old)
+	cmds := splitLines(script)
+
+	for i := 0; i < len(cmds); i++ {
+		cmd := string(cmds[i])
+		if cmd == "" {
+			return nil, fmt.Errorf("пустая команда в строке %d", i+1)
+		}
+
+		op := cmd[len(cmd)-1]
+		start, end, err := parseRange(cmd[:len(cmd)-1])
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: %w", i+1, err)
+		}
+		if end > len(lines) || start > end+1 {
+			return nil, fmt.Errorf("строка %d: диапазон %d,%d вне файла из %d строк", i+1, start, end, len(lines))
+		}
+
+		var text [][]byte
+		if op == 'a' || op == 'c' {
+			for i++; i < len(cmds) && string(cmds[i]) != "."; i++ {
+				text = append(text, cmds[i])
+			}
+			if i == len(cmds) {
+				return nil, fmt.Errorf("не найден конец вставляемого текста")
+			}
+		}
+
+		switch op {
+		case 'a':
+			lines = splice(lines, end, end, text)
+		case 'c':
+			lines = splice(lines, start-1, end, text)
+		case 'd':
+			lines = splice(lines, start-1, end, nil)
+		default:
+			return nil, fmt.Errorf("неизвестная команда %q", cmd)
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, line := range lines {
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes(), nil
+}
+
+// diff находит изменённые участки между a и b алгоритмом Майерса. Общие начало
+// и конец отбрасываются заранее. Если число правок превышает maxEdits, весь
+// отличающийся участок возвращается одним изменением.
+func diff(a, b [][]byte, maxEdits int) []hunk {
+	// Строки заменяем целыми идентификаторами: сравнение становится дешёвым
+	ids := make(map[string]int)
+	id := func(lines [][]byte) []int {
+		result := make([]int, len(lines))
+		for i, line := range lines {
+			v, ok := ids[string(line)]
+			if !ok {
+				v = len(ids)
+				ids[string(line)] = v
+			}
+			result[i] = v
+		}
+		return result
+	}
+	x, y := id(a), id(b)
+
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+	x, y = x[prefix:len(x)-suffix], y[prefix:len(y)-suffix]
+
+	if len(x) == 0 && len(y) == 0 {
+		return nil
+	}
+
+	hunks, ok := myers(x, y, maxEdits)
+	if !ok {
+		hunks = []hunk{{0, len(x), 0, len(y)}}
+	}
+	for i := range hunks {
+		hunks[i].oldStart += prefix
+		hunks[i].oldEnd += prefix
+		hunks[i].newStart += prefix
+		hunks[i].newEnd += prefix
+	}
+
+	return hunks
+}
+
+// myers реализует жадный алгоритм Майерса поиска кратчайшего сценария правок.
+// Для восстановления пути хранится только диапазон [-d, d] диагоналей на каждом шаге,
+// поэтому расход памяти — O(D²). Второе значение равно false, если число правок
+// превысило maxEdits.
+func myers(a, b []int, maxEdits int) ([]hunk, bool) {
+	n, m := len(a), len(b)
+	limit := n + m
+	if limit > maxEdits {
+		limit = maxEdits
+	}
+
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	trace := make([][]int, 0, 16)
+
+	found := false
+	for d := 0; d <= limit && !found; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+			}
+		}
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+	}
+	if !found {
+		return nil, false
+	}
+
+	// Восстанавливаем путь с конца, собирая правки в обратном порядке
+	type edit struct {
+		del  bool
+		x, y int
+	}
+	edits := make([]edit, 0, len(trace))
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1]
+		get := func(k int) int { return prev[k+d-1] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && get(k-1) < get(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := get(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+		}
+		if x == prevX {
+			edits = append(edits, edit{del: false, x: prevX, y: prevY})
+		} else {
+			edits = append(edits, edit{del: true, x: prevX, y: prevY})
+		}
+		x, y = prevX, prevY
+	}
+
+	// Объединяем соседние правки в участки
+	hunks := make([]hunk, 0)
+	for i := len(edits) - 1; i >= 0; i-- {
+		e := edits[i]
+		h := hunk{oldStart: e.x, oldEnd: e.x, newStart: e.y, newEnd: e.y}
+		if e.del {
+			h.oldEnd++
+		} else {
+			h.newEnd++
+		}
+
+		if len(hunks) > 0 {
+			last := &hunks[len(hunks)-1]
+			if last.oldEnd == h.oldStart && last.newEnd == h.newStart {
+				last.oldEnd, last.newEnd = h.oldEnd, h.newEnd
+				continue
+			}
+		}
+		hunks = append(hunks, h)
+	}
+
+	return hunks, true
+}
+
+// splitLines разбивает текст на строки без символа перевода строки.
+func splitLines(data []byte) [][]byte {
+	if len(data) == 0 {
+		return nil
+	}
+
+	lines := bytes.Split(data, []byte("\n"))
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// splice заменяет элементы lines[from:to] на text.
+func splice(lines [][]byte, from, to int, text [][]byte) [][]byte {
+	result := make([][]byte, 0, len(lines)-(to-from)+len(text))
+	result = append(result, lines[:from]...)
+	result = append(result, text...)
+
+	return append(result, lines[to:]...)
+}
+
+// lineRange формирует адрес ed: "N" или "N,M".
+func lineRange(start, end int) string {
+	if start == end {
+		return strconv.Itoa(start)
+	}
+
+	return strconv.Itoa(start) + "," + strconv.Itoa(end)
+}
+
+// parseRange разбирает адрес ed "N" или "N,M".
+func parseRange(s string) (int, int, error) {
+	first, second, hasSecond := bytes.Cut([]byte(s), []byte(","))
+
+	start, err := strconv.Atoi(string(first))
+	if err != nil {
+		return 0, 0, fmt.Errorf("некорректный адрес %q", s)
+	}
+	if !hasSecond {
+		return start, start, nil
+	}
+
+	end, err := strconv.Atoi(string(second))
+	if err != nil {
+		return 0, 0, fmt.Errorf("некорректный адрес %q", s)
+	}
+
+	return start, end, nil
+}
@@ -0,0 +1,150 @@
+package pdiff
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEdScript(t *testing.T) {
+	tests := []struct {
+		name   string
+		old    string
+		new    string
+		script string
+	}{
+		{
+			name: "identical files",
+			old:  "a\nb\nc\n",
+			new:  "a\nb\nc\n",
+		},
+		{
+			name:   "delete line",
+			old:    "a\nb\nc\n",
+			new:    "a\nc\n",
+			script: "2d\n",
+		},
+		{
+			name:   "append at the beginning",
+			old:    "b\nc\n",
+			new:    "a\nb\nc\n",
+			script: "0a\na\n.\n",
+		},
+		{
+			name:   "append at the end",
+			old:    "a\n",
+			new:    "a\nb\nc\n",
+			script: "1a\nb\nc\n.\n",
+		},
+		{
+			name:   "change range",
+			old:    "a\nb\nc\nd\n",
+			new:    "a\nx\ny\nd\n",
+			script: "2,3c\nx\ny\n.\n",
+		},
+		{
+			name:   "several hunks in reverse order",
+			old:    "a\nb\nc\nd\ne\n",
+			new:    "a\nB\nc\ne\nf\n",
+			script: "5a\nf\n.\n4d\n2c\nB\n.\n",
+		},
+		{
+			name:   "from empty file",
+			old:    "",
+			new:    "a\nb\n",
+			script: "0a\na\nb\n.\n",
+		},
+		{
+			name:   "to empty file",
+			old:    "a\nb\n",
+			new:    "",
+			script: "1,2d\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, err := EdScript([]byte(tt.old), []byte(tt.new))
+			if err != nil {
+				t.Fatalf("EdScript() error = %v", err)
+			}
+			if string(script) != tt.script {
+				t.Errorf("EdScript() = %q, want %q", script, tt.script)
+			}
+
+			result, err := Apply([]byte(tt.old), script)
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if string(result) != tt.new {
+				t.Errorf("Apply() = %q, want %q", result, tt.new)
+			}
+		})
+	}
+}
+
+func TestEdScript_packages(t *testing.T) {
+	stanza := func(name, version string) string {
+		return "Package: " + name + "\nVersion: " + version + "\nArchitecture: amd64\n" +
+			"Filename: pool/" + name + "_" + version + "_amd64.deb\nDescription: " + name + "\n .\n long\n\n"
+	}
+
+	var old, new strings.Builder
+	for i := 0; i < 200; i++ {
+		name := "pkg" + strings.Repeat("x", i%7) + string(rune('a'+i%26))
+		old.WriteString(stanza(name, "1.0"))
+		switch {
+		case i%17 == 0:
+		case i%23 == 0:
+			new.WriteString(stanza(name, "2.0"))
+		default:
+			new.WriteString(stanza(name, "1.0"))
+		}
+		if i%31 == 0 {
+			new.WriteString(stanza(name+"-extra", "0.1"))
+		}
+	}
+
+	script, err := EdScript([]byte(old.String()), []byte(new.String()))
+	if err != nil {
+		t.Fatalf("EdScript() error = %v", err)
+	}
+	if len(script) > new.Len()/4 {
+		t.Errorf("EdScript() size = %d, want a small patch", len(script))
+	}
+
+	result, err := Apply([]byte(old.String()), script)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if string(result) != new.String() {
+		t.Errorf("Apply() result differs from the new file")
+	}
+}
+
+func TestEdScript_fallback(t *testing.T) {
+	old := strings.Repeat("old\n", DefaultMaxEdits)
+	new := strings.Repeat("new\n", DefaultMaxEdits)
+
+	script, err := EdScript([]byte(old), []byte(new))
+	if err != nil {
+		t.Fatalf("EdScript() error = %v", err)
+	}
+	if !strings.HasPrefix(string(script), "1,4000c\n") {
+		t.Errorf("EdScript() = %q..., want a single change command", script[:16])
+	}
+
+	result, err := Apply([]byte(old), script)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if string(result) != new {
+		t.Errorf("Apply() result differs from the new file")
+	}
+}
+
+func TestEdScript_dotLine(t *testing.T) {
+	if _, err := EdScript([]byte("a\n"), []byte("a\n.\n")); !errors.Is(err, ErrDotLine) {
+		t.Errorf("EdScript() error = %v, want %v", err, ErrDotLine)
+	}
+}